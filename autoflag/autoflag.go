@@ -5,6 +5,8 @@
 // will result in the flags -conf and -confdir being added to your program's flags.
 //
 // Then you can call autoflag.Load to either load the file in -conf or all files in -confdir.
+// Values given with -confset key=value flags, e.g. -confset redis.timeout=30, are set on top of what
+// is loaded.
 //
 // Note that autoflag.Load will call flag.Parse if you haven't already parsed the flags.
 package autoflag
@@ -12,6 +14,8 @@ package autoflag
 import (
 	"errors"
 	"flag"
+	"fmt"
+	"strings"
 
 	"github.com/EverythingMe/gofigure"
 )
@@ -22,27 +26,56 @@ var ConfigDir string
 // ConfigFile keeps the value of the -conf flag if it was set
 var ConfigFile string
 
+// Settings keeps the key=value pairs of the -confset flags, in the order they were given
+var Settings settings
+
+// settings is a repeatable flag of key=value pairs
+type settings []string
+
+func (s *settings) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *settings) Set(value string) error {
+	if !strings.Contains(value, "=") {
+		return fmt.Errorf("expected key=value, got %s", value)
+	}
+	*s = append(*s, value)
+	return nil
+}
+
 // init automatically adds the flags to go/flag
 func init() {
 	flag.StringVar(&ConfigDir, "confdir", "", "If set, recursively read all config files in -confdir")
 	flag.StringVar(&ConfigFile, "conf", "", "If set, read just one config file in -conf")
+	flag.Var(&Settings, "confset", "Set a config value on top of the config files, as a dotted key=value, e.g. redis.timeout=30. Can be repeated")
 }
 
 // Load either loads the file specified in -conf or the dir in -confdir with loader l to conf
 //
-// Note that if both are set, we read just the conf file and exit
+// Note that if both are set, we read just the conf file and exit. The -confset flags are set on top
+// of the files before the config is bound, normalized and validated, see gofigure.Settings
 func Load(l *gofigure.Loader, conf interface{}) error {
 
 	if !flag.Parsed() {
 		flag.Parse()
 	}
 
-	if ConfigFile != "" {
-		return l.LoadFile(conf, ConfigFile)
-	}
-	if ConfigDir != "" {
-		return l.LoadRecursive(conf, ConfigDir)
+	loader := *l
+	if len(Settings) > 0 {
+		settings, err := gofigure.Settings(conf, Settings...)
+		if err != nil {
+			return fmt.Errorf("gofigure.autoflag: -confset: %s", err)
+		}
+		loader.Resolvers = append(append([]gofigure.Resolver(nil), l.Resolvers...), settings)
 	}
 
-	return errors.New("gofigure.autoflag: No -conf or -confdir given")
+	switch {
+	case ConfigFile != "":
+		return loader.LoadFile(conf, ConfigFile)
+	case ConfigDir != "":
+		return loader.LoadRecursive(conf, ConfigDir)
+	default:
+		return errors.New("gofigure.autoflag: No -conf or -confdir given")
+	}
 }
@@ -0,0 +1,751 @@
+package gofigure
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// bind assigns a generic document value, as produced by decoding into an interface{}, to the
+// value config points to. Like the decoders do, it only overwrites what the document sets, so
+// values already in config act as defaults.
+func bind(src interface{}, config interface{}) error {
+	return (&binder{}).bindConfig(src, config)
+}
+
+// BindError is returned when a document value cannot be assigned to the config field it maps to
+type BindError struct {
+	// Path is the dotted path of the value in the document
+	Path string
+	// Value is the offending document value
+	Value interface{}
+	// Type is the type of the field it should have been assigned to
+	Type reflect.Type
+	// Err is the underlying error, if any
+	Err error
+}
+
+// Unwrap returns the underlying error
+func (e *BindError) Unwrap() error {
+	return e.Err
+}
+
+func (e *BindError) Error() string {
+	path := e.Path
+	if path == "" {
+		path = "(root)"
+	}
+	msg := fmt.Sprintf("gofigure: cannot decode %T into %s at %s", e.Value, e.Type, path)
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// binder walks generic documents and typed config values side by side, assigning the former to
+// the latter
+type binder struct {
+	// lenient makes the binder log and skip values it cannot assign, rather than failing
+	lenient bool
+
+	// weak makes the binder coerce values between compatible types, see Loader.WeaklyTyped
+	weak bool
+
+	// match is how document keys are matched to struct fields
+	match KeyMatching
+
+	// strictKeys makes keys matching no field errors, even in lenient mode
+	strictKeys bool
+
+	// report, if not nil, collects the values skipped in lenient mode
+	report *Report
+}
+
+// durationType is special cased so durations can be written as strings in every format
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// bindConfig assigns src to the value config points to
+func (b *binder) bindConfig(src interface{}, config interface{}) error {
+	v := reflect.ValueOf(config)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("gofigure: cannot load into non-pointer %T", config)
+	}
+	return b.bind(src, v.Elem(), "")
+}
+
+// bindChild binds a value nested in a mapping or list. In lenient mode, errors are logged and the
+// value is skipped
+func (b *binder) bindChild(src interface{}, dst reflect.Value, path string) error {
+	err := b.bind(src, dst, path)
+	if err != nil && b.lenient && !errors.Is(err, ErrUnknownKey) {
+		b.skip(path, err)
+		return nil
+	}
+	return err
+}
+
+// skip reports a value that is skipped because it cannot be bound
+func (b *binder) skip(path string, err error) {
+	b.report.warn(Warning{
+		Key:     path,
+		Message: "skipping bad value: " + err.Error(),
+		Err:     err,
+	})
+}
+
+// bind assigns src to dst. The path is that of src in the document, used for error reporting
+func (b *binder) bind(src interface{}, dst reflect.Value, path string) error {
+
+	if src == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+
+	if handled, err := unmarshalHook(src, dst); handled {
+		if err != nil {
+			return &BindError{path, src, dst.Type(), err}
+		}
+		return nil
+	}
+
+	if text, ok := scalarText(src); ok {
+		if handled, err := unmarshalText(text, dst); handled {
+			if err != nil {
+				return &BindError{path, src, dst.Type(), err}
+			}
+			return nil
+		}
+	}
+
+	if dst.Type() == durationType {
+		return b.bindDuration(src, dst, path)
+	}
+
+	switch dst.Kind() {
+	case reflect.Ptr:
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return b.bind(src, dst.Elem(), path)
+
+	case reflect.Interface:
+		sv := reflect.ValueOf(src)
+		if !sv.Type().AssignableTo(dst.Type()) {
+			return &BindError{path, src, dst.Type(), nil}
+		}
+		dst.Set(sv)
+		return nil
+
+	case reflect.Struct:
+		m, ok := src.(map[string]interface{})
+		if !ok {
+			return &BindError{path, src, dst.Type(), nil}
+		}
+		return b.bindStruct(m, dst, path)
+
+	case reflect.Map:
+		m, ok := src.(map[string]interface{})
+		if !ok {
+			return &BindError{path, src, dst.Type(), nil}
+		}
+		return b.bindMap(m, dst, path)
+
+	case reflect.Slice, reflect.Array:
+		l, ok := src.([]interface{})
+		if !ok {
+			if _, isScalar := scalarText(src); !isScalar || !b.weak {
+				return &BindError{path, src, dst.Type(), nil}
+			}
+			l = []interface{}{src}
+		}
+		return b.bindList(l, dst, path)
+	}
+
+	return b.bindScalar(src, dst, path)
+}
+
+// bindStruct assigns the keys of a mapping to the fields of a struct they match
+func (b *binder) bindStruct(m map[string]interface{}, dst reflect.Value, path string) error {
+	fields := structFields(dst.Type())
+
+	// keys matching an alias are bound first, so that if a document has both the old and the new
+	// name of a field, the new one wins
+	var keys, aliased []string
+	for key := range m {
+		f, alias := fields.match(key, b.match)
+		switch {
+		case f == nil:
+			if b.strictKeys {
+				return &BindError{joinPath(path, key), m[key], dst.Type(), ErrUnknownKey}
+			}
+		case alias:
+			aliased = append(aliased, key)
+		default:
+			keys = append(keys, key)
+		}
+	}
+
+	for _, key := range append(aliased, keys...) {
+		v := m[key]
+		f, _ := fields.match(key, b.match)
+
+		fv, err := fieldByIndex(dst, f.index)
+		if err != nil {
+			return &BindError{joinPath(path, key), v, dst.Type(), err}
+		}
+		if err := b.bindChild(v, fv, joinPath(path, key)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bindMap assigns the entries of a mapping to a map, converting keys to the map's key type
+func (b *binder) bindMap(m map[string]interface{}, dst reflect.Value, path string) error {
+	t := dst.Type()
+	if dst.IsNil() {
+		dst.Set(reflect.MakeMapWithSize(t, len(m)))
+	}
+
+	for key, v := range m {
+		kv := reflect.New(t.Key()).Elem()
+		if err := bindKey(key, kv); err != nil {
+			return &BindError{joinPath(path, key), key, t.Key(), err}
+		}
+
+		ev := reflect.New(t.Elem()).Elem()
+		if err := b.bind(v, ev, joinPath(path, key)); err != nil {
+			if b.lenient && !errors.Is(err, ErrUnknownKey) {
+				b.skip(joinPath(path, key), err)
+				continue
+			}
+			return err
+		}
+		dst.SetMapIndex(kv, ev)
+	}
+	return nil
+}
+
+// bindList assigns the items of a list to a slice or array
+func (b *binder) bindList(l []interface{}, dst reflect.Value, path string) error {
+	if dst.Kind() == reflect.Array {
+		if len(l) > dst.Len() {
+			return &BindError{path, l, dst.Type(), fmt.Errorf("too many items (%d)", len(l))}
+		}
+		dst.Set(reflect.Zero(dst.Type()))
+	} else {
+		dst.Set(reflect.MakeSlice(dst.Type(), len(l), len(l)))
+	}
+
+	for i, v := range l {
+		if err := b.bindChild(v, dst.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bindDuration assigns a time.Duration, parsing strings like "30s" or "5m". Plain numbers are
+// taken as nanoseconds, which is what decoding them into an int64 would give
+func (b *binder) bindDuration(src interface{}, dst reflect.Value, path string) error {
+	s, ok := src.(string)
+	if !ok {
+		return b.bindScalar(src, dst, path)
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return &BindError{path, src, dst.Type(), err}
+	}
+	dst.SetInt(int64(d))
+	return nil
+}
+
+// weaken coerces a scalar to a type that can be assigned to a value of kind k: strings are parsed
+// as numbers and bools, numbers and bools are formatted as strings, numbers are true if they
+// aren't zero, and bools are 1 or 0. Values that need no coercion are returned as is
+func weaken(src interface{}, k reflect.Kind) (interface{}, error) {
+
+	text, isScalar := scalarText(src)
+	if !isScalar {
+		return src, nil
+	}
+	s, isString := src.(string)
+	if isString {
+		s = strings.TrimSpace(s)
+	}
+
+	switch k {
+	case reflect.String:
+		return text, nil
+
+	case reflect.Bool:
+		if isString {
+			return strconv.ParseBool(s)
+		}
+		if _, isBool := src.(bool); !isBool {
+			f, _ := strconv.ParseFloat(text, 64)
+			return f != 0, nil
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if isString {
+			if s == "" {
+				return 0, nil
+			}
+			return strconv.ParseInt(s, 0, 64)
+		}
+		if v, isBool := src.(bool); isBool {
+			return boolToInt(v), nil
+		}
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if isString {
+			if s == "" {
+				return 0, nil
+			}
+			return strconv.ParseUint(s, 0, 64)
+		}
+		if v, isBool := src.(bool); isBool {
+			return boolToInt(v), nil
+		}
+
+	case reflect.Float32, reflect.Float64:
+		if isString {
+			if s == "" {
+				return 0.0, nil
+			}
+			return strconv.ParseFloat(s, 64)
+		}
+		if v, isBool := src.(bool); isBool {
+			return float64(boolToInt(v)), nil
+		}
+	}
+
+	return src, nil
+}
+
+// boolToInt is 1 for true and 0 for false
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// bindKey converts a mapping key to the key type of a map
+func bindKey(key string, dst reflect.Value) error {
+	if handled, err := unmarshalText(key, dst); handled {
+		return err
+	}
+
+	switch dst.Kind() {
+	case reflect.String:
+		dst.SetString(key)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(key, 10, dst.Type().Bits())
+		if err != nil {
+			return err
+		}
+		dst.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u, err := strconv.ParseUint(key, 10, dst.Type().Bits())
+		if err != nil {
+			return err
+		}
+		dst.SetUint(u)
+	default:
+		return fmt.Errorf("unsupported map key type")
+	}
+	return nil
+}
+
+// bindScalar assigns strings, numbers and bools, converting between compatible kinds
+func (b *binder) bindScalar(src interface{}, dst reflect.Value, path string) error {
+
+	fail := func(err error) error {
+		return &BindError{path, src, dst.Type(), err}
+	}
+
+	if b.weak {
+		var err error
+		if src, err = weaken(src, dst.Kind()); err != nil {
+			return fail(err)
+		}
+	}
+
+	switch dst.Kind() {
+	case reflect.String:
+		s, ok := src.(string)
+		if !ok {
+			return fail(nil)
+		}
+		dst.SetString(s)
+
+	case reflect.Bool:
+		v, ok := src.(bool)
+		if !ok {
+			return fail(nil)
+		}
+		dst.SetBool(v)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var i int64
+		switch v := src.(type) {
+		case int:
+			i = int64(v)
+		case int64:
+			i = v
+		case uint64:
+			if v > math.MaxInt64 {
+				return fail(fmt.Errorf("%d overflows", v))
+			}
+			i = int64(v)
+		case float64:
+			if v != math.Trunc(v) {
+				return fail(fmt.Errorf("%v is not an integer", v))
+			}
+			i = int64(v)
+		case json.Number:
+			var err error
+			if i, err = v.Int64(); err != nil {
+				return fail(err)
+			}
+		default:
+			return fail(nil)
+		}
+		if dst.OverflowInt(i) {
+			return fail(fmt.Errorf("%d overflows", i))
+		}
+		dst.SetInt(i)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		var u uint64
+		switch v := src.(type) {
+		case int:
+			if v < 0 {
+				return fail(fmt.Errorf("%d is negative", v))
+			}
+			u = uint64(v)
+		case int64:
+			if v < 0 {
+				return fail(fmt.Errorf("%d is negative", v))
+			}
+			u = uint64(v)
+		case uint64:
+			u = v
+		case float64:
+			if v < 0 || v != math.Trunc(v) {
+				return fail(fmt.Errorf("%v is not a positive integer", v))
+			}
+			u = uint64(v)
+		case json.Number:
+			var err error
+			if u, err = strconv.ParseUint(string(v), 10, 64); err != nil {
+				return fail(err)
+			}
+		default:
+			return fail(nil)
+		}
+		if dst.OverflowUint(u) {
+			return fail(fmt.Errorf("%d overflows", u))
+		}
+		dst.SetUint(u)
+
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		switch v := src.(type) {
+		case int:
+			f = float64(v)
+		case int64:
+			f = float64(v)
+		case uint64:
+			f = float64(v)
+		case float64:
+			f = v
+		case json.Number:
+			var err error
+			if f, err = v.Float64(); err != nil {
+				return fail(err)
+			}
+		default:
+			return fail(nil)
+		}
+		if dst.OverflowFloat(f) {
+			return fail(fmt.Errorf("%v overflows", f))
+		}
+		dst.SetFloat(f)
+
+	default:
+		sv := reflect.ValueOf(src)
+		if !sv.Type().AssignableTo(dst.Type()) {
+			return fail(nil)
+		}
+		dst.Set(sv)
+	}
+
+	return nil
+}
+
+// joinPath appends a key to a dotted document path. An empty key leaves the path as it is
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	if key == "" {
+		return path
+	}
+	return path + "." + key
+}
+
+// KeyMatching selects how document keys are matched to struct fields. Whatever the mode, a
+// field's name can be set explicitly with a `gofigure:"name"` tag, which takes precedence over
+// the tags of the specific formats (`yaml:"..."`, `json:"..."`), which in turn take precedence
+// over the Go field name.
+//
+// Renamed fields can keep accepting their old names with alias options, as in
+// `gofigure:"timeout,alias=timeout_secs"`. A warning is reported whenever an alias is used, and if
+// a document has both names, the field's current name wins. Fields that are on their way out can
+// be tagged `deprecated:"use X instead"`, which reports a warning whenever they are set.
+type KeyMatching int
+
+const (
+	// MatchCaseInsensitive matches keys to field names exactly, and falls back to matching them
+	// case-insensitively, the way encoding/json does. This is the default
+	MatchCaseInsensitive KeyMatching = iota
+
+	// MatchExact only matches keys that are exactly equal to a field's name
+	MatchExact
+
+	// MatchLoose also ignores underscores and dashes, so max_connections, max-connections and
+	// maxConnections all match a field named MaxConnections
+	MatchLoose
+)
+
+// field describes a struct field configs can be bound to
+type field struct {
+	// names are the keys the field is known by, from its tags and its Go name
+	names []string
+	// aliases are older names the field is still known by, declared with alias= tag options
+	aliases []string
+	// deprecated is the message of the field's deprecated tag, if it has one
+	deprecated string
+	// secret is true for fields tagged secret:"true", whose values are redacted from debug output
+	secret bool
+	// resolvePath is true for fields tagged path:"resolve", whose relative paths are resolved
+	// against the directory of the file that set them
+	resolvePath bool
+	// bounds are the options of the field's range tag, see RangeError
+	bounds string
+	// requiredIf and exclusive are the constraints of the field's tags, see ConstraintError
+	requiredIf string
+	exclusive  string
+	// oneOf are the values of the field's oneof tag, the only ones it accepts
+	oneOf []string
+	index []int
+}
+
+// fieldList is the bindable fields of a struct type
+type fieldList []field
+
+// match finds the field a document key maps to, and tells whether the key is one of the field's
+// aliases. Names are matched before aliases, and for both, keys are matched exactly first and
+// then more loosely depending on the matching mode
+func (fl fieldList) match(key string, mode KeyMatching) (*field, bool) {
+	if f := fl.matchNames(key, mode, func(f *field) []string { return f.names }); f != nil {
+		return f, false
+	}
+	if f := fl.matchNames(key, mode, func(f *field) []string { return f.aliases }); f != nil {
+		return f, true
+	}
+	return nil, false
+}
+
+// matchNames finds the field one of whose names, as returned by namesOf, match key
+func (fl fieldList) matchNames(key string, mode KeyMatching, namesOf func(*field) []string) *field {
+	for i := range fl {
+		for _, name := range namesOf(&fl[i]) {
+			if name == key {
+				return &fl[i]
+			}
+		}
+	}
+	if mode == MatchExact {
+		return nil
+	}
+
+	for i := range fl {
+		for _, name := range namesOf(&fl[i]) {
+			if strings.EqualFold(name, key) {
+				return &fl[i]
+			}
+		}
+	}
+	if mode != MatchLoose {
+		return nil
+	}
+
+	key = looseKey(key)
+	for i := range fl {
+		for _, name := range namesOf(&fl[i]) {
+			if looseKey(name) == key {
+				return &fl[i]
+			}
+		}
+	}
+	return nil
+}
+
+// looseKey lower cases a key and strips the word separators from it
+func looseKey(key string) string {
+	return strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(key))
+}
+
+// formatTags are the struct tags of the supported formats that can rename fields
+var formatTags = []string{"yaml", "json", "toml"}
+
+// fieldCache holds the fieldList of every struct type seen so far
+var fieldCache sync.Map
+
+// structFields lists the fields of a struct type that can be bound, flattening embedded structs
+// and those marked inline
+func structFields(t reflect.Type) fieldList {
+	if fl, found := fieldCache.Load(t); found {
+		return fl.(fieldList)
+	}
+
+	var fields fieldList
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" && !sf.Anonymous {
+			continue
+		}
+
+		names, inline, skip := fieldNames(sf)
+		if skip {
+			continue
+		}
+
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if (inline || (sf.Anonymous && len(names) == 0)) && ft.Kind() == reflect.Struct {
+			for _, f := range structFields(ft) {
+				f.index = append([]int{i}, f.index...)
+				fields = append(fields, f)
+			}
+			continue
+		}
+		if sf.PkgPath != "" {
+			continue
+		}
+
+		if len(names) == 0 {
+			names = []string{sf.Name}
+		}
+		fields = append(fields, field{
+			names:       names,
+			aliases:     tagOptions(sf.Tag.Get("gofigure"), "alias"),
+			deprecated:  sf.Tag.Get("deprecated"),
+			secret:      sf.Tag.Get("secret") == "true",
+			resolvePath: sf.Tag.Get("path") == "resolve",
+			bounds:      sf.Tag.Get("range"),
+			requiredIf:  sf.Tag.Get("required_if"),
+			exclusive:   sf.Tag.Get("exclusive"),
+			oneOf:       strings.Fields(sf.Tag.Get("oneof")),
+			index:       []int{i},
+		})
+	}
+
+	fieldCache.Store(t, fields)
+	return fields
+}
+
+// fieldNames reads the names a field is known by from its tags. An explicit gofigure tag wins
+// over the format tags. It also tells whether the field is to be inlined, or skipped entirely
+func fieldNames(sf reflect.StructField) (names []string, inline, skip bool) {
+
+	if tag, found := sf.Tag.Lookup("gofigure"); found {
+		name, opts := parseTag(tag)
+		if name == "-" && opts == "" {
+			return nil, false, true
+		}
+		if name != "" {
+			names = []string{name}
+		}
+		return names, hasOption(opts, "inline"), false
+	}
+
+	for _, tag := range formatTags {
+		name, opts := parseTag(sf.Tag.Get(tag))
+		if name == "-" && opts == "" {
+			return nil, false, true
+		}
+		if hasOption(opts, "inline") {
+			inline = true
+		}
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) > 0 {
+		names = append(names, sf.Name)
+	}
+	return names, inline, false
+}
+
+// tagOptions returns the values of all the key=value options named key in a struct tag, e.g.
+// the aliases in `gofigure:"timeout,alias=timeout_secs,alias=timeout_sec"`
+func tagOptions(tag, key string) []string {
+	_, opts := parseTag(tag)
+
+	var values []string
+	for _, o := range strings.Split(opts, ",") {
+		if strings.HasPrefix(o, key+"=") {
+			values = append(values, strings.TrimPrefix(o, key+"="))
+		}
+	}
+	return values
+}
+
+// hasOption tells whether the comma separated tag options opts include opt
+func hasOption(opts, opt string) bool {
+	for _, o := range strings.Split(opts, ",") {
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTag splits a struct tag value into a name and its comma separated options
+func parseTag(tag string) (string, string) {
+	if i := strings.Index(tag, ","); i >= 0 {
+		return tag[:i], tag[i+1:]
+	}
+	return tag, ""
+}
+
+// fieldByIndex is like reflect.Value.FieldByIndex, but allocates nil embedded struct pointers
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, error) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, fmt.Errorf("cannot allocate unexported embedded %s", v.Type().Elem())
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, nil
+}
@@ -0,0 +1,310 @@
+package gofigure
+
+import (
+	"encoding/json"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	gjson "github.com/EverythingMe/gofigure/json"
+	gyaml "github.com/EverythingMe/gofigure/yaml"
+	"gopkg.in/yaml.v3"
+)
+
+func TestBind(t *testing.T) {
+
+	type inner struct {
+		Name string `yaml:"name"`
+	}
+	type embedded struct {
+		Region string
+	}
+	type target struct {
+		embedded
+		Port    uint16            `json:"port"`
+		Ratio   float64           `yaml:"ratio"`
+		Enabled bool              `yaml:"enabled"`
+		Hosts   []string          `yaml:"hosts"`
+		Pair    [2]int            `yaml:"pair"`
+		Weights map[int]float32   `yaml:"weights"`
+		Labels  map[string]string `yaml:"labels"`
+		Inner   *inner            `yaml:"inner"`
+		Any     interface{}       `yaml:"any"`
+		IP      net.IP            `yaml:"ip"`
+		Ignored string            `yaml:"-"`
+	}
+
+	doc := map[string]interface{}{
+		"region":  "eu",
+		"port":    float64(8080),
+		"ratio":   1,
+		"enabled": true,
+		"hosts":   []interface{}{"a", "b"},
+		"pair":    []interface{}{1, 2},
+		"weights": map[string]interface{}{"1": 0.5},
+		"labels":  map[string]interface{}{"env": "prod"},
+		"inner":   map[string]interface{}{"NAME": "x"},
+		"any":     []interface{}{1, "two"},
+		"ip":      "10.0.0.1",
+		"ignored": "no",
+	}
+
+	out := target{Ignored: "default", Labels: map[string]string{"team": "core"}}
+	if err := bind(doc, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := target{
+		embedded: embedded{Region: "eu"},
+		Port:     8080,
+		Ratio:    1,
+		Enabled:  true,
+		Hosts:    []string{"a", "b"},
+		Pair:     [2]int{1, 2},
+		Weights:  map[int]float32{1: 0.5},
+		Labels:   map[string]string{"team": "core", "env": "prod"},
+		Inner:    &inner{"x"},
+		Any:      []interface{}{1, "two"},
+		IP:       net.ParseIP("10.0.0.1"),
+		Ignored:  "default",
+	}
+	if !reflect.DeepEqual(out, expected) {
+		t.Errorf("Bound data not as expected: %#v", out)
+	}
+}
+
+// yamlName, yamlV2Name and jsonName prefix the names they are decoded from, through yaml.v3's
+// and yaml.v2's Unmarshaler interfaces and json.Unmarshaler
+type yamlName string
+
+func (n *yamlName) UnmarshalYAML(node *yaml.Node) error {
+	*n = yamlName("X" + node.Value)
+	return nil
+}
+
+type yamlV2Name string
+
+func (n *yamlV2Name) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	*n = yamlV2Name("Y" + s)
+	return nil
+}
+
+type jsonName string
+
+func (n *jsonName) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*n = jsonName("Z" + s)
+	return nil
+}
+
+func TestBindUnmarshalers(t *testing.T) {
+
+	type target struct {
+		YAML   yamlName    `yaml:"yaml" json:"yaml"`
+		YAMLv2 *yamlV2Name `yaml:"yamlv2" json:"yamlv2"`
+		JSON   jsonName    `yaml:"json" json:"json"`
+	}
+
+	for _, d := range []Decoder{gyaml.Decoder{}, gjson.Decoder{}} {
+		var conf target
+		doc := []byte(`{"yaml": "foo", "yamlv2": "bar", "json": "baz"}`)
+		if err := NewLoader(d, true).MergeDocuments([][]byte{doc}, &conf); err != nil {
+			t.Fatal(err)
+		}
+		if conf.YAML != "Xfoo" || conf.YAMLv2 == nil || *conf.YAMLv2 != "Ybar" || conf.JSON != "Zbaz" {
+			t.Errorf("Expected the unmarshalers to be called decoding with %T, got %#v", d, conf)
+		}
+	}
+
+	var conf target
+	err := bind(map[string]interface{}{"json": []interface{}{1}}, &conf)
+	if err == nil || !strings.Contains(err.Error(), "at json") {
+		t.Errorf("Expected the error of UnmarshalJSON to point at json, got %v", err)
+	}
+}
+
+func TestBindErrors(t *testing.T) {
+
+	var conf struct {
+		Redis struct {
+			Monitor int8 `yaml:"monitor"`
+		} `yaml:"redis"`
+	}
+
+	for _, doc := range []map[string]interface{}{
+		{"redis": map[string]interface{}{"monitor": "1000"}},
+		{"redis": map[string]interface{}{"monitor": 1000}},
+		{"redis": map[string]interface{}{"monitor": 1.5}},
+		{"redis": "localhost"},
+	} {
+		err := bind(doc, &conf)
+		if _, ok := err.(*BindError); !ok {
+			t.Errorf("Expected a BindError binding %v, got %v", doc, err)
+		}
+	}
+
+	err := bind(map[string]interface{}{"redis": map[string]interface{}{"monitor": "x"}}, &conf)
+	if be, ok := err.(*BindError); !ok || be.Path != "redis.monitor" {
+		t.Errorf("Expected the error to point at redis.monitor, got %v", err)
+	}
+
+	if err := bind(map[string]interface{}{}, conf); err == nil {
+		t.Errorf("Expected binding into a non-pointer to fail")
+	}
+}
+
+func TestBindLenient(t *testing.T) {
+
+	var conf config
+	doc := map[string]interface{}{
+		"redis": map[string]interface{}{"server": "localhost:6379", "monitor": "often"},
+		"mysql": map[string]interface{}{"user": "root"},
+	}
+
+	if err := (Loader{StrictMode: true}).bind(nil, doc, &conf); err == nil {
+		t.Errorf("Expected a bad value to fail a strict bind")
+	}
+
+	conf = config{}
+	if err := (Loader{StrictMode: false}).bind(nil, doc, &conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Redis.Server != "localhost:6379" || conf.Mysql.User != "root" || conf.Redis.Monitor != 0 {
+		t.Errorf("Expected only the bad value to be skipped: %v", conf)
+	}
+}
+
+func TestBindWeaklyTyped(t *testing.T) {
+
+	type target struct {
+		Port    int
+		Limit   uint
+		Ratio   float64
+		Debug   bool
+		Verbose bool
+		Name    string
+		Hosts   []string
+		Ports   []int
+		Count   int
+	}
+
+	doc := map[string]interface{}{
+		"port":    "8080",
+		"limit":   " 0x10 ",
+		"ratio":   "0.25",
+		"debug":   1,
+		"verbose": "false",
+		"name":    42,
+		"hosts":   "localhost",
+		"ports":   []interface{}{"80", 443.0},
+		"count":   true,
+	}
+
+	var strict target
+	if err := bind(doc, &strict); err == nil {
+		t.Errorf("Expected sloppy types to fail without weak typing")
+	}
+
+	var conf target
+	if err := (Loader{StrictMode: true, WeaklyTyped: true}).bind(nil, doc, &conf); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := target{
+		Port:    8080,
+		Limit:   16,
+		Ratio:   0.25,
+		Debug:   true,
+		Verbose: false,
+		Name:    "42",
+		Hosts:   []string{"localhost"},
+		Ports:   []int{80, 443},
+		Count:   1,
+	}
+	if !reflect.DeepEqual(conf, expected) {
+		t.Errorf("Weakly typed values not coerced as expected: %#v", conf)
+	}
+
+	if err := (Loader{StrictMode: true, WeaklyTyped: true}).bind(nil, map[string]interface{}{"port": "http"}, &conf); err == nil {
+		t.Errorf("Expected unparsable values to fail even with weak typing")
+	}
+}
+
+func TestKeyMatching(t *testing.T) {
+
+	type target struct {
+		MaxConnections int
+		IdleTimeout    int    `yaml:"idle_timeout"`
+		Name           string `gofigure:"service_name" yaml:"name"`
+		Skipped        string `gofigure:"-" yaml:"skipped"`
+	}
+
+	doc := map[string]interface{}{
+		"max_connections": 100,
+		"IDLE_TIMEOUT":    30,
+		"service_name":    "api",
+		"name":            "ignored",
+		"skipped":         "ignored",
+	}
+
+	var conf target
+	if err := (Loader{StrictMode: true}).bind(nil, doc, &conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf != (target{IdleTimeout: 30, Name: "api"}) {
+		t.Errorf("Unexpected case-insensitive matching: %#v", conf)
+	}
+
+	conf = target{}
+	if err := (Loader{StrictMode: true, KeyMatching: MatchLoose}).bind(nil, doc, &conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf != (target{MaxConnections: 100, IdleTimeout: 30, Name: "api"}) {
+		t.Errorf("Unexpected loose matching: %#v", conf)
+	}
+
+	conf = target{}
+	if err := (Loader{StrictMode: true, KeyMatching: MatchExact}).bind(nil, doc, &conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf != (target{Name: "api"}) {
+		t.Errorf("Unexpected exact matching: %#v", conf)
+	}
+}
+
+func TestFieldAliases(t *testing.T) {
+
+	var conf struct {
+		Timeout int    `gofigure:"timeout,alias=timeout_secs,alias=timeoutSeconds"`
+		Host    string `gofigure:"host,alias=server"`
+	}
+
+	doc := map[string]interface{}{
+		"timeout_secs": 10,
+		"server":       "old",
+		"host":         "new",
+	}
+	for i := 0; i < 10; i++ {
+		if err := bind(doc, &conf); err != nil {
+			t.Fatal(err)
+		}
+		if conf.Timeout != 10 || conf.Host != "new" {
+			t.Fatalf("Aliases not resolved as expected: %#v", conf)
+		}
+	}
+
+	if err := bind(map[string]interface{}{"TIMEOUTSECONDS": 20}, &conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Timeout != 20 {
+		t.Errorf("Second alias not matched case-insensitively: %#v", conf)
+	}
+}
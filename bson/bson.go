@@ -0,0 +1,98 @@
+// Package bson implements a gofigure decoder for BSON documents, such as config blobs exported
+// from control planes backed by MongoDB.
+package bson
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/EverythingMe/gofigure/internal/tree"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Decoder decodes BSON files into config structs. A file can hold several documents one after
+// another, like the output of mongodump, in which case they are all decoded in order, so later
+// documents override the values set by earlier ones.
+//
+// Values of types that configs don't have are converted: dates to RFC 3339 strings, which
+// time.Time fields accept, object ids to hex strings, decimals to strings and binary data to base64
+// strings. The _id field of documents is dropped.
+type Decoder struct{}
+
+// Decode unmarshals the documents read from r into config
+func (d Decoder) Decode(r io.Reader, config interface{}) error {
+
+	for i := 0; ; i++ {
+		raw, err := bson.ReadDocument(r)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("bson: document %d: %s", i, err)
+		}
+
+		dec := bson.NewDecoder(bson.NewDocumentReader(bytes.NewReader(raw)))
+		dec.DefaultDocumentMap()
+		var doc map[string]interface{}
+		if err := dec.Decode(&doc); err != nil {
+			return fmt.Errorf("bson: document %d: %s", i, err)
+		}
+		delete(doc, "_id")
+
+		v, err := normalize(doc)
+		if err != nil {
+			return fmt.Errorf("bson: document %d: %s", i, err)
+		}
+		if err := tree.Assign(v, config); err != nil {
+			return err
+		}
+	}
+}
+
+// CanDecode returns true if this is a BSON file
+func (d Decoder) CanDecode(path string) bool {
+	return strings.HasSuffix(path, ".bson")
+}
+
+// normalize converts BSON values to generic document values
+func normalize(v interface{}) (interface{}, error) {
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			var err error
+			if v[k], err = normalize(e); err != nil {
+				return nil, err
+			}
+		}
+		return v, nil
+	case bson.A:
+		return normalize([]interface{}(v))
+	case []interface{}:
+		for i, e := range v {
+			var err error
+			if v[i], err = normalize(e); err != nil {
+				return nil, err
+			}
+		}
+		return v, nil
+
+	case nil, bool, string, int64, float64:
+		return v, nil
+	case int32:
+		return int64(v), nil
+	case bson.DateTime:
+		return v.Time().UTC().Format(time.RFC3339Nano), nil
+	case bson.ObjectID:
+		return v.Hex(), nil
+	case bson.Decimal128:
+		return v.String(), nil
+	case bson.Binary:
+		return base64.StdEncoding.EncodeToString(v.Data), nil
+	}
+	return nil, fmt.Errorf("unsupported value of type %T", v)
+}
@@ -0,0 +1,73 @@
+package bson
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func marshal(t *testing.T, buf *bytes.Buffer, doc interface{}) {
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Write(data)
+}
+
+func TestDecode(t *testing.T) {
+
+	id := bson.NewObjectID()
+	since := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	var buf bytes.Buffer
+	marshal(t, &buf, bson.D{
+		{Key: "_id", Value: id},
+		{Key: "redis", Value: bson.D{{Key: "server", Value: "localhost:6378"}, {Key: "timeout", Value: int32(10)}}},
+		{Key: "tags", Value: bson.A{"a", "b"}},
+		{Key: "owner", Value: id},
+		{Key: "since", Value: bson.NewDateTimeFromTime(since)},
+		{Key: "ratio", Value: 0.5},
+	})
+	marshal(t, &buf, bson.D{
+		{Key: "redis", Value: bson.D{{Key: "server", Value: "localhost:6379"}}},
+		{Key: "token", Value: bson.Binary{Data: []byte("hello")}},
+	})
+	data := buf.Bytes()
+
+	var conf interface{}
+	if err := (Decoder{}).Decode(bytes.NewReader(data), &conf); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"redis": map[string]interface{}{"server": "localhost:6379", "timeout": int64(10)},
+		"tags":  []interface{}{"a", "b"},
+		"owner": id.Hex(),
+		"since": "2024-01-02T03:04:05Z",
+		"ratio": 0.5,
+		"token": "aGVsbG8=",
+	}
+	if !reflect.DeepEqual(conf, expected) {
+		t.Errorf("Unexpected config %#v", conf)
+	}
+
+	var typed struct {
+		Redis struct {
+			Server  string
+			Timeout int
+		}
+		Since time.Time
+	}
+	if err := (Decoder{}).Decode(bytes.NewReader(data), &typed); err != nil {
+		t.Fatal(err)
+	}
+	if typed.Redis.Server != "localhost:6379" || typed.Redis.Timeout != 10 || !typed.Since.Equal(since) {
+		t.Errorf("Unexpected typed config %#v", typed)
+	}
+
+	if err := (Decoder{}).Decode(bytes.NewReader(data[:len(data)-3]), &conf); err == nil {
+		t.Error("Expected an error for a truncated document")
+	}
+}
@@ -0,0 +1,117 @@
+package gofigure
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// bundleReport is how the report of a load is written in a support bundle
+type bundleReport struct {
+	Version  string            `json:"version"`
+	Files    []string          `json:"files"`
+	Origins  map[string]string `json:"origins"`
+	Warnings []string          `json:"warnings,omitempty"`
+	Created  time.Time         `json:"created"`
+}
+
+// WriteSupportBundle loads the config tree under paths into config the way LoadRecursive would,
+// and writes a gzipped tar archive of what the loader saw to w, for attaching to support tickets:
+//
+//	config.json   the effective config, with secret fields redacted, see Dump
+//	report.json   the files that were read, where each value came from, and the load's warnings
+//	files/...     copies of the files that were read, under their absolute paths
+//
+// The loader's cache is bypassed, so every file is read, and the load isn't recorded in its Status
+// or passed to its OnChange hook. Note that the copies of the files are not redacted, so bundles of
+// trees holding secrets should be handled like the secrets themselves.
+func (l Loader) WriteSupportBundle(w io.Writer, config interface{}, paths ...string) error {
+
+	report := l.newReport()
+	if err := l.loadRecursive(report, config, paths...); err != nil {
+		return err
+	}
+	report.Version, _ = configVersion(config)
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+
+	dumped, err := Dump(config)
+	if err != nil {
+		return err
+	}
+	if err := writeBundleEntry(tw, "config.json", dumped, now); err != nil {
+		return err
+	}
+
+	out := bundleReport{
+		Version: report.Version,
+		Files:   report.Files,
+		Origins: report.Origins,
+		Created: now,
+	}
+	for _, warning := range report.Warnings {
+		out.Warnings = append(out.Warnings, warning.String())
+	}
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeBundleEntry(tw, "report.json", data, now); err != nil {
+		return err
+	}
+
+	for _, file := range report.Files {
+		// the names of sources are listed in the report, but have no file to copy
+		info, err := os.Stat(file)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			log.Warning("Could not copy %s to the support bundle: %s", file, err)
+			continue
+		}
+		if err := writeBundleEntry(tw, bundleName(file), data, info.ModTime()); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// writeBundleEntry adds a file named name with data to a support bundle
+func writeBundleEntry(tw *tar.Writer, name string, data []byte, modTime time.Time) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: modTime,
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// bundleName returns the name of the copy of the file at p in a support bundle, under files/ by
+// its absolute path, so that no name escapes the archive's root
+func bundleName(p string) string {
+	if abs, err := filepath.Abs(p); err == nil {
+		p = abs
+	}
+	p = strings.TrimPrefix(p, filepath.VolumeName(p))
+	return path.Join("files", strings.TrimLeft(filepath.ToSlash(p), "/"))
+}
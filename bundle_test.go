@@ -0,0 +1,79 @@
+package gofigure
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/EverythingMe/gofigure/yaml"
+)
+
+func TestWriteSupportBundle(t *testing.T) {
+
+	var conf struct {
+		Redis redisConfig `yaml:"redis"`
+		Mysql struct {
+			Server   string `yaml:"server"`
+			Password string `yaml:"password" secret:"true"`
+		} `yaml:"mysql"`
+	}
+
+	var buf bytes.Buffer
+	loader := NewLoader(yaml.Decoder{}, true)
+	if err := loader.WriteSupportBundle(&buf, &conf, "./testdata"); err != nil {
+		t.Fatal(err)
+	}
+
+	gz, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := map[string][]byte{}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if entries[hdr.Name], err = ioutil.ReadAll(tr); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var dumped map[string]map[string]interface{}
+	if err := json.Unmarshal(entries["config.json"], &dumped); err != nil {
+		t.Fatal(err)
+	}
+	if dumped["mysql"]["password"] != redacted || dumped["redis"]["timeout"] != 10.0 {
+		t.Errorf("Unexpected config in the bundle %v", dumped)
+	}
+
+	var report bundleReport
+	if err := json.Unmarshal(entries["report.json"], &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Version == "" || len(report.Files) == 0 || report.Origins["redis.timeout"] != "testdata/sub/overrided.yaml" {
+		t.Errorf("Unexpected report in the bundle %+v", report)
+	}
+
+	var copies int
+	for name, data := range entries {
+		if !strings.HasPrefix(name, "files/") {
+			continue
+		}
+		copies++
+		if strings.HasSuffix(name, "/testdata/test.yaml") && !bytes.Contains(data, []byte("localhost:3306")) {
+			t.Errorf("Unexpected copy of %s: %s", name, data)
+		}
+	}
+	if copies != len(report.Files) {
+		t.Errorf("Expected a copy of each of %v, got %d", report.Files, copies)
+	}
+}
@@ -0,0 +1,128 @@
+package gofigure
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// KeyStyle is a naming convention of keys, see CanonicalKeys
+type KeyStyle int
+
+const (
+	// SnakeCase keys are like "max_idle_conns"
+	SnakeCase KeyStyle = iota
+
+	// KebabCase keys are like "max-idle-conns"
+	KebabCase
+
+	// CamelCase keys are like "maxIdleConns"
+	CamelCase
+)
+
+// CanonicalKeys returns a hook converting every key of the documents to style, so that configs
+// mixing the styles of different teams merge and bind predictably: "maxIdleConns",
+// "max-idle-conns" and "MaxIdleConns" all become "max_idle_conns" in SnakeCase, and the last
+// document setting any of them wins. Add it first to Loader.DocumentHooks, so the other hooks see
+// canonical keys.
+//
+// Keys are converted at any depth, including those of mappings bound to Go maps, so it's meant for
+// configs whose keys are all identifiers. Keys starting with $, like "$include", are left alone.
+// Two keys of a mapping that convert to the same key are an error.
+func CanonicalKeys(style KeyStyle) DocumentHook {
+	return DocumentHookFunc(func(source string, doc map[string]interface{}) (map[string]interface{}, error) {
+		v, err := canonicalizeKeys(doc, style)
+		if err != nil {
+			return nil, err
+		}
+		return v.(map[string]interface{}), nil
+	})
+}
+
+// canonicalizeKeys returns v with the keys of all its mappings converted to style
+func canonicalizeKeys(v interface{}, style KeyStyle) (interface{}, error) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		originals := make(map[string]string, len(v))
+		for k, e := range v {
+			ck := k
+			if !strings.HasPrefix(k, "$") {
+				ck = convertKey(k, style)
+			}
+			if original, found := originals[ck]; found {
+				return nil, fmt.Errorf("keys %s and %s are both %s", original, k, ck)
+			}
+			originals[ck] = k
+
+			var err error
+			if out[ck], err = canonicalizeKeys(e, style); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, e := range v {
+			var err error
+			if out[i], err = canonicalizeKeys(e, style); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	}
+	return v, nil
+}
+
+// convertKey converts a key to style
+func convertKey(key string, style KeyStyle) string {
+	words := keyWords(key)
+	if len(words) == 0 {
+		return key
+	}
+	switch style {
+	case KebabCase:
+		return strings.Join(words, "-")
+	case CamelCase:
+		for i := 1; i < len(words); i++ {
+			r := []rune(words[i])
+			r[0] = unicode.ToUpper(r[0])
+			words[i] = string(r)
+		}
+		return strings.Join(words, "")
+	}
+	return strings.Join(words, "_")
+}
+
+// keyWords splits a key of any style into lower cased words, at underscores, dashes and case
+// changes. Runs of capitals are acronyms, so "HTTPServer" is "http" and "server"
+func keyWords(key string) []string {
+	var words []string
+	var word []rune
+
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, strings.ToLower(string(word)))
+			word = word[:0]
+		}
+	}
+
+	runes := []rune(key)
+	for i, r := range runes {
+		switch {
+		case r == '_' || r == '-' || r == ' ':
+			flush()
+			continue
+		case unicode.IsUpper(r) && i > 0:
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				flush()
+			}
+		}
+		word = append(word, r)
+	}
+	flush()
+	return words
+}
@@ -0,0 +1,53 @@
+package gofigure
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/EverythingMe/gofigure/yaml"
+)
+
+func TestConvertKey(t *testing.T) {
+	for key, expected := range map[string][3]string{
+		"max_idle_conns": {"max_idle_conns", "max-idle-conns", "maxIdleConns"},
+		"max-idle-conns": {"max_idle_conns", "max-idle-conns", "maxIdleConns"},
+		"maxIdleConns":   {"max_idle_conns", "max-idle-conns", "maxIdleConns"},
+		"MaxIdleConns":   {"max_idle_conns", "max-idle-conns", "maxIdleConns"},
+		"HTTPServer":     {"http_server", "http-server", "httpServer"},
+		"tlsV2Enabled":   {"tls_v2_enabled", "tls-v2-enabled", "tlsV2Enabled"},
+	} {
+		for style, want := range expected {
+			if got := convertKey(key, KeyStyle(style)); got != want {
+				t.Errorf("Expected %s in style %d to be %s, got %s", key, style, want, got)
+			}
+		}
+	}
+}
+
+func TestCanonicalKeys(t *testing.T) {
+
+	var conf struct {
+		Pool struct {
+			MaxIdleConns int `yaml:"max_idle_conns"`
+			MaxOpenConns int `yaml:"max_open_conns"`
+		} `yaml:"pool"`
+	}
+
+	loader := NewLoader(yaml.Decoder{}, true)
+	loader.DocumentHooks = []DocumentHook{CanonicalKeys(SnakeCase)}
+	docs := [][]byte{
+		[]byte("pool: {maxIdleConns: 1, max-open-conns: 2}"),
+		[]byte("Pool: {max-idle-conns: 3}"),
+	}
+	if err := loader.MergeDocuments(docs, &conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Pool.MaxIdleConns != 3 || conf.Pool.MaxOpenConns != 2 {
+		t.Errorf("Unexpected config %+v", conf.Pool)
+	}
+
+	err := loader.MergeDocuments([][]byte{[]byte("pool: {maxIdleConns: 1, max_idle_conns: 2}")}, &conf)
+	if err == nil || !strings.Contains(err.Error(), "max_idle_conns") {
+		t.Errorf("Expected an error for keys converting to the same key, got %v", err)
+	}
+}
@@ -0,0 +1,179 @@
+// Package cel computes config values from CEL (https://cel.dev) expressions over the rest of the
+// config, for derived values such as a worker count based on the number of cores.
+package cel
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/EverythingMe/gofigure/internal/tree"
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/ext"
+)
+
+// DefaultPrefix marks the string values that are CEL expressions, unless the resolver says otherwise
+const DefaultPrefix = "cel:"
+
+// Resolver is a gofigure.Resolver replacing string values such as "cel: cores * 2" with the result
+// of the CEL expression that follows the prefix. It's used by adding it to a loader's Resolvers.
+//
+// Expressions can use the top level keys of the merged config as variables, as well as env (a map
+// of the environment variables), cores (the number of CPUs) and the resolver's Vars, along with
+// the CEL string extensions (replace, split, lowerAscii, etc). They see the config as it was
+// merged, before any expression is evaluated, so an expression cannot use the result of another.
+type Resolver struct {
+	// Prefix marks the values that are expressions, DefaultPrefix if empty
+	Prefix string
+
+	// Vars are extra variables available to expressions, overriding config keys of the same name
+	Vars map[string]interface{}
+}
+
+// identifier matches the config keys that can be used as CEL variables
+var identifier = regexp.MustCompile(`^[_a-zA-Z][_a-zA-Z0-9]*$`)
+
+// Resolve evaluates the expressions in the merged config t in place
+func (r Resolver) Resolve(t map[string]interface{}) error {
+
+	vars := map[string]interface{}{}
+	for k, v := range t {
+		if identifier.MatchString(k) {
+			vars[k] = tree.Copy(v)
+		}
+	}
+
+	env := map[string]string{}
+	for _, kv := range os.Environ() {
+		if i := strings.IndexByte(kv, '='); i > 0 {
+			env[kv[:i]] = kv[i+1:]
+		}
+	}
+	vars["env"] = env
+	vars["cores"] = runtime.NumCPU()
+
+	for k, v := range r.Vars {
+		vars[k] = v
+	}
+
+	opts := []cel.EnvOption{ext.Strings()}
+	for name := range vars {
+		opts = append(opts, cel.Variable(name, cel.DynType))
+	}
+	celEnv, err := cel.NewEnv(opts...)
+	if err != nil {
+		return err
+	}
+
+	e := evaluator{resolver: r, env: celEnv, vars: vars}
+	_, err = e.walk(t, "")
+	return err
+}
+
+// evaluator walks a config tree, evaluating the expressions it finds
+type evaluator struct {
+	resolver Resolver
+	env      *cel.Env
+	vars     map[string]interface{}
+}
+
+func (e evaluator) prefix() string {
+	if e.resolver.Prefix == "" {
+		return DefaultPrefix
+	}
+	return e.resolver.Prefix
+}
+
+// walk replaces the expressions in v in place and returns the resulting value
+func (e evaluator) walk(v interface{}, path string) (interface{}, error) {
+	switch v := v.(type) {
+	case string:
+		if !strings.HasPrefix(v, e.prefix()) {
+			return v, nil
+		}
+		result, err := e.eval(strings.TrimSpace(strings.TrimPrefix(v, e.prefix())))
+		if err != nil {
+			return nil, fmt.Errorf("cel: %s: %s", path, err)
+		}
+		return result, nil
+
+	case map[string]interface{}:
+		for k, elem := range v {
+			p := k
+			if path != "" {
+				p = path + "." + k
+			}
+			result, err := e.walk(elem, p)
+			if err != nil {
+				return nil, err
+			}
+			v[k] = result
+		}
+		return v, nil
+
+	case []interface{}:
+		for i, elem := range v {
+			result, err := e.walk(elem, fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			v[i] = result
+		}
+		return v, nil
+
+	default:
+		return v, nil
+	}
+}
+
+// eval compiles and evaluates a single expression
+func (e evaluator) eval(expr string) (interface{}, error) {
+
+	ast, issues := e.env.Compile(expr)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+
+	prg, err := e.env.Program(ast)
+	if err != nil {
+		return nil, err
+	}
+
+	out, _, err := prg.Eval(e.vars)
+	if err != nil {
+		return nil, err
+	}
+	return native(out)
+}
+
+var (
+	listType = reflect.TypeOf([]interface{}{})
+	mapType  = reflect.TypeOf(map[string]interface{}{})
+)
+
+// native converts a CEL value to the generic values config trees are made of
+func native(v ref.Val) (interface{}, error) {
+	switch v.Type() {
+	case types.ListType:
+		l, err := v.ConvertToNative(listType)
+		if err != nil {
+			return nil, err
+		}
+		return tree.Normalize(l), nil
+
+	case types.MapType:
+		m, err := v.ConvertToNative(mapType)
+		if err != nil {
+			return nil, err
+		}
+		return tree.Normalize(m), nil
+
+	default:
+		return v.Value(), nil
+	}
+}
@@ -0,0 +1,80 @@
+package cel
+
+import (
+	"os"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/EverythingMe/gofigure"
+	"github.com/EverythingMe/gofigure/yaml"
+)
+
+func TestResolver(t *testing.T) {
+
+	os.Setenv("GOFIGURE_TEST_REGION", "eu")
+	defer os.Unsetenv("GOFIGURE_TEST_REGION")
+
+	doc := map[string]interface{}{
+		"workers": "cel: cores * 2",
+		"limits": map[string]interface{}{
+			"base":    10,
+			"max":     "cel: limits.base * factor",
+			"regions": "cel: [env.GOFIGURE_TEST_REGION, 'us']",
+		},
+		"debug":  "cel: name.startsWith('dev')",
+		"name":   "dev-box",
+		"labels": []interface{}{"cel: {'a': 1}", "plain"},
+	}
+
+	r := Resolver{Vars: map[string]interface{}{"factor": 3}}
+	if err := r.Resolve(doc); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]interface{}{
+		"workers": int64(runtime.NumCPU() * 2),
+		"limits": map[string]interface{}{
+			"base":    10,
+			"max":     int64(30),
+			"regions": []interface{}{"eu", "us"},
+		},
+		"debug":  true,
+		"name":   "dev-box",
+		"labels": []interface{}{map[string]interface{}{"a": int64(1)}, "plain"},
+	}
+	if !reflect.DeepEqual(doc, expected) {
+		t.Errorf("Expressions not evaluated as expected: %#v", doc)
+	}
+
+	for _, expr := range []string{"cel: 1 +", "cel: missing * 2", "cel: 1 / 0"} {
+		if err := (Resolver{}).Resolve(map[string]interface{}{"x": expr}); err == nil {
+			t.Errorf("Expected an error evaluating %s", expr)
+		}
+	}
+}
+
+func TestLoader(t *testing.T) {
+
+	var conf struct {
+		Redis struct {
+			Server  string
+			Monitor int
+		}
+	}
+
+	loader := gofigure.NewLoader(yaml.Decoder{}, true)
+	loader.Resolvers = []gofigure.Resolver{
+		gofigure.ResolveFunc(func(t map[string]interface{}) error {
+			t["redis"].(map[string]interface{})["server"] = "=: 'localhost:' + string(redis.monitor + port)"
+			return nil
+		}),
+		Resolver{Prefix: "=:", Vars: map[string]interface{}{"port": 5380}},
+	}
+	if err := loader.LoadFile(&conf, "../testdata/test.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Redis.Server != "localhost:6380" || conf.Redis.Monitor != 1000 {
+		t.Errorf("Computed value not bound: %#v", conf)
+	}
+}
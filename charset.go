@@ -0,0 +1,64 @@
+package gofigure
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"unicode/utf16"
+)
+
+// byte order marks of the encodings files are transcoded from
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// transcode returns a reader of the contents of r in UTF-8, without a byte order mark, for files
+// saved as UTF-16 or with a BOM, as editors on Windows tend to. UTF-16 is recognized by its BOM,
+// or without one by the zero bytes of a file starting with two ASCII characters. Other files are
+// read as they are
+func transcode(path string, r io.Reader) (io.Reader, error) {
+
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(4)
+
+	var order binary.ByteOrder
+	switch {
+	case bytes.HasPrefix(head, bomUTF8):
+		br.Discard(len(bomUTF8))
+		return br, nil
+	case bytes.HasPrefix(head, bomUTF16LE):
+		br.Discard(len(bomUTF16LE))
+		order = binary.LittleEndian
+	case bytes.HasPrefix(head, bomUTF16BE):
+		br.Discard(len(bomUTF16BE))
+		order = binary.BigEndian
+	case len(head) == 4 && head[0] != 0 && head[1] == 0 && head[2] != 0 && head[3] == 0:
+		order = binary.LittleEndian
+	case len(head) == 4 && head[0] == 0 && head[1] != 0 && head[2] == 0 && head[3] != 0:
+		order = binary.BigEndian
+	default:
+		return br, nil
+	}
+
+	log.Debug("Transcoding %s from UTF-16", path)
+	data, err := ioutil.ReadAll(br)
+	if err != nil {
+		return nil, err
+	}
+	if len(data)%2 != 0 {
+		return nil, fmt.Errorf("gofigure: %s is not valid UTF-16, it has an odd number of bytes", path)
+	}
+
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = order.Uint16(data[2*i:])
+	}
+
+	return strings.NewReader(string(utf16.Decode(units))), nil
+}
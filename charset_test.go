@@ -0,0 +1,73 @@
+package gofigure
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/EverythingMe/gofigure/json"
+)
+
+// encodeUTF16 encodes s as UTF-16 in the given byte order, after bom
+func encodeUTF16(s string, order binary.ByteOrder, bom []byte) []byte {
+	buf := bytes.NewBuffer(bom)
+	for _, u := range utf16.Encode([]rune(s)) {
+		binary.Write(buf, order, u)
+	}
+	return buf.Bytes()
+}
+
+func TestTranscode(t *testing.T) {
+
+	const doc = `{"redis": {"server": "héllo:6379"}}`
+	cases := map[string][]byte{
+		"plain":       []byte(doc),
+		"utf8 bom":    append(append([]byte{}, bomUTF8...), doc...),
+		"utf16le bom": encodeUTF16(doc, binary.LittleEndian, bomUTF16LE),
+		"utf16be bom": encodeUTF16(doc, binary.BigEndian, bomUTF16BE),
+		"utf16le":     encodeUTF16(doc, binary.LittleEndian, nil),
+		"utf16be":     encodeUTF16(doc, binary.BigEndian, nil),
+	}
+
+	for name, data := range cases {
+		r, err := transcode(name, bytes.NewReader(data))
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+			continue
+		}
+		if out, _ := ioutil.ReadAll(r); string(out) != doc {
+			t.Errorf("%s: got %q", name, out)
+		}
+	}
+
+	odd := append(encodeUTF16(doc, binary.LittleEndian, bomUTF16LE), 'x')
+	if _, err := transcode("odd", bytes.NewReader(odd)); err == nil {
+		t.Error("Expected an error for truncated UTF-16")
+	}
+}
+
+func TestLoadUTF16(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gofigure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	data := encodeUTF16(`{"redis": {"server": "windows:6379"}}`, binary.LittleEndian, bomUTF16LE)
+	if err := ioutil.WriteFile(filepath.Join(dir, "conf.json"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var conf config
+	if err := NewLoader(json.Decoder{}, true).LoadRecursive(&conf, dir); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Redis.Server != "windows:6379" {
+		t.Errorf("Unexpected config: %#v", conf.Redis)
+	}
+}
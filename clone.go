@@ -0,0 +1,97 @@
+package gofigure
+
+import (
+	"reflect"
+)
+
+// Clone returns a deep copy of config, sharing none of its maps, slices and pointers, e.g. to derive
+// variants of a base config that can be changed without changing it:
+//
+//	tenant := gofigure.Clone(&base)
+//	tenant.Database.Name = "tenant_" + id
+//
+// Unexported fields are copied as they are, and so are funcs and channels. Clone(nil) is nil
+func Clone[T any](config *T) *T {
+	if config == nil {
+		return nil
+	}
+	return deepCopy(reflect.ValueOf(config)).Interface().(*T)
+}
+
+// deepCopy copies v, and the pointers, maps, slices and interfaces it holds, so nothing is shared
+// between v and its copy. Values reachable through several pointers are copied once. Unexported
+// fields are copied as they are, since reflection can't set them, and so are funcs and channels
+func deepCopy(v reflect.Value) reflect.Value {
+	return copier{}.copy(v)
+}
+
+// copier remembers the pointers it copied, so shared and cyclic values stay that way in the copy
+type copier map[uintptr]reflect.Value
+
+func (c copier) copy(v reflect.Value) reflect.Value {
+
+	if !v.IsValid() {
+		return v
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		if p, ok := c[v.Pointer()]; ok && p.Type() == v.Type() {
+			return p
+		}
+		p := reflect.New(v.Type().Elem())
+		c[v.Pointer()] = p
+		p.Elem().Set(c.copy(v.Elem()))
+		return p
+
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.New(v.Type()).Elem()
+		out.Set(c.copy(v.Elem()))
+		return out
+
+	case reflect.Struct:
+		out := reflect.New(v.Type()).Elem()
+		out.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if out.Field(i).CanSet() {
+				out.Field(i).Set(c.copy(v.Field(i)))
+			}
+		}
+		return out
+
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for it := v.MapRange(); it.Next(); {
+			out.SetMapIndex(c.copy(it.Key()), c.copy(it.Value()))
+		}
+		return out
+
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(c.copy(v.Index(i)))
+		}
+		return out
+
+	case reflect.Array:
+		out := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(c.copy(v.Index(i)))
+		}
+		return out
+	}
+
+	return v
+}
@@ -0,0 +1,61 @@
+package gofigure
+
+import (
+	"testing"
+	"time"
+
+	"github.com/EverythingMe/gofigure/yaml"
+)
+
+func TestClone(t *testing.T) {
+
+	type tenantConfig struct {
+		Redis    *redisConfig
+		Mysql    mysqlConfig
+		Shards   []redisConfig
+		Limits   map[string][]int
+		Extra    map[string]interface{}
+		Timeout  time.Duration
+		Deadline time.Time
+	}
+
+	base := tenantConfig{
+		Redis:    &redisConfig{Server: "localhost:6379"},
+		Mysql:    mysqlConfig{Server: "localhost:3306", User: "root"},
+		Shards:   []redisConfig{{Server: "shard1"}, {Server: "shard2"}},
+		Limits:   map[string][]int{"rps": {10, 20}},
+		Extra:    map[string]interface{}{"tags": []interface{}{"a"}},
+		Timeout:  time.Second,
+		Deadline: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+
+	tenant := Clone(&base)
+	tenantVersion, _ := configVersion(tenant)
+	baseVersion, _ := configVersion(&base)
+	if tenantVersion != baseVersion || !tenant.Deadline.Equal(base.Deadline) {
+		t.Fatalf("Clone differs from the original: %+v", tenant)
+	}
+
+	tenant.Redis.Server = "tenant:6379"
+	tenant.Mysql.User = "tenant"
+	tenant.Shards[0].Server = "tenant-shard"
+	tenant.Limits["rps"][0] = 1
+	tenant.Extra["tags"].([]interface{})[0] = "b"
+	if base.Redis.Server != "localhost:6379" || base.Mysql.User != "root" || base.Shards[0].Server != "shard1" ||
+		base.Limits["rps"][0] != 10 || base.Extra["tags"].([]interface{})[0] != "a" {
+		t.Errorf("Changing the clone changed the original: %+v", base)
+	}
+
+	if Clone[tenantConfig](nil) != nil {
+		t.Error("Expected the clone of nil to be nil")
+	}
+
+	// loaded configs are cloned entirely
+	var conf config
+	if err := NewLoader(yaml.Decoder{}, true).LoadRecursive(&conf, "./testdata"); err != nil {
+		t.Fatal(err)
+	}
+	if *Clone(&conf) != conf {
+		t.Errorf("Unexpected clone of a loaded config %+v", *Clone(&conf))
+	}
+}
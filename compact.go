@@ -0,0 +1,61 @@
+package gofigure
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// Encoder is implemented by the decoders that can also write generic documents in their format,
+// like those of the yaml, json and toml packages
+type Encoder interface {
+	Encode(w io.Writer, v interface{}) error
+}
+
+// Compact merges the config tree under paths and the loader's sources the way LoadRecursive
+// would, and writes the merged tree to a single file at outPath in format, the name of a registered
+// decoder that is also an Encoder, like "yaml" or "json". If format is empty, it's the format of
+// the registered decoder that can decode outPath. This bakes a resolved config into a container
+// image, or into a support bundle.
+//
+// References and the environment are expanded if the loader is set to, and resolvers like secret
+// managers are run, so the file may hold secrets. It's written with mode 0600, like the copies
+// Persist saves, and should be protected like the secrets it may hold.
+func (l Loader) Compact(outPath, format string, paths ...string) error {
+
+	enc, err := encoderFor(outPath, format)
+	if err != nil {
+		return err
+	}
+
+	merged, err := l.LoadMap(paths...)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := enc.Encode(&buf, map[string]interface{}(merged)); err != nil {
+		return fmt.Errorf("gofigure: encoding %s: %s", outPath, err)
+	}
+	return writeFileAtomic(outPath, buf.Bytes())
+}
+
+// encoderFor returns the encoder of format, or the one for the file at path if format is empty
+func encoderFor(path, format string) (Encoder, error) {
+
+	var d Decoder
+	if format != "" {
+		var found bool
+		if d, found = LookupDecoder(format); !found {
+			return nil, fmt.Errorf("gofigure: no decoder registered for format %s", format)
+		}
+	} else if d = lookupDecoderFor(path); d == nil {
+		return nil, fmt.Errorf("gofigure: no registered decoder for %s", path)
+	}
+
+	enc, ok := d.(Encoder)
+	if !ok {
+		return nil, fmt.Errorf("gofigure: the decoder of %s can't encode", path)
+	}
+	return enc, nil
+}
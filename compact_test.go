@@ -0,0 +1,46 @@
+package gofigure
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/EverythingMe/gofigure/yaml"
+)
+
+func TestCompact(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gofigure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	loader := NewLoader(yaml.Decoder{}, true)
+	for _, format := range []string{"yaml", "json"} {
+		out := filepath.Join(dir, "compacted."+format)
+		if err := loader.Compact(out, "", "./testdata"); err != nil {
+			t.Fatal(err)
+		}
+
+		// the compacted file loads into the same config as the tree
+		var conf config
+		if err := DefaultLoader.LoadFile(&conf, out); err != nil {
+			t.Fatal(err)
+		}
+		if conf != expectedConf {
+			t.Errorf("Unexpected config loaded from the compacted %s: %+v", format, conf)
+		}
+		if info, err := os.Stat(out); err != nil || info.Mode().Perm() != 0600 {
+			t.Errorf("Unexpected compacted file %v %v", info, err)
+		}
+	}
+
+	if err := loader.Compact(filepath.Join(dir, "out.conf"), "", "./testdata"); err == nil {
+		t.Error("Expected an error compacting to a file of no known format")
+	}
+	if err := loader.Compact(filepath.Join(dir, "out.conf"), "ini", "./testdata"); err == nil {
+		t.Error("Expected an error compacting to an unknown format")
+	}
+}
@@ -0,0 +1,71 @@
+package gofigure
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+)
+
+// Decompressor returns a reader of the decompressed contents of r
+type Decompressor func(r io.Reader) (io.ReadCloser, error)
+
+// compressionsMu guards compressions, which compression packages add to in their init functions
+var compressionsMu sync.RWMutex
+
+// compressions maps the extensions of compressed files to their decompressors. gzip is built in
+var compressions = map[string]Decompressor{
+	".gz": func(r io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(r)
+	},
+}
+
+// RegisterCompression makes the loaders read files with the extension ext, like ".zst",
+// decompressing them with d. A compressed file is decoded by the decoder of its name without the
+// extension, so "conf.yaml.gz" is read as yaml. gzip is built in, and the zstd package registers
+// ".zst" when it's imported.
+//
+// It panics if the extension is already taken, as this is a programming error usually made in
+// init functions.
+func RegisterCompression(ext string, d Decompressor) {
+	compressionsMu.Lock()
+	defer compressionsMu.Unlock()
+
+	if _, found := compressions[ext]; found {
+		panic(fmt.Sprintf("gofigure: compression %s registered twice", ext))
+	}
+	compressions[ext] = d
+}
+
+// compression returns the decompressor of the file at path and the path without its compression
+// extension, or nil and the path itself if the file isn't compressed
+func compression(path string) (Decompressor, string) {
+	compressionsMu.RLock()
+	defer compressionsMu.RUnlock()
+
+	for ext, d := range compressions {
+		if strings.HasSuffix(path, ext) && len(path) > len(ext) {
+			return d, strings.TrimSuffix(path, ext)
+		}
+	}
+	return nil, path
+}
+
+// decompress returns a reader of the decompressed contents of the file at path, to be closed
+// once read, and the path to pick its decoder by. Files that aren't compressed are read as they are
+func decompress(path string, r io.Reader) (io.ReadCloser, string, error) {
+
+	d, base := compression(path)
+	if d == nil {
+		return ioutil.NopCloser(r), path, nil
+	}
+
+	log.Debug("Decompressing %s", path)
+	rc, err := d(r)
+	if err != nil {
+		return nil, path, fmt.Errorf("gofigure: decompressing %s: %s", path, err)
+	}
+	return rc, base, nil
+}
@@ -0,0 +1,50 @@
+package gofigure
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/EverythingMe/gofigure/yaml"
+)
+
+func TestLoadGzipped(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gofigure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write([]byte("redis:\n  server: compressed:6379\n"))
+	w.Close()
+	if err := ioutil.WriteFile(filepath.Join(dir, "conf.yaml.gz"), buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// not a yaml file once decompressed
+	if err := ioutil.WriteFile(filepath.Join(dir, "data.bin.gz"), buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	loader := NewLoader(yaml.Decoder{}, true)
+	var conf config
+	report, err := loader.LoadWithReport(&conf, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Redis.Server != "compressed:6379" || len(report.Files) != 1 {
+		t.Errorf("Unexpected config: %#v, %v", conf.Redis, report.Files)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "conf.yaml.gz"), []byte("redis: {}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := loader.LoadRecursive(&conf, dir); err == nil {
+		t.Error("Expected an error for a file that isn't gzipped")
+	}
+}
@@ -0,0 +1,83 @@
+package gofigure
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/EverythingMe/gofigure/yaml"
+)
+
+// TestConcurrentLoads shares a loader using most of its options between goroutines, for the race
+// detector to check that loads don't share mutable state
+func TestConcurrentLoads(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gofigure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeFiles(t, dir, map[string]string{
+		"conf.d/10-base.yaml":  "redis:\n  server: ${mysql.server}\n  timeout: 1\n",
+		"conf.d/20-mysql.yaml": "mysql: !include ../mysql.yaml\n",
+		"mysql.yaml":           "server: db:3306\nuser: app\n",
+	})
+
+	migrations := NewMigrations("version")
+	migrations.Add(0, func(doc map[string]interface{}) error {
+		return nil
+	})
+
+	var mu sync.Mutex
+	warnings := 0
+	loader := NewLoader(yaml.Decoder{}, true)
+	loader.FollowIncludes = true
+	loader.ExpandReferences = true
+	loader.Migrations = migrations
+	loader.Cache = NewCache()
+	loader.Status = NewStatus()
+	loader.Sources = []Source{BytesSource("defaults", []byte("redis:\n  monitor: 5\n"))}
+	loader.OnWarning = func(Warning) {
+		mu.Lock()
+		warnings++
+		mu.Unlock()
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 40)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			var conf config
+			var err error
+			switch i % 4 {
+			case 0:
+				err = loader.LoadRecursive(&conf, dir+"/conf.d")
+			case 1:
+				err = loader.LoadPath(&conf.Redis, "redis", dir+"/conf.d")
+			case 2:
+				_, err = loader.LoadMap(dir + "/conf.d")
+				conf.Redis.Server = "db:3306"
+			case 3:
+				err = loader.MergeDocuments([][]byte{[]byte("redis:\n  server: db:3306\n")}, &conf)
+			}
+			if err == nil && conf.Redis.Server != "db:3306" {
+				err = fmt.Errorf("load %d: unexpected config %#v", i, conf.Redis)
+			}
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+}
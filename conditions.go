@@ -0,0 +1,235 @@
+package gofigure
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// whenKey is the key of the guard of conditional sections
+const whenKey = "$when"
+
+// applyConditions removes the sections of a generic tree whose "$when" guard is false, and drops
+// the guards of the others. It returns the resulting value, and false if v itself is dropped
+func applyConditions(v interface{}, vars map[string]string) (interface{}, bool, error) {
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if guard, found := v[whenKey]; found {
+			expr, ok := guard.(string)
+			if !ok {
+				return nil, false, fmt.Errorf("%s must be an expression, got %v", whenKey, guard)
+			}
+			keep, err := evalCondition(expr, vars)
+			if err != nil {
+				return nil, false, fmt.Errorf("%s %q: %s", whenKey, expr, err)
+			}
+			if !keep {
+				return nil, false, nil
+			}
+			delete(v, whenKey)
+		}
+
+		for k, e := range v {
+			e, keep, err := applyConditions(e, vars)
+			if err != nil {
+				return nil, false, err
+			}
+			if keep {
+				v[k] = e
+			} else {
+				delete(v, k)
+			}
+		}
+		return v, true, nil
+
+	case []interface{}:
+		kept := v[:0]
+		for _, e := range v {
+			e, keep, err := applyConditions(e, vars)
+			if err != nil {
+				return nil, false, err
+			}
+			if keep {
+				kept = append(kept, e)
+			}
+		}
+		return kept, true, nil
+
+	default:
+		return v, true, nil
+	}
+}
+
+// conditionVars returns the variables $when guards are evaluated against: the loader's
+// conditions, and the hostname unless the conditions set it
+func (l Loader) conditionVars() map[string]string {
+	vars := map[string]string{}
+	if host, err := os.Hostname(); err == nil {
+		vars["hostname"] = host
+	}
+	for k, v := range l.Conditions {
+		vars[k] = v
+	}
+	return vars
+}
+
+// evalCondition evaluates a guard expression. Expressions compare variables to quoted strings
+// with == and !=, and combine comparisons with &&, || and ! and parentheses. A variable on its own
+// is true if it is set and not empty.
+func evalCondition(expr string, vars map[string]string) (bool, error) {
+	tokens, err := tokenizeCondition(expr)
+	if err != nil {
+		return false, err
+	}
+
+	p := &conditionParser{tokens: tokens, vars: vars}
+	result, err := p.or()
+	if err != nil {
+		return false, err
+	}
+	if p.pos < len(p.tokens) {
+		return false, fmt.Errorf("unexpected %s", p.tokens[p.pos].text)
+	}
+	return result, nil
+}
+
+// conditionToken is a lexical token of a guard expression
+type conditionToken struct {
+	text   string
+	quoted bool
+}
+
+// tokenizeCondition splits a guard expression into identifiers, quoted strings and operators
+func tokenizeCondition(expr string) ([]conditionToken, error) {
+
+	var tokens []conditionToken
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(expr[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			tokens = append(tokens, conditionToken{expr[i+1 : i+1+end], true})
+			i += end + 2
+
+		case strings.HasPrefix(expr[i:], "==") || strings.HasPrefix(expr[i:], "!=") ||
+			strings.HasPrefix(expr[i:], "&&") || strings.HasPrefix(expr[i:], "||"):
+			tokens = append(tokens, conditionToken{expr[i : i+2], false})
+			i += 2
+
+		case c == '!' || c == '(' || c == ')':
+			tokens = append(tokens, conditionToken{expr[i : i+1], false})
+			i++
+
+		case isIdentRune(rune(c)):
+			start := i
+			for i < len(expr) && isIdentRune(rune(expr[i])) {
+				i++
+			}
+			tokens = append(tokens, conditionToken{expr[start:i], false})
+
+		default:
+			return nil, fmt.Errorf("unexpected character %q", c)
+		}
+	}
+	return tokens, nil
+}
+
+func isIdentRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.' || r == '-'
+}
+
+// conditionParser is a recursive descent parser evaluating guard expressions as it parses them
+type conditionParser struct {
+	tokens []conditionToken
+	pos    int
+	vars   map[string]string
+}
+
+func (p *conditionParser) peek() string {
+	if p.pos < len(p.tokens) && !p.tokens[p.pos].quoted {
+		return p.tokens[p.pos].text
+	}
+	return ""
+}
+
+func (p *conditionParser) or() (bool, error) {
+	result, err := p.and()
+	for err == nil && p.peek() == "||" {
+		p.pos++
+		var rhs bool
+		rhs, err = p.and()
+		result = result || rhs
+	}
+	return result, err
+}
+
+func (p *conditionParser) and() (bool, error) {
+	result, err := p.unary()
+	for err == nil && p.peek() == "&&" {
+		p.pos++
+		var rhs bool
+		rhs, err = p.unary()
+		result = result && rhs
+	}
+	return result, err
+}
+
+func (p *conditionParser) unary() (bool, error) {
+
+	if p.pos >= len(p.tokens) {
+		return false, fmt.Errorf("unexpected end of expression")
+	}
+
+	switch p.peek() {
+	case "!":
+		p.pos++
+		result, err := p.unary()
+		return !result, err
+
+	case "(":
+		p.pos++
+		result, err := p.or()
+		if err != nil {
+			return false, err
+		}
+		if p.peek() != ")" {
+			return false, fmt.Errorf("missing )")
+		}
+		p.pos++
+		return result, nil
+	}
+
+	return p.comparison()
+}
+
+func (p *conditionParser) comparison() (bool, error) {
+
+	tok := p.tokens[p.pos]
+	if tok.quoted || !isIdentRune(rune(tok.text[0])) {
+		return false, fmt.Errorf("expected a variable, got %s", tok.text)
+	}
+	p.pos++
+	value := p.vars[tok.text]
+
+	op := p.peek()
+	if op != "==" && op != "!=" {
+		return value != "", nil
+	}
+	p.pos++
+
+	if p.pos >= len(p.tokens) || !p.tokens[p.pos].quoted {
+		return false, fmt.Errorf("expected a quoted string after %s", op)
+	}
+	operand := p.tokens[p.pos].text
+	p.pos++
+
+	return (value == operand) == (op == "=="), nil
+}
@@ -0,0 +1,92 @@
+package gofigure
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/EverythingMe/gofigure/yaml"
+)
+
+func TestEvalCondition(t *testing.T) {
+
+	vars := map[string]string{"profile": "prod", "region": "eu-west-1", "empty": ""}
+	cases := map[string]bool{
+		`profile == "prod"`:                               true,
+		`profile != 'prod'`:                               false,
+		`profile == "prod" && region == "us-east-1"`:      false,
+		`profile == "dev" || region == "eu-west-1"`:       true,
+		`!(profile == "dev") && region`:                   true,
+		`empty || missing`:                                false,
+		`!missing`:                                        true,
+		`profile == "dev" || profile == "prod" && !empty`: true,
+	}
+	for expr, expected := range cases {
+		result, err := evalCondition(expr, vars)
+		if err != nil {
+			t.Errorf("Evaluating %s: %s", expr, err)
+		}
+		if result != expected {
+			t.Errorf("%s evaluated to %v", expr, result)
+		}
+	}
+
+	for _, expr := range []string{``, `profile ==`, `profile == prod`, `(profile`, `"prod"`, `profile "prod"`, `profile = "prod"`} {
+		if _, err := evalCondition(expr, vars); err == nil {
+			t.Errorf("Expected an error evaluating %s", expr)
+		}
+	}
+}
+
+func TestConditions(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gofigure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeFiles(t, dir, map[string]string{
+		"a.yaml": `
+workers: 2
+debug:
+  $when: profile == "dev"
+  verbose: true
+servers:
+  - {$when: 'profile == "prod"', host: prod-1}
+  - {$when: 'profile == "dev"', host: dev-1}
+  - {host: any}
+`,
+		"b.yaml": "$when: profile == 'prod'\nworkers: 16\n",
+		"c.yaml": "$when: profile == 'dev'\nworkers: 1\n",
+	})
+
+	type server struct {
+		Host string
+	}
+	var conf struct {
+		Workers int
+		Debug   map[string]interface{}
+		Servers []server
+	}
+
+	loader := NewLoader(yaml.Decoder{}, true)
+	loader.Conditions = map[string]string{"profile": "prod"}
+	if err := loader.LoadRecursive(&conf, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if conf.Workers != 16 || conf.Debug != nil {
+		t.Errorf("Conditional sections not applied: %#v", conf)
+	}
+	if !reflect.DeepEqual(conf.Servers, []server{{"prod-1"}, {"any"}}) {
+		t.Errorf("Conditional list items not applied: %#v", conf.Servers)
+	}
+
+	writeFiles(t, dir, map[string]string{"d.yaml": "$when: 'profile =='\n"})
+	if err := loader.LoadFile(&conf, filepath.Join(dir, "d.yaml")); err == nil {
+		t.Error("Expected an error for an invalid guard")
+	}
+}
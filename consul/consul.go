@@ -0,0 +1,131 @@
+// Package consul loads config documents from Consul KV keys, and watches them for changes with
+// Consul's blocking queries, so programs reload with low latency and without polling.
+package consul
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"time"
+
+	"github.com/EverythingMe/gofigure"
+	"github.com/EverythingMe/gofigure/internal/monitor"
+	"github.com/hashicorp/consul/api"
+	"github.com/op/go-logging"
+)
+
+var log = logging.MustGetLogger("gofigure")
+
+// Source is a gofigure.Source reading a document from the value of a Consul KV key
+type Source struct {
+	Client *api.Client
+	Key    string
+}
+
+// Name returns the source's key
+func (s Source) Name() string {
+	return "consul:" + s.Key
+}
+
+// Fetch gets the value of the source's key. A missing key is a permanent failure
+func (s Source) Fetch(ctx context.Context) (io.ReadCloser, error) {
+	pair, _, err := s.Client.KV().Get(s.Key, (&api.QueryOptions{}).WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if pair == nil {
+		return nil, gofigure.Permanent(fmt.Errorf("consul: key %s not found", s.Key))
+	}
+	return ioutil.NopCloser(bytes.NewReader(pair.Value)), nil
+}
+
+// Version returns the index the source's key was last modified at
+func (s Source) Version(ctx context.Context) (string, error) {
+	pair, _, err := s.Client.KV().Get(s.Key, (&api.QueryOptions{}).WithContext(ctx))
+	if err != nil || pair == nil {
+		return "", err
+	}
+	return strconv.FormatUint(pair.ModifyIndex, 10), nil
+}
+
+// Monitor is a gofigure.ReloadMonitor calling its Reloader whenever one of its keys changes.
+//
+// Keys are watched with blocking queries, which are resumed from the last index they saw after a
+// failure, with a backoff. Changes made while Consul was unreachable trigger a reload once it is
+// reachable again.
+type Monitor struct {
+	client *api.Client
+	keys   []string
+
+	// WaitTime is how long a blocking query waits for a change before it's made again, 5 minutes
+	// if zero
+	WaitTime time.Duration
+
+	group monitor.Group
+}
+
+// NewMonitor creates a monitor watching keys
+func NewMonitor(client *api.Client, keys ...string) *Monitor {
+	return &Monitor{
+		client: client,
+		keys:   keys,
+	}
+}
+
+// Watch starts watching the keys, calling r whenever one of them changes
+func (m *Monitor) Watch(r gofigure.Reloader) {
+	m.group.Start(len(m.keys), func(ctx context.Context, i int) {
+		m.watch(ctx, m.keys[i], r)
+	})
+}
+
+// Stop stops watching, and waits for the watches to end
+func (m *Monitor) Stop() {
+	m.group.Stop("consul")
+}
+
+// watch watches a single key until ctx is done
+func (m *Monitor) watch(ctx context.Context, key string, r gofigure.Reloader) {
+
+	wait := m.WaitTime
+	if wait == 0 {
+		wait = 5 * time.Minute
+	}
+
+	var index uint64
+	var modified uint64
+	first := true
+	var backoff monitor.Backoff
+
+	for ctx.Err() == nil {
+		opts := (&api.QueryOptions{WaitIndex: index, WaitTime: wait}).WithContext(ctx)
+		pair, meta, err := m.client.KV().Get(key, opts)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Info("consul watch of %s failed, retrying in %s: %s", key, backoff.Delay(), err)
+			backoff.Wait(ctx)
+			continue
+		}
+		backoff.Reset()
+
+		// indexes can go backwards, e.g. after a snapshot restore, in which case watching starts over
+		if index = meta.LastIndex; index < opts.WaitIndex {
+			index = 0
+		}
+
+		var current uint64
+		if pair != nil {
+			current = pair.ModifyIndex
+		}
+		if !first && current != modified {
+			log.Info("consul key %s changed, reloading", key)
+			m.group.Reload(r)
+		}
+		modified, first = current, false
+	}
+}
@@ -0,0 +1,141 @@
+package consul
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/EverythingMe/gofigure"
+	"github.com/EverythingMe/gofigure/yaml"
+	"github.com/hashicorp/consul/api"
+)
+
+// fakeKV is a minimal in memory implementation of Consul's KV HTTP API, supporting blocking queries
+type fakeKV struct {
+	mu      sync.Mutex
+	changed *sync.Cond
+	index   uint64
+	values  map[string]uint64
+	data    map[string]string
+	failing int
+}
+
+func newFakeKV() *fakeKV {
+	kv := &fakeKV{index: 1, values: map[string]uint64{}, data: map[string]string{}}
+	kv.changed = sync.NewCond(&kv.mu)
+	return kv
+}
+
+func (kv *fakeKV) put(key, value string) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	kv.index++
+	kv.values[key] = kv.index
+	kv.data[key] = value
+	kv.changed.Broadcast()
+}
+
+func (kv *fakeKV) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	if kv.failing > 0 {
+		kv.failing--
+		http.Error(w, "unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	if wait, _ := strconv.ParseUint(r.URL.Query().Get("index"), 10, 64); wait > 0 {
+		deadline := time.AfterFunc(time.Second, kv.changed.Broadcast)
+		defer deadline.Stop()
+		start := time.Now()
+		for kv.index <= wait && time.Since(start) < time.Second {
+			kv.changed.Wait()
+		}
+	}
+
+	w.Header().Set("X-Consul-Index", strconv.FormatUint(kv.index, 10))
+	modified, found := kv.values[key]
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+	fmt.Fprintf(w, `[{"Key": %q, "Value": %q, "ModifyIndex": %d}]`,
+		key, base64.StdEncoding.EncodeToString([]byte(kv.data[key])), modified)
+}
+
+func TestConsul(t *testing.T) {
+
+	kv := newFakeKV()
+	server := httptest.NewServer(kv)
+	defer server.Close()
+
+	client, err := api.NewClient(&api.Config{Address: strings.TrimPrefix(server.URL, "http://")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kv.put("app/config", "redis:\n  server: consul:6379\n")
+
+	var conf struct {
+		Redis struct {
+			Server string
+		}
+	}
+	loader := gofigure.NewLoader(yaml.Decoder{}, true)
+	loader.Sources = []gofigure.Source{Source{Client: client, Key: "app/config"}}
+	if err := loader.LoadRecursive(&conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Redis.Server != "consul:6379" {
+		t.Errorf("Unexpected config %#v", conf)
+	}
+
+	loader.Sources = []gofigure.Source{Source{Client: client, Key: "app/missing"}}
+	if err := loader.LoadRecursive(&conf); err == nil {
+		t.Error("Expected an error for a missing key")
+	}
+
+	reloads := make(chan bool, 10)
+	m := NewMonitor(client, "app/config")
+	m.WaitTime = time.Second
+	m.Watch(gofigure.ReloadFunc(func() { reloads <- true }))
+	defer m.Stop()
+
+	// give the first query time to return
+	time.Sleep(100 * time.Millisecond)
+	kv.put("app/config", "redis:\n  server: consul:6380\n")
+	select {
+	case <-reloads:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Change did not trigger a reload")
+	}
+
+	kv.put("app/other", "x")
+	select {
+	case <-reloads:
+		t.Fatal("Other keys should not trigger reloads")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	// changes made while consul fails are picked up when the watch resumes
+	kv.mu.Lock()
+	kv.failing = 2
+	kv.mu.Unlock()
+	kv.put("app/other", "y")
+	time.Sleep(50 * time.Millisecond)
+	kv.put("app/config", "redis:\n  server: consul:6381\n")
+	select {
+	case <-reloads:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Change did not trigger a reload after a failure")
+	}
+}
@@ -0,0 +1,135 @@
+package gofigure
+
+import (
+	"encoding"
+	"encoding/json"
+	"net"
+	"net/url"
+	"reflect"
+	"strconv"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// converter parses a string from a document into a value of a specific type
+type converter func(s string) (interface{}, error)
+
+// convertersMu guards converters, which can be registered while configs are being loaded
+var convertersMu sync.RWMutex
+
+// converters are used for types that don't implement encoding.TextUnmarshaler, either because
+// they are common enough in configs to be supported out of the box, or because they were
+// registered with RegisterConverter. Types that do, such as net.IP, netip.Addr, netip.Prefix and
+// regexp.Regexp, need no converter
+var converters = map[reflect.Type]converter{
+	reflect.TypeOf(url.URL{}): func(s string) (interface{}, error) {
+		u, err := url.Parse(s)
+		if err != nil {
+			return nil, err
+		}
+		return *u, nil
+	},
+	reflect.TypeOf(net.IPNet{}): func(s string) (interface{}, error) {
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, err
+		}
+		return *n, nil
+	},
+}
+
+// RegisterConverter registers a function parsing strings into values of type T, for types that
+// cannot implement encoding.TextUnmarshaler because they belong to another package. Fields of
+// type T (or *T) are then decoded through fn, which takes precedence over any UnmarshalText
+// method T might have
+func RegisterConverter[T any](fn func(s string) (T, error)) {
+	convertersMu.Lock()
+	defer convertersMu.Unlock()
+
+	converters[reflect.TypeOf((*T)(nil)).Elem()] = func(s string) (interface{}, error) {
+		return fn(s)
+	}
+}
+
+// unmarshalText assigns text to dst through a converter or the UnmarshalText method of dst,
+// and reports whether dst's type is handled by either
+func unmarshalText(text string, dst reflect.Value) (bool, error) {
+	convertersMu.RLock()
+	c, found := converters[dst.Type()]
+	convertersMu.RUnlock()
+
+	if found {
+		v, err := c(text)
+		if err != nil {
+			return true, err
+		}
+		dst.Set(reflect.ValueOf(v))
+		return true, nil
+	}
+
+	if dst.CanAddr() {
+		if u, ok := dst.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			return true, u.UnmarshalText([]byte(text))
+		}
+	}
+	return false, nil
+}
+
+// obsoleteUnmarshaler is the Unmarshaler interface of yaml.v2, which yaml.v3 still honors
+type obsoleteUnmarshaler interface {
+	UnmarshalYAML(unmarshal func(interface{}) error) error
+}
+
+// unmarshalHook assigns a document value to dst through the UnmarshalYAML or UnmarshalJSON method
+// of dst, re-encoding the value for it, and reports whether dst's type has either. Types with a
+// converter are left to it, and YAML unmarshalers win over JSON ones, as documents are YAML's
+// superset
+func unmarshalHook(src interface{}, dst reflect.Value) (bool, error) {
+	if !dst.CanAddr() {
+		return false, nil
+	}
+	convertersMu.RLock()
+	_, found := converters[dst.Type()]
+	convertersMu.RUnlock()
+	if found {
+		return false, nil
+	}
+
+	switch u := dst.Addr().Interface().(type) {
+	case yaml.Unmarshaler, obsoleteUnmarshaler:
+		var node yaml.Node
+		if err := node.Encode(src); err != nil {
+			return true, err
+		}
+		return true, node.Decode(u)
+
+	case json.Unmarshaler:
+		data, err := json.Marshal(src)
+		if err != nil {
+			return true, err
+		}
+		return true, u.UnmarshalJSON(data)
+	}
+	return false, nil
+}
+
+// scalarText returns the text form of a scalar document value, so types that are parsed from
+// text also accept values that a decoder resolved to numbers or bools
+func scalarText(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case bool:
+		return strconv.FormatBool(v), true
+	case int:
+		return strconv.Itoa(v), true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case uint64:
+		return strconv.FormatUint(v, 10), true
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), true
+	}
+	return "", false
+}
@@ -0,0 +1,143 @@
+package gofigure
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestBindBuiltinTypes(t *testing.T) {
+
+	var conf struct {
+		Endpoint  *url.URL
+		Fallback  url.URL
+		Listen    net.IP
+		Gateway   netip.Addr
+		Allow     []netip.Prefix
+		Office    *net.IPNet
+		Pattern   *regexp.Regexp
+		Blocklist []*regexp.Regexp
+	}
+
+	doc := map[string]interface{}{
+		"endpoint":  "https://api.example.com:8443/v1",
+		"fallback":  "http://localhost",
+		"listen":    "0.0.0.0",
+		"gateway":   "10.0.0.1",
+		"allow":     []interface{}{"10.0.0.0/8", "fd00::/8"},
+		"office":    "192.168.1.0/24",
+		"pattern":   "^/api/v[0-9]+/",
+		"blocklist": []interface{}{"\\.php$", "^/wp-"},
+	}
+	if err := bind(doc, &conf); err != nil {
+		t.Fatal(err)
+	}
+
+	if conf.Endpoint.Host != "api.example.com:8443" || conf.Endpoint.Path != "/v1" || conf.Fallback.Host != "localhost" {
+		t.Errorf("URLs not decoded: %v %v", conf.Endpoint, conf.Fallback)
+	}
+	if !conf.Listen.Equal(net.IPv4zero) || conf.Gateway != netip.MustParseAddr("10.0.0.1") {
+		t.Errorf("IPs not decoded: %v %v", conf.Listen, conf.Gateway)
+	}
+	if len(conf.Allow) != 2 || !conf.Allow[0].Contains(netip.MustParseAddr("10.1.2.3")) {
+		t.Errorf("Prefixes not decoded: %v", conf.Allow)
+	}
+	if !conf.Office.Contains(net.ParseIP("192.168.1.7")) {
+		t.Errorf("CIDR not decoded: %v", conf.Office)
+	}
+	if !conf.Pattern.MatchString("/api/v2/users") || !conf.Blocklist[1].MatchString("/wp-admin") {
+		t.Errorf("Regexps not decoded: %v %v", conf.Pattern, conf.Blocklist)
+	}
+
+	for key, bad := range map[string]string{
+		"endpoint": "http://[::1",
+		"listen":   "not an ip",
+		"office":   "10.0.0.0/33",
+		"pattern":  "([a-z]",
+	} {
+		if err := bind(map[string]interface{}{key: bad}, &conf); err == nil {
+			t.Errorf("Expected %q to be rejected for %s", bad, key)
+		}
+	}
+}
+
+// version implements encoding.TextUnmarshaler, and is written as a bare number in configs
+type version struct {
+	major, minor string
+}
+
+func (v *version) UnmarshalText(text []byte) error {
+	parts := strings.SplitN(string(text), ".", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("bad version %q", text)
+	}
+	v.major, v.minor = parts[0], parts[1]
+	return nil
+}
+
+// celsius stands for a third party type we cannot add methods to
+type celsius float64
+
+func TestTextUnmarshaler(t *testing.T) {
+
+	var conf struct {
+		Version version
+		Compat  []*version
+	}
+
+	doc := map[string]interface{}{
+		"version": 1.5,
+		"compat":  []interface{}{"1.4", 1.3},
+	}
+	if err := bind(doc, &conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Version != (version{"1", "5"}) || *conf.Compat[1] != (version{"1", "3"}) {
+		t.Errorf("Versions not decoded through UnmarshalText: %v", conf)
+	}
+
+	if err := bind(map[string]interface{}{"version": 2}, &conf); err == nil {
+		t.Errorf("Expected UnmarshalText errors to be returned")
+	}
+}
+
+func TestRegisterConverter(t *testing.T) {
+
+	RegisterConverter(func(s string) (celsius, error) {
+		if !strings.HasSuffix(s, "F") {
+			return 0, fmt.Errorf("unknown unit in %q", s)
+		}
+		f, err := strconv.ParseFloat(strings.TrimSuffix(s, "F"), 64)
+		return celsius((f - 32) * 5 / 9), err
+	})
+	defer func() {
+		convertersMu.Lock()
+		delete(converters, reflect.TypeOf(celsius(0)))
+		convertersMu.Unlock()
+	}()
+
+	var conf struct {
+		Max   celsius
+		Zones map[string]*celsius
+	}
+	doc := map[string]interface{}{
+		"max":   "212F",
+		"zones": map[string]interface{}{"attic": "32F"},
+	}
+	if err := bind(doc, &conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Max != 100 || *conf.Zones["attic"] != 0 {
+		t.Errorf("Values not decoded through the converter: %v", conf)
+	}
+
+	if err := bind(map[string]interface{}{"max": "30C"}, &conf); err == nil {
+		t.Errorf("Expected converter errors to be returned")
+	}
+}
@@ -0,0 +1,161 @@
+package gofigure
+
+import (
+	"io"
+	"sync"
+
+	"github.com/EverythingMe/gofigure/json"
+	"github.com/EverythingMe/gofigure/yaml"
+)
+
+// Decoders is a Decoder made of several decoders, which decodes every file with the first of them
+// that can decode it, so a loader can read a directory holding files of several formats:
+//
+//	NewLoader(Decoders{yaml.Decoder{}, json.Decoder{}, toml.Decoder{}}, true)
+type Decoders []Decoder
+
+// For returns the first decoder that can decode the file at path, or nil if none can
+func (ds Decoders) For(path string) Decoder {
+	for _, d := range ds {
+		if d.CanDecode(path) {
+			return d
+		}
+	}
+	return nil
+}
+
+// CanDecode returns true if any of the decoders can decode the file at path
+func (ds Decoders) CanDecode(path string) bool {
+	return ds.For(path) != nil
+}
+
+// Decode decodes with the first decoder. Loaders pick the decoder of every file with For instead,
+// since which one to use depends on the file's path
+func (ds Decoders) Decode(r io.Reader, config interface{}) error {
+	if len(ds) == 0 {
+		return nil
+	}
+	return ds[0].Decode(r, config)
+}
+
+// decoderSet is implemented by decoders that delegate to other decoders depending on the path of
+// the file, like Decoders
+type decoderSet interface {
+	For(path string) Decoder
+}
+
+// decoderFor returns the decoder to use for the file at path: the loader's decoder, or the one
+// it delegates to
+func (l Loader) decoderFor(path string) Decoder {
+	if set, ok := l.decoder.(decoderSet); ok {
+		if d := set.For(path); d != nil {
+			return d
+		}
+	}
+	return l.decoder
+}
+
+// namedDecoder is a registered decoder
+type namedDecoder struct {
+	name    string
+	decoder Decoder
+}
+
+// decodersMu guards registeredDecoders, which decoder packages add to in their init functions
+var decodersMu sync.RWMutex
+
+// registeredDecoders are the decoders registered with RegisterDecoder, in the order they were
+// registered. The yaml decoder is built in
+var registeredDecoders = []namedDecoder{
+	{"yaml", yaml.Decoder{}},
+}
+
+// builtinDecoders can be looked up by name without being registered, for the sources serving their
+// format, but DefaultLoader only decodes files with them once they are registered
+var builtinDecoders = []namedDecoder{
+	{"json", json.Decoder{}},
+}
+
+// RegisterDecoder makes a decoder available under a format name, like "toml". Registered decoders
+// are what DefaultLoader decodes files with, and can be looked up by name with LookupDecoder.
+//
+// The decoder packages of gofigure register themselves when they are imported, so importing one
+// for its side effects is enough for DefaultLoader to read its format:
+//
+//	import _ "github.com/EverythingMe/gofigure/toml"
+//
+// The json decoder is the exception, as gofigure always imports it: DefaultLoader reads json files
+// once it's registered with RegisterDecoder("json", json.Decoder{}).
+//
+// Building with the gofigure_noregister tag disables this. RegisterDecoder panics if the name is
+// already taken, as this is a programming error usually made in init functions.
+func RegisterDecoder(name string, d Decoder) {
+	decodersMu.Lock()
+	defer decodersMu.Unlock()
+
+	for _, nd := range registeredDecoders {
+		if nd.name == name {
+			panic("gofigure: decoder registered twice: " + name)
+		}
+	}
+	registeredDecoders = append(registeredDecoders, namedDecoder{name, d})
+}
+
+// LookupDecoder returns the decoder registered under a format name, and whether there is one. The
+// json decoder is found even if it isn't registered
+func LookupDecoder(name string) (Decoder, bool) {
+	decodersMu.RLock()
+	defer decodersMu.RUnlock()
+
+	for _, nds := range [][]namedDecoder{registeredDecoders, builtinDecoders} {
+		for _, nd := range nds {
+			if nd.name == name {
+				return nd.decoder, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// lookupDecoderFor returns the registered or built in decoder that can decode the file at path, or
+// nil if none can
+func lookupDecoderFor(path string) Decoder {
+	if d := RegisteredDecoders().For(path); d != nil {
+		return d
+	}
+	for _, nd := range builtinDecoders {
+		if nd.decoder.CanDecode(path) {
+			return nd.decoder
+		}
+	}
+	return nil
+}
+
+// RegisteredDecoders returns the registered decoders, in the order they were registered
+func RegisteredDecoders() Decoders {
+	decodersMu.RLock()
+	defer decodersMu.RUnlock()
+
+	ds := make(Decoders, len(registeredDecoders))
+	for i, nd := range registeredDecoders {
+		ds[i] = nd.decoder
+	}
+	return ds
+}
+
+// registered is a Decoder delegating to the registered decoders. It looks them up every time, so
+// that decoders registered after it's created, like in the init functions of packages importing
+// gofigure, are used too
+type registered struct{}
+
+func (registered) For(path string) Decoder {
+	return RegisteredDecoders().For(path)
+}
+
+func (registered) CanDecode(path string) bool {
+	return RegisteredDecoders().CanDecode(path)
+}
+
+func (registered) Decode(r io.Reader, config interface{}) error {
+	return RegisteredDecoders().Decode(r, config)
+}
@@ -0,0 +1,79 @@
+package gofigure
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/EverythingMe/gofigure/json"
+	"github.com/EverythingMe/gofigure/yaml"
+)
+
+func TestDecoders(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gofigure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeFiles(t, dir, map[string]string{
+		"a.yaml": "redis:\n  server: localhost:6379\n",
+		"b.json": `{"mysql": {"user": "root"}}`,
+		"c.txt":  "ignored",
+	})
+
+	var conf config
+	loader := NewLoader(Decoders{yaml.Decoder{}, json.Decoder{}}, true)
+	if err := loader.LoadRecursive(&conf, dir); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Redis.Server != "localhost:6379" || conf.Mysql.User != "root" {
+		t.Errorf("Files not decoded by their decoders: %#v", conf)
+	}
+
+	// json files aren't decoded by DefaultLoader unless the json decoder is registered
+	conf = config{}
+	if err := DefaultLoader.LoadRecursive(&conf, dir); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Redis.Server != "localhost:6379" || conf.Mysql.User != "" {
+		t.Errorf("Files not decoded by the registered decoders: %#v", conf)
+	}
+	if _, found := LookupDecoder("json"); !found {
+		t.Error("Expected the json decoder to be found by name")
+	}
+}
+
+// upperDecoder decodes files with an .upper extension, for testing registration
+type upperDecoder struct{}
+
+func (upperDecoder) Decode(r io.Reader, config interface{}) error { return nil }
+func (upperDecoder) CanDecode(path string) bool                   { return false }
+
+func TestRegisterDecoder(t *testing.T) {
+
+	if _, found := LookupDecoder("yaml"); !found {
+		t.Error("yaml should be registered")
+	}
+	if _, found := LookupDecoder("gofigure-test"); found {
+		t.Error("Unexpected decoder")
+	}
+
+	RegisterDecoder("gofigure-test", upperDecoder{})
+	if d, found := LookupDecoder("gofigure-test"); !found || d != (upperDecoder{}) {
+		t.Errorf("Registered decoder not found: %v", d)
+	}
+	ds := RegisteredDecoders()
+	if ds[len(ds)-1] != (upperDecoder{}) {
+		t.Errorf("Decoders not in registration order: %v", ds)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a panic registering a decoder twice")
+		}
+	}()
+	RegisterDecoder("gofigure-test", upperDecoder{})
+}
@@ -0,0 +1,119 @@
+// Package decrypt has preprocessors decrypting whole config files encrypted with age
+// (https://age-encryption.org) or PGP, in memory, before they are decoded.
+//
+// Encrypted files keep the extension of their format, e.g. secrets.yaml, so that the loader's
+// decoder picks them up. Files that aren't encrypted are passed through untouched, unless the
+// preprocessor requires encryption.
+package decrypt
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+
+	"filippo.io/age"
+	agearmor "filippo.io/age/armor"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	pgparmor "github.com/ProtonMail/go-crypto/openpgp/armor"
+)
+
+// ErrNotEncrypted is returned for plain files by preprocessors that require encryption
+var ErrNotEncrypted = errors.New("decrypt: file is not encrypted")
+
+var (
+	ageHeader      = []byte("age-encryption.org/")
+	ageArmorHeader = []byte(agearmor.Header)
+	pgpArmorHeader = []byte("-----BEGIN PGP MESSAGE-----")
+)
+
+// Age is a gofigure.Preprocessor decrypting files encrypted with age, in binary or armored form
+type Age struct {
+	// Identities are the keys files can be decrypted with, e.g. as parsed by age.ParseIdentities
+	Identities []age.Identity
+
+	// Required makes plain files an error
+	Required bool
+}
+
+// Preprocess decrypts the file's contents if they are age encrypted
+func (a Age) Preprocess(path string, data []byte) ([]byte, error) {
+
+	var r io.Reader
+	switch trimmed := bytes.TrimSpace(data); {
+	case bytes.HasPrefix(data, ageHeader):
+		r = bytes.NewReader(data)
+	case bytes.HasPrefix(trimmed, ageArmorHeader):
+		r = agearmor.NewReader(bytes.NewReader(trimmed))
+	default:
+		return plain(data, a.Required)
+	}
+
+	out, err := age.Decrypt(r, a.Identities...)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(out)
+}
+
+// PGP is a gofigure.Preprocessor decrypting files encrypted with PGP, in binary or armored form
+type PGP struct {
+	// Keyring holds the private keys files can be decrypted with, e.g. as read by
+	// openpgp.ReadArmoredKeyRing
+	Keyring openpgp.KeyRing
+
+	// Prompt, if set, is called to decrypt passphrase protected keys, or for the passphrase of
+	// symmetrically encrypted files. See openpgp.PromptFunction
+	Prompt openpgp.PromptFunction
+
+	// Required makes plain files an error
+	Required bool
+}
+
+// Preprocess decrypts the file's contents if they are PGP encrypted
+func (p PGP) Preprocess(path string, data []byte) ([]byte, error) {
+
+	var r io.Reader
+	switch trimmed := bytes.TrimSpace(data); {
+	case bytes.HasPrefix(trimmed, pgpArmorHeader):
+		block, err := pgparmor.Decode(bytes.NewReader(trimmed))
+		if err != nil {
+			return nil, err
+		}
+		r = block.Body
+	case isPGPPacket(data):
+		r = bytes.NewReader(data)
+	default:
+		return plain(data, p.Required)
+	}
+
+	md, err := openpgp.ReadMessage(r, p.Keyring, p.Prompt, nil)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(md.UnverifiedBody)
+}
+
+// isPGPPacket returns true if data starts with the packet tag of a PGP encrypted message: a public
+// key or symmetric key encrypted session key. Text config files never start with such a byte
+func isPGPPacket(data []byte) bool {
+	if len(data) == 0 || data[0]&0x80 == 0 {
+		return false
+	}
+
+	var tag byte
+	if data[0]&0x40 != 0 {
+		tag = data[0] & 0x3f
+	} else {
+		tag = (data[0] >> 2) & 0x0f
+	}
+	return tag == 1 || tag == 3
+}
+
+// plain passes a plain file through, unless encryption is required
+func plain(data []byte, required bool) ([]byte, error) {
+	if required {
+		return nil, ErrNotEncrypted
+	}
+	return data, nil
+}
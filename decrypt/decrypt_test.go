@@ -0,0 +1,130 @@
+package decrypt
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+	agearmor "filippo.io/age/armor"
+	"github.com/EverythingMe/gofigure"
+	"github.com/EverythingMe/gofigure/yaml"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	pgparmor "github.com/ProtonMail/go-crypto/openpgp/armor"
+)
+
+const plaintext = "redis:\n  server: localhost:6379\n"
+
+func TestAge(t *testing.T) {
+
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var binary, armored bytes.Buffer
+	for _, out := range []io.Writer{&binary, agearmor.NewWriter(&armored)} {
+		w, err := age.Encrypt(out, identity.Recipient())
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(w, plaintext)
+		w.Close()
+		if c, ok := out.(io.Closer); ok {
+			c.Close()
+		}
+	}
+
+	p := Age{Identities: []age.Identity{identity}}
+	for _, data := range [][]byte{binary.Bytes(), armored.Bytes(), []byte(plaintext)} {
+		out, err := p.Preprocess("conf.yaml", data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != plaintext {
+			t.Errorf("Unexpected decrypted contents %q", out)
+		}
+	}
+
+	other, _ := age.GenerateX25519Identity()
+	if _, err := (Age{Identities: []age.Identity{other}}).Preprocess("conf.yaml", binary.Bytes()); err == nil {
+		t.Error("Expected an error decrypting with the wrong identity")
+	}
+	if _, err := (Age{Required: true}).Preprocess("conf.yaml", []byte(plaintext)); err != ErrNotEncrypted {
+		t.Errorf("Expected ErrNotEncrypted, got %v", err)
+	}
+
+	dir, err := ioutil.TempDir("", "gofigure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "secrets.yaml"), armored.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var conf struct {
+		Redis struct {
+			Server string
+		}
+	}
+	loader := gofigure.NewLoader(yaml.Decoder{}, true)
+	loader.Preprocessors = []gofigure.Preprocessor{p}
+	if err := loader.LoadRecursive(&conf, dir); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Redis.Server != "localhost:6379" {
+		t.Errorf("Encrypted file not loaded: %#v", conf)
+	}
+}
+
+func TestPGP(t *testing.T) {
+
+	entity, err := openpgp.NewEntity("gofigure", "", "test@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyring := openpgp.EntityList{entity}
+
+	var binary, armored bytes.Buffer
+	aw, err := pgparmor.Encode(&armored, "PGP MESSAGE", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, out := range []io.WriteCloser{nopCloser{&binary}, aw} {
+		w, err := openpgp.Encrypt(out, keyring, nil, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(w, plaintext)
+		w.Close()
+		out.Close()
+	}
+
+	p := PGP{Keyring: keyring}
+	for _, data := range [][]byte{binary.Bytes(), armored.Bytes(), []byte(plaintext)} {
+		out, err := p.Preprocess("conf.yaml", data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != plaintext {
+			t.Errorf("Unexpected decrypted contents %q", out)
+		}
+	}
+
+	if _, err := (PGP{Keyring: openpgp.EntityList{}}).Preprocess("conf.yaml", armored.Bytes()); err == nil {
+		t.Error("Expected an error decrypting without the key")
+	}
+	if _, err := (PGP{Required: true}).Preprocess("conf.yaml", []byte(plaintext)); err != ErrNotEncrypted {
+		t.Errorf("Expected ErrNotEncrypted, got %v", err)
+	}
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
@@ -0,0 +1,46 @@
+package gofigure
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+)
+
+// duplicate returns the first file read by the load with the same contents as the file at path,
+// or "" if there's none, in which case path is recorded as the first with its contents
+func (s *loadState) duplicate(path string) (string, error) {
+
+	fp, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer fp.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, fp); err != nil {
+		return "", err
+	}
+	digest := hex.EncodeToString(h.Sum(nil))
+
+	if original, found := s.digests[digest]; found {
+		return original, nil
+	}
+	if s.digests == nil {
+		s.digests = map[string]string{}
+	}
+	s.digests[digest] = path
+	return "", nil
+}
+
+// duplicateMessage describes the duplicate at path of the file original: a link to the same file,
+// or a copy of its contents
+func duplicateMessage(path, original string) string {
+	a, errA := os.Stat(path)
+	b, errB := os.Stat(original)
+	if errA == nil && errB == nil && os.SameFile(a, b) {
+		return fmt.Sprintf("same file as %s, skipped", original)
+	}
+	return fmt.Sprintf("same contents as %s, skipped", original)
+}
@@ -0,0 +1,58 @@
+package gofigure
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/EverythingMe/gofigure/yaml"
+)
+
+func TestSkipDuplicates(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gofigure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(name, contents string) {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("10-base.yaml", "redis:\n  server: base:6379\n  timeout: 1\n")
+	write("20-override.yaml", "redis:\n  server: override:6379\n")
+	write("30-copy.yaml", "redis:\n  server: base:6379\n  timeout: 1\n")
+	if err := os.Symlink(filepath.Join(dir, "20-override.yaml"), filepath.Join(dir, "40-link.yaml")); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+
+	loader := NewLoader(yaml.Decoder{}, true)
+	var conf config
+	if err := loader.LoadRecursive(&conf, dir); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Redis.Server != "override:6379" {
+		t.Fatalf("Unexpected config: %#v", conf.Redis)
+	}
+
+	loader.SkipDuplicates = true
+	conf = config{}
+	report, err := loader.LoadWithReport(&conf, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the copy of the base file doesn't undo the override
+	if conf.Redis.Server != "override:6379" || len(report.Files) != 2 {
+		t.Errorf("Duplicates not skipped: %#v, %v", conf.Redis, report.Files)
+	}
+	if len(report.Warnings) != 2 ||
+		!strings.Contains(report.Warnings[0].Message, "same contents as") ||
+		!strings.Contains(report.Warnings[1].Message, "same file as") {
+		t.Errorf("Duplicates not reported: %v", report.Warnings)
+	}
+}
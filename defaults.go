@@ -0,0 +1,88 @@
+package gofigure
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/EverythingMe/gofigure/internal/tree"
+)
+
+// defaultsSource is the source defaults are reported to come from
+const defaultsSource = "defaults"
+
+// DefaultsProvider is implemented by config types that provide their own defaults, so they can
+// live next to the struct definition rather than in a checked-in defaults file:
+//
+//	func (Config) Defaults() interface{} {
+//		return Config{Port: 8080, Timeout: 10 * time.Second}
+//	}
+//
+// Defaults returns a value of the config type, or a pointer to one, and is the lowest layer of every
+// load into the type: files, sources and Loader.Defaults are merged on top of it. Only the fields
+// that aren't zero are defaults, so a zero value can't be set as a default, which it is anyway.
+type DefaultsProvider interface {
+	Defaults() interface{}
+}
+
+// defaultsDocument converts a populated config value to a document to merge the documents of a
+// load on top of, keyed by the names the fields are loaded from. Zero fields are left out, so they
+// don't hide the defaults of lower layers or make every key look set
+func defaultsDocument(defaults interface{}) document {
+	v := reflect.ValueOf(defaults)
+	doc, _ := pruneDefaults(dump(v, false), v).(map[string]interface{})
+	if doc == nil {
+		doc = map[string]interface{}{}
+	}
+	return document{defaultsSource, doc}
+}
+
+// pruneDefaults removes the zero fields of the struct v from its dump d, walking the two side by
+// side, and widens the values that remain to those of generic documents
+func pruneDefaults(d interface{}, v reflect.Value) interface{} {
+
+	v = indirectValue(v)
+	switch d := d.(type) {
+	case map[string]interface{}:
+		switch v.Kind() {
+		case reflect.Struct:
+			for _, f := range structFields(v.Type()) {
+				fv, ok := fieldValue(v, f.index)
+				if !ok || fv.IsZero() {
+					delete(d, f.names[0])
+					continue
+				}
+				d[f.names[0]] = pruneDefaults(d[f.names[0]], fv)
+			}
+		case reflect.Map:
+			for it := v.MapRange(); it.Next(); {
+				key := fmt.Sprint(it.Key().Interface())
+				d[key] = pruneDefaults(d[key], it.Value())
+			}
+		}
+		return d
+
+	case []interface{}:
+		for i := range d {
+			d[i] = pruneDefaults(d[i], v.Index(i))
+		}
+		return d
+	}
+	return widen(d)
+}
+
+// withDefaults returns docs with the defaults config provides, if its type is a DefaultsProvider,
+// as their first document. A load at a dotted path loads the config's type at that path, so its
+// defaults are nested there
+func withDefaults(docs []document, path string, config interface{}) []document {
+	provider, ok := config.(DefaultsProvider)
+	if !ok {
+		return docs
+	}
+
+	doc := defaultsDocument(provider.Defaults())
+	keys := tree.Split(path)
+	for i := len(keys) - 1; i >= 0; i-- {
+		doc.tree = map[string]interface{}{keys[i]: doc.tree}
+	}
+	return append([]document{doc}, docs...)
+}
@@ -0,0 +1,68 @@
+package gofigure
+
+import (
+	"testing"
+	"time"
+
+	"github.com/EverythingMe/gofigure/yaml"
+)
+
+type level string
+
+type defaultedConfig struct {
+	Redis struct {
+		Server  string        `yaml:"server"`
+		Timeout int           `yaml:"timeout"`
+		Retries uint8         `yaml:"retries"`
+		Backoff time.Duration `yaml:"backoff"`
+	} `yaml:"redis"`
+	Level level `yaml:"level"`
+}
+
+func (defaultedConfig) Defaults() interface{} {
+	var d defaultedConfig
+	d.Redis.Server = "default:6379"
+	d.Redis.Retries = 3
+	d.Redis.Backoff = time.Second
+	d.Level = "info"
+	return d
+}
+
+func TestDefaults(t *testing.T) {
+
+	loader := NewLoader(yaml.Decoder{}, true)
+
+	var conf defaultedConfig
+	report, err := loader.LoadWithReport(&conf, "./testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Redis.Server != expectedConf.Redis.Server || conf.Redis.Timeout != 10 || conf.Redis.Retries != 3 ||
+		conf.Redis.Backoff != time.Second || conf.Level != "info" {
+		t.Errorf("Unexpected config with the type's defaults %+v", conf)
+	}
+	if report.Origins["redis.retries"] != "defaults" || report.Origins["redis.server"] != "testdata/test.yaml" {
+		t.Errorf("Unexpected origins %v", report.Origins)
+	}
+
+	// the loader's defaults are merged on top of the type's
+	var defaults defaultedConfig
+	defaults.Redis.Retries = 5
+	defaults.Level = "debug"
+	loader.Defaults = defaults
+
+	conf = defaultedConfig{}
+	if err := loader.LoadRecursive(&conf, "./testdata"); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Redis.Retries != 5 || conf.Level != "debug" || conf.Redis.Backoff != time.Second {
+		t.Errorf("Unexpected config with the loader's defaults %+v", conf)
+	}
+
+	var redis struct {
+		Retries int `yaml:"retries"`
+	}
+	if err := loader.LoadPath(&redis, "redis", "./testdata"); err != nil || redis.Retries != 5 {
+		t.Errorf("Unexpected section with the loader's defaults %+v %v", redis, err)
+	}
+}
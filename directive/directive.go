@@ -0,0 +1,201 @@
+// Package directive implements a gofigure decoder for directive based config files, in the brace
+// delimited syntax of nginx or the section syntax of Apache:
+//
+//	worker_processes 4;                  ServerName example.com
+//	http {                               <VirtualHost *:80>
+//	    server {                             DocumentRoot /var/www
+//	        listen 80;                   </VirtualHost>
+//	        location /api {
+//	            proxy_pass http://api;
+//	        }
+//	    }
+//	}
+//
+// Directives map to keys. A directive with a single argument has it as its value, one with several
+// has the list of them, and one with none is true. Arguments are typed like plain YAML scalars are:
+// integers, floats and on/off/true/false are numbers and bools, and everything else, including
+// quoted arguments, is a string. A directive repeated in a block has the list of its values.
+//
+// Blocks map to nested mappings. A block with arguments, like "location /api" or
+// "<VirtualHost *:80>", is nested under its arguments as well, so that blocks of the same kind
+// with different arguments end up side by side: location: {"/api": {...}, "/": {...}}. Blocks
+// without arguments that are repeated, like nginx server blocks, have the list of their mappings.
+package directive
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/EverythingMe/gofigure/internal/tree"
+)
+
+// Syntax selects the syntax of the files a decoder reads
+type Syntax int
+
+const (
+	// Nginx is the syntax of nginx: directives end with a semicolon, and blocks are enclosed in
+	// braces
+	Nginx Syntax = iota
+
+	// Apache is the syntax of Apache httpd: directives end with the line, unless it ends with a
+	// backslash, and sections are enclosed in <Name args> and </Name> lines
+	Apache
+)
+
+// Decoder decodes directive based config files into config structs
+type Decoder struct {
+	// Syntax is the syntax of the files, nginx's by default
+	Syntax Syntax
+
+	// Extensions are the extensions of the files to decode, ".conf" if empty
+	Extensions []string
+}
+
+// node is a parsed directive, with the directives of its block if it has one
+type node struct {
+	name     string
+	args     []arg
+	block    bool
+	children []node
+	line     int
+}
+
+// arg is an argument of a directive
+type arg struct {
+	text   string
+	quoted bool
+}
+
+// Decode parses the file read from r and unmarshals it into config
+func (d Decoder) Decode(r io.Reader, config interface{}) error {
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	var nodes []node
+	if d.Syntax == Apache {
+		nodes, err = parseApache(string(data))
+	} else {
+		nodes, err = parseNginx(string(data))
+	}
+	if err != nil {
+		return err
+	}
+
+	return tree.Assign(build(nodes), config)
+}
+
+// CanDecode returns true if the file has one of the decoder's extensions
+func (d Decoder) CanDecode(path string) bool {
+	extensions := d.Extensions
+	if len(extensions) == 0 {
+		extensions = []string{".conf"}
+	}
+	for _, ext := range extensions {
+		if strings.HasSuffix(path, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// build converts parsed directives to a generic tree
+func build(nodes []node) map[string]interface{} {
+
+	m := map[string]interface{}{}
+	// keys holding lists built from repeated directives, as opposed to lists of arguments
+	repeated := map[string]bool{}
+
+	add := func(key string, v interface{}) {
+		prev, found := m[key]
+		switch {
+		case !found:
+			m[key] = v
+		case repeated[key]:
+			m[key] = append(prev.([]interface{}), v)
+		default:
+			m[key] = []interface{}{prev, v}
+			repeated[key] = true
+		}
+	}
+
+	for _, n := range nodes {
+		if !n.block {
+			add(n.name, value(n.args))
+			continue
+		}
+
+		block := build(n.children)
+		if len(n.args) == 0 {
+			add(n.name, block)
+			continue
+		}
+
+		// blocks with arguments are nested under them, and merged with blocks of the same kind
+		key := make([]string, len(n.args))
+		for i, a := range n.args {
+			key[i] = a.text
+		}
+		byArgs, ok := m[n.name].(map[string]interface{})
+		if !ok || repeated[n.name] {
+			byArgs = map[string]interface{}{}
+			add(n.name, byArgs)
+		}
+		byArgs[strings.Join(key, " ")] = tree.MergeValue(byArgs[strings.Join(key, " ")], block)
+	}
+	return m
+}
+
+// value is the value of a directive with args
+func value(args []arg) interface{} {
+	switch len(args) {
+	case 0:
+		return true
+	case 1:
+		return scalar(args[0])
+	}
+	values := make([]interface{}, len(args))
+	for i, a := range args {
+		values[i] = scalar(a)
+	}
+	return values
+}
+
+// scalar types an argument like YAML types plain scalars
+func scalar(a arg) interface{} {
+	if a.quoted {
+		return a.text
+	}
+
+	switch strings.ToLower(a.text) {
+	case "on", "true":
+		return true
+	case "off", "false":
+		return false
+	}
+
+	s := strings.TrimPrefix(strings.TrimPrefix(a.text, "-"), "+")
+	if s == "" || s[0] < '0' || s[0] > '9' || (len(s) > 1 && s[0] == '0' && s[1] != '.') {
+		// leading zeros, as in file modes, are not decimal integers
+		return a.text
+	}
+	if i, err := strconv.Atoi(a.text); err == nil {
+		return i
+	}
+	if strings.Contains(a.text, ".") {
+		if f, err := strconv.ParseFloat(a.text, 64); err == nil {
+			return f
+		}
+	}
+	return a.text
+}
+
+// syntaxError is a parse error at a line
+func syntaxError(line int, format string, args ...interface{}) error {
+	return fmt.Errorf("directive: line %d: %s", line, fmt.Sprintf(format, args...))
+}
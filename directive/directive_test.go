@@ -0,0 +1,154 @@
+package directive
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/EverythingMe/gofigure"
+)
+
+func decode(t *testing.T, d Decoder, s string) map[string]interface{} {
+	var v interface{}
+	if err := d.Decode(strings.NewReader(s), &v); err != nil {
+		t.Fatal(err)
+	}
+	return v.(map[string]interface{})
+}
+
+func TestNginx(t *testing.T) {
+
+	doc := decode(t, Decoder{}, `
+# global settings
+worker_processes 4;
+daemon off;
+pid "/run/nginx pid";
+
+http {
+    sendfile;
+    server {
+        listen 80;
+        listen 443 ssl;
+        location /api {
+            proxy_pass http://api;
+        }
+        location / { root /var/www; }
+    }
+    server {
+        listen 8080;
+        keepalive_timeout 1.5;
+        mode 0644;
+    }
+}
+`)
+
+	expected := map[string]interface{}{
+		"worker_processes": 4,
+		"daemon":           false,
+		"pid":              "/run/nginx pid",
+		"http": map[string]interface{}{
+			"sendfile": true,
+			"server": []interface{}{
+				map[string]interface{}{
+					"listen": []interface{}{80, []interface{}{443, "ssl"}},
+					"location": map[string]interface{}{
+						"/api": map[string]interface{}{"proxy_pass": "http://api"},
+						"/":    map[string]interface{}{"root": "/var/www"},
+					},
+				},
+				map[string]interface{}{"listen": 8080, "keepalive_timeout": 1.5, "mode": "0644"},
+			},
+		},
+	}
+	if !reflect.DeepEqual(doc, expected) {
+		t.Errorf("Unexpected document %#v", doc)
+	}
+
+	for _, bad := range []string{"a 1", "a { b 1; ", "a 1; }", "a \"b;", "{ a 1; }", "a }"} {
+		var v interface{}
+		if err := (Decoder{}).Decode(strings.NewReader(bad), &v); err == nil {
+			t.Errorf("Expected an error decoding %q", bad)
+		}
+	}
+}
+
+func TestApache(t *testing.T) {
+
+	doc := decode(t, Decoder{Syntax: Apache}, `
+# modules
+LoadModule ssl_module \
+    modules/mod_ssl.so
+ServerName "example.com"
+<IfModule mod_ssl.c>
+    Listen 443
+</IfModule>
+<VirtualHost *:80>
+    DocumentRoot /var/www
+    <Directory /var/www>
+        Options Indexes FollowSymLinks
+    </Directory>
+</virtualhost>
+<VirtualHost *:443>
+    SSLEngine on
+</VirtualHost>
+`)
+
+	expected := map[string]interface{}{
+		"LoadModule": []interface{}{"ssl_module", "modules/mod_ssl.so"},
+		"ServerName": "example.com",
+		"IfModule": map[string]interface{}{
+			"mod_ssl.c": map[string]interface{}{"Listen": 443},
+		},
+		"VirtualHost": map[string]interface{}{
+			"*:80": map[string]interface{}{
+				"DocumentRoot": "/var/www",
+				"Directory": map[string]interface{}{
+					"/var/www": map[string]interface{}{"Options": []interface{}{"Indexes", "FollowSymLinks"}},
+				},
+			},
+			"*:443": map[string]interface{}{"SSLEngine": true},
+		},
+	}
+	if !reflect.DeepEqual(doc, expected) {
+		t.Errorf("Unexpected document %#v", doc)
+	}
+
+	for _, bad := range []string{"<A>\n", "</A>\n", "<A>\n</B>\n", "<A\n", "A \"b\n"} {
+		var v interface{}
+		if err := (Decoder{Syntax: Apache}).Decode(strings.NewReader(bad), &v); err == nil {
+			t.Errorf("Expected an error decoding %q", bad)
+		}
+	}
+}
+
+func TestDecodeStruct(t *testing.T) {
+
+	var conf struct {
+		WorkerProcesses int `json:"worker_processes"`
+		Events          struct {
+			WorkerConnections int `json:"worker_connections"`
+		}
+	}
+	err := Decoder{}.Decode(strings.NewReader("worker_processes 2; events { worker_connections 512; }"), &conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.WorkerProcesses != 2 || conf.Events.WorkerConnections != 512 {
+		t.Errorf("Unexpected config %#v", conf)
+	}
+}
+
+func TestRegistered(t *testing.T) {
+
+	nginx, _ := gofigure.LookupDecoder("nginx")
+	apache, _ := gofigure.LookupDecoder("apache")
+	if nginx == nil || apache == nil {
+		t.Fatal("Expected nginx and apache decoders to be registered")
+	}
+	if !nginx.CanDecode("site.conf") || apache.CanDecode("site.conf") {
+		t.Error("Expected .conf files to be decoded by the nginx decoder only")
+	}
+	if !apache.CanDecode("www/.htaccess") || nginx.CanDecode("www/.htaccess") {
+		t.Error("Expected .htaccess files to be decoded by the apache decoder only")
+	}
+}
@@ -0,0 +1,225 @@
+package directive
+
+import (
+	"strings"
+)
+
+// parseNginx parses the nginx syntax
+func parseNginx(s string) ([]node, error) {
+	p := &nginxParser{s: s, line: 1}
+	nodes, err := p.block()
+	if err != nil {
+		return nil, err
+	}
+	if tok, _ := p.next(); tok.text != "" || tok.quoted {
+		return nil, syntaxError(p.line, "unexpected }")
+	}
+	return nodes, nil
+}
+
+// nginxParser is a recursive descent parser of the nginx syntax
+type nginxParser struct {
+	s    string
+	pos  int
+	line int
+}
+
+// token is a lexical token: a word or quoted string, or one of the punctuation characters, or
+// an empty unquoted text at the end of the input
+type token struct {
+	arg
+	punct bool
+}
+
+// next returns the next token
+func (p *nginxParser) next() (token, error) {
+
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		switch {
+		case c == '\n':
+			p.line++
+			p.pos++
+		case c == ' ' || c == '\t' || c == '\r':
+			p.pos++
+		case c == '#':
+			for p.pos < len(p.s) && p.s[p.pos] != '\n' {
+				p.pos++
+			}
+		case c == '{' || c == '}' || c == ';':
+			p.pos++
+			return token{arg{text: string(c)}, true}, nil
+		case c == '"' || c == '\'':
+			return p.quoted(c)
+		default:
+			start := p.pos
+			for p.pos < len(p.s) && !strings.ContainsRune(" \t\r\n{};#\"'", rune(p.s[p.pos])) {
+				p.pos++
+			}
+			return token{arg{text: p.s[start:p.pos]}, false}, nil
+		}
+	}
+	return token{}, nil
+}
+
+// quoted reads a string quoted with q, unescaping backslash escapes
+func (p *nginxParser) quoted(q byte) (token, error) {
+	line := p.line
+	var b strings.Builder
+	for p.pos++; p.pos < len(p.s); p.pos++ {
+		c := p.s[p.pos]
+		switch {
+		case c == q:
+			p.pos++
+			return token{arg{b.String(), true}, false}, nil
+		case c == '\\' && p.pos+1 < len(p.s):
+			p.pos++
+			c = p.s[p.pos]
+		case c == '\n':
+			p.line++
+		}
+		b.WriteByte(c)
+	}
+	return token{}, syntaxError(line, "unterminated string")
+}
+
+// block parses directives until the end of the input or a closing brace, which is left unread
+func (p *nginxParser) block() ([]node, error) {
+
+	var nodes []node
+	for {
+		pos, line := p.pos, p.line
+		tok, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		if tok.text == "" && !tok.quoted {
+			return nodes, nil
+		}
+		if tok.punct {
+			if tok.text == "}" {
+				p.pos, p.line = pos, line
+				return nodes, nil
+			}
+			return nil, syntaxError(p.line, "unexpected %s", tok.text)
+		}
+
+		n := node{name: tok.text, line: p.line}
+		for {
+			tok, err := p.next()
+			if err != nil {
+				return nil, err
+			}
+			if tok.text == "" && !tok.quoted {
+				return nil, syntaxError(n.line, "%s: missing ;", n.name)
+			}
+			if !tok.punct {
+				n.args = append(n.args, tok.arg)
+				continue
+			}
+
+			if tok.text == "{" {
+				if n.children, err = p.block(); err != nil {
+					return nil, err
+				}
+				if end, _ := p.next(); end.text != "}" || !end.punct {
+					return nil, syntaxError(n.line, "%s: missing }", n.name)
+				}
+				n.block = true
+			} else if tok.text == "}" {
+				return nil, syntaxError(n.line, "%s: missing ;", n.name)
+			}
+			break
+		}
+		nodes = append(nodes, n)
+	}
+}
+
+// parseApache parses the Apache syntax
+func parseApache(s string) ([]node, error) {
+
+	// the root is a section without a name, holding the open sections' nodes on a stack
+	stack := []*node{{}}
+	lines := strings.Split(s, "\n")
+
+	for i := 0; i < len(lines); i++ {
+		lineno := i + 1
+		line := strings.TrimSpace(strings.TrimSuffix(lines[i], "\r"))
+		for strings.HasSuffix(line, "\\") && i+1 < len(lines) {
+			i++
+			line = strings.TrimSuffix(line, "\\") + " " + strings.TrimSpace(strings.TrimSuffix(lines[i], "\r"))
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		top := stack[len(stack)-1]
+		switch {
+		case strings.HasPrefix(line, "</"):
+			name := strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(line, "</"), ">"))
+			if len(stack) == 1 || !strings.EqualFold(name, top.name) {
+				return nil, syntaxError(lineno, "unexpected </%s>", name)
+			}
+			stack = stack[:len(stack)-1]
+			parent := stack[len(stack)-1]
+			parent.children = append(parent.children, *top)
+
+		case strings.HasPrefix(line, "<"):
+			if !strings.HasSuffix(line, ">") {
+				return nil, syntaxError(lineno, "missing > in %s", line)
+			}
+			args, err := splitArgs(strings.TrimSuffix(line[1:], ">"), lineno)
+			if err != nil {
+				return nil, err
+			}
+			if len(args) == 0 {
+				return nil, syntaxError(lineno, "section without a name")
+			}
+			stack = append(stack, &node{name: args[0].text, args: args[1:], block: true, line: lineno})
+
+		default:
+			args, err := splitArgs(line, lineno)
+			if err != nil {
+				return nil, err
+			}
+			top.children = append(top.children, node{name: args[0].text, args: args[1:], line: lineno})
+		}
+	}
+
+	if len(stack) > 1 {
+		open := stack[len(stack)-1]
+		return nil, syntaxError(open.line, "<%s> is not closed", open.name)
+	}
+	return stack[0].children, nil
+}
+
+// splitArgs splits an Apache directive line into its words and double quoted strings
+func splitArgs(line string, lineno int) ([]arg, error) {
+	var args []arg
+	for i := 0; i < len(line); {
+		switch c := line[i]; {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '"':
+			var b strings.Builder
+			for i++; i < len(line) && line[i] != '"'; i++ {
+				if line[i] == '\\' && i+1 < len(line) {
+					i++
+				}
+				b.WriteByte(line[i])
+			}
+			if i >= len(line) {
+				return nil, syntaxError(lineno, "unterminated string")
+			}
+			i++
+			args = append(args, arg{b.String(), true})
+		default:
+			start := i
+			for i < len(line) && line[i] != ' ' && line[i] != '\t' {
+				i++
+			}
+			args = append(args, arg{text: line[start:i]})
+		}
+	}
+	return args, nil
+}
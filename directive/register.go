@@ -0,0 +1,12 @@
+//go:build !gofigure_noregister
+
+package directive
+
+import "github.com/EverythingMe/gofigure"
+
+// nginx decodes .conf files, and Apache the .htaccess files that can only be its. Apache .conf
+// files need a "# gofigure: format=apache" modeline, or a loader of their own
+func init() {
+	gofigure.RegisterDecoder("nginx", Decoder{})
+	gofigure.RegisterDecoder("apache", Decoder{Syntax: Apache, Extensions: []string{".htaccess"}})
+}
@@ -0,0 +1,207 @@
+// Package dnstxt loads small config documents from DNS TXT records, such as the bootstrap
+// settings of edge deployments, and refreshes them when the TTL of the records expires.
+//
+// A TXT record holds strings of up to 255 bytes, which are joined into one. The records of a name
+// have no order, so when it has several they are sorted and joined with newlines: a document can
+// be a single record, or a set of records like "server: 10.0.0.1" making up a YAML mapping.
+package dnstxt
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/EverythingMe/gofigure"
+	"github.com/EverythingMe/gofigure/internal/monitor"
+	"github.com/miekg/dns"
+	"github.com/op/go-logging"
+)
+
+var log = logging.MustGetLogger("gofigure")
+
+// Source is a gofigure.Source reading a document from the TXT records of a domain name
+type Source struct {
+	Domain string
+
+	// Server is the address of the DNS server queried, like "10.0.0.53:53", or the first server
+	// of /etc/resolv.conf if empty
+	Server string
+}
+
+// Name returns the source's domain name
+func (s Source) Name() string {
+	return "dns:" + s.Domain
+}
+
+// Fetch looks up the source's records. A name without TXT records is a permanent failure
+func (s Source) Fetch(ctx context.Context) (io.ReadCloser, error) {
+	doc, _, err := s.lookup(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(strings.NewReader(doc)), nil
+}
+
+// Version returns a hash of the source's records
+func (s Source) Version(ctx context.Context) (string, error) {
+	doc, _, err := s.lookup(ctx)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(doc))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// lookup queries the TXT records of the domain, returning the document they make up and the
+// lowest of their TTLs
+func (s Source) lookup(ctx context.Context) (string, time.Duration, error) {
+
+	server := s.Server
+	if server == "" {
+		conf, err := dns.ClientConfigFromFile("/etc/resolv.conf")
+		if err != nil {
+			return "", 0, gofigure.Permanent(fmt.Errorf("dnstxt: %s", err))
+		}
+		if len(conf.Servers) == 0 {
+			return "", 0, gofigure.Permanent(fmt.Errorf("dnstxt: no servers in /etc/resolv.conf"))
+		}
+		server = net.JoinHostPort(conf.Servers[0], conf.Port)
+	}
+
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(s.Domain), dns.TypeTXT)
+
+	c := new(dns.Client)
+	in, _, err := c.ExchangeContext(ctx, m, server)
+	if err == nil && in.Truncated {
+		// big documents don't fit in UDP responses
+		c.Net = "tcp"
+		in, _, err = c.ExchangeContext(ctx, m, server)
+	}
+	if err != nil {
+		return "", 0, err
+	}
+	switch in.Rcode {
+	case dns.RcodeSuccess:
+	case dns.RcodeNameError:
+		return "", 0, gofigure.Permanent(fmt.Errorf("dnstxt: %s not found", s.Domain))
+	default:
+		return "", 0, fmt.Errorf("dnstxt: looking up %s: %s", s.Domain, dns.RcodeToString[in.Rcode])
+	}
+
+	var records []string
+	var ttl uint32
+	for _, rr := range in.Answer {
+		txt, ok := rr.(*dns.TXT)
+		if !ok {
+			continue
+		}
+		records = append(records, strings.Join(txt.Txt, ""))
+		if len(records) == 1 || txt.Hdr.Ttl < ttl {
+			ttl = txt.Hdr.Ttl
+		}
+	}
+	if len(records) == 0 {
+		return "", 0, gofigure.Permanent(fmt.Errorf("dnstxt: %s has no TXT records", s.Domain))
+	}
+	sort.Strings(records)
+	return strings.Join(records, "\n"), time.Duration(ttl) * time.Second, nil
+}
+
+// Monitor is a gofigure.ReloadMonitor calling its Reloader whenever the records of one of its
+// sources change. Each source is looked up again when the TTL of its records expires, within
+// MinInterval and MaxInterval, and after MinInterval if the lookup fails.
+type Monitor struct {
+	sources []Source
+
+	// MinInterval is the shortest time between lookups of a source, 10 seconds if zero, so that
+	// records with a TTL of 0 don't make the monitor flood the server
+	MinInterval time.Duration
+
+	// MaxInterval is the longest time between lookups of a source, an hour if zero
+	MaxInterval time.Duration
+
+	group monitor.Group
+}
+
+// NewMonitor creates a monitor refreshing sources
+func NewMonitor(sources ...Source) *Monitor {
+	return &Monitor{
+		sources: sources,
+	}
+}
+
+// Watch starts refreshing the sources, calling r whenever their records change. The records they
+// have when Watch is called are the baseline, so they don't trigger a reload; they are looked up
+// before Watch returns, and if a lookup fails, the first records that are found trigger one
+func (m *Monitor) Watch(r gofigure.Reloader) {
+
+	docs := make([]string, len(m.sources))
+	known := make([]bool, len(m.sources))
+	ttls := make([]time.Duration, len(m.sources))
+	for i, s := range m.sources {
+		var err error
+		if docs[i], ttls[i], err = s.lookup(context.Background()); err != nil {
+			log.Info("dns lookup of %s failed, its records will trigger a reload: %s", s.Domain, err)
+		}
+		known[i] = err == nil
+	}
+
+	m.group.Start(len(m.sources), func(ctx context.Context, i int) {
+		m.watch(ctx, m.sources[i], r, docs[i], known[i], ttls[i])
+	})
+}
+
+// Stop stops refreshing, and waits for the lookups in progress to end
+func (m *Monitor) Stop() {
+	m.group.Stop("dns")
+}
+
+// watch refreshes a single source until ctx is done, starting from the records looked up by
+// Watch, if they are known, and their TTL
+func (m *Monitor) watch(ctx context.Context, s Source, r gofigure.Reloader, current string, known bool, ttl time.Duration) {
+
+	min, max := m.MinInterval, m.MaxInterval
+	if min == 0 {
+		min = 10 * time.Second
+	}
+	if max == 0 {
+		max = time.Hour
+	}
+
+	for {
+		if ttl < min {
+			ttl = min
+		}
+		if ttl > max {
+			ttl = max
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(ttl):
+		}
+
+		doc, t, err := s.lookup(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Info("dns lookup of %s failed, retrying in %s: %s", s.Domain, min, err)
+			ttl = min
+			continue
+		}
+		if !known || doc != current {
+			log.Info("dns records of %s changed, reloading", s.Domain)
+			m.group.Reload(r)
+		}
+		current, known, ttl = doc, true, t
+	}
+}
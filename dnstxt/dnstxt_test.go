@@ -0,0 +1,129 @@
+package dnstxt
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/EverythingMe/gofigure"
+	"github.com/EverythingMe/gofigure/yaml"
+	"github.com/miekg/dns"
+)
+
+// fakeZone is a DNS server answering TXT queries from memory, with a TTL of 0
+type fakeZone struct {
+	mu      sync.Mutex
+	records map[string][]string
+}
+
+func (z *fakeZone) set(name string, txts ...string) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	z.records[dns.Fqdn(name)] = txts
+}
+
+func (z *fakeZone) ServeDNS(w dns.ResponseWriter, req *dns.Msg) {
+	m := new(dns.Msg)
+	m.SetReply(req)
+	name := req.Question[0].Name
+
+	z.mu.Lock()
+	txts, found := z.records[name]
+	z.mu.Unlock()
+
+	if !found {
+		m.Rcode = dns.RcodeNameError
+	}
+	for _, txt := range txts {
+		m.Answer = append(m.Answer, &dns.TXT{
+			Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeTXT, Class: dns.ClassINET},
+			Txt: []string{txt},
+		})
+	}
+	w.WriteMsg(m)
+}
+
+// startServer runs a fake zone, returning its address
+func startServer(t *testing.T, zone *fakeZone) (string, func()) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	started := make(chan bool)
+	server := &dns.Server{PacketConn: pc, Handler: zone, NotifyStartedFunc: func() { close(started) }}
+	go server.ActivateAndServe()
+	<-started
+	return pc.LocalAddr().String(), func() { server.Shutdown() }
+}
+
+func TestDNSTXT(t *testing.T) {
+
+	zone := &fakeZone{records: map[string][]string{}}
+	zone.set("config.edge.example", "timeout: 5", "server: 10.0.0.1")
+	addr, stop := startServer(t, zone)
+	defer stop()
+
+	var conf struct {
+		Server  string
+		Timeout int
+	}
+	loader := gofigure.NewLoader(yaml.Decoder{}, true)
+	loader.Sources = []gofigure.Source{Source{Domain: "config.edge.example", Server: addr}}
+	if err := loader.LoadRecursive(&conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Server != "10.0.0.1" || conf.Timeout != 5 {
+		t.Errorf("Unexpected config %#v", conf)
+	}
+
+	loader.Sources = []gofigure.Source{Source{Domain: "missing.edge.example", Server: addr}}
+	if err := loader.LoadRecursive(&conf); err == nil {
+		t.Error("Expected an error for a missing name")
+	}
+
+	reloads := make(chan bool, 10)
+	m := NewMonitor(Source{Domain: "config.edge.example", Server: addr})
+	m.MinInterval = 20 * time.Millisecond
+	var _ gofigure.ReloadMonitor = m
+	m.Watch(gofigure.ReloadFunc(func() { reloads <- true }))
+
+	select {
+	case <-reloads:
+		t.Fatal("Unchanged records should not trigger reloads")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	zone.set("config.edge.example", "timeout: 5", "server: 10.0.0.2")
+	select {
+	case <-reloads:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Change did not trigger a reload")
+	}
+
+	m.Stop()
+
+	// records changed right after Watch returns aren't taken for the baseline
+	m = NewMonitor(Source{Domain: "config.edge.example", Server: addr})
+	m.MinInterval = 20 * time.Millisecond
+	m.Watch(gofigure.ReloadFunc(func() { reloads <- true }))
+	zone.set("config.edge.example", "timeout: 5", "server: 10.0.0.3")
+	select {
+	case <-reloads:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Change right after Watch did not trigger a reload")
+	}
+	m.Stop()
+
+	// records that couldn't be looked up by Watch trigger a reload once they are found
+	m = NewMonitor(Source{Domain: "later.edge.example", Server: addr})
+	m.MinInterval = 20 * time.Millisecond
+	m.Watch(gofigure.ReloadFunc(func() { reloads <- true }))
+	defer m.Stop()
+	zone.set("later.edge.example", "timeout: 5")
+	select {
+	case <-reloads:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Records found after a failed lookup did not trigger a reload")
+	}
+}
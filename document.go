@@ -0,0 +1,449 @@
+package gofigure
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime/debug"
+	"time"
+
+	"github.com/EverythingMe/gofigure/internal/tree"
+)
+
+// loadState is the state of a load shared by the roots it walks
+type loadState struct {
+	progress
+
+	// digests maps the digests of the contents of the files read so far to the first file with
+	// that content, when the loader skips duplicates
+	digests map[string]string
+}
+
+// document is a config file decoded into a generic tree, along with where it came from
+type document struct {
+	source string
+	tree   map[string]interface{}
+}
+
+// loadDocuments decodes every relevant file under paths, then every source of the loader, into
+// generic documents, in order. The files and sources that were decoded are added to the report
+func (l Loader) loadDocuments(report *Report, paths ...string) ([]document, error) {
+
+	if docs, rolledBack := l.History.rolledBack(); rolledBack {
+		log.Debug("Loading the snapshot the history was rolled back to")
+		for _, doc := range docs {
+			if report != nil {
+				report.Files = append(report.Files, doc.source)
+			}
+		}
+		return docs, nil
+	}
+
+	state := &loadState{progress: progress{hook: l.OnProgress}}
+
+	var docs []document
+	if l.Defaults != nil {
+		docs = append(docs, defaultsDocument(l.Defaults))
+	}
+	for _, root := range paths {
+		rootDocs, err := l.loadRoot(report, state, root)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, rootDocs...)
+	}
+
+	for _, source := range l.Sources {
+		doc, err := l.loadSource(report, source)
+		if err != nil {
+			log.Info("Error loading %s: %s", source.Name(), err)
+			if l.strictFor(err) {
+				return nil, err
+			}
+			report.warn(Warning{Source: source.Name(), Message: "skipped: " + err.Error(), Err: err})
+			continue
+		}
+
+		docs = append(docs, doc)
+		state.decoded(doc.source, false)
+		if report != nil {
+			report.Files = append(report.Files, doc.source)
+		}
+	}
+
+	l.History.record(docs)
+	return docs, nil
+}
+
+// loadRoot decodes every relevant file under a single root path, verifying them against the
+// root's manifest if the loader is configured to. The state of the load is shared by its roots
+func (l Loader) loadRoot(report *Report, state *loadState, root string) ([]document, error) {
+
+	var m *manifest
+	if l.VerifyManifest {
+		var err error
+		if m, err = readManifest(root); err != nil {
+			log.Info("Error reading manifest of %s: %s", root, err)
+			if l.strict(IOErrors) {
+				return nil, err
+			}
+			report.warn(Warning{Source: root, Message: "manifest could not be read, skipped: " + err.Error(), Err: err})
+			return nil, nil
+		}
+	}
+
+	ch, cancelc := walk(root)
+	defer close(cancelc)
+
+	var docs []document
+	for path := range ch {
+
+		if !l.canDecode(path) {
+			continue
+		}
+		state.discovered(path)
+
+		if m != nil {
+			if _, err := m.expected(path); err != nil {
+				if l.strict(IOErrors) {
+					return nil, fmt.Errorf("gofigure: %s %s", path, err)
+				}
+				report.warn(Warning{Source: path, Message: err.Error() + ", skipped", Err: err})
+				continue
+			}
+		}
+
+		if l.SkipDuplicates {
+			original, err := state.duplicate(path)
+			if err != nil {
+				log.Info("Error reading %s: %s", path, err)
+				if l.strict(IOErrors) {
+					return nil, err
+				}
+				report.warn(Warning{Source: path, Message: "skipped: " + err.Error(), Err: err})
+				continue
+			}
+			if original != "" {
+				report.warn(Warning{Source: path, Message: duplicateMessage(path, original)})
+				continue
+			}
+		}
+
+		doc, err := l.decodeDocument(path, m)
+		if err != nil {
+			log.Info("Error loading %s: %s", path, err)
+			if l.strictFor(err) {
+				return nil, err
+			}
+			report.warn(Warning{Source: path, Message: "skipped: " + err.Error(), Err: err})
+			continue
+		}
+
+		docs = append(docs, doc)
+		state.decoded(path, true)
+		if report != nil {
+			report.Files = append(report.Files, path)
+		}
+	}
+
+	if m != nil {
+		for _, path := range m.missing() {
+			if l.strict(IOErrors) {
+				return nil, fmt.Errorf("gofigure: %s is listed in the manifest but missing", path)
+			}
+			report.warn(Warning{Source: path, Message: "listed in the manifest but missing"})
+		}
+	}
+
+	return docs, nil
+}
+
+// decodeDocument reads a single file into a generic document using the loader's decoder, checking
+// its contents against the manifest m if it's not nil
+func (l Loader) decodeDocument(path string, m *manifest) (document, error) {
+
+	v, err := l.decodeFile(path, m)
+	if err != nil {
+		return document{}, err
+	}
+
+	if l.FollowIncludes {
+		if v, err = l.include(v, filepath.Dir(path), []string{path}); err != nil {
+			return document{}, fmt.Errorf("gofigure: %s: %w", path, err)
+		}
+	}
+
+	return l.prepareDocument(path, v)
+}
+
+// prepareDocument turns a tree decoded from path into a document, applying its conditional
+// sections, migrations and hooks
+func (l Loader) prepareDocument(path string, v interface{}) (document, error) {
+
+	var err error
+	if l.Conditions != nil {
+		var keep bool
+		if v, keep, err = applyConditions(v, l.conditionVars()); err != nil {
+			return document{}, fmt.Errorf("gofigure: %s: %w", path, err)
+		}
+		if !keep {
+			log.Debug("Skipping %s, its %s guard is false", path, whenKey)
+			return document{path, map[string]interface{}{}}, nil
+		}
+	}
+
+	var doc map[string]interface{}
+	switch v := v.(type) {
+	case map[string]interface{}:
+		doc = v
+	case nil:
+		doc = map[string]interface{}{}
+	default:
+		return document{}, fmt.Errorf("gofigure: %s does not contain a mapping", path)
+	}
+
+	if l.Migrations != nil {
+		if err := l.Migrations.migrate(doc); err != nil {
+			return document{}, fmt.Errorf("gofigure: %s: %w", path, err)
+		}
+	}
+
+	for _, hook := range l.DocumentHooks {
+		if doc, err = hook.Transform(path, doc); err != nil {
+			return document{}, fmt.Errorf("gofigure: %s: %w", path, err)
+		}
+		if doc == nil {
+			doc = map[string]interface{}{}
+		}
+	}
+
+	return document{path, doc}, nil
+}
+
+// decodeFile preprocesses and decodes a single file into a normalized generic tree. Files that
+// change while they are read are read again, a few times, as they are probably being written. If
+// the manifest m isn't nil, the contents that are decoded are checked against it
+func (l Loader) decodeFile(path string, m *manifest) (interface{}, error) {
+
+	for attempt := 1; ; attempt++ {
+		v, changed, err := l.readFile(path, m)
+		if !changed {
+			return v, err
+		}
+		if attempt == readAttempts {
+			return nil, fmt.Errorf("gofigure: %s kept changing while being read", path)
+		}
+
+		log.Debug("%s changed while being read, retrying", path)
+		time.Sleep(readRetryDelay)
+	}
+}
+
+const readAttempts = 3
+
+var readRetryDelay = 50 * time.Millisecond
+
+// readFile makes a single attempt at decoding a file, and reports whether its size or
+// modification time changed while it was read, in which case the result can't be trusted
+func (l Loader) readFile(path string, m *manifest) (interface{}, bool, error) {
+
+	log.Debug("Reading config file %s", path)
+	fp, err := os.Open(path)
+	if err != nil {
+		return nil, false, ioError{err}
+	}
+	defer fp.Close()
+
+	if l.LockFiles {
+		if err := lockShared(fp, lockTimeout); err != nil {
+			return nil, false, ioError{fmt.Errorf("gofigure: locking %s: %s", path, err)}
+		}
+		defer unlock(fp)
+	}
+
+	before, err := fp.Stat()
+	if err != nil {
+		return nil, false, ioError{err}
+	}
+
+	var v interface{}
+	if m != nil {
+		v, err = l.decodeVerified(path, fp, m)
+	} else {
+		mapped := false
+		if l.MemoryMap && before.Size() >= mmapMinSize {
+			v, mapped, err = l.decodeMapped(path, fp, before.Size())
+		}
+		if !mapped {
+			v, err = l.decodeReader(path, fp)
+		}
+	}
+
+	after, statErr := fp.Stat()
+	if statErr == nil && (after.Size() != before.Size() || !after.ModTime().Equal(before.ModTime())) {
+		return nil, true, nil
+	}
+	return v, false, err
+}
+
+// decodeReader decompresses, preprocesses and decodes the contents of the file at path
+func (l Loader) decodeReader(path string, r io.Reader) (interface{}, error) {
+	return l.decodeReaderWith(path, r, nil)
+}
+
+// decodeReaderWith is decodeReader with the decoder d, if it's not nil, instead of the one the
+// loader picks for path. A modeline still takes precedence
+func (l Loader) decodeReaderWith(path string, r io.Reader, d Decoder) (interface{}, error) {
+
+	rc, base, err := decompress(path, r)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	if r, err = l.preprocess(path, rc); err != nil {
+		return nil, err
+	}
+	if r, err = transcode(path, r); err != nil {
+		return nil, err
+	}
+
+	if d == nil {
+		d = l.decoderFor(base)
+	}
+	if l.Modelines {
+		var forced Decoder
+		if forced, r, err = modelineDecoder(r); err != nil {
+			return nil, err
+		}
+		if forced != nil {
+			d = forced
+		}
+	}
+
+	var v interface{}
+	if err := decode(d, path, r, &v); err != nil {
+		return nil, err
+	}
+	return tree.Normalize(v), nil
+}
+
+// decode decodes r with d, turning a panic of the decoder into an error attributed to the file at
+// path, so a broken decoder fails the load of a file rather than crashing the program
+func decode(d Decoder, path string, r io.Reader, v interface{}) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			log.Debug("Decoder panic decoding %s: %v\n%s", path, p, debug.Stack())
+			err = fmt.Errorf("gofigure: decoding %s: decoder panicked: %v", path, p)
+		}
+	}()
+	return d.Decode(r, v)
+}
+
+// merge deep merges documents in order, expands the references in the result if the loader is
+// configured to, and runs it through the loader's resolvers
+func (l Loader) merge(docs []document) (map[string]interface{}, error) {
+
+	merged := mergeDocuments(docs)
+
+	var unresolved map[string]interface{}
+	if len(l.Trace) > 0 {
+		l.traceDocuments(docs)
+		unresolved = tree.Copy(merged).(map[string]interface{})
+	}
+
+	var expandValue func(string) (interface{}, error)
+	switch {
+	case l.ExpandReferences:
+		expandValue = newResolver(merged, l.ExpandEnv).expandValue
+	case l.ExpandEnv:
+		expandValue = expandEnv
+	}
+	if expandValue != nil {
+		if _, err := interpolate(merged, expandValue, ""); err != nil {
+			return nil, fmt.Errorf("gofigure: %s", err)
+		}
+	}
+
+	for _, r := range l.Resolvers {
+		if err := r.Resolve(merged); err != nil {
+			return nil, err
+		}
+	}
+
+	if unresolved != nil {
+		l.traceResolved(unresolved, merged)
+	}
+	return merged, nil
+}
+
+// mergeDocuments deep merges documents in order into a single tree, leaving the documents intact
+func mergeDocuments(docs []document) map[string]interface{} {
+	merged := map[string]interface{}{}
+	for _, doc := range docs {
+		tree.Merge(merged, tree.Copy(doc.tree).(map[string]interface{}))
+	}
+	return merged
+}
+
+// inspect walks a document value alongside the config type t it is going to be bound to, and
+// reports the keys that deserve a warning: deprecated fields, and old aliases of renamed ones.
+// source is the file the value comes from, and path its location in the document
+func (l Loader) inspect(report *Report, source string, v interface{}, t reflect.Type, path string) {
+
+	if t == nil {
+		return
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return
+		}
+
+		fields := structFields(t)
+		for key, e := range m {
+			f, alias := fields.match(key, l.KeyMatching)
+			if f == nil {
+				continue
+			}
+
+			keyPath := joinPath(path, key)
+			if alias {
+				report.warn(Warning{
+					Key:     keyPath,
+					Source:  source,
+					Message: fmt.Sprintf("%s is deprecated, use %s instead", key, f.names[0]),
+				})
+			}
+			if f.deprecated != "" {
+				report.warn(Warning{
+					Key:     keyPath,
+					Source:  source,
+					Message: fmt.Sprintf("%s is deprecated: %s", key, f.deprecated),
+				})
+			}
+			l.inspect(report, source, e, t.FieldByIndex(f.index).Type, keyPath)
+		}
+
+	case reflect.Map:
+		if m, ok := v.(map[string]interface{}); ok {
+			for key, e := range m {
+				l.inspect(report, source, e, t.Elem(), joinPath(path, key))
+			}
+		}
+
+	case reflect.Slice, reflect.Array:
+		if items, ok := v.([]interface{}); ok {
+			for i, e := range items {
+				l.inspect(report, source, e, t.Elem(), fmt.Sprintf("%s[%d]", path, i))
+			}
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package gofigure
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/EverythingMe/gofigure/yaml"
+)
+
+// writingDecoder simulates a writer appending to the file being decoded, the first n times
+type writingDecoder struct {
+	yaml.Decoder
+	path  string
+	n     *int
+	calls *int
+}
+
+func (d writingDecoder) Decode(r io.Reader, config interface{}) error {
+	*d.calls++
+	if *d.calls <= *d.n {
+		fp, err := os.OpenFile(d.path, os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		fp.WriteString("# more\n")
+		fp.Close()
+	}
+	return d.Decoder.Decode(r, config)
+}
+
+func TestChangingFiles(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gofigure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "conf.yaml")
+	writeFiles(t, dir, map[string]string{"conf.yaml": "redis:\n  monitor: 10\n"})
+
+	defer func(delay time.Duration) { readRetryDelay = delay }(readRetryDelay)
+	readRetryDelay = time.Millisecond
+
+	n, calls := 1, 0
+	loader := NewLoader(writingDecoder{path: path, n: &n, calls: &calls}, true)
+
+	var conf config
+	if err := loader.LoadFile(&conf, path); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 || conf.Redis.Monitor != 10 {
+		t.Errorf("Expected the file to be read again once, got %d reads: %#v", calls, conf.Redis)
+	}
+
+	n, calls = readAttempts, 0
+	if err := loader.LoadFile(&conf, path); err == nil {
+		t.Error("Expected an error for a file that keeps changing")
+	}
+	if calls != readAttempts {
+		t.Errorf("Expected %d reads, got %d", readAttempts, calls)
+	}
+}
+
+func TestTempFiles(t *testing.T) {
+
+	for name, expected := range map[string]bool{
+		"conf.yaml":          false,
+		".hidden.yaml":       false,
+		"conf.yaml~":         true,
+		"conf.yaml.tmp":      true,
+		".conf.yaml.swp":     true,
+		".#conf.yaml":        true,
+		"#conf.yaml#":        true,
+		"conf.yaml.dpkg-new": true,
+	} {
+		if isTempFile(name) != expected {
+			t.Errorf("isTempFile(%q) should be %v", name, expected)
+		}
+	}
+}
+
+// panickingDecoder is a broken decoder, panicking on every file
+type panickingDecoder struct {
+	yaml.Decoder
+}
+
+func (panickingDecoder) Decode(r io.Reader, config interface{}) error {
+	var m map[string]interface{}
+	m["boom"] = true
+	return nil
+}
+
+func TestDecoderPanics(t *testing.T) {
+
+	loader := NewLoader(panickingDecoder{}, true)
+
+	var conf config
+	err := loader.LoadRecursive(&conf, "./testdata")
+	if err == nil || !strings.Contains(err.Error(), "decoder panicked") || !strings.Contains(err.Error(), "testdata") {
+		t.Errorf("Expected the panic to fail the load of a file, got %v", err)
+	}
+	if err := loader.LoadFile(&conf, "./testdata/test.yaml"); err == nil || !strings.Contains(err.Error(), "decoder panicked") {
+		t.Error("Expected the panic to fail LoadFile")
+	}
+
+	// outside of strict mode, the broken files are skipped, as are missing ones
+	loader.StrictMode = false
+	if err := loader.LoadRecursive(&conf, "./testdata"); err != nil {
+		t.Error(err)
+	}
+	if err := loader.LoadFile(&conf, "./testdata/missing.yaml"); err != nil {
+		t.Error(err)
+	}
+}
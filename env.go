@@ -0,0 +1,53 @@
+package gofigure
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// ExportEnv flattens the config into environment variable assignments, for spawning child
+// processes that only read their config from the environment, e.g.
+//
+//	cmd.Env = append(os.Environ(), conf.ExportEnv("APP")...)
+//
+// The name of a variable is the path of its value as returned by Flatten, upper cased with every
+// character other than letters and digits replaced by underscores and prefixed by prefix, so
+// redis.server becomes APP_REDIS_SERVER and servers.0.host APP_SERVERS_0_HOST. The assignments
+// are sorted by name.
+func (m Map) ExportEnv(prefix string) []string {
+
+	env := []string{}
+	for path, value := range m.Flatten() {
+		name := envName(path)
+		if prefix != "" {
+			name = envName(prefix) + "_" + name
+		}
+		env = append(env, name+"="+value)
+	}
+	sort.Strings(env)
+	return env
+}
+
+// Setenv sets the environment variables ExportEnv returns in the current process
+func (m Map) Setenv(prefix string) error {
+	for _, kv := range m.ExportEnv(prefix) {
+		i := strings.IndexByte(kv, '=')
+		if err := os.Setenv(kv[:i], kv[i+1:]); err != nil {
+			return fmt.Errorf("gofigure: setting %s: %s", kv[:i], err)
+		}
+	}
+	return nil
+}
+
+// envName upper cases a key and replaces the characters that aren't letters or digits in it
+func envName(k string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToUpper(r)
+		}
+		return '_'
+	}, k)
+}
@@ -0,0 +1,42 @@
+package gofigure
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestExportEnv(t *testing.T) {
+
+	m := Map{
+		"redis": map[string]interface{}{"server": "localhost:6379", "max-conns": 10},
+		"servers": []interface{}{
+			map[string]interface{}{"host": "a", "tls": true},
+			"b",
+		},
+		"empty": nil,
+	}
+
+	expected := []string{
+		"APP_EMPTY=",
+		"APP_REDIS_MAX_CONNS=10",
+		"APP_REDIS_SERVER=localhost:6379",
+		"APP_SERVERS_0_HOST=a",
+		"APP_SERVERS_0_TLS=true",
+		"APP_SERVERS_1=b",
+	}
+	if env := m.ExportEnv("APP"); !reflect.DeepEqual(env, expected) {
+		t.Errorf("Unexpected env %v", env)
+	}
+	if env := m.ExportEnv(""); env[0] != "EMPTY=" {
+		t.Errorf("Unexpected env without a prefix %v", env)
+	}
+
+	defer os.Unsetenv("GOFIGURE_TEST_REDIS_SERVER")
+	if err := m.Setenv("gofigure-test"); err != nil {
+		t.Fatal(err)
+	}
+	if v := os.Getenv("GOFIGURE_TEST_REDIS_SERVER"); v != "localhost:6379" {
+		t.Errorf("Variable not set, got %q", v)
+	}
+}
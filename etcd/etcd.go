@@ -0,0 +1,140 @@
+// Package etcd loads config documents from etcd keys, and watches them for changes with etcd's
+// watch API, so programs reload with low latency and without polling.
+package etcd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+
+	"github.com/EverythingMe/gofigure"
+	"github.com/EverythingMe/gofigure/internal/monitor"
+	"github.com/op/go-logging"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+var log = logging.MustGetLogger("gofigure")
+
+// Source is a gofigure.Source reading a document from the value of an etcd key
+type Source struct {
+	Client *clientv3.Client
+	Key    string
+}
+
+// Name returns the source's key
+func (s Source) Name() string {
+	return "etcd:" + s.Key
+}
+
+// Fetch gets the value of the source's key. A missing key is a permanent failure
+func (s Source) Fetch(ctx context.Context) (io.ReadCloser, error) {
+	res, err := s.Client.Get(ctx, s.Key)
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Kvs) == 0 {
+		return nil, gofigure.Permanent(fmt.Errorf("etcd: key %s not found", s.Key))
+	}
+	return ioutil.NopCloser(bytes.NewReader(res.Kvs[0].Value)), nil
+}
+
+// Version returns the revision the source's key was last modified at
+func (s Source) Version(ctx context.Context) (string, error) {
+	res, err := s.Client.Get(ctx, s.Key, clientv3.WithKeysOnly())
+	if err != nil {
+		return "", err
+	}
+	if len(res.Kvs) == 0 {
+		return "", nil
+	}
+	return strconv.FormatInt(res.Kvs[0].ModRevision, 10), nil
+}
+
+// Monitor is a gofigure.ReloadMonitor calling its Reloader whenever one of its keys changes.
+//
+// Watches survive connection loss: the client reconnects on its own, and a watch that is canceled
+// is resumed from the last revision it saw. If that revision was compacted in the meantime, the
+// Reloader is called since changes may have been missed.
+type Monitor struct {
+	client *clientv3.Client
+	keys   []string
+
+	// Prefix makes the monitor watch every key under its keys rather than the keys themselves
+	Prefix bool
+
+	group monitor.Group
+}
+
+// NewMonitor creates a monitor watching keys
+func NewMonitor(client *clientv3.Client, keys ...string) *Monitor {
+	return &Monitor{
+		client: client,
+		keys:   keys,
+	}
+}
+
+// Watch starts watching the keys, calling r whenever one of them changes
+func (m *Monitor) Watch(r gofigure.Reloader) {
+	m.group.Start(len(m.keys), func(ctx context.Context, i int) {
+		m.watch(ctx, m.keys[i], r)
+	})
+}
+
+// Stop stops watching, and waits for the watches to end
+func (m *Monitor) Stop() {
+	m.group.Stop("etcd")
+}
+
+// watch watches a single key until ctx is done, resuming the watch whenever it is interrupted
+func (m *Monitor) watch(ctx context.Context, key string, r gofigure.Reloader) {
+
+	var rev int64
+	var backoff monitor.Backoff
+
+	for ctx.Err() == nil {
+		var opts []clientv3.OpOption
+		if m.Prefix {
+			opts = append(opts, clientv3.WithPrefix())
+		}
+		if rev == 0 {
+			// the revision the watch starts at is needed to resume it without missing changes
+			res, err := m.client.Get(ctx, key, clientv3.WithKeysOnly(), clientv3.WithLimit(1))
+			if err == nil {
+				rev = res.Header.Revision
+			} else if ctx.Err() == nil {
+				log.Info("etcd watch of %s could not get the current revision: %s", key, err)
+			}
+		}
+		if rev > 0 {
+			opts = append(opts, clientv3.WithRev(rev+1))
+		}
+
+		for res := range m.client.Watch(clientv3.WithRequireLeader(ctx), key, opts...) {
+			if err := res.Err(); err != nil {
+				if err == rpctypes.ErrCompacted {
+					log.Info("etcd watch of %s lost revisions to compaction, reloading", key)
+					rev = res.CompactRevision - 1
+					m.group.Reload(r)
+					break
+				}
+				log.Info("etcd watch of %s failed: %s", key, err)
+				continue
+			}
+
+			if len(res.Events) > 0 {
+				rev = res.Header.Revision
+				log.Info("etcd key %s changed, reloading", key)
+				m.group.Reload(r)
+			}
+			backoff.Reset()
+		}
+
+		if backoff.Wait(ctx); ctx.Err() == nil {
+			log.Info("Resuming etcd watch of %s at revision %d", key, rev+1)
+		}
+	}
+}
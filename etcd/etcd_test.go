@@ -0,0 +1,115 @@
+package etcd
+
+import (
+	"context"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/EverythingMe/gofigure"
+	"github.com/EverythingMe/gofigure/yaml"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/server/v3/embed"
+)
+
+// startEtcd runs an embedded single node etcd server, returning a client to it
+func startEtcd(t *testing.T) (*clientv3.Client, func()) {
+
+	dir, err := ioutil.TempDir("", "gofigure-etcd")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := embed.NewConfig()
+	cfg.Dir = dir
+	cfg.LogLevel = "error"
+	client, _ := url.Parse("http://127.0.0.1:0")
+	peer, _ := url.Parse("http://127.0.0.1:0")
+	cfg.ListenClientUrls = []url.URL{*client}
+	cfg.AdvertiseClientUrls = []url.URL{*client}
+	cfg.ListenPeerUrls = []url.URL{*peer}
+	cfg.AdvertisePeerUrls = []url.URL{*peer}
+	cfg.InitialCluster = cfg.Name + "=" + peer.String()
+
+	server, err := embed.StartEtcd(cfg)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	select {
+	case <-server.Server.ReadyNotify():
+	case <-time.After(10 * time.Second):
+		t.Fatal("etcd did not start")
+	}
+
+	c, err := clientv3.New(clientv3.Config{
+		Endpoints:   []string{server.Clients[0].Addr().String()},
+		DialTimeout: 5 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c, func() {
+		c.Close()
+		server.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestEtcd(t *testing.T) {
+
+	client, stop := startEtcd(t)
+	defer stop()
+
+	ctx := context.Background()
+	if _, err := client.Put(ctx, "/app/config", "redis:\n  server: etcd:6379\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	var conf struct {
+		Redis struct {
+			Server string
+		}
+	}
+	loader := gofigure.NewLoader(yaml.Decoder{}, true)
+	loader.Sources = []gofigure.Source{Source{Client: client, Key: "/app/config"}}
+	if err := loader.LoadRecursive(&conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Redis.Server != "etcd:6379" {
+		t.Errorf("Unexpected config %#v", conf)
+	}
+
+	loader.Sources = []gofigure.Source{Source{Client: client, Key: "/app/missing"}}
+	if err := loader.LoadRecursive(&conf); err == nil {
+		t.Error("Expected an error for a missing key")
+	}
+
+	reloads := make(chan bool, 10)
+	var m gofigure.ReloadMonitor = NewMonitor(client, "/app/config")
+	m.Watch(gofigure.ReloadFunc(func() { reloads <- true }))
+	defer m.Stop()
+
+	// give the watch time to be established
+	time.Sleep(100 * time.Millisecond)
+	if _, err := client.Put(ctx, "/app/config", "redis:\n  server: etcd:6380\n"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-reloads:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Change did not trigger a reload")
+	}
+
+	if _, err := client.Put(ctx, "/app/other", "x"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-reloads:
+		t.Fatal("Other keys should not trigger reloads")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
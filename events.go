@@ -0,0 +1,193 @@
+package gofigure
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/EverythingMe/gofigure/internal/monitor"
+)
+
+// EventMonitor is a ReloadMonitor for config services that push change notifications over HTTP,
+// either as server-sent events or by answering long-poll requests. It tells them apart by the
+// content type of the response, and calls its Reloader on every change.
+//
+// With server-sent events (text/event-stream), every event is a change. When the stream breaks,
+// it's reopened with the Last-Event-ID header if the events have ids, so the server can replay the
+// missed ones; otherwise a reload is triggered when it's reopened, since changes may have been
+// missed.
+//
+// With long-polling, requests carry the ETag of the previous response in If-None-Match, and the
+// server is expected to hold them until the document changes, answering 304 Not Modified (or 204
+// No Content) if it doesn't change for a while. A response with another ETag, or any 200 response
+// if the server sends no ETags, is a change. The first response is the baseline.
+//
+// The endpoint's client must not have a timeout shorter than the server holds requests or streams
+// open; http.DefaultClient has none.
+type EventMonitor struct {
+	endpoint HTTPSource
+
+	group monitor.Group
+}
+
+// NewEventMonitor creates a monitor subscribing to endpoint for change notifications. The
+// endpoint is typically not the URL of the document itself
+func NewEventMonitor(endpoint HTTPSource) *EventMonitor {
+	return &EventMonitor{
+		endpoint: endpoint,
+	}
+}
+
+// Watch subscribes to the endpoint, calling r's Reload method on every change
+func (m *EventMonitor) Watch(r Reloader) {
+	m.group.Start(1, func(ctx context.Context, _ int) {
+		m.watch(ctx, r)
+	})
+}
+
+// Stop unsubscribes, and waits for the subscription to end
+func (m *EventMonitor) Stop() {
+	m.group.Stop("event")
+}
+
+// subscription is the state of an EventMonitor that outlives the requests it makes
+type subscription struct {
+	// etag is the ETag of the last long-poll response, and polled is true once there was one
+	etag   string
+	polled bool
+
+	// lastID is the id of the last event received, connected is true once a stream was opened and
+	// retry is the reconnection delay the server asked for, if any
+	lastID    string
+	connected bool
+	retry     time.Duration
+}
+
+// watch makes requests until ctx is done, backing off when they fail
+func (m *EventMonitor) watch(ctx context.Context, r Reloader) {
+
+	sub := &subscription{}
+	var backoff monitor.Backoff
+
+	for ctx.Err() == nil {
+		err := m.subscribe(ctx, sub, r)
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			backoff.Reset()
+			continue
+		}
+
+		// the server's reconnection delay, if it asked for one, replaces the backoff
+		if sub.retry > 0 {
+			log.Info("Subscription to %s failed, retrying in %s: %s", m.endpoint.Name(), sub.retry, err)
+			monitor.Sleep(ctx, sub.retry)
+			continue
+		}
+		log.Info("Subscription to %s failed, retrying in %s: %s", m.endpoint.Name(), backoff.Delay(), err)
+		backoff.Wait(ctx)
+	}
+}
+
+// subscribe makes a single request, calling r for the changes it's notified of. It returns nil if
+// the next request can be made right away, like after a long-poll timeout
+func (m *EventMonitor) subscribe(ctx context.Context, sub *subscription, r Reloader) error {
+
+	endpoint := m.endpoint
+	endpoint.Header = http.Header{}
+	for k, v := range m.endpoint.Header {
+		endpoint.Header[k] = v
+	}
+	endpoint.Header.Set("Accept", "text/event-stream, */*")
+	if sub.etag != "" {
+		endpoint.Header.Set("If-None-Match", sub.etag)
+	}
+	if sub.lastID != "" {
+		endpoint.Header.Set("Last-Event-ID", sub.lastID)
+	}
+
+	res, err := endpoint.request(ctx, "GET")
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode == http.StatusNotModified || res.StatusCode == http.StatusNoContent:
+		return nil
+	case res.StatusCode < 200 || res.StatusCode > 299:
+		io.Copy(ioutil.Discard, res.Body)
+		return fmt.Errorf("unexpected status %s", res.Status)
+	}
+
+	if mediaType, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type")); mediaType == "text/event-stream" {
+		return m.events(res.Body, sub, r)
+	}
+
+	io.Copy(ioutil.Discard, res.Body)
+	etag := res.Header.Get("ETag")
+	if sub.polled && (etag == "" || etag != sub.etag) {
+		log.Info("%s notified of a change, reloading", m.endpoint.Name())
+		r.Reload()
+	}
+	sub.etag, sub.polled = etag, true
+	return nil
+}
+
+// events reads a stream of server-sent events, calling r for each of them, until it breaks
+func (m *EventMonitor) events(body io.Reader, sub *subscription, r Reloader) error {
+
+	if sub.connected && sub.lastID == "" {
+		log.Info("Reconnected to %s, reloading in case changes were missed", m.endpoint.Name())
+		r.Reload()
+	}
+	sub.connected = true
+
+	scanner := bufio.NewScanner(body)
+	pending := false
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// a blank line dispatches the event, if it had any field
+		if line == "" {
+			if pending {
+				log.Info("%s notified of a change, reloading", m.endpoint.Name())
+				r.Reload()
+			}
+			pending = false
+			continue
+		}
+		// comments are typically keep-alives
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		name, value := line, ""
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			name, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+		}
+		switch name {
+		case "id":
+			sub.lastID = value
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil {
+				sub.retry = time.Duration(ms) * time.Millisecond
+			}
+			continue
+		}
+		pending = true
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("event stream closed by the server")
+}
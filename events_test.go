@@ -0,0 +1,140 @@
+package gofigure
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// expectReloads waits for n reloads, failing if they don't come, or if they are followed by more
+func expectReloads(t *testing.T, reloads chan bool, n int, msg string) {
+	for i := 0; i < n; i++ {
+		select {
+		case <-reloads:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: got %d reloads, expected %d", msg, i, n)
+		}
+	}
+	select {
+	case <-reloads:
+		t.Fatalf("%s: got more than %d reloads", msg, n)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestEventMonitorSSE(t *testing.T) {
+
+	events := make(chan string)
+	lastIDs := make(chan string, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastIDs <- r.Header.Get("Last-Event-ID")
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "retry: 50\n: keep-alive\n\n")
+		w.(http.Flusher).Flush()
+		for {
+			select {
+			case event := <-events:
+				if event == "" {
+					return
+				}
+				fmt.Fprint(w, event)
+				w.(http.Flusher).Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	reloads := make(chan bool, 10)
+	m := NewEventMonitor(HTTPSource{URL: server.URL})
+	m.Watch(ReloadFunc(func() { reloads <- true }))
+	defer m.Stop()
+
+	if id := <-lastIDs; id != "" {
+		t.Errorf("Unexpected Last-Event-ID on the first request: %q", id)
+	}
+	expectReloads(t, reloads, 0, "connecting")
+
+	events <- "data: changed\n\n"
+	events <- "event: config\nid: 1\ndata: changed\n\n"
+	expectReloads(t, reloads, 2, "events")
+
+	// the stream is reopened from the last event
+	events <- ""
+	if id := <-lastIDs; id != "1" {
+		t.Errorf("Expected the stream to resume from event 1, got %q", id)
+	}
+	expectReloads(t, reloads, 0, "resuming")
+	events <- "id: 2\ndata: changed\n\n"
+	expectReloads(t, reloads, 1, "resumed events")
+}
+
+func TestEventMonitorSSEWithoutIDs(t *testing.T) {
+
+	closing := make(chan bool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "retry: 50\n\n")
+		w.(http.Flusher).Flush()
+		select {
+		case <-closing:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	reloads := make(chan bool, 10)
+	m := NewEventMonitor(HTTPSource{URL: server.URL})
+	m.Watch(ReloadFunc(func() { reloads <- true }))
+	defer m.Stop()
+
+	expectReloads(t, reloads, 0, "connecting")
+	closing <- true
+	expectReloads(t, reloads, 1, "reconnecting")
+}
+
+func TestEventMonitorLongPoll(t *testing.T) {
+
+	var mu sync.Mutex
+	version := 1
+	changed := make(chan bool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		etag := strconv.Quote(strconv.Itoa(version))
+		mu.Unlock()
+
+		if r.Header.Get("If-None-Match") == etag {
+			select {
+			case <-changed:
+			case <-time.After(50 * time.Millisecond):
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			mu.Lock()
+			etag = strconv.Quote(strconv.Itoa(version))
+			mu.Unlock()
+		}
+		w.Header().Set("ETag", etag)
+		fmt.Fprint(w, "ok")
+	}))
+	defer server.Close()
+
+	reloads := make(chan bool, 10)
+	m := NewEventMonitor(HTTPSource{URL: server.URL})
+	m.Watch(ReloadFunc(func() { reloads <- true }))
+	defer m.Stop()
+
+	// a few polls time out without a change
+	expectReloads(t, reloads, 0, "polling")
+
+	mu.Lock()
+	version++
+	mu.Unlock()
+	changed <- true
+	expectReloads(t, reloads, 1, "change")
+}
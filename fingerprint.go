@@ -0,0 +1,260 @@
+package gofigure
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Fingerprint computes a digest of the config tree found under paths, covering every file the
+// loader's decoder would read. By default it uses each file's path, size and modification time,
+// which is cheap enough to run before every load. If hashContents is true, the files' contents
+// are hashed instead, which is slower but immune to mtime tricks.
+func (l Loader) Fingerprint(hashContents bool, paths ...string) (string, error) {
+
+	ch, cancelc := walk(paths...)
+	defer close(cancelc)
+
+	h := sha256.New()
+	for path := range ch {
+		if !l.canDecode(path) {
+			continue
+		}
+
+		if err := fingerprintFile(h, path, hashContents); err != nil {
+			return "", err
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// fingerprintFile writes the identity of a single file into the hash h
+func fingerprintFile(h io.Writer, path string, hashContents bool) error {
+
+	if !hashContents {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "%s\x00%d\x00%d\n", path, info.Size(), info.ModTime().UnixNano())
+		return nil
+	}
+
+	fp, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+
+	fmt.Fprintf(h, "%s\x00", path)
+	if _, err := io.Copy(h, fp); err != nil {
+		return err
+	}
+	fmt.Fprint(h, "\n")
+	return nil
+}
+
+// Cache keeps the merged results of previous loads, along with the fingerprint of the tree they
+// were loaded from. When a Loader has a Cache, LoadRecursive fingerprints the tree first and,
+// if nothing changed since the last load into the same config type, fills the config from the
+// cached result without decoding a single file.
+//
+// Note that a cache hit replaces the config struct entirely with the cached result, so defaults
+// set on the struct before calling LoadRecursive are those that were set on the first load.
+//
+// A Cache can also be persisted to a file (see NewFileCache), which is where it really pays off
+// for short lived processes such as CLI tools.
+type Cache struct {
+	// HashContents makes fingerprints hash file contents rather than sizes and mtimes
+	HashContents bool
+
+	// Path, if set, is a file the cache is persisted to, so it survives process restarts
+	Path string
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	loaded  bool
+}
+
+// cacheEntry is a snapshot of a merged config, gob encoded, and the fingerprint of its sources
+type cacheEntry struct {
+	Fingerprint string
+	Data        []byte
+}
+
+// NewCache creates a new empty in-memory load cache
+func NewCache() *Cache {
+	return &Cache{
+		entries: map[string]cacheEntry{},
+	}
+}
+
+// NewFileCache creates a load cache persisted to the file at path. The file is read lazily on the
+// first load, and rewritten whenever a new result is cached. A missing or corrupt cache file is
+// treated as an empty cache.
+func NewFileCache(path string) *Cache {
+	return &Cache{
+		Path:    path,
+		entries: map[string]cacheEntry{},
+	}
+}
+
+// get returns the cached snapshot for key, if it was taken from a tree with the same fingerprint
+func (c *Cache) get(key, fingerprint string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.readFile()
+	e, found := c.entries[key]
+	if !found || e.Fingerprint != fingerprint {
+		return nil, false
+	}
+	return e.Data, true
+}
+
+// put stores a snapshot for key
+func (c *Cache) put(key, fingerprint string, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.readFile()
+	if c.entries == nil {
+		c.entries = map[string]cacheEntry{}
+	}
+	c.entries[key] = cacheEntry{fingerprint, data}
+
+	if err := c.writeFile(); err != nil {
+		log.Info("Could not persist config cache to %s: %s", c.Path, err)
+	}
+}
+
+// readFile loads the persisted cache entries the first time it's called. It must be called with
+// the cache's lock held
+func (c *Cache) readFile() {
+	if c.Path == "" || c.loaded {
+		return
+	}
+	c.loaded = true
+
+	fp, err := os.Open(c.Path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Info("Could not open config cache %s: %s", c.Path, err)
+		}
+		return
+	}
+	defer fp.Close()
+
+	entries := map[string]cacheEntry{}
+	if err := gob.NewDecoder(fp).Decode(&entries); err != nil {
+		log.Info("Ignoring corrupt config cache %s: %s", c.Path, err)
+		return
+	}
+
+	// entries cached in memory before the file was read take precedence
+	for k, e := range c.entries {
+		entries[k] = e
+	}
+	c.entries = entries
+}
+
+// writeFile atomically replaces the persisted cache with the current entries. It must be called
+// with the cache's lock held
+func (c *Cache) writeFile() error {
+	if c.Path == "" {
+		return nil
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(c.Path), filepath.Base(c.Path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := gob.NewEncoder(tmp).Encode(c.entries); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.Path)
+}
+
+// cacheKey identifies a load by the prefix of the loader, the type of the config it loads into
+// and the paths it reads
+func cacheKey(prefix string, config interface{}, paths []string) string {
+	return fmt.Sprintf("%s\x00%T\x00%s", prefix, config, strings.Join(paths, "\x00"))
+}
+
+// loadCached is LoadRecursive backed by the loader's cache
+func (l Loader) loadCached(report *Report, config interface{}, paths ...string) error {
+
+	if len(l.Sources) > 0 {
+		log.Debug("Not using the cache, remote sources can't be fingerprinted")
+		return l.loadRecursive(report, config, paths...)
+	}
+
+	key := cacheKey(l.prefix, config, paths)
+	fingerprint, err := l.Fingerprint(l.Cache.HashContents, paths...)
+	if err != nil {
+		log.Info("Could not fingerprint config tree, not using cache: %s", err)
+		return l.loadRecursive(report, config, paths...)
+	}
+
+	if data, found := l.Cache.get(key, fingerprint); found {
+		err := restoreSnapshot(config, data)
+		if err == nil {
+			log.Debug("Config tree unchanged, using cached result")
+			report.Cached = true
+			return nil
+		}
+		log.Info("Could not restore cached config: %s", err)
+	}
+
+	if err := l.loadRecursive(report, config, paths...); err != nil {
+		return err
+	}
+
+	data, err := takeSnapshot(config)
+	if err != nil {
+		log.Info("Could not cache loaded config: %s", err)
+		return nil
+	}
+	l.Cache.put(key, fingerprint, data)
+	return nil
+}
+
+// takeSnapshot serializes a loaded config so it can be restored later
+func takeSnapshot(config interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(config); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// restoreSnapshot replaces the value config points to with a decoded snapshot
+func restoreSnapshot(config interface{}, data []byte) error {
+	v := reflect.ValueOf(config)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("gofigure: cannot restore into non-pointer %T", config)
+	}
+
+	fresh := reflect.New(v.Elem().Type())
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(fresh.Interface()); err != nil {
+		return err
+	}
+	v.Elem().Set(fresh.Elem())
+	return nil
+}
@@ -0,0 +1,161 @@
+package gofigure
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/EverythingMe/gofigure/yaml"
+)
+
+// countingDecoder wraps a decoder and counts how many files it decoded
+type countingDecoder struct {
+	Decoder
+	n int
+}
+
+func (d *countingDecoder) Decode(r io.Reader, config interface{}) error {
+	d.n++
+	return d.Decoder.Decode(r, config)
+}
+
+func TestFingerprint(t *testing.T) {
+
+	loader := NewLoader(yaml.Decoder{}, true)
+
+	fp1, err := loader.Fingerprint(false, "./testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fp2, err := loader.Fingerprint(false, "./testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fp1 == "" || fp1 != fp2 {
+		t.Errorf("Fingerprint not stable: %s != %s", fp1, fp2)
+	}
+
+	fp3, err := loader.Fingerprint(true, "./testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fp3 == fp1 {
+		t.Errorf("Content fingerprint should differ from stat fingerprint")
+	}
+
+	fp4, err := loader.Fingerprint(false, "./testdata/sub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fp4 == fp1 {
+		t.Errorf("Fingerprints of different trees should differ")
+	}
+}
+
+func TestCache(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gofigure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "conf.yaml")
+	if err := ioutil.WriteFile(file, []byte("redis:\n  server: localhost:6379\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dec := &countingDecoder{Decoder: yaml.Decoder{}}
+	loader := NewLoader(dec, true)
+	loader.Cache = NewCache()
+
+	var conf, cached config
+	if err := loader.LoadRecursive(&conf, dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := loader.LoadRecursive(&cached, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if dec.n != 1 {
+		t.Errorf("Expected a single decode, got %d", dec.n)
+	}
+	if !reflect.DeepEqual(conf, cached) || cached.Redis.Server != "localhost:6379" {
+		t.Errorf("Cached result not as expected: %v", cached)
+	}
+
+	// change the file and make sure it's decoded again
+	if err := ioutil.WriteFile(file, []byte("redis:\n  server: localhost:6380\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Second)
+	if err := os.Chtimes(file, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := loader.LoadRecursive(&cached, dir); err != nil {
+		t.Fatal(err)
+	}
+	if dec.n != 2 {
+		t.Errorf("Expected the changed tree to be decoded, got %d decodes", dec.n)
+	}
+	if cached.Redis.Server != "localhost:6380" {
+		t.Errorf("Changed config not loaded: %v", cached)
+	}
+}
+
+func TestFileCache(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gofigure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cachePath := filepath.Join(dir, "config.cache")
+
+	var conf config
+	dec := &countingDecoder{Decoder: yaml.Decoder{}}
+	loader := NewLoader(dec, true)
+	loader.Cache = NewFileCache(cachePath)
+	if err := loader.LoadRecursive(&conf, "./testdata"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(cachePath); err != nil {
+		t.Fatalf("Cache file not written: %s", err)
+	}
+
+	// a fresh cache on the same file, like a new process would have, should skip decoding
+	var cached config
+	dec = &countingDecoder{Decoder: yaml.Decoder{}}
+	loader = NewLoader(dec, true)
+	loader.Cache = NewFileCache(cachePath)
+	if err := loader.LoadRecursive(&cached, "./testdata"); err != nil {
+		t.Fatal(err)
+	}
+
+	if dec.n != 0 {
+		t.Errorf("Expected no decoding with a valid cache file, got %d decodes", dec.n)
+	}
+	if !reflect.DeepEqual(conf, cached) {
+		t.Errorf("Cached data not as expected: %v", cached)
+	}
+
+	// a corrupt cache file is ignored
+	if err := ioutil.WriteFile(cachePath, []byte("garbage"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cached = config{}
+	loader.Cache = NewFileCache(cachePath)
+	if err := loader.LoadRecursive(&cached, "./testdata"); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(conf, cached) {
+		t.Errorf("Data loaded with corrupt cache not as expected: %v", cached)
+	}
+}
@@ -0,0 +1,173 @@
+// Package flags is a feature flag layer over gofigure, reading the flags from a section of the
+// config tree and evaluating them locally:
+//
+//	flags:
+//	  new-pipeline:
+//	    enabled: false
+//	    percentage: 25   # of the users, by their "user" attribute
+//	    by: user
+//	    rules:
+//	      - attribute: country
+//	        values: [IL, US]
+//	        enabled: true
+//
+// Flags are reloaded with the rest of the config, e.g. by one of gofigure's monitors:
+//
+//	features, err := flags.New(loader, "flags", "conf.d")
+//	monitor.Watch(features)
+//	...
+//	if features.EnabledFor("new-pipeline", flags.Attributes{"user": id, "country": country}) {
+package flags
+
+import (
+	"hash/fnv"
+	"sort"
+	"sync"
+
+	"github.com/EverythingMe/gofigure"
+	"github.com/op/go-logging"
+)
+
+var log = logging.MustGetLogger("gofigure")
+
+// Attributes describe what a flag is evaluated for, like the user or the country of a request
+type Attributes map[string]string
+
+// Flag is the definition of a feature flag
+type Flag struct {
+	// Enabled is whether the flag is on when no rule matches and it isn't rolled out by percentage
+	Enabled bool `gofigure:"enabled"`
+
+	// Percentage rolls the flag out to a share of the values of the By attribute, from 0 to 100.
+	// The same values always get the same result, and raising the percentage only adds values
+	Percentage float64 `gofigure:"percentage"`
+
+	// By is the attribute the percentage is of, "id" by default
+	By string `gofigure:"by"`
+
+	// Rules turn the flag on or off for specific attribute values. The first matching rule wins over
+	// the percentage and the default
+	Rules []Rule `gofigure:"rules"`
+}
+
+// Rule turns a flag on or off when an attribute has one of its values
+type Rule struct {
+	Attribute string   `gofigure:"attribute"`
+	Values    []string `gofigure:"values"`
+	Enabled   bool     `gofigure:"enabled"`
+}
+
+// Flags holds the feature flags defined in a section of the config tree. It loads them when it's
+// created, and again whenever Reload is called. It's safe for concurrent use
+type Flags struct {
+	loader  *gofigure.Loader
+	section string
+	paths   []string
+
+	mu    sync.RWMutex
+	flags map[string]Flag
+}
+
+// New creates flags defined in the section of the config tree under paths, loaded with loader,
+// and loads them
+func New(loader *gofigure.Loader, section string, paths ...string) (*Flags, error) {
+	f := &Flags{
+		loader:  loader,
+		section: section,
+		paths:   paths,
+	}
+	if err := f.Load(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Load loads the flags again. If loading fails, the flags that were loaded last are kept
+func (f *Flags) Load() error {
+	var flags map[string]Flag
+	if err := f.loader.LoadPath(&flags, f.section, f.paths...); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.flags = flags
+	return nil
+}
+
+// Reload is Load, logging errors, making Flags a gofigure.Reloader
+func (f *Flags) Reload() {
+	if err := f.Load(); err != nil {
+		log.Error("Could not reload feature flags: %s", err)
+	}
+}
+
+// Enabled tells whether the flag name is on, without attributes: only its default applies, or a
+// full rollout. Undefined flags are off
+func (f *Flags) Enabled(name string) bool {
+	return f.EnabledFor(name, nil)
+}
+
+// EnabledFor tells whether the flag name is on for attrs. Undefined flags are off
+func (f *Flags) EnabledFor(name string, attrs Attributes) bool {
+	f.mu.RLock()
+	flag, found := f.flags[name]
+	f.mu.RUnlock()
+
+	return found && flag.evaluate(name, attrs)
+}
+
+// Names returns the names of the defined flags, sorted
+func (f *Flags) Names() []string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	names := make([]string, 0, len(f.flags))
+	for name := range f.flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// evaluate evaluates the flag name for attrs: its first matching rule, then its percentage, then
+// its default
+func (flag Flag) evaluate(name string, attrs Attributes) bool {
+
+	for _, rule := range flag.Rules {
+		value, found := attrs[rule.Attribute]
+		if !found {
+			continue
+		}
+		for _, v := range rule.Values {
+			if v == value {
+				return rule.Enabled
+			}
+		}
+	}
+
+	if flag.Percentage >= 100 {
+		return true
+	}
+	if flag.Percentage > 0 {
+		by := flag.By
+		if by == "" {
+			by = "id"
+		}
+		if value, found := attrs[by]; found {
+			return bucket(name, value) < flag.Percentage
+		}
+	}
+
+	return flag.Enabled
+}
+
+// bucket places a value in [0, 100) for the percentage rollout of a flag. The flag's name is hashed
+// too, so the same users aren't the first to get every flag
+func bucket(name, value string) float64 {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	h.Write([]byte{0})
+	h.Write([]byte(value))
+	return float64(h.Sum32()%10000) / 100
+}
@@ -0,0 +1,93 @@
+package flags
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/EverythingMe/gofigure"
+	"github.com/EverythingMe/gofigure/yaml"
+)
+
+func TestFlags(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gofigure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(contents string) {
+		if err := ioutil.WriteFile(filepath.Join(dir, "conf.yaml"), []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`
+redis:
+  server: localhost:6379
+flags:
+  new-pipeline:
+    percentage: 30
+    by: user
+    rules:
+      - attribute: country
+        values: [IL, US]
+        enabled: true
+      - attribute: user
+        values: [banned]
+        enabled: false
+  dark-mode:
+    enabled: true
+`)
+
+	features, err := New(gofigure.NewLoader(yaml.Decoder{}, true), "flags", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(features.Names(), []string{"dark-mode", "new-pipeline"}) {
+		t.Errorf("Unexpected flags %v", features.Names())
+	}
+	if !features.Enabled("dark-mode") || features.Enabled("new-pipeline") || features.Enabled("missing") {
+		t.Error("Unexpected flag defaults")
+	}
+
+	// rules win over the percentage, in order
+	if !features.EnabledFor("new-pipeline", Attributes{"country": "IL", "user": "banned"}) {
+		t.Error("Expected the country rule to enable the flag")
+	}
+	if features.EnabledFor("new-pipeline", Attributes{"country": "FR", "user": "banned"}) {
+		t.Error("Expected the user rule to disable the flag")
+	}
+
+	// the percentage of users is stable, and about right
+	enabled := 0
+	for i := 0; i < 1000; i++ {
+		attrs := Attributes{"user": fmt.Sprint("user", i)}
+		on := features.EnabledFor("new-pipeline", attrs)
+		if on != features.EnabledFor("new-pipeline", attrs) {
+			t.Fatal("Expected a user to always get the same result")
+		}
+		if on {
+			enabled++
+		}
+	}
+	if enabled < 250 || enabled > 350 {
+		t.Errorf("Expected about 30%% of the users to have the flag, got %d of 1000", enabled)
+	}
+
+	// reloading picks up changes, and keeps the flags if the config is broken
+	write("flags:\n  new-pipeline:\n    percentage: 100\n")
+	features.Reload()
+	if !features.Enabled("new-pipeline") || features.Enabled("dark-mode") {
+		t.Error("Expected the reloaded flags")
+	}
+	write("flags: [\n")
+	features.Reload()
+	if !features.Enabled("new-pipeline") {
+		t.Error("Expected the last flags to be kept")
+	}
+}
@@ -0,0 +1,116 @@
+package gofigure
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/EverythingMe/gofigure/internal/tree"
+)
+
+// Flatten returns every value of the config by its dotted path, the same paths Get takes: list
+// items are keyed by their index, as in "servers.0.host". Values are formatted as strings, null
+// values as empty strings, and empty mappings and lists are left out.
+//
+// Keys that contain dots themselves are ambiguous once flattened, so Unflatten does not restore
+// them.
+func (m Map) Flatten() map[string]string {
+	flat := map[string]string{}
+	flatten(flat, "", map[string]interface{}(m))
+	return flat
+}
+
+// flatten adds the values of v to flat, under the dotted path prefix
+func flatten(flat map[string]string, prefix string, v interface{}) {
+
+	join := func(k string) string {
+		if prefix == "" {
+			return k
+		}
+		return prefix + "." + k
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			flatten(flat, join(k), e)
+		}
+	case []interface{}:
+		for i, e := range v {
+			flatten(flat, join(strconv.Itoa(i)), e)
+		}
+	case nil:
+		flat[prefix] = ""
+	default:
+		flat[prefix] = fmt.Sprint(v)
+	}
+}
+
+// Unflatten rebuilds a nested config from values keyed by dotted paths, as returned by Flatten or
+// read from a flat store like environment variables or a key/value service. Mappings whose keys
+// are all the indexes from 0 up are rebuilt as lists, except for the root, which is always a
+// mapping. If a path is both a value and the parent of
+// other paths, the latter win.
+//
+// Values are kept as strings; loaders binding the result into typed fields should be WeaklyTyped.
+func Unflatten(flat map[string]string) Map {
+
+	paths := make([]string, 0, len(flat))
+	for path := range flat {
+		paths = append(paths, path)
+	}
+	// parents sort before their children, so children replace them
+	sort.Strings(paths)
+
+	m := map[string]interface{}{}
+	for _, path := range paths {
+		keys := tree.Split(path)
+		if len(keys) == 0 {
+			continue
+		}
+
+		node := m
+		for _, k := range keys[:len(keys)-1] {
+			child, ok := node[k].(map[string]interface{})
+			if !ok {
+				child = map[string]interface{}{}
+				node[k] = child
+			}
+			node = child
+		}
+		if _, isParent := node[keys[len(keys)-1]].(map[string]interface{}); !isParent {
+			node[keys[len(keys)-1]] = flat[path]
+		}
+	}
+
+	// the root is a mapping even if all its keys are indexes
+	for k, e := range m {
+		m[k] = listify(e)
+	}
+	return Map(m)
+}
+
+// listify replaces the mappings keyed by consecutive indexes under v with lists
+func listify(v interface{}) interface{} {
+
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return v
+	}
+	for k, e := range m {
+		m[k] = listify(e)
+	}
+
+	if len(m) == 0 {
+		return m
+	}
+	list := make([]interface{}, len(m))
+	for k, e := range m {
+		i, err := strconv.Atoi(k)
+		if err != nil || i < 0 || i >= len(m) || strconv.Itoa(i) != k {
+			return m
+		}
+		list[i] = e
+	}
+	return list
+}
@@ -0,0 +1,62 @@
+package gofigure
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFlatten(t *testing.T) {
+
+	m := Map{
+		"redis": map[string]interface{}{"server": "localhost:6379", "timeout": 10},
+		"servers": []interface{}{
+			map[string]interface{}{"host": "a", "tls": true},
+			"b",
+		},
+		"empty": nil,
+		"none":  map[string]interface{}{},
+	}
+
+	flat := m.Flatten()
+	expected := map[string]string{
+		"redis.server":   "localhost:6379",
+		"redis.timeout":  "10",
+		"servers.0.host": "a",
+		"servers.0.tls":  "true",
+		"servers.1":      "b",
+		"empty":          "",
+	}
+	if !reflect.DeepEqual(flat, expected) {
+		t.Errorf("Unexpected flattened config %v", flat)
+	}
+
+	unflat := Unflatten(flat)
+	if unflat.GetString("servers.0.host", "") != "a" || unflat.GetString("servers.1", "") != "b" {
+		t.Errorf("Lists not rebuilt: %v", unflat)
+	}
+	if !reflect.DeepEqual(unflat.Flatten(), flat) {
+		t.Errorf("Flatten and Unflatten don't round trip: %v", unflat.Flatten())
+	}
+
+	// indexes that don't start at 0 or have gaps are kept as mapping keys
+	unflat = Unflatten(map[string]string{"a.1": "x", "b.0": "x", "b.2": "y", "c": "v", "c.d": "w"})
+	if _, ok := unflat["a"].(map[string]interface{}); !ok {
+		t.Errorf("Expected a mapping for a, got %v", unflat["a"])
+	}
+	if _, ok := unflat["b"].(map[string]interface{}); !ok {
+		t.Errorf("Expected a mapping for b, got %v", unflat["b"])
+	}
+	if unflat.GetString("c.d", "") != "w" {
+		t.Errorf("Expected children to replace their parent, got %v", unflat["c"])
+	}
+
+	// a root keyed by indexes stays a mapping
+	unflat = Unflatten(map[string]string{"0": "a", "1.0": "b"})
+	expected = map[string]string{"0": "a", "1.0": "b"}
+	if !reflect.DeepEqual(unflat.Flatten(), expected) {
+		t.Errorf("Expected the root to stay a mapping, got %v", unflat)
+	}
+	if _, ok := unflat["1"].([]interface{}); !ok {
+		t.Errorf("Expected a list for 1, got %v", unflat["1"])
+	}
+}
@@ -0,0 +1,95 @@
+package gofigure
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// ErrFrozen is the error of changing a frozen config
+var ErrFrozen = errors.New("gofigure: config is frozen")
+
+// Frozen is a read-only view of a config, sharable between goroutines and components that must
+// not change it. Freezing copies the config deeply, so changing the original afterwards doesn't
+// change the frozen one either:
+//
+//	frozen, err := gofigure.Freeze(&conf)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	server := NewServer(frozen.Config().(*Config))
+//	...
+//	if err := frozen.Verify(); err != nil {
+//		log.Fatal(err) // something changed the shared config
+//	}
+//
+// Go can't make a struct read-only, so Config returns the frozen copy itself; Verify catches
+// the changes made to it anyway, and Copy returns a copy that can be changed freely. It does so by
+// hashing the config, so configs that can't be hashed, like those holding funcs or channels, can't
+// be frozen.
+type Frozen struct {
+	config  reflect.Value
+	version string
+}
+
+// Freeze returns a frozen deep copy of config, which is a struct or a pointer to one. It fails if
+// the config can't be hashed to verify it later
+func Freeze(config interface{}) (*Frozen, error) {
+	v := deepCopy(reflect.ValueOf(config))
+	if v.Kind() != reflect.Ptr {
+		p := reflect.New(v.Type())
+		p.Elem().Set(v)
+		v = p
+	}
+	version, err := configVersion(v.Interface())
+	if err != nil {
+		return nil, fmt.Errorf("gofigure: cannot freeze %s: %w", v.Type().Elem(), err)
+	}
+	return &Frozen{
+		config:  v,
+		version: version,
+	}, nil
+}
+
+// Config returns a pointer to the frozen config, of the type of the config passed to Freeze, or a
+// pointer to it. It must not be changed
+func (f *Frozen) Config() interface{} {
+	return f.config.Interface()
+}
+
+// Copy returns a pointer to a deep copy of the frozen config, which can be changed
+func (f *Frozen) Copy() interface{} {
+	return deepCopy(f.config).Interface()
+}
+
+// Version returns a hash of the frozen config, equal for equal configs
+func (f *Frozen) Version() string {
+	return f.version
+}
+
+// Set is the setter of a frozen config, which always fails with ErrFrozen. Code receiving either
+// a Frozen or a mutable config through an interface thus fails instead of changing frozen configs
+func (f *Frozen) Set(key string, value interface{}) error {
+	return fmt.Errorf("%w: cannot set %s", ErrFrozen, key)
+}
+
+// Verify returns an error wrapping ErrFrozen if the frozen config was changed since Freeze,
+// through the pointer returned by Config or the maps, slices and pointers it holds, or if it can't
+// be hashed anymore
+func (f *Frozen) Verify() error {
+	version, err := configVersion(f.config.Interface())
+	if err != nil {
+		return fmt.Errorf("%w: cannot verify the frozen %s: %s", ErrFrozen, f.config.Type().Elem(), err)
+	}
+	if version != f.version {
+		return fmt.Errorf("%w: the frozen %s was changed", ErrFrozen, f.config.Type().Elem())
+	}
+	return nil
+}
+
+// MustVerify is like Verify, but panics if the frozen config was changed
+func (f *Frozen) MustVerify() {
+	if err := f.Verify(); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,119 @@
+package gofigure
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestFreeze(t *testing.T) {
+
+	type node struct {
+		Name string
+	}
+	type frozenConfig struct {
+		Redis  redisConfig
+		Hosts  []string
+		Labels map[string]string
+		Extra  interface{}
+		Head   *node
+		Tail   *node
+		secret string
+	}
+	conf := frozenConfig{
+		Redis:  redisConfig{Server: "localhost:6379", Timeout: 10},
+		Hosts:  []string{"a", "b"},
+		Labels: map[string]string{"env": "prod"},
+		Extra:  map[string]interface{}{"list": []interface{}{1, 2}},
+		Head:   &node{Name: "head"},
+		secret: "hidden",
+	}
+	conf.Tail = conf.Head
+
+	frozen, err := Freeze(&conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := frozen.Verify(); err != nil {
+		t.Fatal(err)
+	}
+
+	// changing the original doesn't change the frozen copy
+	conf.Hosts[0] = "changed"
+	conf.Labels["env"] = "dev"
+	conf.Head.Name = "changed"
+	if err := frozen.Verify(); err != nil {
+		t.Errorf("Changing the original changed the frozen config: %s", err)
+	}
+
+	frozenConf := frozen.Config().(*frozenConfig)
+	if frozenConf.Hosts[0] != "a" || frozenConf.Labels["env"] != "prod" || frozenConf.Head.Name != "head" {
+		t.Errorf("Unexpected frozen config %+v", frozenConf)
+	}
+	if frozenConf.Tail != frozenConf.Head || frozenConf.secret != "hidden" {
+		t.Errorf("Frozen config lost its shared pointer or unexported fields: %+v", frozenConf)
+	}
+
+	// copies can be changed freely
+	copied := frozen.Copy().(*frozenConfig)
+	copied.Hosts[1] = "changed"
+	copied.Extra.(map[string]interface{})["list"].([]interface{})[0] = 3
+	if err := frozen.Verify(); err != nil {
+		t.Errorf("Changing a copy changed the frozen config: %s", err)
+	}
+
+	if err := frozen.Set("redis.timeout", 20); !errors.Is(err, ErrFrozen) {
+		t.Errorf("Expected setting a frozen config to fail with ErrFrozen, got %v", err)
+	}
+
+	// changes to the frozen copy itself are caught
+	frozenConf.Labels["env"] = "dev"
+	if err := frozen.Verify(); !errors.Is(err, ErrFrozen) {
+		t.Errorf("Expected a change of the frozen config to be caught, got %v", err)
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected MustVerify to panic")
+			}
+		}()
+		frozen.MustVerify()
+	}()
+
+	// values are frozen behind a pointer
+	if frozen, err := Freeze(redisConfig{}); err != nil {
+		t.Error(err)
+	} else if _, ok := frozen.Config().(*redisConfig); !ok {
+		t.Errorf("Unexpected frozen value %T", frozen.Config())
+	}
+
+	// configs that can't be hashed can't be frozen
+	if _, err := Freeze(&struct{ Hook func() }{Hook: func() {}}); err == nil {
+		t.Error("Expected an error freezing a config holding a func")
+	}
+	ratio := struct{ Ratio float64 }{}
+	frozen, err = Freeze(&ratio)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frozen.Config().(*struct{ Ratio float64 }).Ratio = math.NaN()
+	if err := frozen.Verify(); !errors.Is(err, ErrFrozen) {
+		t.Errorf("Expected a frozen config that can't be hashed anymore to fail verification, got %v", err)
+	}
+
+	// cycles are followed once
+	type cyclic struct {
+		Name string
+		Next *cyclic
+	}
+	ring := &cyclic{Name: "a"}
+	ring.Next = &cyclic{Name: "b", Next: ring}
+	frozen, err = Freeze(ring)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frozen.Config().(*cyclic).Next.Name = "changed"
+	if err := frozen.Verify(); !errors.Is(err, ErrFrozen) {
+		t.Errorf("Expected a change of a cyclic frozen config to be caught, got %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+// Package frontmatter implements a gofigure decoder for files that start with a YAML front matter
+// block, like Markdown templates. Only the front matter is decoded into the config; the body is
+// ignored.
+//
+//	---
+//	subject: Welcome aboard
+//	from: hello@example.com
+//	---
+//	Hi {{ .Name }}, ...
+package frontmatter
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/EverythingMe/gofigure/yaml"
+)
+
+// Decoder decodes the YAML front matter of files into config structs. Files without front
+// matter decode to nothing.
+type Decoder struct {
+	// YAML decodes the front matter, and can be set to tune how
+	YAML yaml.Decoder
+
+	// Extensions are the extensions of the files to decode, ".md" and ".markdown" if empty
+	Extensions []string
+}
+
+// Decode unmarshals the front matter of the file read from r into config
+func (d Decoder) Decode(r io.Reader, config interface{}) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	matter, _, found := Split(data)
+	if !found {
+		return nil
+	}
+	return d.YAML.Decode(bytes.NewReader(matter), config)
+}
+
+// CanDecode returns true if the file has one of the decoder's extensions
+func (d Decoder) CanDecode(path string) bool {
+	extensions := d.Extensions
+	if len(extensions) == 0 {
+		extensions = []string{".md", ".markdown"}
+	}
+	for _, ext := range extensions {
+		if strings.HasSuffix(path, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// Split splits the contents of a file into its front matter and its body, for programs that use
+// the body too, e.g. as a template. The front matter starts with a "---" line at the very start
+// of the file, and ends with a "---" or "..." line. If there is no front matter, the whole file
+// is the body and found is false.
+func Split(data []byte) (matter, body []byte, found bool) {
+
+	content := bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	br := bufio.NewReader(bytes.NewReader(content))
+
+	first, err := br.ReadString('\n')
+	if err != nil || strings.TrimRight(first, "\r\n") != "---" {
+		return nil, data, false
+	}
+
+	offset := len(first)
+	for {
+		line, err := br.ReadString('\n')
+		if trimmed := strings.TrimRight(line, "\r\n"); trimmed == "---" || trimmed == "..." {
+			return content[len(first):offset], content[offset+len(line):], true
+		}
+		if err != nil {
+			// an unterminated block isn't front matter
+			return nil, data, false
+		}
+		offset += len(line)
+	}
+}
@@ -0,0 +1,65 @@
+package frontmatter
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/EverythingMe/gofigure"
+)
+
+func TestSplit(t *testing.T) {
+
+	cases := []struct {
+		data, matter, body string
+		found              bool
+	}{
+		{"---\na: 1\n---\nbody\n", "a: 1\n", "body\n", true},
+		{"\xef\xbb\xbf---\r\na: 1\r\n...\r\nbody", "a: 1\r\n", "body", true},
+		{"---\n---\n", "", "", true},
+		{"---\na: 1\n---", "a: 1\n", "", true},
+		{"no front matter\n---\n", "", "no front matter\n---\n", false},
+		{"---\nunterminated: true\n", "", "---\nunterminated: true\n", false},
+		{"", "", "", false},
+	}
+
+	for _, c := range cases {
+		matter, body, found := Split([]byte(c.data))
+		if string(matter) != c.matter || string(body) != c.body || found != c.found {
+			t.Errorf("Split(%q) = %q, %q, %v", c.data, matter, body, found)
+		}
+	}
+}
+
+func TestDecoder(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gofigure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"welcome.md": "---\nsubject: Welcome\nfrom: hello@example.com\n---\nHi {{ .Name }}: not: yaml\n",
+		"plain.md":   "# Just a body\n",
+		"other.txt":  "---\nsubject: ignored\n---\n",
+	}
+	for name, contents := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var conf struct {
+		Subject string
+		From    string
+	}
+	loader := gofigure.NewLoader(Decoder{}, true)
+	if err := loader.LoadRecursive(&conf, dir); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Subject != "Welcome" || conf.From != "hello@example.com" {
+		t.Errorf("Unexpected config %#v", conf)
+	}
+}
@@ -7,25 +7,33 @@
 package gofigure
 
 import (
+	"bytes"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 
-	"github.com/EverythingMe/gofigure/yaml"
+	"github.com/EverythingMe/gofigure/internal/tree"
 	"github.com/op/go-logging"
 )
 
 // go-logging gives control over log output to gofigure clients
 var log = logging.MustGetLogger("gofigure")
 
-// DefaultDecoder is a yaml based decoder that can be used for convenience
-var DefaultLoader = NewLoader(yaml.Decoder{}, true)
+// DefaultLoader is a strict loader that can be used for convenience. It decodes yaml files, and the
+// formats of the decoder packages that are imported, see RegisterDecoder
+var DefaultLoader = NewLoader(registered{}, true)
 
 // Decoder is the interface for config decoders (right now we've just implemented a YAML one)
 type Decoder interface {
 
-	// Decode reads data from the io stream, and unmarshals it to the config struct.
+	// Decode reads data from the io stream, and unmarshals it to config. The loader passes a
+	// pointer to an interface{}, and binds the generic document decoded into it to the config
+	// struct, through the UnmarshalYAML, UnmarshalJSON and UnmarshalText methods of its fields if
+	// they have any.
 	Decode(r io.Reader, config interface{}) error
 
 	// CanDecode should return true if a file is decode-able by the decoder,
@@ -35,13 +43,164 @@ type Decoder interface {
 
 // Loader traverses directories recursively and lets the decoder decode relevant files.
 //
-// It can also explicitly decode single files
+// It can also explicitly decode single files.
+//
+// Files should be replaced atomically, by writing them under a temporary name and renaming them
+// into place, so the loader never sees them half written. To help with writers that don't, the
+// loader skips editor and temporary files such as "conf.yaml~", "conf.yaml.tmp" or ".#conf.yaml",
+// and reads a file again if it changes while being read.
+//
+// String fields holding file names can be tagged path:"resolve", as in
+//
+//	Cert string `yaml:"cert" path:"resolve"`
+//
+// to have relative paths resolved against the directory of the file that set them, rather than
+// the working directory, so that fragments of a conf.d tree can refer to the files next to them.
+// Lists of strings can be tagged too.
+//
+// A Loader is safe for concurrent use by multiple goroutines, like request handlers lazily
+// loading the configs of plugins, as long as its fields aren't changed while it's in use: every
+// load works on copies of the loader and of the documents it reads, and what loads share, their
+// Cache and Status, is synchronized. Hooks such as OnWarning and OnProgress are called by the
+// goroutines loading, concurrently if they load concurrently.
 type Loader struct {
 	decoder Decoder
 
 	// StrictMode determines whether the loader will completely fail on any IO or decoding error,
 	// or whether it will continue traversing all files even if one of them is invalid.
 	StrictMode bool
+
+	// Strictness, if not zero, replaces StrictMode with a strict mode per class of errors: loads
+	// fail on the errors of its classes, and skip the others, e.g. IOErrors|ValidationErrors fails
+	// on unreadable files and bad values but skips malformed ones. It can also make keys that match
+	// no field errors, with UnknownKeyErrors
+	Strictness ErrorClass
+
+	// WeaklyTyped makes loading tolerate sloppy hand-written configs by coercing values between
+	// compatible types: "8080" is accepted for an int, 1 for a bool, 42 for a string, and a single
+	// value for a list of one
+	WeaklyTyped bool
+
+	// KeyMatching selects how document keys are matched to struct fields. By default they are
+	// matched case-insensitively, and MatchLoose also ignores underscores and dashes
+	KeyMatching KeyMatching
+
+	// Defaults, if set, is a populated config value whose fields that aren't zero are the lowest
+	// layer of every load, below the files and sources, so defaults can be written in Go rather than
+	// in a checked-in defaults file. Values are reported to come from "defaults". Config types can
+	// also provide their own defaults, see DefaultsProvider, which Defaults are merged on top of
+	Defaults interface{}
+
+	// Sources are remote sources of config, loaded in order after the paths given to the loader
+	// and merged on top of them. See HTTPSource
+	Sources []Source
+
+	// Retry is the retry policy of fetching sources. The zero value tries each source once
+	Retry RetryPolicy
+
+	// Cache, if set, lets LoadRecursive skip decoding when the config tree didn't change since
+	// the previous load, and return the cached merged result instead
+	Cache *Cache
+
+	// VerifyManifest makes every path given to the loader require a MANIFEST.sha256 file listing
+	// the checksums of the files under it, in the format of sha256sum. Modified files and files
+	// missing from the manifest fail strict loads, and are reported and skipped otherwise
+	VerifyManifest bool
+
+	// LockFiles makes the loader hold a shared advisory lock (flock) on every file while it reads
+	// it, so writers that take an exclusive lock while writing are never read halfway through.
+	// It's a no-op on platforms without flock
+	LockFiles bool
+
+	// MemoryMap makes the loader memory map large files rather than read them, which saves
+	// copying their contents on platforms with mmap. Files must then be replaced atomically, never
+	// truncated or rewritten in place, as a mapped file shrinking while it's decoded crashes the
+	// program
+	MemoryMap bool
+
+	// SkipDuplicates makes the loader decode every content once, skipping files that are links to
+	// files it already read, or byte-for-byte copies of them, so a fragment linked twice into a
+	// conf.d tree isn't applied twice. Skipped duplicates are reported as warnings
+	SkipDuplicates bool
+
+	// Preprocessors transform the raw contents of every file, in order, before it is decoded
+	Preprocessors []Preprocessor
+
+	// FollowIncludes replaces "$include" keys (and values tagged !include in YAML) with the
+	// contents of the files and directories they list. See MaxIncludeDepth
+	FollowIncludes bool
+
+	// MaxIncludeDepth limits how deeply included files can include other files. Zero means the
+	// default of 8
+	MaxIncludeDepth int
+
+	// Conditions, if set, enables conditional sections: mappings with a "$when" guard such as
+	// `profile == "prod" && region != "eu"` are dropped unless the guard holds. Guards compare the
+	// variables in Conditions, plus the machine's hostname, to quoted strings
+	Conditions map[string]string
+
+	// Migrations, if set, upgrade every document to the current config schema version before
+	// it is merged with the others
+	Migrations *Migrations
+
+	// DocumentHooks transform every document, in order, after its migrations and before it is
+	// merged with the others
+	DocumentHooks []DocumentHook
+
+	// ExpandEnv replaces ${VAR} and ${VAR:-default} references in string values with the
+	// environment variable VAR once all documents are merged. Unset variables without a default
+	// expand to an empty string, and $${ is kept as a literal ${
+	ExpandEnv bool
+
+	// ExpandReferences replaces ${path.to.key} references in string values with the value of
+	// that key in the merged config, so values don't need to be repeated. A value that is a single
+	// reference takes the type of the key it refers to. References can be chained but not form a
+	// cycle. If ExpandEnv is also set, references that aren't keys fall back to the environment
+	ExpandReferences bool
+
+	// Resolvers rewrite the merged config before it is bound, in order. See the cel package for
+	// computed values
+	Resolvers []Resolver
+
+	// Modelines makes a comment on the first line of a file, like "# gofigure: format=toml",
+	// select the registered decoder it's decoded with, for files whose extension doesn't tell
+	// their format. Files the decoder can't decode are read too if they start with one
+	Modelines bool
+
+	// Normalizers canonicalize every loaded config, in order, after the Normalize methods of its
+	// values are called, see Normalizer
+	Normalizers []NormalizeFunc
+
+	// Trace lists dotted keys, like "server.port", whose resolution is logged on every load: each
+	// file and source that sets them, in the order they are merged, with the value it assigns, then
+	// the value they expand or resolve to if it differs. It answers why a value is 5 and not 10
+	Trace []string
+
+	// OnProgress, if set, is called as files are found and decoded, with the progress of the load
+	// so far. It is called synchronously, by the goroutine loading
+	OnProgress func(Progress)
+
+	// OnWarning, if set, is called with every warning of every load, like the use of deprecated
+	// keys and, outside of strict mode, every error that is skipped rather than failing the load,
+	// so programs can report them in their own telemetry. Warnings are logged as well
+	OnWarning func(Warning)
+
+	// OnChange, if set, is called after every LoadRecursive call that changed the effective config,
+	// with its version. It is called synchronously, by the goroutine loading
+	OnChange func(Change)
+
+	// Status, if set, records the outcome of every LoadRecursive call. It can be served over HTTP
+	// for live inspection of the effective config
+	Status *Status
+
+	// History, if set, keeps the last snapshots of the loaded documents, to roll back to
+	History *History
+
+	// versions is the version of the last load
+	versions *versions
+
+	// prefix is the dotted path of the subtree a loader made by Sub loads
+	prefix string
 }
 
 // NewLoader creates and returns a new Loader wrapping a decoder, using strict mode if specified
@@ -49,33 +208,63 @@ func NewLoader(d Decoder, strict bool) *Loader {
 	return &Loader{
 		decoder:    d,
 		StrictMode: strict,
+		versions:   &versions{},
 	}
 }
 
+// Sub returns a loader scoped to the subtree at a dotted path, like "database", so that a library
+// can own its namespace of the config without seeing the rest: LoadRecursive, LoadFile and the
+// other loads of the sub loader bind what the documents have at prefix, and LoadMap returns the
+// subtree. The sub loader reads the same files and sources as l, with the same settings, but has
+// its own Version, and none of l's Status, History and OnChange hook, which are about the whole
+// config. Sub loaders of sub loaders are scoped to the path under both prefixes.
+func (l Loader) Sub(prefix string) *Loader {
+	sub := l
+	sub.prefix = joinPath(l.prefix, prefix)
+	sub.versions = &versions{}
+	sub.Status = nil
+	sub.History = nil
+	sub.OnChange = nil
+	return &sub
+}
+
 // LoadRecursive takes a pointer to a struct containing configurations, and a series of paths.
-// It then traverses the paths recursively in their respective order, and lets the decoder decode
-// every relevant file.
+// It then traverses the paths recursively in their respective order, lets the decoder decode
+// every relevant file, and merges them into the struct.
+//
+// If the loader has a Cache and the tree's fingerprint hasn't changed since the last load, the cached
+// result is used and no file is decoded.
 func (l Loader) LoadRecursive(config interface{}, paths ...string) error {
+	_, err := l.LoadWithReport(config, paths...)
+	return err
+}
 
-	ch, cancelc := walk(paths...)
-	defer close(cancelc)
-
-	for path := range ch {
+// LoadWithReport is LoadRecursive, also returning a report of what was loaded, and of the
+// problems that didn't make loading fail, such as the use of deprecated keys
+func (l Loader) LoadWithReport(config interface{}, paths ...string) (*Report, error) {
+	report := l.newReport()
+	var err error
+	if _, rolledBack := l.History.rolledBack(); l.Cache != nil && !rolledBack {
+		err = l.loadCached(report, config, paths...)
+	} else {
+		err = l.loadRecursive(report, config, paths...)
+	}
+	if err == nil {
+		l.stamp(report, config)
+	}
+	l.Status.record(config, report, err)
+	return report, err
+}
 
-		if l.decoder.CanDecode(path) {
+// loadRecursive does the actual work of LoadRecursive, without consulting the cache
+func (l Loader) loadRecursive(report *Report, config interface{}, paths ...string) error {
 
-			err := l.LoadFile(config, path)
-			if err != nil {
-				log.Info("Error loading %s: %s", path, err)
-				if l.StrictMode {
-					return err
-				}
-			}
-
-		}
+	docs, err := l.loadDocuments(report, paths...)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	return l.bindDocuments(report, docs, "", config)
 }
 
 // LoadFile takes a pointer to a struct containing configurations, and a path to a file,
@@ -83,30 +272,133 @@ func (l Loader) LoadRecursive(config interface{}, paths ...string) error {
 // error if the file could not be opened or properly decoded
 func (l Loader) LoadFile(config interface{}, path string) error {
 
-	log.Debug("Reading config file %s", path)
-	fp, err := os.Open(path)
-
+	report := l.newReport()
+	doc, err := l.decodeDocument(path, nil)
 	if err != nil {
-		log.Info("Error opening file %s: %s", path, err)
-		if l.StrictMode {
+		log.Info("Error loading file %s: %s", path, err)
+		if l.strictFor(err) {
 			return err
 		}
+		report.warn(Warning{Source: path, Message: "skipped: " + err.Error(), Err: err})
+		return nil
 	}
-	defer fp.Close()
 
-	err = l.decoder.Decode(fp, config)
+	return l.bindDocuments(report, []document{doc}, "", config)
+}
+
+// MergeDocuments decodes docs with the loader's decoder, and merges them in order into config the
+// way LoadRecursive merges files with these contents, without touching the file system: includes
+// aren't followed, and the loader's paths and sources aren't read. It's meant for fuzzing and
+// benchmarking the decoding and merging of a config schema, e.g.
+//
+//	func FuzzConfig(f *testing.F) {
+//		f.Fuzz(func(t *testing.T, base, override []byte) {
+//			var conf Config
+//			gofigure.DefaultLoader.MergeDocuments([][]byte{base, override}, &conf)
+//		})
+//	}
+func (l Loader) MergeDocuments(docs [][]byte, config interface{}) error {
+
+	report := l.newReport()
+	parsed := make([]document, 0, len(docs))
+	for i, data := range docs {
+		name := fmt.Sprintf("document %d", i+1)
+
+		v, err := l.decodeReaderWith(name, bytes.NewReader(data), nil)
+		var doc document
+		if err != nil {
+			err = fmt.Errorf("gofigure: %s: %w", name, err)
+		} else {
+			doc, err = l.prepareDocument(name, v)
+		}
+		if err != nil {
+			if l.strictFor(err) {
+				return err
+			}
+			report.warn(Warning{Source: name, Message: "skipped: " + err.Error(), Err: err})
+			continue
+		}
+		parsed = append(parsed, doc)
+	}
+
+	return l.bindDocuments(report, parsed, "", config)
+}
+
+// LoadPath is like LoadRecursive, but only decodes the sub-tree found at a dotted path (e.g.
+// "database.primary") of every document into config. This lets the owner of one section of the
+// configuration read it without knowing the structure of everything around it.
+//
+// The documents are merged in order before the sub-tree is extracted, so a section can be spread
+// over several files. If no document has anything at path, config is left untouched.
+func (l Loader) LoadPath(config interface{}, path string, paths ...string) error {
+
+	report := l.newReport()
+	docs, err := l.loadDocuments(report, paths...)
 	if err != nil {
-		log.Info("Error decodeing file %s: %s", path, err)
-		if l.StrictMode {
-			return err
+		return err
+	}
+
+	return l.bindDocuments(report, docs, path, config)
+}
+
+// bindDocuments merges documents and assigns what they have at path, under the loader's prefix, to
+// config, after checking each of them for deprecated keys and the like. Outside of strict mode,
+// values that cannot be assigned to their fields are reported and skipped
+func (l Loader) bindDocuments(report *Report, docs []document, path string, config interface{}) error {
+
+	path = joinPath(l.prefix, path)
+	docs = withDefaults(docs, path, config)
+	docs = l.resolvePaths(docs, path, reflect.TypeOf(config))
+	merged, err := l.merge(docs)
+	if err != nil {
+		return err
+	}
+
+	sub, found := tree.Lookup(merged, path)
+	if !found {
+		log.Debug("Nothing to load at %s", path)
+		return nil
+	}
+
+	for _, doc := range docs {
+		if v, found := tree.Lookup(doc.tree, path); found {
+			l.inspect(report, doc.source, v, reflect.TypeOf(config), path)
+			report.origin(doc.source, v, "")
 		}
 	}
-	return nil
+
+	return l.bind(report, sub, config)
+}
+
+// bind assigns a decoded document to config, normalizes it and validates it. Outside of strict
+// mode, values that cannot be assigned to their fields are reported and skipped, and values out of
+// range are reported
+func (l Loader) bind(report *Report, doc interface{}, config interface{}) error {
+	b := &binder{
+		lenient:    !l.strict(ValidationErrors),
+		strictKeys: l.strict(UnknownKeyErrors),
+		weak:       l.WeaklyTyped,
+		match:      l.KeyMatching,
+		report:     report,
+	}
+	if err := b.bindConfig(doc, config); err != nil {
+		return err
+	}
+	if err := l.normalize(config); err != nil {
+		return err
+	}
+	return l.validate(report, config)
 }
 
 // walkDir recursively traverses a directory, sending every found file's path to the channel ch.
-// If no one is reading from ch, it times out after a second of waiting, and quits
-func walkDir(path string, ch chan string, cancelc <-chan struct{}) {
+// Symbolic links to directories are followed, but no directory is traversed twice, so symlink
+// loops don't make the walk go on forever and directories linked twice aren't read twice. If no one
+// is reading from ch, it times out after a second of waiting, and quits
+func walkDir(path string, ch chan string, cancelc <-chan struct{}, visited *visitedDirs) {
+
+	if !visited.visit(path) {
+		return
+	}
 
 	files, err := ioutil.ReadDir(path)
 
@@ -117,8 +409,18 @@ func walkDir(path string, ch chan string, cancelc <-chan struct{}) {
 
 	for _, file := range files {
 		fullpath := filepath.Join(path, file.Name())
+		if file.Mode()&os.ModeSymlink != 0 {
+			if target, err := os.Stat(fullpath); err == nil && target.IsDir() {
+				file = target
+			}
+		}
 		if file.IsDir() {
-			walkDir(fullpath, ch, cancelc)
+			walkDir(fullpath, ch, cancelc, visited)
+			continue
+		}
+
+		if isTempFile(file.Name()) {
+			log.Debug("Skipping temporary file %s", fullpath)
 			continue
 		}
 
@@ -134,6 +436,59 @@ func walkDir(path string, ch chan string, cancelc <-chan struct{}) {
 
 }
 
+// visitedDirs are the directories a walk went through, identified by device and inode rather than
+// by path, as symbolic links give them several
+type visitedDirs struct {
+	paths []string
+	infos []os.FileInfo
+}
+
+// visit records the directory at path as visited, and reports whether it wasn't already. A
+// directory reached again is a symlink loop if it's one of the directories path is in, and a
+// directory linked twice otherwise; either way it's logged
+func (v *visitedDirs) visit(path string) bool {
+
+	info, err := os.Stat(path)
+	if err != nil {
+		// the error is reported when the directory is read
+		return true
+	}
+
+	for i, seen := range v.infos {
+		if !os.SameFile(info, seen) {
+			continue
+		}
+		if rel, err := filepath.Rel(v.paths[i], path); err == nil && !strings.HasPrefix(rel, "..") {
+			log.Error("Skipping %s, a symlink loop back to %s", path, v.paths[i])
+		} else {
+			log.Warning("Skipping %s, the same directory as %s which was already read", path, v.paths[i])
+		}
+		return false
+	}
+
+	v.paths = append(v.paths, path)
+	v.infos = append(v.infos, info)
+	return true
+}
+
+// tempSuffixes are the suffixes of the files editors and config management tools write before
+// renaming them into place
+var tempSuffixes = []string{"~", ".tmp", ".temp", ".swp", ".swx", ".part", ".partial", ".new", ".bak", ".dpkg-new", ".rpmnew"}
+
+// isTempFile returns true if a file name looks like a file that's still being written, or an
+// editor's backup or lock file
+func isTempFile(name string) bool {
+	if strings.HasPrefix(name, ".#") || (strings.HasPrefix(name, "#") && strings.HasSuffix(name, "#")) {
+		return true
+	}
+	for _, suffix := range tempSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
 // walk takes a series of paths, and traverses them recursively by order, sending all found files
 // in the returned channel. It then closes the channel
 func walk(paths ...string) (pathchan <-chan string, cancelchan chan<- struct{}) {
@@ -145,7 +500,7 @@ func walk(paths ...string) (pathchan <-chan string, cancelchan chan<- struct{})
 	go func() {
 		defer close(ch)
 		for _, path := range paths {
-			walkDir(path, ch, cancelc)
+			walkDir(path, ch, cancelc, &visitedDirs{})
 		}
 	}()
 
@@ -2,8 +2,12 @@ package gofigure
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/EverythingMe/gofigure/json"
 	"github.com/EverythingMe/gofigure/yaml"
@@ -111,3 +115,158 @@ func ExampleLoader() {
 	fmt.Println(conf.Redis.Server)
 	//Output: localhost:6379
 }
+
+func TestLoadPath(t *testing.T) {
+
+	for _, d := range []Decoder{yaml.Decoder{}, json.Decoder{}} {
+		loader := NewLoader(d, true)
+
+		var redis redisConfig
+		if err := loader.LoadPath(&redis, "redis", "./testdata"); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(redis, expectedConf.Redis) {
+			t.Errorf("Decoded section not as expected: %v", redis)
+		}
+
+		var server string
+		if err := loader.LoadPath(&server, "mysql.server", "./testdata"); err != nil {
+			t.Fatal(err)
+		}
+		if server != "localhost:3306" {
+			t.Errorf("Decoded value not as expected: %v", server)
+		}
+
+		missing := redisConfig{Server: "default"}
+		if err := loader.LoadPath(&missing, "memcache", "./testdata"); err != nil {
+			t.Fatal(err)
+		}
+		if missing.Server != "default" {
+			t.Errorf("Missing section should leave config untouched: %v", missing)
+		}
+	}
+}
+
+func TestDurations(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gofigure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"conf.yaml": "timeout: 30s\nretries: [1s, 1m30s]\nnanos: 1000\ntimeouts: {read: 5m}\n",
+		"conf.json": `{"timeout": "30s", "retries": ["1s", "1m30s"], "nanos": 1000, "timeouts": {"read": "5m"}}`,
+	}
+	decoders := map[string]Decoder{"conf.yaml": yaml.Decoder{}, "conf.json": json.Decoder{}}
+
+	for name, contents := range files {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		var conf struct {
+			Timeout  time.Duration
+			Retries  []time.Duration
+			Nanos    time.Duration
+			Timeouts map[string]*time.Duration
+		}
+		if err := NewLoader(decoders[name], true).LoadFile(&conf, path); err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+
+		if conf.Timeout != 30*time.Second || conf.Nanos != 1000 || *conf.Timeouts["read"] != 5*time.Minute ||
+			!reflect.DeepEqual(conf.Retries, []time.Duration{time.Second, 90 * time.Second}) {
+			t.Errorf("%s: durations not decoded as expected: %v", name, conf)
+		}
+	}
+}
+
+func TestSymlinkedDirectories(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gofigure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeFiles(t, dir, map[string]string{
+		"conf.d/redis.yaml": "redis:\n  server: linked:6379\n",
+		"shared/mysql.yaml": "mysql:\n  user: shared\n",
+	})
+	links := map[string]string{
+		"conf.d/loop":   ".",         // back to conf.d
+		"conf.d/shared": "../shared", // followed
+		"conf.d/again":  "../shared", // the same directory, read once
+	}
+	for link, target := range links {
+		if err := os.Symlink(target, filepath.Join(dir, link)); err != nil {
+			t.Skip("symlinks not supported:", err)
+		}
+	}
+
+	var conf config
+	report, err := NewLoader(yaml.Decoder{}, true).LoadWithReport(&conf, filepath.Join(dir, "conf.d"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Redis.Server != "linked:6379" || conf.Mysql.User != "shared" || len(report.Files) != 2 {
+		t.Errorf("Unexpected config: %#v, %v", conf, report.Files)
+	}
+}
+
+func TestMergeDocuments(t *testing.T) {
+
+	loader := NewLoader(yaml.Decoder{}, true)
+	docs := [][]byte{
+		[]byte("redis:\n  server: base:6379\n  timeout: 1\n"),
+		[]byte(`{"redis": {"server": "override:6379"}}`),
+	}
+
+	var conf config
+	if err := loader.MergeDocuments(docs, &conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Redis.Server != "override:6379" || conf.Redis.Timeout != 1 {
+		t.Errorf("Documents not merged in order: %#v", conf.Redis)
+	}
+
+	docs = append(docs, []byte("redis: [\n"))
+	if err := loader.MergeDocuments(docs, &conf); err == nil {
+		t.Error("Expected an error for a malformed document")
+	}
+	loader.StrictMode = false
+	if err := loader.MergeDocuments(docs, &conf); err != nil {
+		t.Errorf("Expected the malformed document to be skipped: %s", err)
+	}
+}
+
+func BenchmarkMergeDocuments(b *testing.B) {
+
+	loader := NewLoader(yaml.Decoder{}, true)
+	docs := [][]byte{
+		[]byte("redis:\n  server: base:6379\n  timeout: 1\nmysql:\n  server: db:3306\n  user: app\n"),
+		[]byte("redis:\n  server: override:6379\n"),
+	}
+
+	for i := 0; i < b.N; i++ {
+		var conf config
+		if err := loader.MergeDocuments(docs, &conf); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func FuzzMergeDocuments(f *testing.F) {
+
+	f.Add([]byte("redis:\n  server: base:6379\n"), []byte("redis:\n  timeout: 3\n"))
+	f.Add([]byte(`{"mysql": {"user": "app"}}`), []byte("redis: [1, 2]\n"))
+
+	loader := NewLoader(yaml.Decoder{}, false)
+	f.Fuzz(func(t *testing.T, base, override []byte) {
+		var conf config
+		loader.MergeDocuments([][]byte{base, override}, &conf)
+	})
+}
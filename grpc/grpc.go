@@ -0,0 +1,301 @@
+// Package grpc distributes config over gRPC: a Server on a control node serves the merged config
+// of a gofigure loader, and the programs of a fleet load it with a Source, and reload it when it
+// changes with a Monitor, which gets updates pushed over a stream.
+//
+// Documents are sent as JSON bytes. The service is described by hand with well known protobuf
+// types, so it needs no generated code:
+//
+//	service gofigure.Config {
+//	  rpc Get(google.protobuf.StringValue) returns (google.protobuf.BytesValue);
+//	  rpc Watch(google.protobuf.StringValue) returns (stream google.protobuf.BytesValue);
+//	}
+//
+// The request is the dotted path of the section of the config to serve, or empty for all of it.
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+
+	"github.com/EverythingMe/gofigure"
+	"github.com/EverythingMe/gofigure/internal/monitor"
+	"github.com/op/go-logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+var log = logging.MustGetLogger("gofigure")
+
+const (
+	getMethod   = "/gofigure.Config/Get"
+	watchMethod = "/gofigure.Config/Watch"
+)
+
+// Server serves the merged config of a loader over gRPC. It loads the config when it's created,
+// and again whenever Reload is called, e.g. by one of gofigure's monitors, pushing the sections
+// that changed to the clients watching them.
+type Server struct {
+	loader *gofigure.Loader
+	paths  []string
+
+	mu      sync.Mutex
+	config  gofigure.Map
+	changed chan struct{}
+}
+
+// NewServer creates a server serving what loader loads from paths, and loads it
+func NewServer(loader *gofigure.Loader, paths ...string) (*Server, error) {
+	s := &Server{
+		loader:  loader,
+		paths:   paths,
+		changed: make(chan struct{}),
+	}
+	if err := s.Load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Register registers the config service with a gRPC server
+func (s *Server) Register(g *grpc.Server) {
+	g.RegisterService(&serviceDesc, s)
+}
+
+// Load loads the config again, and notifies the clients watching it. If loading fails, the
+// config that was loaded last keeps being served
+func (s *Server) Load() error {
+	config, err := s.loader.LoadMap(s.paths...)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config = config
+	close(s.changed)
+	s.changed = make(chan struct{})
+	return nil
+}
+
+// Reload is Load, logging errors, making Server a gofigure.Reloader
+func (s *Server) Reload() {
+	if err := s.Load(); err != nil {
+		log.Error("Could not reload served config: %s", err)
+	}
+}
+
+// section returns the JSON of the section of the config at path, and a channel closed when the
+// config changes
+func (s *Server) section(path string) ([]byte, <-chan struct{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var v interface{} = map[string]interface{}(s.config)
+	if path != "" {
+		var found bool
+		if v, found = s.config.Get(path); !found {
+			return nil, s.changed, status.Errorf(codes.NotFound, "no config at %s", path)
+		}
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, s.changed, status.Errorf(codes.Internal, "encoding config: %s", err)
+	}
+	return data, s.changed, nil
+}
+
+func (s *Server) get(ctx context.Context, req *wrapperspb.StringValue) (*wrapperspb.BytesValue, error) {
+	data, _, err := s.section(req.GetValue())
+	if err != nil {
+		return nil, err
+	}
+	return wrapperspb.Bytes(data), nil
+}
+
+// watch sends the section first, and again whenever it changes, until the client goes away
+func (s *Server) watch(req *wrapperspb.StringValue, stream grpc.ServerStream) error {
+
+	var last []byte
+	for {
+		data, changed, err := s.section(req.GetValue())
+		if err != nil && last == nil {
+			return err
+		}
+		// a section that disappears is sent as null, so clients reload without it
+		if err != nil {
+			data = []byte("null")
+		}
+		if !bytes.Equal(data, last) {
+			if err := stream.SendMsg(wrapperspb.Bytes(data)); err != nil {
+				return err
+			}
+			last = data
+		}
+
+		select {
+		case <-changed:
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+// configServer is the interface the service's handlers are called on
+type configServer interface {
+	get(context.Context, *wrapperspb.StringValue) (*wrapperspb.BytesValue, error)
+	watch(*wrapperspb.StringValue, grpc.ServerStream) error
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: "gofigure.Config",
+	HandlerType: (*configServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "Get", Handler: getHandler},
+	},
+	Streams: []grpc.StreamDesc{
+		{StreamName: "Watch", Handler: watchHandler, ServerStreams: true},
+	},
+}
+
+var watchStreamDesc = grpc.StreamDesc{StreamName: "Watch", ServerStreams: true}
+
+func getHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(wrapperspb.StringValue)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(configServer).get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: getMethod}
+	return interceptor(ctx, in, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(configServer).get(ctx, req.(*wrapperspb.StringValue))
+	})
+}
+
+func watchHandler(srv interface{}, stream grpc.ServerStream) error {
+	in := new(wrapperspb.StringValue)
+	if err := stream.RecvMsg(in); err != nil {
+		return err
+	}
+	return srv.(configServer).watch(in, stream)
+}
+
+// Source is a gofigure.Source getting a section of the config from a Server. A section the server
+// doesn't have is a permanent failure
+type Source struct {
+	Conn *grpc.ClientConn
+
+	// Section is the dotted path of the section to get, or empty for the whole config
+	Section string
+}
+
+// Name returns the server's target and the section
+func (s Source) Name() string {
+	return fmt.Sprintf("grpc:%s/%s", s.Conn.Target(), s.Section)
+}
+
+// Fetch gets the section from the server
+func (s Source) Fetch(ctx context.Context) (io.ReadCloser, error) {
+	out := new(wrapperspb.BytesValue)
+	if err := s.Conn.Invoke(ctx, getMethod, wrapperspb.String(s.Section), out); err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, gofigure.Permanent(err)
+		}
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(out.GetValue())), nil
+}
+
+// Monitor is a gofigure.ReloadMonitor calling its Reloader whenever the server pushes a change of
+// a section. The stream is reopened with a backoff when it breaks, and a change made while it was
+// broken triggers a reload once it's open again.
+type Monitor struct {
+	conn    *grpc.ClientConn
+	section string
+
+	group monitor.Group
+}
+
+// NewMonitor creates a monitor watching a section of the config served on conn, or all of it if
+// section is empty
+func NewMonitor(conn *grpc.ClientConn, section string) *Monitor {
+	return &Monitor{
+		conn:    conn,
+		section: section,
+	}
+}
+
+// Watch starts watching the section, calling r whenever it changes
+func (m *Monitor) Watch(r gofigure.Reloader) {
+	m.group.Start(1, func(ctx context.Context, _ int) {
+		m.watch(ctx, r)
+	})
+}
+
+// Stop stops watching, and waits for the watch to end
+func (m *Monitor) Stop() {
+	m.group.Stop("grpc")
+}
+
+// watch keeps a stream open until ctx is done, reloading when what it receives differs from what
+// was received last
+func (m *Monitor) watch(ctx context.Context, r gofigure.Reloader) {
+
+	var last []byte
+	var backoff monitor.Backoff
+
+	for ctx.Err() == nil {
+		err := m.stream(ctx, func(data []byte) {
+			backoff.Reset()
+			if last != nil && !bytes.Equal(data, last) {
+				log.Info("Config section %q changed, reloading", m.section)
+				r.Reload()
+			}
+			last = data
+		})
+		if ctx.Err() != nil {
+			return
+		}
+
+		log.Info("grpc watch of %q failed, retrying in %s: %s", m.section, backoff.Delay(), err)
+		backoff.Wait(ctx)
+	}
+}
+
+// stream opens a watch stream, calling fn with every document received until it breaks
+func (m *Monitor) stream(ctx context.Context, fn func([]byte)) error {
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := m.conn.NewStream(ctx, &watchStreamDesc, watchMethod)
+	if err != nil {
+		return err
+	}
+	if err := stream.SendMsg(wrapperspb.String(m.section)); err != nil {
+		return err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return err
+	}
+
+	for {
+		out := new(wrapperspb.BytesValue)
+		if err := stream.RecvMsg(out); err != nil {
+			if err == io.EOF {
+				return fmt.Errorf("stream closed by the server")
+			}
+			return err
+		}
+		fn(out.GetValue())
+	}
+}
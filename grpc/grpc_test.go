@@ -0,0 +1,117 @@
+package grpc
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/EverythingMe/gofigure"
+	"github.com/EverythingMe/gofigure/json"
+	"github.com/EverythingMe/gofigure/yaml"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestGRPC(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gofigure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(contents string) {
+		if err := ioutil.WriteFile(filepath.Join(dir, "conf.yaml"), []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("redis:\n  server: localhost:6379\nmysql:\n  server: localhost:3306\n")
+
+	server, err := NewServer(gofigure.NewLoader(yaml.Decoder{}, true), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the listener is replaced when the grpc server is restarted, to test reconnection
+	var mu sync.Mutex
+	var listener *bufconn.Listener
+	serve := func() *grpc.Server {
+		mu.Lock()
+		defer mu.Unlock()
+		listener = bufconn.Listen(1 << 16)
+		g := grpc.NewServer()
+		server.Register(g)
+		go g.Serve(listener)
+		return g
+	}
+	g := serve()
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			return listener.DialContext(ctx)
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	var conf struct {
+		Server string
+	}
+	loader := gofigure.NewLoader(json.Decoder{}, true)
+	loader.Sources = []gofigure.Source{Source{Conn: conn, Section: "redis"}}
+	if err := loader.LoadRecursive(&conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Server != "localhost:6379" {
+		t.Errorf("Unexpected config %#v", conf)
+	}
+
+	loader.Sources = []gofigure.Source{Source{Conn: conn, Section: "missing"}}
+	if err := loader.LoadRecursive(&conf); err == nil {
+		t.Error("Expected an error for a missing section")
+	}
+
+	reloads := make(chan bool, 10)
+	m := NewMonitor(conn, "redis")
+	m.Watch(gofigure.ReloadFunc(func() { reloads <- true }))
+	defer m.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	write("redis:\n  server: localhost:6380\nmysql:\n  server: localhost:3306\n")
+	server.Reload()
+	select {
+	case <-reloads:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Change did not trigger a reload")
+	}
+
+	write("redis:\n  server: localhost:6380\nmysql:\n  server: localhost:3307\n")
+	server.Reload()
+	select {
+	case <-reloads:
+		t.Fatal("Changes of other sections should not trigger reloads")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// a change made while the stream is broken is picked up when it's reopened
+	g.Stop()
+	write("redis:\n  server: localhost:6381\n")
+	server.Reload()
+	g = serve()
+	defer g.Stop()
+	select {
+	case <-reloads:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Change did not trigger a reload after reconnecting")
+	}
+}
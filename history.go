@@ -0,0 +1,189 @@
+package gofigure
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Snapshot is the state of the config tree at a load recorded by a History
+type Snapshot struct {
+	// ID numbers the snapshots of a history, from 1
+	ID int
+
+	// Time is when the snapshot was taken
+	Time time.Time
+
+	// Version is a hash of the merged documents of the snapshot, equal for equal trees
+	Version string
+
+	// Sources fingerprints the documents of the snapshot by file or source name, telling which ones
+	// changed between two snapshots
+	Sources map[string]string
+
+	docs []document
+}
+
+// History keeps the last snapshots of the config tree a loader loads, so that a bad config push
+// can be rolled back with the normal reload pipeline:
+//
+//	history := gofigure.NewHistory(10)
+//	loader.History = history
+//	history.Watch(app) // app reloads its config with loader
+//	...
+//	history.Rollback(previous.ID) // app reloads the documents of the snapshot
+//	...
+//	history.Release() // app reloads the files again
+//
+// A snapshot is taken whenever the documents a load decodes differ from the last snapshot's. While
+// a snapshot is rolled back to, the loader's loads use its documents instead of decoding the files
+// and sources, and no snapshot is taken. A history is meant for the loads of a single config tree.
+type History struct {
+	size int
+
+	mu        sync.Mutex
+	snapshots []Snapshot
+	lastID    int
+	pinned    *Snapshot
+	watchers  []Reloader
+}
+
+// NewHistory creates a history of the last size snapshots
+func NewHistory(size int) *History {
+	if size < 1 {
+		size = 1
+	}
+	return &History{size: size}
+}
+
+// Snapshots returns the snapshots of the history, oldest first
+func (h *History) Snapshots() []Snapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]Snapshot(nil), h.snapshots...)
+}
+
+// Rollback makes the loads use the documents of the snapshot id until Release, and reloads the
+// watchers of the history. It fails if the snapshot isn't in the history anymore
+func (h *History) Rollback(id int) error {
+	h.mu.Lock()
+	var found *Snapshot
+	for i := range h.snapshots {
+		if h.snapshots[i].ID == id {
+			snapshot := h.snapshots[i]
+			found = &snapshot
+		}
+	}
+	if found == nil {
+		h.mu.Unlock()
+		return fmt.Errorf("gofigure: no snapshot %d in the history", id)
+	}
+	h.pinned = found
+	h.mu.Unlock()
+
+	log.Info("Rolling config back to snapshot %d of %s", id, found.Time)
+	h.notify()
+	return nil
+}
+
+// Release undoes Rollback, making the loads decode the files and sources again, and reloads the
+// watchers of the history
+func (h *History) Release() {
+	h.mu.Lock()
+	h.pinned = nil
+	h.mu.Unlock()
+	h.notify()
+}
+
+// RolledBack returns the snapshot the loads use, if Rollback was called
+func (h *History) RolledBack() (Snapshot, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.pinned == nil {
+		return Snapshot{}, false
+	}
+	return *h.pinned, true
+}
+
+// Watch makes r reloaded whenever the history is rolled back or released, making History a
+// ReloadMonitor
+func (h *History) Watch(r Reloader) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.watchers = append(h.watchers, r)
+}
+
+// Stop stops notifying the watchers of the history
+func (h *History) Stop() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.watchers = nil
+}
+
+// notify reloads the watchers of the history
+func (h *History) notify() {
+	h.mu.Lock()
+	watchers := append([]Reloader(nil), h.watchers...)
+	h.mu.Unlock()
+
+	for _, r := range watchers {
+		r.Reload()
+	}
+}
+
+// rolledBack returns the documents of the snapshot the loads use, if any. It's safe to call on a
+// nil history
+func (h *History) rolledBack() ([]document, bool) {
+	if h == nil {
+		return nil, false
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.pinned == nil {
+		return nil, false
+	}
+	return h.pinned.docs, true
+}
+
+// record takes a snapshot of docs, unless they're those of the last snapshot. It's safe to call on
+// a nil history
+func (h *History) record(docs []document) {
+	if h == nil {
+		return
+	}
+
+	sources := make(map[string]string, len(docs))
+	all := sha256.New()
+	for _, doc := range docs {
+		data, err := json.Marshal(doc.tree)
+		if err != nil {
+			log.Info("Could not take a snapshot of the config: %s", err)
+			return
+		}
+		sum := sha256.Sum256(data)
+		sources[doc.source] = hex.EncodeToString(sum[:])
+		fmt.Fprintf(all, "%s\x00%x\n", doc.source, sum)
+	}
+	version := hex.EncodeToString(all.Sum(nil))
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if n := len(h.snapshots); n > 0 && h.snapshots[n-1].Version == version {
+		return
+	}
+	h.lastID++
+	h.snapshots = append(h.snapshots, Snapshot{
+		ID:      h.lastID,
+		Time:    time.Now(),
+		Version: version,
+		Sources: sources,
+		docs:    docs,
+	})
+	if len(h.snapshots) > h.size {
+		h.snapshots = append([]Snapshot(nil), h.snapshots[len(h.snapshots)-h.size:]...)
+	}
+}
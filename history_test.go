@@ -0,0 +1,92 @@
+package gofigure
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/EverythingMe/gofigure/yaml"
+)
+
+func TestHistory(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gofigure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeFiles(t, dir, map[string]string{
+		"base.yaml": "redis:\n  server: localhost:6379\n",
+		"app.yaml":  "redis:\n  timeout: 10\n",
+	})
+
+	history := NewHistory(2)
+	loader := NewLoader(yaml.Decoder{}, true)
+	loader.History = history
+	loader.Cache = NewCache()
+
+	var conf config
+	history.Watch(ReloadFunc(func() {
+		conf = config{}
+		if err := loader.LoadRecursive(&conf, dir); err != nil {
+			t.Error(err)
+		}
+	}))
+
+	load := func() {
+		if err := loader.LoadRecursive(&conf, dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+	load()
+	load()
+	if len(history.Snapshots()) != 1 {
+		t.Fatalf("Expected a single snapshot of an unchanged tree, got %d", len(history.Snapshots()))
+	}
+	good := history.Snapshots()[0]
+
+	// a bad push
+	writeFiles(t, dir, map[string]string{"app.yaml": "redis:\n  timeout: 1\n"})
+	load()
+	snapshots := history.Snapshots()
+	if len(snapshots) != 2 || conf.Redis.Timeout != 1 {
+		t.Fatalf("Unexpected snapshots %+v", snapshots)
+	}
+	bad := snapshots[1]
+	if bad.Sources[filepath.Join(dir, "base.yaml")] != good.Sources[filepath.Join(dir, "base.yaml")] ||
+		bad.Sources[filepath.Join(dir, "app.yaml")] == good.Sources[filepath.Join(dir, "app.yaml")] {
+		t.Errorf("Expected only app.yaml to have changed: %v %v", good.Sources, bad.Sources)
+	}
+
+	// rolling back reloads the good snapshot, whatever the files say
+	if err := history.Rollback(good.ID); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Redis.Timeout != 10 || conf.Redis.Server != "localhost:6379" {
+		t.Errorf("Expected the rolled back config, got %+v", conf.Redis)
+	}
+	writeFiles(t, dir, map[string]string{"app.yaml": "redis:\n  timeout: 2\n"})
+	load()
+	if conf.Redis.Timeout != 10 {
+		t.Errorf("Expected loads to use the snapshot while rolled back, got %+v", conf.Redis)
+	}
+	if snapshot, rolledBack := history.RolledBack(); !rolledBack || snapshot.ID != good.ID {
+		t.Errorf("Unexpected rolled back snapshot %+v", snapshot)
+	}
+
+	history.Release()
+	if conf.Redis.Timeout != 2 {
+		t.Errorf("Expected the files to be loaded again after release, got %+v", conf.Redis)
+	}
+
+	// the history is bounded
+	snapshots = history.Snapshots()
+	if len(snapshots) != 2 || snapshots[0].ID != bad.ID || snapshots[1].ID != bad.ID+1 {
+		t.Errorf("Unexpected snapshots %+v", snapshots)
+	}
+	if err := history.Rollback(good.ID); err == nil {
+		t.Error("Expected an error rolling back to a dropped snapshot")
+	}
+}
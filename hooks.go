@@ -0,0 +1,76 @@
+package gofigure
+
+import (
+	"github.com/EverythingMe/gofigure/internal/tree"
+)
+
+// DocumentHook transforms every decoded document before it is merged with the others, e.g. to
+// rename keys, inject values or strip sections during a gradual schema migration. The source is
+// the file or source the document comes from. Hooks can change the document in place and return
+// it, or return another one
+type DocumentHook interface {
+	Transform(source string, doc map[string]interface{}) (map[string]interface{}, error)
+}
+
+// DocumentHookFunc is a convenience wrapper that lets us use a function as a DocumentHook
+type DocumentHookFunc func(source string, doc map[string]interface{}) (map[string]interface{}, error)
+
+// Transform calls the underlying function
+func (f DocumentHookFunc) Transform(source string, doc map[string]interface{}) (map[string]interface{}, error) {
+	return f(source, doc)
+}
+
+// RenameKey returns a hook moving the value at the dotted path from to the dotted path to, in the
+// documents that have one. A value already at to is replaced
+func RenameKey(from, to string) DocumentHook {
+	return DocumentHookFunc(func(source string, doc map[string]interface{}) (map[string]interface{}, error) {
+		if v, found := deleteKey(doc, from); found {
+			setKey(doc, to, v)
+		}
+		return doc, nil
+	})
+}
+
+// DeleteKey returns a hook removing the value at a dotted path from the documents that have one
+func DeleteKey(path string) DocumentHook {
+	return DocumentHookFunc(func(source string, doc map[string]interface{}) (map[string]interface{}, error) {
+		deleteKey(doc, path)
+		return doc, nil
+	})
+}
+
+// deleteKey removes the value at a dotted path of mappings from doc, and returns it
+func deleteKey(doc map[string]interface{}, path string) (interface{}, bool) {
+	keys := tree.Split(path)
+	if len(keys) == 0 {
+		return nil, false
+	}
+	parent, ok := doc, true
+	for _, key := range keys[:len(keys)-1] {
+		if parent, ok = parent[key].(map[string]interface{}); !ok {
+			return nil, false
+		}
+	}
+	v, found := parent[keys[len(keys)-1]]
+	delete(parent, keys[len(keys)-1])
+	return v, found
+}
+
+// setKey sets the value at a dotted path of doc, creating the mappings on the way, and replacing
+// the values that aren't mappings
+func setKey(doc map[string]interface{}, path string, v interface{}) {
+	keys := tree.Split(path)
+	if len(keys) == 0 {
+		return
+	}
+	parent := doc
+	for _, key := range keys[:len(keys)-1] {
+		child, ok := parent[key].(map[string]interface{})
+		if !ok {
+			child = map[string]interface{}{}
+			parent[key] = child
+		}
+		parent = child
+	}
+	parent[keys[len(keys)-1]] = v
+}
@@ -0,0 +1,42 @@
+package gofigure
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/EverythingMe/gofigure/yaml"
+)
+
+func TestDocumentHooks(t *testing.T) {
+
+	var sources []string
+	loader := NewLoader(yaml.Decoder{}, true)
+	loader.DocumentHooks = []DocumentHook{
+		RenameKey("cache.host", "redis.server"),
+		DeleteKey("legacy"),
+		DocumentHookFunc(func(source string, doc map[string]interface{}) (map[string]interface{}, error) {
+			sources = append(sources, source)
+			doc["mysql"] = map[string]interface{}{"user": "injected"}
+			return doc, nil
+		}),
+	}
+
+	var conf config
+	doc := "cache: {host: 'cache:6379'}\nlegacy: {anything: 1}\nredis: {timeout: 3}"
+	if err := loader.MergeDocuments([][]byte{[]byte(doc)}, &conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Redis.Server != "cache:6379" || conf.Redis.Timeout != 3 || conf.Mysql.User != "injected" {
+		t.Errorf("Unexpected config %+v", conf)
+	}
+	if len(sources) != 1 || sources[0] != "document 1" {
+		t.Errorf("Unexpected sources %v", sources)
+	}
+
+	loader.DocumentHooks = []DocumentHook{DocumentHookFunc(func(string, map[string]interface{}) (map[string]interface{}, error) {
+		return nil, errors.New("refused")
+	})}
+	if err := loader.MergeDocuments([][]byte{[]byte(doc)}, &conf); err == nil {
+		t.Error("Expected the error of a hook to fail the load")
+	}
+}
@@ -0,0 +1,170 @@
+package gofigure
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/EverythingMe/gofigure/internal/tree"
+)
+
+// includeKey is the key of the include directive
+const includeKey = "$include"
+
+// defaultMaxIncludeDepth is how deeply files can include each other unless the loader says otherwise
+const defaultMaxIncludeDepth = 8
+
+// include replaces the include directives in a generic tree with what they include, and returns the
+// resulting value. An include directive is a mapping with an "$include" key listing one or more
+// files or directories, relative to dir. Directories are read recursively like LoadRecursive
+// would, and what they include is merged in order, with the other keys of the mapping merged on
+// top of it.
+//
+// stack is the chain of files that led to this tree, used to detect cycles and limit depth.
+func (l Loader) include(v interface{}, dir string, stack []string) (interface{}, error) {
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			if k == includeKey {
+				continue
+			}
+			included, err := l.include(e, dir, stack)
+			if err != nil {
+				return nil, err
+			}
+			v[k] = included
+		}
+
+		targets, found := v[includeKey]
+		if !found {
+			return v, nil
+		}
+		delete(v, includeKey)
+
+		included, err := l.includeTargets(targets, dir, stack)
+		if err != nil {
+			return nil, err
+		}
+		if len(v) == 0 {
+			return included, nil
+		}
+		if included == nil {
+			return v, nil
+		}
+		m, ok := included.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s next to other keys must include mappings", includeKey)
+		}
+		tree.Merge(m, v)
+		return m, nil
+
+	case []interface{}:
+		for i, e := range v {
+			included, err := l.include(e, dir, stack)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = included
+		}
+		return v, nil
+
+	default:
+		return v, nil
+	}
+}
+
+// includeTargets reads and merges the files and directories listed by an include directive
+func (l Loader) includeTargets(targets interface{}, dir string, stack []string) (interface{}, error) {
+
+	var paths []string
+	switch targets := targets.(type) {
+	case string:
+		paths = []string{targets}
+	case []interface{}:
+		for _, t := range targets {
+			s, ok := t.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s must list paths, got %v", includeKey, t)
+			}
+			paths = append(paths, s)
+		}
+	default:
+		return nil, fmt.Errorf("%s must list paths, got %v", includeKey, targets)
+	}
+
+	var result interface{}
+	for _, path := range paths {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			v, err := l.includeFile(path, stack)
+			if err != nil {
+				return nil, err
+			}
+			result = tree.MergeValue(result, v)
+			continue
+		}
+
+		ch, cancelc := walk(path)
+		for file := range ch {
+			if !l.canDecode(file) {
+				continue
+			}
+			v, err := l.includeFile(file, stack)
+			if err != nil {
+				close(cancelc)
+				return nil, err
+			}
+			result = tree.MergeValue(result, v)
+		}
+		close(cancelc)
+	}
+
+	return result, nil
+}
+
+// includeFile decodes an included file, and follows the includes it has in turn
+func (l Loader) includeFile(path string, stack []string) (interface{}, error) {
+
+	max := l.MaxIncludeDepth
+	if max == 0 {
+		max = defaultMaxIncludeDepth
+	}
+	if len(stack) > max {
+		return nil, fmt.Errorf("includes nested more than %d deep at %s", max, path)
+	}
+
+	// files are compared by device and inode as well as by path, so links to a file including
+	// them are caught too
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	info, statErr := os.Stat(path)
+	for i, p := range stack {
+		same := false
+		if p, err := filepath.Abs(p); err == nil && p == abs {
+			same = true
+		} else if seen, err := os.Stat(p); err == nil && statErr == nil && os.SameFile(info, seen) {
+			same = true
+		}
+		if same {
+			return nil, fmt.Errorf("include cycle: %s", strings.Join(append(stack[i:len(stack):len(stack)], path), " -> "))
+		}
+	}
+
+	v, err := l.decodeFile(path, nil)
+	if err != nil {
+		return nil, err
+	}
+	return l.include(v, filepath.Dir(path), append(stack[:len(stack):len(stack)], path))
+}
@@ -0,0 +1,157 @@
+package gofigure
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/EverythingMe/gofigure/json"
+	"github.com/EverythingMe/gofigure/yaml"
+)
+
+// writeFiles creates files with the given contents under dir, creating parent directories
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, contents := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestIncludes(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gofigure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeFiles(t, dir, map[string]string{
+		"conf/main.yaml": `
+database: !include ../inc/db.yaml
+servers: !include [../inc/servers]
+name: main
+`,
+		"inc/db.yaml":            "host: localhost\nport: 5432\n",
+		"inc/servers/a.yaml":     "list: [a]\nweight: 1\n",
+		"inc/servers/b.yaml":     "list: [b]\n",
+		"inc/servers/ignore.txt": "not yaml",
+		"json/main.json":         `{"database": {"$include": "db.json", "port": 6543}}`,
+		"json/db.json":           `{"host": "db", "port": 5432}`,
+	})
+
+	var conf struct {
+		Name     string
+		Database struct {
+			Host string
+			Port int
+		}
+		Servers struct {
+			List   []string
+			Weight int
+		}
+	}
+
+	loader := NewLoader(yaml.Decoder{}, true)
+	loader.FollowIncludes = true
+	if err := loader.LoadRecursive(&conf, filepath.Join(dir, "conf")); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Name != "main" || conf.Database.Host != "localhost" || conf.Database.Port != 5432 {
+		t.Errorf("Included file not loaded as expected: %#v", conf)
+	}
+	if !reflect.DeepEqual(conf.Servers.List, []string{"b"}) || conf.Servers.Weight != 1 {
+		t.Errorf("Included directory not merged as expected: %#v", conf.Servers)
+	}
+
+	jsonLoader := NewLoader(json.Decoder{}, true)
+	jsonLoader.FollowIncludes = true
+	if err := jsonLoader.LoadFile(&conf, filepath.Join(dir, "json/main.json")); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Database.Host != "db" || conf.Database.Port != 6543 {
+		t.Errorf("Keys next to $include should override included ones: %#v", conf.Database)
+	}
+}
+
+func TestIncludeErrors(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gofigure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeFiles(t, dir, map[string]string{
+		"cycle/a.yaml": "b: !include b.yaml\n",
+		"cycle/b.yaml": "a: !include a.yaml\n",
+		"self/a.yaml":  "!include a.yaml\n",
+		"deep/a.yaml":  "x: !include b.yaml\n",
+		"deep/b.yaml":  "x: !include c.yaml\n",
+		"deep/c.yaml":  "x: 1\n",
+		"bad/a.yaml":   "x: !include\n  - 1\n",
+	})
+
+	loader := NewLoader(yaml.Decoder{}, true)
+	loader.FollowIncludes = true
+
+	var conf map[string]interface{}
+	for _, path := range []string{"cycle/a.yaml", "self/a.yaml", "bad/a.yaml", "missing.yaml"} {
+		if err := loader.LoadFile(&conf, filepath.Join(dir, path)); err == nil {
+			t.Errorf("Expected an error including %s", path)
+		}
+	}
+
+	if err := loader.LoadFile(&conf, filepath.Join(dir, "deep/a.yaml")); err != nil {
+		t.Error(err)
+	}
+	loader.MaxIncludeDepth = 1
+	if err := loader.LoadFile(&conf, filepath.Join(dir, "deep/a.yaml")); err == nil {
+		t.Error("Expected an error for includes nested too deep")
+	}
+
+	// without FollowIncludes, directives are left alone
+	loader = NewLoader(yaml.Decoder{}, true)
+	conf = nil
+	if err := loader.LoadFile(&conf, filepath.Join(dir, "cycle/a.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	if _, found := Map(conf).Get("b.$include"); !found {
+		t.Errorf("Unexpected document %v", conf)
+	}
+}
+
+func TestIncludeLinkCycle(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gofigure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// b.yaml includes a link to a.yaml, which includes b.yaml
+	writeFiles(t, dir, map[string]string{
+		"a.yaml": "b: !include b.yaml\n",
+		"b.yaml": "a: !include link.yaml\n",
+	})
+	if err := os.Symlink(filepath.Join(dir, "a.yaml"), filepath.Join(dir, "link.yaml")); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+
+	loader := NewLoader(yaml.Decoder{}, true)
+	loader.FollowIncludes = true
+	loader.MaxIncludeDepth = 100
+
+	var conf map[string]interface{}
+	err = loader.LoadFile(&conf, filepath.Join(dir, "a.yaml"))
+	if err == nil || !strings.Contains(err.Error(), "include cycle: ") || !strings.Contains(err.Error(), "b.yaml -> ") {
+		t.Errorf("Expected the cycle to be described, got %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+// Package monitor holds what the ReloadMonitors of gofigure and its providers share: running
+// their watches until they are stopped, calling their Reloader one change at a time, and backing
+// off when a watch fails.
+package monitor
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/op/go-logging"
+)
+
+var log = logging.MustGetLogger("gofigure")
+
+// Reloader is gofigure.Reloader, which this package can't import
+type Reloader interface {
+	Reload()
+}
+
+// Group runs the watches of a monitor until it's stopped. Its zero value is ready to use
+type Group struct {
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	mu     sync.Mutex
+}
+
+// Start runs n watches, each in its own goroutine, calling watch with the context of the group,
+// which is done when it's stopped, and the index of the watch
+func (g *Group) Start(n int, watch func(ctx context.Context, i int)) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	g.cancel = cancel
+
+	for i := 0; i < n; i++ {
+		g.wg.Add(1)
+		go func(i int) {
+			defer g.wg.Done()
+			watch(ctx, i)
+		}(i)
+	}
+}
+
+// Stop stops the watches, and waits for them to end. name names the monitor in the log, like
+// "consul"
+func (g *Group) Stop(name string) {
+	if g.cancel != nil {
+		g.cancel()
+	}
+	g.wg.Wait()
+	log.Info("Stopped %s monitor", name)
+}
+
+// Reload calls r, one change at a time, whichever watch saw it
+func (g *Group) Reload(r Reloader) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	r.Reload()
+}
+
+const (
+	minBackoff = 100 * time.Millisecond
+	maxBackoff = 10 * time.Second
+)
+
+// Backoff is the delay before a failed watch is tried again, which doubles with every failure
+// from 100ms to 10s. Its zero value is at the shortest delay
+type Backoff struct {
+	delay time.Duration
+}
+
+// Delay returns how long the next Wait waits
+func (b *Backoff) Delay() time.Duration {
+	if b.delay == 0 {
+		return minBackoff
+	}
+	return b.delay
+}
+
+// Wait waits for the delay, or until ctx is done, and doubles it
+func (b *Backoff) Wait(ctx context.Context) {
+	delay := b.Delay()
+	Sleep(ctx, delay)
+	if b.delay = delay * 2; b.delay > maxBackoff {
+		b.delay = maxBackoff
+	}
+}
+
+// Reset brings the delay back to the shortest, once a watch succeeds
+func (b *Backoff) Reset() {
+	b.delay = 0
+}
+
+// Sleep waits for d, or until ctx is done
+func Sleep(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
+}
@@ -0,0 +1,163 @@
+// Package tree holds helpers shared by gofigure and its decoders for working with generic
+// documents, i.e. configs decoded into an interface{} rather than into a struct.
+package tree
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Normalize converts a generic value so that every mapping in it is a map[string]interface{},
+// and every number an int, int64, uint64 or float64, no matter which decoder produced it
+func Normalize(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		if u, err := strconv.ParseUint(string(v), 10, 64); err == nil {
+			return u
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = Normalize(e)
+		}
+		return v
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[fmt.Sprint(k)] = Normalize(e)
+		}
+		return m
+	case []interface{}:
+		for i, e := range v {
+			v[i] = Normalize(e)
+		}
+		return v
+	}
+	return v
+}
+
+// Merge deep merges src into dst: mappings present in both are merged key by key, and any other
+// value in src replaces the one in dst
+func Merge(dst, src map[string]interface{}) {
+	for k, v := range src {
+		sm, srcIsMap := v.(map[string]interface{})
+		dm, dstIsMap := dst[k].(map[string]interface{})
+		if srcIsMap && dstIsMap {
+			Merge(dm, sm)
+			continue
+		}
+		dst[k] = v
+	}
+}
+
+// Copy returns a deep copy of a normalized value, so it can be merged into without affecting the
+// original
+func Copy(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[k] = Copy(e)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(v))
+		for i, e := range v {
+			l[i] = Copy(e)
+		}
+		return l
+	default:
+		return v
+	}
+}
+
+// MergeValue merges the generic value src over dst and returns the result. If both are mappings
+// they are deep merged, otherwise src simply wins
+func MergeValue(dst, src interface{}) interface{} {
+	dm, dstIsMap := dst.(map[string]interface{})
+	sm, srcIsMap := src.(map[string]interface{})
+	if dstIsMap && srcIsMap {
+		Merge(dm, sm)
+		return dm
+	}
+	return src
+}
+
+// Split splits a dotted path like "database.primary" into its components. The empty path has none
+func Split(path string) []string {
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, ".")
+}
+
+// Place merges v into the document t at a dotted path, creating the mappings on the way and
+// replacing the values that aren't mappings. At the empty path, a mapping v is merged into t
+func Place(t map[string]interface{}, path string, v interface{}) {
+
+	keys := Split(path)
+	if len(keys) == 0 {
+		if m, ok := v.(map[string]interface{}); ok {
+			Merge(t, m)
+		}
+		return
+	}
+
+	parent := t
+	for _, key := range keys[:len(keys)-1] {
+		child, ok := parent[key].(map[string]interface{})
+		if !ok {
+			child = map[string]interface{}{}
+			parent[key] = child
+		}
+		parent = child
+	}
+	last := keys[len(keys)-1]
+	parent[last] = MergeValue(parent[last], v)
+}
+
+// Lookup returns the value at the dotted path in the document t, and whether it was found.
+// Components of the path can be list indexes as well as mapping keys, e.g. "servers.0.host"
+func Lookup(t map[string]interface{}, path string) (interface{}, bool) {
+	var v interface{} = t
+	for _, key := range Split(path) {
+		switch node := v.(type) {
+		case map[string]interface{}:
+			var found bool
+			if v, found = node[key]; !found {
+				return nil, false
+			}
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			v = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// Assign stores a generic document decoded by a decoder that builds documents itself into config.
+// Generic documents are merged into what config already holds, as decoders of multi-document
+// streams do, and structs are filled the way the json decoder fills them
+func Assign(doc interface{}, config interface{}) error {
+	if generic, ok := config.(*interface{}); ok {
+		*generic = MergeValue(*generic, doc)
+		return nil
+	}
+
+	encoded, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(encoded, config)
+}
@@ -0,0 +1,189 @@
+package gofigure
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/EverythingMe/gofigure/internal/tree"
+)
+
+// lookupFunc resolves the name of a ${name} reference to its value, and whether it was found
+type lookupFunc func(name string) (string, bool, error)
+
+// expand replaces the ${name} and ${name:-default} references in s with what lookup resolves them
+// to. References that can't be resolved and have no default are replaced with an empty string,
+// like a shell would. $${ escapes a literal ${
+func expand(s string, lookup lookupFunc) (string, error) {
+
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+
+	var buf strings.Builder
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			buf.WriteString(s)
+			return buf.String(), nil
+		}
+
+		if i > 0 && s[i-1] == '$' {
+			buf.WriteString(s[:i-1])
+			buf.WriteString("${")
+			s = s[i+2:]
+			continue
+		}
+		buf.WriteString(s[:i])
+
+		end := strings.IndexByte(s[i:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated reference in %q", s)
+		}
+		ref := s[i+2 : i+end]
+		s = s[i+end+1:]
+
+		name, def, hasDefault := ref, "", false
+		if j := strings.Index(ref, ":-"); j >= 0 {
+			name, def, hasDefault = ref[:j], ref[j+2:], true
+		}
+
+		value, found, err := lookup(name)
+		if err != nil {
+			return "", err
+		}
+		if !found && hasDefault {
+			value = def
+		}
+		buf.WriteString(value)
+	}
+}
+
+// interpolate replaces every string of a generic tree in place with what expandValue turns it
+// into, and returns the resulting value
+func interpolate(v interface{}, expandValue func(string) (interface{}, error), path string) (interface{}, error) {
+	switch v := v.(type) {
+	case string:
+		expanded, err := expandValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", path, err)
+		}
+		return expanded, nil
+
+	case map[string]interface{}:
+		for k, e := range v {
+			expanded, err := interpolate(e, expandValue, joinPath(path, k))
+			if err != nil {
+				return nil, err
+			}
+			v[k] = expanded
+		}
+		return v, nil
+
+	case []interface{}:
+		for i, e := range v {
+			expanded, err := interpolate(e, expandValue, fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			v[i] = expanded
+		}
+		return v, nil
+
+	default:
+		return v, nil
+	}
+}
+
+// expandEnv expands the references to environment variables in a string value
+func expandEnv(s string) (interface{}, error) {
+	return expand(s, lookupEnv)
+}
+
+// lookupEnv resolves references to environment variables
+func lookupEnv(name string) (string, bool, error) {
+	value, found := os.LookupEnv(name)
+	return value, found, nil
+}
+
+// resolver resolves references to other keys of a merged tree, like ${paths.base}, falling back
+// to environment variables if env is set
+type resolver struct {
+	root      map[string]interface{}
+	env       bool
+	resolved  map[string]interface{}
+	resolving map[string]bool
+}
+
+// newResolver creates a resolver for the references in root. The resolver works on its own copy
+// of root, so that root can be expanded in place without values being expanded twice
+func newResolver(root map[string]interface{}, env bool) *resolver {
+	return &resolver{
+		root:      tree.Copy(root).(map[string]interface{}),
+		env:       env,
+		resolved:  map[string]interface{}{},
+		resolving: map[string]bool{},
+	}
+}
+
+// expandValue expands the references in a string value. A string made of a single reference to a
+// non string key is replaced with that key's value, so "${defaults.port}" can be used for an int
+func (r *resolver) expandValue(s string) (interface{}, error) {
+
+	if strings.HasPrefix(s, "${") && strings.IndexByte(s, '}') == len(s)-1 && !strings.Contains(s, ":-") {
+		v, found, err := r.resolve(s[2 : len(s)-1])
+		if err != nil {
+			return nil, err
+		}
+		if _, isString := v.(string); found && !isString {
+			return tree.Copy(v), nil
+		}
+	}
+
+	return expand(s, r.lookup)
+}
+
+// lookup resolves a reference to the string form of the value it refers to
+func (r *resolver) lookup(name string) (string, bool, error) {
+	v, found, err := r.resolve(name)
+	if err != nil || !found {
+		return "", found, err
+	}
+
+	switch v.(type) {
+	case map[string]interface{}, []interface{}:
+		return "", false, fmt.Errorf("%s cannot be referenced inside a string", name)
+	}
+	return fmt.Sprint(v), true, nil
+}
+
+// resolve returns the fully expanded value of the key at the dotted path name
+func (r *resolver) resolve(name string) (interface{}, bool, error) {
+
+	if v, found := r.resolved[name]; found {
+		return v, true, nil
+	}
+
+	v, found := tree.Lookup(r.root, name)
+	if !found {
+		if r.env {
+			value, found, err := lookupEnv(name)
+			return value, found, err
+		}
+		log.Warning("Reference to unknown key %s", name)
+		return nil, false, nil
+	}
+
+	if r.resolving[name] {
+		return nil, false, fmt.Errorf("reference cycle through %s", name)
+	}
+	r.resolving[name] = true
+	defer delete(r.resolving, name)
+
+	v, err := interpolate(tree.Copy(v), r.expandValue, name)
+	if err != nil {
+		return nil, false, err
+	}
+	r.resolved[name] = v
+	return v, true, nil
+}
@@ -0,0 +1,119 @@
+package gofigure
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestExpandEnv(t *testing.T) {
+
+	os.Setenv("GOFIGURE_TEST_HOST", "example.com")
+	defer os.Unsetenv("GOFIGURE_TEST_HOST")
+	os.Unsetenv("GOFIGURE_TEST_PORT")
+
+	cases := map[string]string{
+		"plain":                              "plain",
+		"${GOFIGURE_TEST_HOST}":              "example.com",
+		"http://${GOFIGURE_TEST_HOST}/x":     "http://example.com/x",
+		"${GOFIGURE_TEST_PORT:-8080}":        "8080",
+		"${GOFIGURE_TEST_HOST:-localhost}":   "example.com",
+		"${GOFIGURE_TEST_PORT}":              "",
+		"$${GOFIGURE_TEST_HOST}":             "${GOFIGURE_TEST_HOST}",
+		"$$${GOFIGURE_TEST_HOST}":            "$${GOFIGURE_TEST_HOST}",
+		"${GOFIGURE_TEST_HOST}:${NOPE:-80}$": "example.com:80$",
+	}
+	for in, expected := range cases {
+		out, err := expand(in, lookupEnv)
+		if err != nil {
+			t.Errorf("Expanding %q: %s", in, err)
+		}
+		if out != expected {
+			t.Errorf("Expanding %q gave %q, expected %q", in, out, expected)
+		}
+	}
+
+	if _, err := expand("${GOFIGURE_TEST_HOST", lookupEnv); err == nil {
+		t.Error("Expected an error for an unterminated reference")
+	}
+
+	doc := map[string]interface{}{
+		"url":   "http://${GOFIGURE_TEST_HOST}",
+		"hosts": []interface{}{"${GOFIGURE_TEST_HOST}", "b"},
+		"port":  8080,
+	}
+	var conf struct {
+		URL   string
+		Hosts []string
+		Port  int
+	}
+	loader := Loader{StrictMode: true, ExpandEnv: true}
+	if err := loader.bindDocuments(nil, []document{{"test", doc}}, "", &conf); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"example.com", "b"}
+	if conf.URL != "http://example.com" || !reflect.DeepEqual(conf.Hosts, expected) || conf.Port != 8080 {
+		t.Errorf("Config not expanded as expected: %#v", conf)
+	}
+}
+
+func TestExpandReferences(t *testing.T) {
+
+	os.Setenv("GOFIGURE_TEST_HOST", "example.com")
+	defer os.Unsetenv("GOFIGURE_TEST_HOST")
+
+	docs := []document{
+		{"a", map[string]interface{}{
+			"paths": map[string]interface{}{"base": "/srv", "logs": "${paths.base}/logs"},
+			"host":  "${GOFIGURE_TEST_HOST}",
+		}},
+		{"b", map[string]interface{}{
+			"paths":    map[string]interface{}{"base": "/opt/${name}"},
+			"name":     "app",
+			"defaults": map[string]interface{}{"port": 8080},
+			"port":     "${defaults.port}",
+			"escaped":  "$${paths.base}",
+			"ref":      "${escaped}!",
+			"list":     []interface{}{"${paths.logs}/a", "${missing:-none}"},
+		}},
+	}
+
+	var conf struct {
+		Paths struct {
+			Base, Logs string
+		}
+		Host    string
+		Port    int
+		Escaped string
+		Ref     string
+		List    []string
+	}
+	loader := Loader{StrictMode: true, ExpandReferences: true, ExpandEnv: true}
+	if err := loader.bindDocuments(nil, docs, "", &conf); err != nil {
+		t.Fatal(err)
+	}
+
+	if conf.Paths.Base != "/opt/app" || conf.Paths.Logs != "/opt/app/logs" {
+		t.Errorf("References not resolved after merging: %#v", conf.Paths)
+	}
+	if conf.Host != "example.com" || conf.Port != 8080 {
+		t.Errorf("Unexpected host and port: %#v", conf)
+	}
+	if conf.Escaped != "${paths.base}" || conf.Ref != "${paths.base}!" {
+		t.Errorf("Escaped references expanded: %#v", conf)
+	}
+	if !reflect.DeepEqual(conf.List, []string{"/opt/app/logs/a", "none"}) {
+		t.Errorf("Unexpected list %v", conf.List)
+	}
+
+	cycles := []map[string]interface{}{
+		{"a": "${b}", "b": "x${a}"},
+		{"a": "${a}"},
+		{"a": map[string]interface{}{"b": "${a}"}},
+	}
+	for _, doc := range cycles {
+		if _, err := loader.merge([]document{{"cycle", doc}}); err == nil {
+			t.Errorf("Expected a cycle error for %v", doc)
+		}
+	}
+}
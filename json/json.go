@@ -11,15 +11,27 @@ import (
 // config structs
 type Decoder struct{}
 
-// Decode just wraps using a json decoder to unmarshal into config, which is a pointer to a struct
+// Decode just wraps using a json decoder to unmarshal into config, which is a pointer to a struct.
+// When decoding into a pointer to an interface{}, numbers are kept as json.Number so large
+// integers don't lose precision
 func (d Decoder) Decode(r io.Reader, config interface{}) error {
 
 	dec := json.NewDecoder(r)
+	if _, isGeneric := config.(*interface{}); isGeneric {
+		dec.UseNumber()
+	}
 
 	return dec.Decode(config)
 
 }
 
+// Encode writes v to w as indented json, making Decoder a gofigure.Encoder
+func (d Decoder) Encode(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
 // CanDecode returns true if this is a json file
 func (d Decoder) CanDecode(path string) bool {
 	return strings.HasSuffix(path, ".json")
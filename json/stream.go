@@ -0,0 +1,121 @@
+package json
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/EverythingMe/gofigure/internal/tree"
+)
+
+// Stream calls fn with every item of the collection at the dotted path at of the json document
+// read from r ("" for the document itself), with the key of every mapping entry or the index of
+// every list element. Items are decoded one at a time, and what comes before the collection is
+// skipped over, so the document is never held in memory as a whole. Nothing is streamed if there
+// is nothing at the path
+func (d Decoder) Stream(r io.Reader, at string, fn func(key string, item interface{}) error) error {
+
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	found, err := seek(dec, tree.Split(at))
+	if err != nil || !found {
+		return err
+	}
+
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	isMapping := tok == json.Delim('{')
+	if !isMapping && tok != json.Delim('[') {
+		return fmt.Errorf("json: the value at %q is not a mapping or a list", at)
+	}
+
+	for i := 0; dec.More(); i++ {
+		key := strconv.Itoa(i)
+		if isMapping {
+			tok, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			key = tok.(string)
+		}
+
+		var item interface{}
+		if err := dec.Decode(&item); err != nil {
+			return err
+		}
+		if err := fn(key, item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// seek moves dec to the value at the path keys, skipping everything before it, and reports
+// whether it was found
+func seek(dec *json.Decoder, keys []string) (bool, error) {
+
+	for _, key := range keys {
+		tok, err := dec.Token()
+		if err != nil {
+			return false, err
+		}
+
+		found := false
+		switch tok {
+		case json.Delim('{'):
+			for !found && dec.More() {
+				k, err := dec.Token()
+				if err != nil {
+					return false, err
+				}
+				if found = k == key; !found {
+					if err := skip(dec); err != nil {
+						return false, err
+					}
+				}
+			}
+
+		case json.Delim('['):
+			index, err := strconv.Atoi(key)
+			if err != nil {
+				return false, nil
+			}
+			for i := 0; !found && dec.More(); i++ {
+				if found = i == index; !found {
+					if err := skip(dec); err != nil {
+						return false, err
+					}
+				}
+			}
+		}
+
+		if !found {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// skip reads past the next value of dec
+func skip(dec *json.Decoder) error {
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,242 @@
+// Package kafka materializes config from a compacted Kafka topic, for pipelines that already
+// distribute settings through Kafka, and keeps it current by consuming the topic.
+//
+// The key of every record is the dotted path of a section of the config, or empty for the root,
+// and its value is the document of the section, in YAML or JSON. A record without a value, a
+// tombstone, removes its section. The latest record of every key is kept, so the config is what
+// Kafka's log compaction leaves of the topic:
+//
+//	key: redis     value: {server: "cache:6379"}   ->  redis: {server: "cache:6379"}
+//	key: db.main   value: {pool: 20}               ->  db: {main: {pool: 20}}
+//
+// Sections are merged in the order of their paths, so those at deeper paths are merged over the
+// sections holding them.
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/EverythingMe/gofigure"
+	"github.com/EverythingMe/gofigure/internal/tree"
+	"github.com/EverythingMe/gofigure/yaml"
+	"github.com/op/go-logging"
+	"github.com/twmb/franz-go/pkg/kadm"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+var log = logging.MustGetLogger("gofigure")
+
+// Topic is a compacted topic consumed from its start, holding the latest value of every key. It's
+// a gofigure.Source serving the config it makes up, and a gofigure.ReloadMonitor calling its
+// Reloader when the config changes. Close it to stop consuming
+type Topic struct {
+	topic  string
+	client *kgo.Client
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu       sync.Mutex
+	values   map[string][]byte
+	version  uint64
+	ends     map[int32]int64
+	ready    chan struct{}
+	reloader gofigure.Reloader
+}
+
+// NewTopic starts consuming a topic. The options configure the client, and must at least set the
+// brokers with kgo.SeedBrokers; the topic is consumed without a consumer group, from the start
+// of all its partitions
+func NewTopic(topic string, opts ...kgo.Opt) (*Topic, error) {
+
+	opts = append(opts, kgo.ConsumeTopics(topic), kgo.ConsumeResetOffset(kgo.NewOffset().AtStart()))
+	client, err := kgo.NewClient(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("kafka: %s", err)
+	}
+
+	// the topic is materialized once every partition is consumed up to the end it has now
+	ctx, cancel := context.WithCancel(context.Background())
+	listed, err := kadm.NewClient(client).ListEndOffsets(ctx, topic)
+	if err == nil {
+		err = listed.Error()
+	}
+	if err != nil {
+		cancel()
+		client.Close()
+		return nil, fmt.Errorf("kafka: listing the offsets of %s: %s", topic, err)
+	}
+
+	t := &Topic{
+		topic:  topic,
+		client: client,
+		cancel: cancel,
+		done:   make(chan struct{}),
+		values: map[string][]byte{},
+		ends:   map[int32]int64{},
+		ready:  make(chan struct{}),
+	}
+	listed.Each(func(o kadm.ListedOffset) {
+		if o.Offset > 0 {
+			t.ends[o.Partition] = o.Offset
+		}
+	})
+	t.caughtUp()
+
+	go t.consume(ctx)
+	return t, nil
+}
+
+// Close stops consuming the topic
+func (t *Topic) Close() {
+	t.cancel()
+	<-t.done
+	t.client.Close()
+}
+
+// Name returns the topic's name
+func (t *Topic) Name() string {
+	return "kafka:" + t.topic
+}
+
+// Fetch returns the config the topic makes up, once it's consumed up to the end it had when it was
+// created. A value that can't be decoded is a permanent failure
+func (t *Topic) Fetch(ctx context.Context) (io.ReadCloser, error) {
+
+	select {
+	case <-t.ready:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	t.mu.Lock()
+	keys := make([]string, 0, len(t.values))
+	values := make(map[string][]byte, len(t.values))
+	for k, v := range t.values {
+		keys = append(keys, k)
+		values[k] = v
+	}
+	t.mu.Unlock()
+
+	// parents sort before their children, which are merged over them
+	sort.Strings(keys)
+	doc := map[string]interface{}{}
+	for _, key := range keys {
+		var v interface{}
+		if err := (yaml.Decoder{}).Decode(bytes.NewReader(values[key]), &v); err != nil {
+			return nil, gofigure.Permanent(fmt.Errorf("kafka: %s of %s: %s", key, t.topic, err))
+		}
+		tree.Place(doc, key, tree.Normalize(v))
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, gofigure.Permanent(err)
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+// Version returns the number of changes the topic went through since it was created
+func (t *Topic) Version(ctx context.Context) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return strconv.FormatUint(t.version, 10), nil
+}
+
+// Watch makes the topic call r whenever a record changes the config, once the topic is
+// materialized
+func (t *Topic) Watch(r gofigure.Reloader) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.reloader = r
+}
+
+// Stop stops calling the Reloader. The topic is still consumed, until it's closed
+func (t *Topic) Stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.reloader = nil
+	log.Info("Stopped kafka monitor")
+}
+
+// consume applies the records of the topic until ctx is done
+func (t *Topic) consume(ctx context.Context) {
+	defer close(t.done)
+
+	for {
+		fetches := t.client.PollFetches(ctx)
+		if ctx.Err() != nil || fetches.IsClientClosed() {
+			return
+		}
+		fetches.EachError(func(topic string, partition int32, err error) {
+			log.Info("kafka fetch of %s/%d failed: %s", topic, partition, err)
+		})
+
+		changed := false
+		t.mu.Lock()
+		fetches.EachRecord(func(rec *kgo.Record) {
+			if t.apply(rec) {
+				changed = true
+			}
+		})
+		materialized := t.isReady()
+		t.caughtUp()
+		r := t.reloader
+		t.mu.Unlock()
+
+		if changed && materialized && r != nil {
+			log.Info("kafka topic %s changed, reloading", t.topic)
+			r.Reload()
+		}
+	}
+}
+
+// apply keeps the value of a record, or removes its key if it's a tombstone, returning whether
+// the config changed. The topic must be locked
+func (t *Topic) apply(rec *kgo.Record) bool {
+
+	if end, found := t.ends[rec.Partition]; found && rec.Offset+1 >= end {
+		delete(t.ends, rec.Partition)
+	}
+
+	key := string(rec.Key)
+	old, found := t.values[key]
+	switch {
+	case rec.Value == nil && !found:
+		return false
+	case rec.Value == nil:
+		delete(t.values, key)
+	case found && bytes.Equal(old, rec.Value):
+		return false
+	default:
+		t.values[key] = rec.Value
+	}
+	t.version++
+	return true
+}
+
+// caughtUp marks the topic ready once every partition is consumed up to the end it had when the
+// topic was created. The topic must be locked, or not shared yet
+func (t *Topic) caughtUp() {
+	if len(t.ends) == 0 && !t.isReady() {
+		close(t.ready)
+		log.Info("kafka topic %s materialized, %d keys", t.topic, len(t.values))
+	}
+}
+
+// isReady tells whether the topic is materialized
+func (t *Topic) isReady() bool {
+	select {
+	case <-t.ready:
+		return true
+	default:
+		return false
+	}
+}
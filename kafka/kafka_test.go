@@ -0,0 +1,95 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/EverythingMe/gofigure"
+	"github.com/EverythingMe/gofigure/yaml"
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+func TestTopic(t *testing.T) {
+
+	cluster, err := kfake.NewCluster(kfake.NumBrokers(1), kfake.SeedTopics(2, "config"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+
+	producer, err := kgo.NewClient(kgo.SeedBrokers(cluster.ListenAddrs()...), kgo.DefaultProduceTopic("config"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer producer.Close()
+	produce := func(key, value string) {
+		rec := &kgo.Record{Key: []byte(key)}
+		if value != "" {
+			rec.Value = []byte(value)
+		}
+		if err := producer.ProduceSync(context.Background(), rec).FirstErr(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	produce("", "name: app")
+	produce("redis", `{"server": "old:6379"}`)
+	produce("redis", `{"server": "cache:6379"}`)
+	produce("redis.pool", "10")
+	produce("db", "pool: 5")
+	produce("db", "")
+
+	topic, err := NewTopic("config", kgo.SeedBrokers(cluster.ListenAddrs()...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer topic.Close()
+
+	type config struct {
+		Name  string
+		Redis struct {
+			Server string
+			Pool   int
+		}
+		DB *struct {
+			Pool int
+		}
+	}
+	var conf config
+	loader := gofigure.NewLoader(yaml.Decoder{}, true)
+	loader.Sources = []gofigure.Source{topic}
+	if err := loader.LoadRecursive(&conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Name != "app" || conf.Redis.Server != "cache:6379" || conf.Redis.Pool != 10 || conf.DB != nil {
+		t.Errorf("Unexpected config %#v", conf)
+	}
+
+	reloads := make(chan bool, 10)
+	var m gofigure.ReloadMonitor = topic
+	m.Watch(gofigure.ReloadFunc(func() { reloads <- true }))
+	defer m.Stop()
+
+	produce("redis.pool", "20")
+	select {
+	case <-reloads:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Change did not trigger a reload")
+	}
+	conf = config{}
+	if err := loader.LoadRecursive(&conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Redis.Pool != 20 {
+		t.Errorf("Unexpected config after a change %#v", conf)
+	}
+
+	produce("redis.pool", "20")
+	select {
+	case <-reloads:
+		t.Fatal("A record that changes nothing should not trigger a reload")
+	case <-time.After(200 * time.Millisecond):
+	}
+}
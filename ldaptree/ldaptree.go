@@ -0,0 +1,145 @@
+// Package ldaptree loads config from the attributes of the entries of an LDAP subtree, for
+// enterprises mandating LDAP as the source of truth of their settings.
+//
+// The hierarchy of the entries under the source's base DN is that of the config: every entry is a
+// mapping, keyed in its parent by the value of its RDN, and its attributes are the keys of the
+// mapping. Attributes with several values are lists:
+//
+//	dn: ou=app,dc=example,dc=com           ->  (the root of the config)
+//	description: orders service                description: orders service
+//
+//	dn: cn=redis,ou=app,dc=example,dc=com  ->  redis:
+//	server: cache:6379                           server: cache:6379
+//	replica: cache-1:6379                        replica: [cache-1:6379, cache-2:6379]
+//	replica: cache-2:6379
+//
+// The objectClass attribute, and those naming entries in their DN, are left out. Values are kept
+// as strings, so loaders binding them into typed fields should be WeaklyTyped. To reload the
+// config when entries change, poll the source with a gofigure.PollMonitor.
+package ldaptree
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/EverythingMe/gofigure"
+	"github.com/EverythingMe/gofigure/internal/tree"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// pageSize is the number of entries searches get at a time
+const pageSize = 500
+
+// Source is a gofigure.Source reading the config from the subtree under BaseDN. A missing base
+// entry is a permanent failure
+type Source struct {
+	Conn   *ldap.Conn
+	BaseDN string
+
+	// Filter selects the entries of the subtree that are read, all of them if empty
+	Filter string
+
+	// Attributes are those read, all the user attributes if empty
+	Attributes []string
+}
+
+// Name returns the source's base DN
+func (s Source) Name() string {
+	return "ldap:" + s.BaseDN
+}
+
+// Fetch searches the subtree, and builds the config from its entries
+func (s Source) Fetch(ctx context.Context) (io.ReadCloser, error) {
+
+	base, err := ldap.ParseDN(s.BaseDN)
+	if err != nil {
+		return nil, gofigure.Permanent(fmt.Errorf("ldaptree: invalid base DN: %s", err))
+	}
+	filter := s.Filter
+	if filter == "" {
+		filter = "(objectClass=*)"
+	}
+
+	req := ldap.NewSearchRequest(s.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		filter, s.Attributes, nil)
+	res, err := s.Conn.SearchWithPaging(req, pageSize)
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+		return nil, gofigure.Permanent(fmt.Errorf("ldaptree: %s not found", s.BaseDN))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := document(base, res.Entries)
+	if err != nil {
+		return nil, gofigure.Permanent(err)
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, gofigure.Permanent(err)
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+// document builds the config from the entries under base
+func document(base *ldap.DN, entries []*ldap.Entry) (map[string]interface{}, error) {
+
+	doc := map[string]interface{}{}
+	for _, entry := range entries {
+		dn, err := ldap.ParseDN(entry.DN)
+		if err != nil {
+			return nil, fmt.Errorf("ldaptree: entry %s: %s", entry.DN, err)
+		}
+		if !base.Equal(dn) && !base.AncestorOf(dn) {
+			return nil, fmt.Errorf("ldaptree: entry %s is not under %s", entry.DN, base)
+		}
+
+		// the RDNs of the entry under the base, from the outermost
+		rdns := dn.RDNs[:len(dn.RDNs)-len(base.RDNs)]
+		keys := make([]string, 0, len(rdns))
+		for i := len(rdns) - 1; i >= 0; i-- {
+			keys = append(keys, rdns[i].Attributes[0].Value)
+		}
+
+		// the attributes naming the entry, the base included, are in its own RDN
+		naming := map[string]bool{"objectclass": true}
+		if len(dn.RDNs) > 0 {
+			for _, a := range dn.RDNs[0].Attributes {
+				naming[strings.ToLower(a.Type)] = true
+			}
+		}
+
+		m := map[string]interface{}{}
+		for _, a := range entry.Attributes {
+			if naming[strings.ToLower(a.Name)] || len(a.Values) == 0 {
+				continue
+			}
+			if len(a.Values) == 1 {
+				m[a.Name] = a.Values[0]
+				continue
+			}
+			values := make([]interface{}, len(a.Values))
+			for i, v := range a.Values {
+				values[i] = v
+			}
+			m[a.Name] = values
+		}
+		// RDN values can hold dots, so they aren't joined into a dotted path
+		parent := doc
+		for _, key := range keys {
+			child, ok := parent[key].(map[string]interface{})
+			if !ok {
+				child = map[string]interface{}{}
+				parent[key] = child
+			}
+			parent = child
+		}
+		tree.Merge(parent, m)
+	}
+	return doc, nil
+}
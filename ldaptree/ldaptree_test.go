@@ -0,0 +1,58 @@
+package ldaptree
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+func TestDocument(t *testing.T) {
+
+	base, err := ldap.ParseDN("ou=app,dc=example,dc=com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries := []*ldap.Entry{
+		ldap.NewEntry("cn=primary,cn=redis,ou=app,dc=example,dc=com", map[string][]string{
+			"objectClass": {"applicationProcess"},
+			"cn":          {"primary"},
+			"server":      {"cache:6379"},
+		}),
+		ldap.NewEntry("ou=app,dc=example,dc=com", map[string][]string{
+			"objectClass": {"organizationalUnit"},
+			"ou":          {"app"},
+			"description": {"orders service"},
+		}),
+		ldap.NewEntry("cn=redis,ou=app,dc=example,dc=com", map[string][]string{
+			"cn":      {"redis"},
+			"replica": {"cache-1:6379", "cache-2:6379"},
+		}),
+		ldap.NewEntry("cn=example.com,ou=app,dc=example,dc=com", map[string][]string{
+			"cn":    {"example.com"},
+			"owner": {"web"},
+		}),
+	}
+
+	doc, err := document(base, entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"description": "orders service",
+		"redis": map[string]interface{}{
+			"replica": []interface{}{"cache-1:6379", "cache-2:6379"},
+			"primary": map[string]interface{}{"server": "cache:6379"},
+		},
+		"example.com": map[string]interface{}{"owner": "web"},
+	}
+	if !reflect.DeepEqual(doc, expected) {
+		t.Errorf("Expected %#v, got %#v", expected, doc)
+	}
+
+	outside := ldap.NewEntry("cn=other,dc=example,dc=com", map[string][]string{"cn": {"other"}})
+	if _, err := document(base, []*ldap.Entry{outside}); err == nil {
+		t.Error("Expected an error for an entry outside of the base")
+	}
+}
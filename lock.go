@@ -0,0 +1,11 @@
+package gofigure
+
+import (
+	"time"
+)
+
+// lockTimeout is how long reading a file waits for a writer to release its exclusive lock
+var lockTimeout = 5 * time.Second
+
+// lockPollInterval is how often a lock held by a writer is retried
+const lockPollInterval = 10 * time.Millisecond
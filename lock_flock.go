@@ -0,0 +1,36 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package gofigure
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+)
+
+// lockShared takes a shared advisory lock on an open file, waiting up to timeout for an exclusive
+// lock to be released
+func lockShared(fp *os.File, timeout time.Duration) error {
+
+	deadline := time.Now().Add(timeout)
+	for {
+		err := syscall.Flock(int(fp.Fd()), syscall.LOCK_SH|syscall.LOCK_NB)
+		switch {
+		case err == nil:
+			return nil
+		case err == syscall.EINTR:
+			continue
+		case err != syscall.EWOULDBLOCK:
+			return err
+		case time.Now().After(deadline):
+			return fmt.Errorf("still locked by a writer after %s", timeout)
+		}
+		time.Sleep(lockPollInterval)
+	}
+}
+
+// unlock releases the lock taken by lockShared
+func unlock(fp *os.File) {
+	syscall.Flock(int(fp.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,16 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package gofigure
+
+import (
+	"os"
+	"time"
+)
+
+// lockShared is a no-op on platforms without flock
+func lockShared(fp *os.File, timeout time.Duration) error {
+	return nil
+}
+
+// unlock is a no-op on platforms without flock
+func unlock(fp *os.File) {}
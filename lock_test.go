@@ -0,0 +1,60 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package gofigure
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/EverythingMe/gofigure/yaml"
+)
+
+func TestLockFiles(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gofigure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "conf.yaml")
+	writeFiles(t, dir, map[string]string{"conf.yaml": "redis:\n  monitor: 10\n"})
+
+	writer, err := os.OpenFile(path, os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer writer.Close()
+	if err := syscall.Flock(int(writer.Fd()), syscall.LOCK_EX); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(timeout time.Duration) { lockTimeout = timeout }(lockTimeout)
+	lockTimeout = 50 * time.Millisecond
+
+	loader := NewLoader(yaml.Decoder{}, true)
+	loader.LockFiles = true
+
+	var conf config
+	if err := loader.LoadFile(&conf, path); err == nil {
+		t.Error("Expected reading a file locked by a writer to time out")
+	}
+
+	// the writer finishes while the loader is waiting
+	lockTimeout = 5 * time.Second
+	fd := int(writer.Fd())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		syscall.Flock(fd, syscall.LOCK_UN)
+	}()
+	if err := loader.LoadFile(&conf, path); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Redis.Monitor != 10 {
+		t.Errorf("Unexpected config %#v", conf.Redis)
+	}
+}
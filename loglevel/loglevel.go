@@ -0,0 +1,130 @@
+// Package loglevel sets the levels of go-logging loggers from a section of the config tree, and
+// sets them again when the config is reloaded, so the verbosity of a running program can be
+// changed by editing its config:
+//
+//	logging:
+//	  level: info        # the default level
+//	  modules:
+//	    gofigure: warning
+//	    db: debug
+//
+//	levels, err := loglevel.New(loader, "logging", "conf.d")
+//	monitor.Watch(levels)
+package loglevel
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/EverythingMe/gofigure"
+	"github.com/op/go-logging"
+)
+
+var log = logging.MustGetLogger("gofigure")
+
+// Config is the logging section of the config tree
+type Config struct {
+	// Level is the default level of the modules, left as is if empty
+	Level string `gofigure:"level"`
+
+	// Modules are the levels of specific modules, by the module names of their loggers
+	Modules map[string]string `gofigure:"modules"`
+}
+
+// Levels applies the log levels configured in a section of the config tree. It loads and applies
+// them when it's created, and again whenever Reload is called. A module removed from the section
+// goes back to the default level
+type Levels struct {
+	loader  *gofigure.Loader
+	section string
+	paths   []string
+
+	mu      sync.Mutex
+	modules map[string]bool
+}
+
+// New creates levels configured in the section of the config tree under paths, loaded with loader,
+// and applies them to go-logging's default backend
+func New(loader *gofigure.Loader, section string, paths ...string) (*Levels, error) {
+	l := &Levels{
+		loader:  loader,
+		section: section,
+		paths:   paths,
+	}
+	if err := l.Load(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Load loads the levels again and applies them. If loading fails, or a level is invalid, no level
+// is changed
+func (l *Levels) Load() error {
+	var conf Config
+	if err := l.loader.LoadPath(&conf, l.section, l.paths...); err != nil {
+		return err
+	}
+
+	// check all the levels before setting any
+	def, err := parseLevel(conf.Level, "")
+	if err != nil {
+		return err
+	}
+	levels := map[string]logging.Level{}
+	for module, name := range conf.Modules {
+		if name == "" {
+			return fmt.Errorf("loglevel: no level for %s", module)
+		}
+		if levels[module], err = parseLevel(name, module); err != nil {
+			return err
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if conf.Level != "" {
+		logging.SetLevel(def, "")
+	}
+	for module := range l.modules {
+		if _, found := levels[module]; !found {
+			logging.SetLevel(logging.GetLevel(""), module)
+		}
+	}
+	modules := map[string]bool{}
+	for module, level := range levels {
+		if logging.GetLevel(module) != level {
+			log.Info("Setting log level of %s to %s", module, level)
+		}
+		logging.SetLevel(level, module)
+		modules[module] = true
+	}
+	l.modules = modules
+	return nil
+}
+
+// Reload is Load, logging errors, making Levels a gofigure.Reloader
+func (l *Levels) Reload() {
+	if err := l.Load(); err != nil {
+		log.Error("Could not reload log levels: %s", err)
+	}
+}
+
+// parseLevel parses the name of a level, which is case insensitive, accepting "warn" for WARNING
+func parseLevel(name, module string) (logging.Level, error) {
+	if name == "" {
+		return 0, nil
+	}
+	if strings.EqualFold(name, "warn") {
+		name = "warning"
+	}
+	level, err := logging.LogLevel(name)
+	if err != nil {
+		if module == "" {
+			return 0, fmt.Errorf("loglevel: invalid default level %q", name)
+		}
+		return 0, fmt.Errorf("loglevel: invalid level %q of %s", name, module)
+	}
+	return level, nil
+}
@@ -0,0 +1,55 @@
+package loglevel
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/EverythingMe/gofigure"
+	"github.com/EverythingMe/gofigure/yaml"
+	"github.com/op/go-logging"
+)
+
+func TestLevels(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gofigure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(contents string) {
+		if err := ioutil.WriteFile(filepath.Join(dir, "conf.yaml"), []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer logging.SetLevel(logging.GetLevel(""), "")
+
+	write("logging:\n  level: notice\n  modules:\n    db: debug\n    cache: WARN\n")
+	levels, err := New(gofigure.NewLoader(yaml.Decoder{}, true), "logging", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if logging.GetLevel("") != logging.NOTICE || logging.GetLevel("db") != logging.DEBUG ||
+		logging.GetLevel("cache") != logging.WARNING || logging.GetLevel("other") != logging.NOTICE {
+		t.Errorf("Unexpected levels %s %s %s %s", logging.GetLevel(""), logging.GetLevel("db"),
+			logging.GetLevel("cache"), logging.GetLevel("other"))
+	}
+
+	// removed modules go back to the default
+	write("logging:\n  level: error\n  modules:\n    db: info\n")
+	levels.Reload()
+	if logging.GetLevel("db") != logging.INFO || logging.GetLevel("cache") != logging.ERROR {
+		t.Errorf("Unexpected reloaded levels %s %s", logging.GetLevel("db"), logging.GetLevel("cache"))
+	}
+
+	// invalid levels change nothing
+	write("logging:\n  level: debug\n  modules:\n    db: loud\n")
+	if err := levels.Load(); err == nil {
+		t.Error("Expected an error loading an invalid level")
+	}
+	if logging.GetLevel("") != logging.ERROR || logging.GetLevel("db") != logging.INFO {
+		t.Errorf("Expected invalid levels not to be applied, got %s %s", logging.GetLevel(""), logging.GetLevel("db"))
+	}
+}
@@ -0,0 +1,189 @@
+// Package lua implements a gofigure decoder for config files written as Lua scripts returning a
+// table, for teams coming from daemons configured in Lua:
+//
+//	local port = 8080
+//	return {
+//	    listen = "0.0.0.0:" .. port,
+//	    backends = { "10.0.0.1", "10.0.0.2" },
+//	}
+//
+// Scripts run in a sandbox with only the base, table, string and math libraries, without the
+// functions of the base library that read files or load code.
+package lua
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/EverythingMe/gofigure/internal/tree"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// Decoder runs Lua config scripts and decodes the table they return into config structs.
+//
+// Tables whose keys are the integers from 1 up are decoded as lists, and other tables as
+// mappings, with numeric keys formatted as strings. Empty tables are empty mappings.
+type Decoder struct {
+	// Globals are values available to the scripts, such as a build's version
+	Globals map[string]lua.LValue
+
+	// Timeout bounds how long a script can run, so that a runaway loop fails rather than hanging
+	// the loader. Zero means no limit
+	Timeout time.Duration
+}
+
+// unsafeGlobals are the functions of the base library that escape the sandbox
+var unsafeGlobals = []string{"dofile", "loadfile", "load", "loadstring", "require", "module", "collectgarbage"}
+
+// Decode runs the script read from r, and unmarshals the table it returns into config
+func (d Decoder) Decode(r io.Reader, config interface{}) error {
+
+	src, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	L := lua.NewState(lua.Options{SkipOpenLibs: true})
+	defer L.Close()
+	for _, lib := range []struct {
+		name string
+		open lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		L.Push(L.NewFunction(lib.open))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+	for _, name := range unsafeGlobals {
+		L.SetGlobal(name, lua.LNil)
+	}
+	for name, v := range d.Globals {
+		L.SetGlobal(name, v)
+	}
+
+	if d.Timeout > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), d.Timeout)
+		defer cancel()
+		L.SetContext(ctx)
+	}
+
+	fn, err := L.LoadString(string(src))
+	if err != nil {
+		return fmt.Errorf("lua: %s", err)
+	}
+	L.Push(fn)
+	if err := L.PCall(0, 1, nil); err != nil {
+		return fmt.Errorf("lua: %s", err)
+	}
+
+	table, ok := L.Get(-1).(*lua.LTable)
+	if !ok {
+		return fmt.Errorf("lua: the script must return a table, got %s", L.Get(-1).Type())
+	}
+	doc, err := toGo(table, "", 0)
+	if err != nil {
+		return err
+	}
+	return tree.Assign(doc, config)
+}
+
+// CanDecode returns true if this is a Lua file
+func (d Decoder) CanDecode(path string) bool {
+	return strings.HasSuffix(path, ".lua")
+}
+
+// maxDepth bounds the nesting of tables, which can refer to themselves
+const maxDepth = 100
+
+// toGo converts a Lua value to a generic tree value. The path of the value is used in errors
+func toGo(v lua.LValue, path string, depth int) (interface{}, error) {
+
+	fail := func(format string, args ...interface{}) error {
+		if path == "" {
+			path = "(root)"
+		}
+		return fmt.Errorf("lua: %s: %s", path, fmt.Sprintf(format, args...))
+	}
+
+	switch v := v.(type) {
+	case *lua.LNilType:
+		return nil, nil
+	case lua.LBool:
+		return bool(v), nil
+	case lua.LString:
+		return string(v), nil
+	case lua.LNumber:
+		f := float64(v)
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			return nil, fail("%v is not a number", f)
+		}
+		if f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+			return int(f), nil
+		}
+		return f, nil
+
+	case *lua.LTable:
+		if depth >= maxDepth {
+			return nil, fail("tables nested too deep")
+		}
+		join := func(k string) string {
+			if path == "" {
+				return k
+			}
+			return path + "." + k
+		}
+
+		if n := v.MaxN(); n > 0 && countKeys(v) == n {
+			l := make([]interface{}, n)
+			for i := range l {
+				e, err := toGo(v.RawGetInt(i+1), join(fmt.Sprint(i)), depth+1)
+				if err != nil {
+					return nil, err
+				}
+				l[i] = e
+			}
+			return l, nil
+		}
+
+		m := map[string]interface{}{}
+		var err error
+		v.ForEach(func(k, e lua.LValue) {
+			if err != nil {
+				return
+			}
+			var key string
+			switch k := k.(type) {
+			case lua.LString:
+				key = string(k)
+			case lua.LNumber:
+				key = k.String()
+			default:
+				err = fail("keys must be strings or numbers, got %s", k.Type())
+				return
+			}
+			m[key], err = toGo(e, join(key), depth+1)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return m, nil
+	}
+
+	return nil, fail("cannot use a %s in a config", v.Type())
+}
+
+// countKeys counts the keys of a table
+func countKeys(t *lua.LTable) int {
+	n := 0
+	t.ForEach(func(lua.LValue, lua.LValue) { n++ })
+	return n
+}
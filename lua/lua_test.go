@@ -0,0 +1,81 @@
+package lua
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestDecode(t *testing.T) {
+
+	src := `
+local port = 8080
+local backends = {}
+for i = 1, 2 do
+    table.insert(backends, string.format("10.0.0.%d", i))
+end
+return {
+    listen = "0.0.0.0:" .. port,
+    backends = backends,
+    weights = { [1] = 0.5, [2] = 1 },
+    codes = { [404] = "missing" },
+    tls = { enabled = true },
+    empty = {},
+    version = version,
+}
+`
+	var conf interface{}
+	d := Decoder{Globals: map[string]lua.LValue{"version": lua.LString("1.2")}}
+	if err := d.Decode(strings.NewReader(src), &conf); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]interface{}{
+		"listen":   "0.0.0.0:8080",
+		"backends": []interface{}{"10.0.0.1", "10.0.0.2"},
+		"weights":  []interface{}{0.5, 1},
+		"codes":    map[string]interface{}{"404": "missing"},
+		"tls":      map[string]interface{}{"enabled": true},
+		"empty":    map[string]interface{}{},
+		"version":  "1.2",
+	}
+	if !reflect.DeepEqual(conf, expected) {
+		t.Errorf("Unexpected config %#v", conf)
+	}
+
+	var typed struct {
+		Listen   string
+		Backends []string
+	}
+	if err := d.Decode(strings.NewReader(src), &typed); err != nil {
+		t.Fatal(err)
+	}
+	if typed.Listen != "0.0.0.0:8080" || len(typed.Backends) != 2 {
+		t.Errorf("Unexpected typed config %#v", typed)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+
+	cases := map[string]string{
+		"syntax":    "return {",
+		"runtime":   "error('boom')",
+		"not table": "return 1",
+		"function":  "return { f = function() end }",
+		"key":       "return { [true] = 1 }",
+		"cycle":     "local t = {}\nt.self = t\nreturn t",
+		"dofile":    "dofile('/etc/passwd')\nreturn {}",
+		"io":        "io.open('/etc/passwd')\nreturn {}",
+		"os":        "os.exit(1)\nreturn {}",
+		"infinite":  "while true do end",
+	}
+	for name, src := range cases {
+		var conf interface{}
+		if err := (Decoder{Timeout: 100 * time.Millisecond}).Decode(strings.NewReader(src), &conf); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !gofigure_noregister
+
+package lua
+
+import "github.com/EverythingMe/gofigure"
+
+func init() {
+	gofigure.RegisterDecoder("lua", Decoder{})
+}
@@ -0,0 +1,124 @@
+package gofigure
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ManifestName is the name of the checksum manifest at the root of a config tree
+const ManifestName = "MANIFEST.sha256"
+
+// manifest holds the expected checksums of the files under a config root
+type manifest struct {
+	root string
+	sums map[string]string
+	seen map[string]bool
+}
+
+// readManifest parses the manifest of a config root. Each line holds a hex encoded sha256 digest
+// and the path of a file relative to the root, separated by whitespace, as written by
+// `sha256sum`. Empty lines and lines starting with a # are ignored
+func readManifest(root string) (*manifest, error) {
+
+	fp, err := os.Open(filepath.Join(root, ManifestName))
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+
+	m := &manifest{
+		root: root,
+		sums: map[string]string{},
+		seen: map[string]bool{},
+	}
+
+	scanner := bufio.NewScanner(fp)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 || len(fields[0]) != sha256.Size*2 {
+			return nil, fmt.Errorf("gofigure: %s: line %d: expected a checksum and a path", ManifestName, n)
+		}
+		// sha256sum marks files hashed in binary mode with a *
+		path := filepath.Clean(strings.TrimPrefix(fields[1], "*"))
+		m.sums[path] = strings.ToLower(fields[0])
+	}
+
+	return m, scanner.Err()
+}
+
+// expected returns the checksum of a file under the root in the manifest, and marks the file as
+// seen. Its errors describe what's wrong with the file, and are meant to follow its path
+func (m *manifest) expected(path string) (string, error) {
+
+	rel, err := filepath.Rel(m.root, path)
+	if err != nil {
+		return "", err
+	}
+
+	sum, found := m.sums[rel]
+	if !found {
+		return "", fmt.Errorf("is not listed in the manifest")
+	}
+	m.seen[rel] = true
+	return sum, nil
+}
+
+// verify checks the contents of a file under the root against its checksum in the manifest. Its
+// errors describe what's wrong with the file, and are meant to follow its path
+func (m *manifest) verify(path string, data []byte) error {
+
+	expected, err := m.expected(path)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != expected {
+		return fmt.Errorf("was modified since the manifest was written")
+	}
+	return nil
+}
+
+// decodeVerified reads a file, checks its contents against the manifest, and decodes them. The
+// contents that are checked are those that are decoded, so a file replaced in between can't get
+// through
+func (l Loader) decodeVerified(path string, r io.Reader, m *manifest) (interface{}, error) {
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, ioError{err}
+	}
+	if err := m.verify(path, data); err != nil {
+		return nil, ioError{fmt.Errorf("gofigure: %s %s", path, err)}
+	}
+	return l.decodeReader(path, bytes.NewReader(data))
+}
+
+// missing returns the files listed in the manifest that don't exist under the root
+func (m *manifest) missing() []string {
+	var missing []string
+	for rel := range m.sums {
+		if m.seen[rel] {
+			continue
+		}
+		path := filepath.Join(m.root, rel)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			missing = append(missing, path)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
@@ -0,0 +1,87 @@
+package gofigure
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/EverythingMe/gofigure/yaml"
+)
+
+func TestManifest(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gofigure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"a.yaml":     "redis:\n  server: localhost:6379\n",
+		"sub/b.yaml": "redis:\n  monitor: 10\n",
+		"notes.txt":  "not config",
+	}
+	writeFiles(t, dir, files)
+
+	var lines []string
+	for name, contents := range files {
+		sum := sha256.Sum256([]byte(contents))
+		lines = append(lines, hex.EncodeToString(sum[:])+"  "+name)
+	}
+	writeFiles(t, dir, map[string]string{ManifestName: "# checksums\n" + strings.Join(lines, "\n") + "\n"})
+
+	var conf config
+	loader := NewLoader(yaml.Decoder{}, true)
+	loader.VerifyManifest = true
+	if err := loader.LoadRecursive(&conf, dir); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Redis.Server != "localhost:6379" || conf.Redis.Monitor != 10 {
+		t.Errorf("Verified files not loaded: %#v", conf.Redis)
+	}
+
+	// a modified file, an extra file and a missing one
+	writeFiles(t, dir, map[string]string{
+		"a.yaml": "redis:\n  server: evil\n",
+		"c.yaml": "redis:\n  server: extra\n",
+	})
+	os.Remove(filepath.Join(dir, "sub/b.yaml"))
+
+	if err := loader.LoadRecursive(&conf, dir); err == nil {
+		t.Error("Expected strict loads to fail")
+	}
+
+	conf = config{}
+	loader.StrictMode = false
+	report, err := loader.LoadWithReport(&conf, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Redis.Server != "" || len(report.Files) != 0 {
+		t.Errorf("Unverified files should be skipped: %#v, %v", conf.Redis, report.Files)
+	}
+
+	expected := map[string]string{
+		filepath.Join(dir, "a.yaml"):     "modified",
+		filepath.Join(dir, "c.yaml"):     "not listed",
+		filepath.Join(dir, "sub/b.yaml"): "missing",
+	}
+	if len(report.Warnings) != len(expected) {
+		t.Errorf("Unexpected warnings %v", report.Warnings)
+	}
+	for _, w := range report.Warnings {
+		if !strings.Contains(w.Message, expected[w.Source]) {
+			t.Errorf("Unexpected warning %v", w)
+		}
+	}
+
+	os.Remove(filepath.Join(dir, ManifestName))
+	report, err = loader.LoadWithReport(&conf, dir)
+	if err != nil || len(report.Files) != 0 || len(report.Warnings) != 1 {
+		t.Errorf("Roots without a manifest should be skipped: %v, %v", report, err)
+	}
+}
@@ -0,0 +1,173 @@
+package gofigure
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/EverythingMe/gofigure/internal/tree"
+)
+
+// Map is an untyped configuration tree, for programs that don't know the schema of what they
+// load at compile time, like generic config inspectors. Values are looked up by dotted paths
+// such as "redis.server" or "servers.0.host", with typed getters that fall back to a default
+// when a value is missing or, except for GetInt, of the wrong type.
+type Map map[string]interface{}
+
+// LoadMap reads and merges all the files under paths into a Map, the same way LoadRecursive
+// would merge them into a struct. For a loader made by Sub, it's the subtree at its prefix, empty
+// if there is none
+func (l Loader) LoadMap(paths ...string) (Map, error) {
+	docs, err := l.loadDocuments(l.newReport(), paths...)
+	if err != nil {
+		return nil, err
+	}
+	merged, err := l.merge(docs)
+	if err != nil {
+		return nil, err
+	}
+	if l.prefix != "" {
+		sub, _ := tree.Lookup(merged, l.prefix)
+		if merged, _ = sub.(map[string]interface{}); merged == nil {
+			merged = map[string]interface{}{}
+		}
+	}
+	return Map(merged), nil
+}
+
+// Get returns the value at the dotted path key, and whether it was found
+func (m Map) Get(key string) (interface{}, bool) {
+	return tree.Lookup(m, key)
+}
+
+// Has tells whether anything is set at the dotted path key
+func (m Map) Has(key string) bool {
+	_, found := m.Get(key)
+	return found
+}
+
+// GetMap returns the sub-tree at key as a Map, or nil if there is no mapping there
+func (m Map) GetMap(key string) Map {
+	v, _ := m.Get(key)
+	if sub, ok := v.(map[string]interface{}); ok {
+		return Map(sub)
+	}
+	return nil
+}
+
+// GetString returns the string at key, or def if there is none. Numbers and bools are formatted
+func (m Map) GetString(key string, def string) string {
+	switch v, _ := m.Get(key); v := v.(type) {
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	return def
+}
+
+// GetInt returns the integer at key, or def if there is none. Numeric strings are parsed. Unlike
+// the other getters, it doesn't fall back to def for values of other types, or numbers that don't
+// fit an int, like a uint64 above math.MaxInt64: they are an error
+func (m Map) GetInt(key string, def int) (int, error) {
+	v, found := m.Get(key)
+	if !found {
+		return def, nil
+	}
+	switch v := v.(type) {
+	case int:
+		return v, nil
+	case int64:
+		if int64(int(v)) == v {
+			return int(v), nil
+		}
+	case uint64:
+		if v <= math.MaxInt64 && int64(int(v)) == int64(v) {
+			return int(v), nil
+		}
+	case float64:
+		if v == float64(int(v)) {
+			return int(v), nil
+		}
+	case string:
+		if i, err := strconv.Atoi(v); err == nil {
+			return i, nil
+		}
+	}
+	return def, fmt.Errorf("gofigure: %s is %v, not an int", key, v)
+}
+
+// GetFloat returns the number at key, or def if there is none. Numeric strings are parsed
+func (m Map) GetFloat(key string, def float64) float64 {
+	switch v, _ := m.Get(key); v := v.(type) {
+	case float64:
+		return v
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case string:
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	}
+	return def
+}
+
+// GetBool returns the bool at key, or def if there is none. Strings like "true" are parsed
+func (m Map) GetBool(key string, def bool) bool {
+	switch v, _ := m.Get(key); v := v.(type) {
+	case bool:
+		return v
+	case string:
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return def
+}
+
+// GetDuration returns the duration at key, or def if there is none. Strings are parsed with
+// time.ParseDuration (e.g. "30s"), and plain numbers are taken as seconds
+func (m Map) GetDuration(key string, def time.Duration) time.Duration {
+	switch v, _ := m.Get(key); v := v.(type) {
+	case string:
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
+	case int, int64, uint64, float64:
+		return time.Duration(m.GetFloat(key, 0) * float64(time.Second))
+	}
+	return def
+}
+
+// GetStrings returns the list of strings at key, or def if there is none or it holds anything
+// other than strings
+func (m Map) GetStrings(key string, def []string) []string {
+	v, _ := m.Get(key)
+	l, ok := v.([]interface{})
+	if !ok {
+		return def
+	}
+
+	out := make([]string, 0, len(l))
+	for _, e := range l {
+		s, ok := e.(string)
+		if !ok {
+			return def
+		}
+		out = append(out, s)
+	}
+	return out
+}
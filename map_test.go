@@ -0,0 +1,81 @@
+package gofigure
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/EverythingMe/gofigure/json"
+	"github.com/EverythingMe/gofigure/yaml"
+)
+
+func TestLoadMap(t *testing.T) {
+
+	for _, d := range []Decoder{yaml.Decoder{}, json.Decoder{}} {
+		m, err := NewLoader(d, true).LoadMap("./testdata")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if s := m.GetString("redis.server", ""); s != expectedConf.Redis.Server {
+			t.Errorf("Unexpected redis server: %s", s)
+		}
+		if i, err := m.GetInt("redis.monitor", 0); err != nil || i != expectedConf.Redis.Monitor {
+			t.Errorf("Unexpected redis monitor: %d (%v)", i, err)
+		}
+		if i, err := m.GetInt("redis.nothing", 42); err != nil || i != 42 {
+			t.Errorf("Expected default for missing key, got %d (%v)", i, err)
+		}
+		if sub := m.GetMap("mysql"); sub.GetString("user", "") != "root" {
+			t.Errorf("Unexpected mysql section: %v", sub)
+		}
+		if !m.Has("mysql.password") || m.Has("mysql.port") {
+			t.Errorf("Has reports wrong keys")
+		}
+	}
+}
+
+func TestMapGetters(t *testing.T) {
+
+	m := Map{
+		"timeout": "1m30s",
+		"retry":   2,
+		"huge":    uint64(math.MaxUint64),
+		"ratio":   "0.5",
+		"debug":   "true",
+		"hosts":   []interface{}{"a", "b"},
+		"servers": []interface{}{map[string]interface{}{"host": "srv1"}},
+	}
+
+	if d := m.GetDuration("timeout", 0); d != 90*time.Second {
+		t.Errorf("Unexpected duration: %s", d)
+	}
+	if d := m.GetDuration("retry", 0); d != 2*time.Second {
+		t.Errorf("Unexpected numeric duration: %s", d)
+	}
+	if d := m.GetDuration("hosts", time.Minute); d != time.Minute {
+		t.Errorf("Expected default duration for a list, got %s", d)
+	}
+	if i, err := m.GetInt("huge", 0); err == nil {
+		t.Errorf("Expected an error for a uint64 overflowing an int, got %d", i)
+	}
+	if i, err := m.GetInt("hosts", 0); err == nil {
+		t.Errorf("Expected an error for a list, got %d", i)
+	}
+	if f := m.GetFloat("ratio", 0); f != 0.5 {
+		t.Errorf("Unexpected ratio: %v", f)
+	}
+	if !m.GetBool("debug", false) {
+		t.Errorf("Expected debug to be true")
+	}
+	if s := m.GetString("retry", ""); s != "2" {
+		t.Errorf("Expected number formatted as string, got %s", s)
+	}
+	if l := m.GetStrings("hosts", nil); !reflect.DeepEqual(l, []string{"a", "b"}) {
+		t.Errorf("Unexpected hosts: %v", l)
+	}
+	if s := m.GetString("servers.0.host", ""); s != "srv1" {
+		t.Errorf("Unexpected indexed lookup: %s", s)
+	}
+}
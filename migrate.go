@@ -0,0 +1,96 @@
+package gofigure
+
+import (
+	"fmt"
+)
+
+// Migration upgrades a decoded document in place from one version of a config schema to the next
+type Migration func(doc map[string]interface{}) error
+
+// Migrations upgrade documents written for older versions of a config schema to the current one,
+// before they are merged and decoded. Every document declares the version it was written for in
+// a version key, and has the migrations from that version on applied to it in order.
+//
+// Documents without a version key are considered to be of version 0. Once migrated, a document's
+// version key is set to the current version, so a Version field in the config struct always holds
+// the current version.
+type Migrations struct {
+	// Key is the document key holding the schema version, "version" if empty
+	Key string
+
+	steps map[int]Migration
+}
+
+// NewMigrations creates an empty set of migrations, using the given document key to hold the
+// schema version
+func NewMigrations(key string) *Migrations {
+	return &Migrations{
+		Key:   key,
+		steps: map[int]Migration{},
+	}
+}
+
+// Add registers a migration upgrading documents from version from to version from+1. It panics if
+// a migration from the same version was already added, as that's a programming error
+func (m *Migrations) Add(from int, fn Migration) *Migrations {
+	if m.steps == nil {
+		m.steps = map[int]Migration{}
+	}
+	if _, found := m.steps[from]; found {
+		panic(fmt.Sprintf("gofigure: migration from version %d added twice", from))
+	}
+	m.steps[from] = fn
+	return m
+}
+
+// Current returns the version documents are migrated to, one past the latest migration
+func (m *Migrations) Current() int {
+	current := 0
+	for from := range m.steps {
+		if from >= current {
+			current = from + 1
+		}
+	}
+	return current
+}
+
+func (m *Migrations) key() string {
+	if m.Key == "" {
+		return "version"
+	}
+	return m.Key
+}
+
+// migrate upgrades doc to the current version
+func (m *Migrations) migrate(doc map[string]interface{}) error {
+
+	version := 0
+	if v, found := doc[m.key()]; found {
+		var err error
+		if version, err = Map(doc).GetInt(m.key(), -1); err != nil || version < 0 {
+			return fmt.Errorf("invalid schema version %v", v)
+		}
+	}
+
+	current := m.Current()
+	if version > current {
+		return fmt.Errorf("schema version %d is newer than the current version %d", version, current)
+	}
+
+	// a gap in the migrations is caught before any of them runs, so doc isn't left half migrated
+	for from := version; from < current; from++ {
+		if _, found := m.steps[from]; !found {
+			return fmt.Errorf("no migration from schema version %d", from)
+		}
+	}
+
+	for from := version; from < current; from++ {
+		log.Debug("Migrating config from schema version %d", from)
+		if err := m.steps[from](doc); err != nil {
+			return fmt.Errorf("migrating from schema version %d: %s", from, err)
+		}
+	}
+
+	doc[m.key()] = current
+	return nil
+}
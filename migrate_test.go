@@ -0,0 +1,102 @@
+package gofigure
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/EverythingMe/gofigure/yaml"
+)
+
+func TestMigrations(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gofigure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"a.yaml": "host: localhost\nport: 80\n",
+		"b.yaml": "version: 1\nserver: {addr: 'localhost:80', timeout: 5}\n",
+		"c.yaml": "version: 2\nserver: {addr: 'localhost:8080'}\n",
+	}
+	for name, contents := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	migrations := NewMigrations("").
+		Add(0, func(doc map[string]interface{}) error {
+			doc["server"] = map[string]interface{}{"addr": Map(doc).GetString("host", "") + ":" + Map(doc).GetString("port", "")}
+			delete(doc, "host")
+			delete(doc, "port")
+			return nil
+		}).
+		Add(1, func(doc map[string]interface{}) error {
+			server := Map(doc).GetMap("server")
+			if t, found := server["timeout"]; found {
+				server["timeout_ms"] = t.(int) * 1000
+				delete(server, "timeout")
+			}
+			return nil
+		})
+
+	if migrations.Current() != 2 {
+		t.Fatalf("Unexpected current version %d", migrations.Current())
+	}
+
+	var conf struct {
+		Version int
+		Server  struct {
+			Addr      string
+			TimeoutMS int `yaml:"timeout_ms"`
+		}
+	}
+
+	loader := NewLoader(yaml.Decoder{}, true)
+	loader.Migrations = migrations
+	if err := loader.LoadRecursive(&conf, dir); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Version != 2 || conf.Server.Addr != "localhost:8080" || conf.Server.TimeoutMS != 5000 {
+		t.Errorf("Documents not migrated as expected: %#v", conf)
+	}
+
+	// documents newer than the latest migration are rejected
+	if err := ioutil.WriteFile(filepath.Join(dir, "d.yaml"), []byte("version: 3\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := loader.LoadRecursive(&conf, dir); err == nil {
+		t.Error("Expected an error for a document from the future")
+	}
+}
+
+func TestMigrationErrors(t *testing.T) {
+
+	failing := errors.New("boom")
+	migrations := NewMigrations("schema").
+		Add(0, func(doc map[string]interface{}) error { return nil }).
+		Add(2, func(doc map[string]interface{}) error { return failing })
+
+	doc := map[string]interface{}{"name": "x"}
+	if err := migrations.migrate(doc); err == nil {
+		t.Error("Expected an error for a gap in the migrations")
+	}
+	if _, found := doc["schema"]; found {
+		t.Error("Document should not be touched when migrations are missing")
+	}
+
+	if err := migrations.migrate(map[string]interface{}{"schema": 2}); err == nil {
+		t.Error("Expected a failing migration's error")
+	}
+	if err := migrations.migrate(map[string]interface{}{"schema": "two"}); err == nil {
+		t.Error("Expected an error for an invalid version")
+	}
+	if err := migrations.migrate(map[string]interface{}{"schema": 3}); err != nil {
+		t.Error(err)
+	}
+}
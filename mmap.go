@@ -0,0 +1,25 @@
+package gofigure
+
+import (
+	"bytes"
+	"os"
+)
+
+// mmapMinSize is the size from which files are memory mapped when the loader is configured to;
+// smaller files are cheaper to read
+const mmapMinSize = 64 << 10
+
+// decodeMapped decodes the file fp, of the given size, by memory mapping it. It reports false if
+// the file can't be mapped, in which case it should be read instead
+func (l Loader) decodeMapped(path string, fp *os.File, size int64) (interface{}, bool, error) {
+
+	data, err := mapFile(fp, size)
+	if err != nil {
+		log.Debug("Could not memory map %s, reading it: %s", path, err)
+		return nil, false, nil
+	}
+	defer unmapFile(data)
+
+	v, err := l.decodeReader(path, bytes.NewReader(data))
+	return v, true, err
+}
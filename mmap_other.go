@@ -0,0 +1,16 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package gofigure
+
+import (
+	"errors"
+	"os"
+)
+
+// mapFile is not supported on platforms without mmap, where files are read instead
+func mapFile(fp *os.File, size int64) ([]byte, error) {
+	return nil, errors.New("memory mapping is not supported on this platform")
+}
+
+// unmapFile is a no-op on platforms without mmap
+func unmapFile(data []byte) {}
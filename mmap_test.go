@@ -0,0 +1,50 @@
+package gofigure
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/EverythingMe/gofigure/yaml"
+)
+
+func TestMemoryMap(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gofigure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var buf bytes.Buffer
+	buf.WriteString("redis:\n  server: mapped:6379\nroutes:\n")
+	for i := 0; buf.Len() < 2*mmapMinSize; i++ {
+		fmt.Fprintf(&buf, "  - /route/%d\n", i)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "routes.yaml"), buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	type routes struct {
+		Redis  redisConfig
+		Routes []string
+	}
+
+	loader := NewLoader(yaml.Decoder{}, true)
+	var read, mapped routes
+	if err := loader.LoadRecursive(&read, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	loader.MemoryMap = true
+	if err := loader.LoadRecursive(&mapped, dir); err != nil {
+		t.Fatal(err)
+	}
+	if mapped.Redis.Server != "mapped:6379" || len(mapped.Routes) < 1000 || !reflect.DeepEqual(read, mapped) {
+		t.Errorf("Mapped file decoded differently: %v routes vs %v", len(mapped.Routes), len(read.Routes))
+	}
+}
@@ -0,0 +1,20 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package gofigure
+
+import (
+	"os"
+	"syscall"
+)
+
+// mapFile maps the first size bytes of an open file into memory, read only
+func mapFile(fp *os.File, size int64) ([]byte, error) {
+	return syscall.Mmap(int(fp.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+}
+
+// unmapFile releases memory mapped by mapFile
+func unmapFile(data []byte) {
+	if err := syscall.Munmap(data); err != nil {
+		log.Warning("Could not unmap a config file: %s", err)
+	}
+}
@@ -0,0 +1,94 @@
+package gofigure
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// modelineRe matches a modeline: a comment on the first line of a file, like
+// "# gofigure: format=toml", holding space separated key=value options
+var modelineRe = regexp.MustCompile(`^\s*(?:#|//|;|--)\s*gofigure:\s*(.*?)\s*$`)
+
+// maxModeline bounds how much of a file is read looking for a modeline
+const maxModeline = 256
+
+// parseModeline parses the first line of a file, returning the name of the format it selects if
+// it's a modeline
+func parseModeline(line string) (string, bool, error) {
+
+	match := modelineRe.FindStringSubmatch(strings.TrimRight(line, "\r\n"))
+	if match == nil {
+		return "", false, nil
+	}
+
+	format := ""
+	for _, opt := range strings.Fields(match[1]) {
+		kv := strings.SplitN(opt, "=", 2)
+		if len(kv) != 2 || kv[0] != "format" {
+			return "", false, fmt.Errorf("bad modeline option %q", opt)
+		}
+		format = kv[1]
+	}
+	if format == "" {
+		return "", false, fmt.Errorf("modeline without a format")
+	}
+	return format, true, nil
+}
+
+// modelineDecoder reads the modeline of r if it has one. It returns the decoder the modeline
+// selects, or nil, and a reader of the contents with the modeline blanked out, so that decoders
+// that don't allow comments can read them and line numbers stay right
+func modelineDecoder(r io.Reader) (Decoder, io.Reader, error) {
+
+	br := bufio.NewReaderSize(r, maxModeline)
+	head, _ := br.Peek(maxModeline)
+	line := string(head)
+	if i := strings.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i+1]
+	}
+
+	format, found, err := parseModeline(line)
+	if err != nil || !found {
+		return nil, br, err
+	}
+	d, found := LookupDecoder(format)
+	if !found {
+		return nil, nil, fmt.Errorf("modeline: unknown format %s", format)
+	}
+
+	br.Discard(len(line))
+	blank := ""
+	if strings.HasSuffix(line, "\n") {
+		blank = "\n"
+	}
+	return d, io.MultiReader(strings.NewReader(blank), br), nil
+}
+
+// hasModeline tells whether the file at path starts with a modeline
+func hasModeline(path string) bool {
+	fp, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer fp.Close()
+
+	line, _ := bufio.NewReaderSize(fp, maxModeline).ReadSlice('\n')
+	_, found, _ := parseModeline(string(line))
+	return found
+}
+
+// canDecode tells whether the loader reads the file at path: if its decoder can decode it, also
+// once decompressed, or if it has a modeline and the loader reads modelines
+func (l Loader) canDecode(path string) bool {
+	if l.decoder.CanDecode(path) {
+		return true
+	}
+	if d, base := compression(path); d != nil && l.decoder.CanDecode(base) {
+		return true
+	}
+	return l.Modelines && hasModeline(path)
+}
@@ -0,0 +1,81 @@
+package gofigure
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/EverythingMe/gofigure/yaml"
+)
+
+func TestModelines(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gofigure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeFiles(t, dir, map[string]string{
+		"a.yaml":     "redis:\n  server: localhost:6379\n",
+		"b.yaml":     "# gofigure: format=json\n{\"mysql\": {\"user\": \"root\"}}\n",
+		"c.conf":     "// gofigure: format=json\n{\"mysql\": {\"server\": \"db\"}}",
+		"d.conf":     "not a config\n",
+		"bad/x.yaml": "# gofigure: format=nope\n",
+		"bad/y.yaml": "# gofigure: mode=strict\n",
+		"line/x.yml": "# gofigure: format=json\n{\n  \"a\": \n}\n",
+	})
+
+	var conf config
+	loader := NewLoader(yaml.Decoder{}, true)
+	loader.Modelines = true
+	if err := loader.LoadRecursive(&conf, filepath.Join(dir)); err == nil {
+		t.Fatal("Expected an error for a bad modeline")
+	}
+
+	if err := os.RemoveAll(filepath.Join(dir, "bad")); err != nil {
+		t.Fatal(err)
+	}
+	if err := loader.LoadRecursive(&conf, dir); err == nil {
+		t.Fatal("Expected an error for a bad json file")
+	}
+
+	if err := os.RemoveAll(filepath.Join(dir, "line")); err != nil {
+		t.Fatal(err)
+	}
+	if err := loader.LoadRecursive(&conf, dir); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Redis.Server != "localhost:6379" || conf.Mysql.User != "root" || conf.Mysql.Server != "db" {
+		t.Errorf("Modelines not honored: %#v", conf)
+	}
+
+	// without modelines, the yaml decoder reads the json file, and the .conf ones are skipped
+	conf = config{}
+	loader.Modelines = false
+	if err := loader.LoadRecursive(&conf, dir); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Mysql.User != "root" || conf.Mysql.Server != "" {
+		t.Errorf("Unexpected config without modelines: %#v", conf)
+	}
+}
+
+func TestParseModeline(t *testing.T) {
+
+	cases := map[string]string{
+		"# gofigure: format=toml\n": "toml",
+		"//gofigure:format=json":    "json",
+		"  ; gofigure: format=ini ": "ini",
+		"-- gofigure: format=lua":   "lua",
+		"# just a comment":          "",
+		"gofigure: format=toml":     "",
+	}
+	for line, expected := range cases {
+		format, found, err := parseModeline(line)
+		if err != nil || format != expected || found != (expected != "") {
+			t.Errorf("parseModeline(%q) = %q, %v, %v", line, format, found, err)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+// Package mongodb loads config from MongoDB documents, for teams whose admin tools write settings
+// straight into Mongo.
+//
+// A source finds the documents of a collection matching a filter, and merges them as layers, in
+// the order of the source's sort, so later documents override the values set by earlier ones:
+//
+//	mongodb.Source{
+//		Collection: client.Database("admin").Collection("settings"),
+//		Filter:     bson.M{"scope": bson.M{"$in": []string{"global", tenant}}},
+//		Sort:       bson.D{{Key: "priority", Value: 1}},
+//	}
+//
+// Values are converted the way the bson decoder converts them, and the _id field of documents is
+// dropped. To reload the config when documents change, poll the source with a
+// gofigure.PollMonitor.
+package mongodb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/EverythingMe/gofigure"
+	gbson "github.com/EverythingMe/gofigure/bson"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Source is a gofigure.Source reading the config from the documents of a collection. A filter
+// matching no documents is a permanent failure
+type Source struct {
+	Collection *mongo.Collection
+
+	// Filter selects the documents, or all of them if nil
+	Filter interface{}
+
+	// Sort orders the documents, the last of which wins, e.g. bson.D{{Key: "priority", Value: 1}}.
+	// Without it, documents are merged in the order the server returns them
+	Sort interface{}
+}
+
+// Name returns the source's collection
+func (s Source) Name() string {
+	return "mongodb:" + s.Collection.Database().Name() + "." + s.Collection.Name()
+}
+
+// Fetch finds the documents and merges them into one
+func (s Source) Fetch(ctx context.Context) (io.ReadCloser, error) {
+
+	filter := s.Filter
+	if filter == nil {
+		filter = bson.D{}
+	}
+	opts := options.Find()
+	if s.Sort != nil {
+		opts.SetSort(s.Sort)
+	}
+
+	cursor, err := s.Collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	// the documents are decoded as a stream, like that of a BSON file
+	var stream bytes.Buffer
+	for cursor.Next(ctx) {
+		stream.Write(cursor.Current)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+	if stream.Len() == 0 {
+		return nil, gofigure.Permanent(fmt.Errorf("mongodb: no documents of %s match the filter", s.Name()))
+	}
+
+	var doc interface{}
+	if err := (gbson.Decoder{}).Decode(&stream, &doc); err != nil {
+		return nil, gofigure.Permanent(fmt.Errorf("mongodb: %s", err))
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, gofigure.Permanent(err)
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
@@ -0,0 +1,71 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/EverythingMe/gofigure"
+	"github.com/EverythingMe/gofigure/yaml"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// connect connects to the server at MONGODB_URI, like "mongodb://localhost:27017", skipping the
+// test if it's not set, and returns a fresh collection on it
+func connect(t *testing.T) *mongo.Collection {
+	uri := os.Getenv("MONGODB_URI")
+	if uri == "" {
+		t.Skip("MONGODB_URI is not set")
+	}
+	client, err := mongo.Connect(options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client.Database("gofigure_test").Collection(fmt.Sprintf("settings_%d", time.Now().UnixNano()))
+}
+
+func TestSource(t *testing.T) {
+
+	coll := connect(t)
+	ctx := context.Background()
+	defer coll.Database().Client().Disconnect(ctx)
+	defer coll.Drop(ctx)
+
+	_, err := coll.InsertMany(ctx, []interface{}{
+		bson.M{"scope": "acme", "priority": 2, "redis": bson.M{"pool": 20}},
+		bson.M{"scope": "global", "priority": 1, "name": "app", "redis": bson.M{"server": "cache:6379", "pool": 10}},
+		bson.M{"scope": "other", "priority": 3, "redis": bson.M{"pool": 30}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var conf struct {
+		Name  string
+		Redis struct {
+			Server string
+			Pool   int
+		}
+	}
+	loader := gofigure.NewLoader(yaml.Decoder{}, true)
+	loader.Sources = []gofigure.Source{Source{
+		Collection: coll,
+		Filter:     bson.M{"scope": bson.M{"$in": []string{"global", "acme"}}},
+		Sort:       bson.D{{Key: "priority", Value: 1}},
+	}}
+	if err := loader.LoadRecursive(&conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Name != "app" || conf.Redis.Server != "cache:6379" || conf.Redis.Pool != 20 {
+		t.Errorf("Unexpected config %#v", conf)
+	}
+
+	loader.Sources = []gofigure.Source{Source{Collection: coll, Filter: bson.M{"scope": "missing"}}}
+	if err := loader.LoadRecursive(&conf); err == nil {
+		t.Error("Expected an error for a filter matching no documents")
+	}
+}
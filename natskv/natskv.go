@@ -0,0 +1,194 @@
+// Package natskv loads config documents from the keys of a NATS JetStream key/value bucket, and
+// watches them for changes, so services already on NATS don't need another config channel.
+//
+// A source reads either the value of a single key as a document, or with a key ending in the ">"
+// wildcard, the values of all the keys under it: their tokens are the dotted paths of the values
+// in the document, as with gofigure.Unflatten.
+//
+//	app.server          api.example.com   ->  server: api.example.com
+//	app.redis.port      6379              ->  redis: {port: 6379}
+//
+// Values read from wildcards are kept as strings, so loaders binding them into typed fields
+// should be WeaklyTyped.
+package natskv
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/EverythingMe/gofigure"
+	"github.com/EverythingMe/gofigure/internal/monitor"
+	"github.com/nats-io/nats.go"
+	"github.com/op/go-logging"
+)
+
+var log = logging.MustGetLogger("gofigure")
+
+// Source is a gofigure.Source reading a document from a key of a bucket, or from the keys under
+// it if it ends with ">", like "app.>". A missing key, or a wildcard matching no keys, is a
+// permanent failure
+type Source struct {
+	KV  nats.KeyValue
+	Key string
+}
+
+// Name returns the source's bucket and key
+func (s Source) Name() string {
+	return "nats:" + s.KV.Bucket() + "/" + s.Key
+}
+
+// Fetch gets the value of the source's key, or the values of the keys under it
+func (s Source) Fetch(ctx context.Context) (io.ReadCloser, error) {
+
+	if !isWildcard(s.Key) {
+		entry, err := s.KV.Get(s.Key)
+		if err == nats.ErrKeyNotFound {
+			return nil, gofigure.Permanent(fmt.Errorf("natskv: key %s not found", s.Key))
+		}
+		if err != nil {
+			return nil, err
+		}
+		return ioutil.NopCloser(bytes.NewReader(entry.Value())), nil
+	}
+
+	// a watcher sends the current values first, then nil, in one round trip
+	w, err := s.KV.Watch(s.Key, nats.IgnoreDeletes(), nats.Context(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer w.Stop()
+
+	prefix := strings.TrimSuffix(s.Key, ">")
+	flat := map[string]string{}
+	for entry := range w.Updates() {
+		if entry == nil {
+			if len(flat) == 0 {
+				return nil, gofigure.Permanent(fmt.Errorf("natskv: no keys match %s", s.Key))
+			}
+			data, err := json.Marshal(gofigure.Unflatten(flat))
+			if err != nil {
+				return nil, gofigure.Permanent(err)
+			}
+			return ioutil.NopCloser(bytes.NewReader(data)), nil
+		}
+		flat[strings.TrimPrefix(entry.Key(), prefix)] = string(entry.Value())
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return nil, fmt.Errorf("natskv: watch of %s stopped", s.Key)
+}
+
+// Version returns the revision the source's key was last modified at. The keys under a wildcard
+// have no single version, so they are fetched and hashed to be polled
+func (s Source) Version(ctx context.Context) (string, error) {
+	if isWildcard(s.Key) {
+		return "", nil
+	}
+	entry, err := s.KV.Get(s.Key)
+	if err == nats.ErrKeyNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatUint(entry.Revision(), 10), nil
+}
+
+// isWildcard tells whether a key matches the keys under it
+func isWildcard(key string) bool {
+	return key == ">" || strings.HasSuffix(key, ".>")
+}
+
+// Monitor is a gofigure.ReloadMonitor calling its Reloader whenever one of its keys changes, or
+// one under them for keys ending with ">".
+//
+// The NATS client reconnects on its own and resumes the watches. A watch that stops anyway is
+// started again with a backoff, and the Reloader is then called, since changes may have been
+// missed.
+type Monitor struct {
+	kv   nats.KeyValue
+	keys []string
+
+	group monitor.Group
+}
+
+// NewMonitor creates a monitor watching keys of a bucket
+func NewMonitor(kv nats.KeyValue, keys ...string) *Monitor {
+	return &Monitor{
+		kv:   kv,
+		keys: keys,
+	}
+}
+
+// Watch starts watching the keys, calling r whenever one of them changes
+func (m *Monitor) Watch(r gofigure.Reloader) {
+	m.group.Start(len(m.keys), func(ctx context.Context, i int) {
+		m.watch(ctx, m.keys[i], r)
+	})
+}
+
+// Stop stops watching, and waits for the watches to end
+func (m *Monitor) Stop() {
+	m.group.Stop("nats")
+}
+
+// watch watches a single key until ctx is done
+func (m *Monitor) watch(ctx context.Context, key string, r gofigure.Reloader) {
+
+	var backoff monitor.Backoff
+	resumed := false
+
+	for ctx.Err() == nil {
+		w, err := m.kv.Watch(key, nats.Context(ctx))
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Info("nats watch of %s failed, retrying in %s: %s", key, backoff.Delay(), err)
+			backoff.Wait(ctx)
+			continue
+		}
+		backoff.Reset()
+
+		if resumed {
+			log.Info("nats watch of %s was resumed, reloading", key)
+			m.group.Reload(r)
+		}
+		m.consume(ctx, w, r)
+		w.Stop()
+		resumed = true
+	}
+}
+
+// consume calls r for every change a watcher sends, after the current values it sends first,
+// until the watcher stops or ctx is done
+func (m *Monitor) consume(ctx context.Context, w nats.KeyWatcher, r gofigure.Reloader) {
+
+	current := true
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case entry, ok := <-w.Updates():
+			if !ok {
+				return
+			}
+			if entry == nil {
+				current = false
+				continue
+			}
+			if !current {
+				log.Info("nats key %s changed, reloading", entry.Key())
+				m.group.Reload(r)
+			}
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package natskv
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/EverythingMe/gofigure"
+	"github.com/EverythingMe/gofigure/yaml"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+)
+
+// startNATS runs an embedded NATS server with JetStream, returning a bucket on it
+func startNATS(t *testing.T) (nats.KeyValue, func()) {
+
+	dir, err := ioutil.TempDir("", "gofigure-nats")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: -1, JetStream: true, StoreDir: dir})
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	go s.Start()
+	if !s.ReadyForConnections(10 * time.Second) {
+		t.Fatal("nats did not start")
+	}
+
+	nc, err := nats.Connect(s.ClientURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	js, err := nc.JetStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	kv, err := js.CreateKeyValue(&nats.KeyValueConfig{Bucket: "config"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return kv, func() {
+		nc.Close()
+		s.Shutdown()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestNATS(t *testing.T) {
+
+	kv, stop := startNATS(t)
+	defer stop()
+
+	if _, err := kv.PutString("app", "redis:\n  server: nats:6379\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	var conf struct {
+		Redis struct {
+			Server string
+			Port   int
+		}
+	}
+	loader := gofigure.NewLoader(yaml.Decoder{}, true)
+	loader.Sources = []gofigure.Source{Source{KV: kv, Key: "app"}}
+	if err := loader.LoadRecursive(&conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Redis.Server != "nats:6379" {
+		t.Errorf("Unexpected config %#v", conf)
+	}
+
+	loader.Sources = []gofigure.Source{Source{KV: kv, Key: "missing"}}
+	if err := loader.LoadRecursive(&conf); err == nil {
+		t.Error("Expected an error for a missing key")
+	}
+
+	for key, value := range map[string]string{"tree.redis.server": "tree:6379", "tree.redis.port": "6380"} {
+		if _, err := kv.PutString(key, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	loader.WeaklyTyped = true
+	loader.Sources = []gofigure.Source{Source{KV: kv, Key: "tree.>"}}
+	if err := loader.LoadRecursive(&conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Redis.Server != "tree:6379" || conf.Redis.Port != 6380 {
+		t.Errorf("Unexpected config %#v", conf)
+	}
+
+	reloads := make(chan bool, 10)
+	var m gofigure.ReloadMonitor = NewMonitor(kv, "tree.>")
+	m.Watch(gofigure.ReloadFunc(func() { reloads <- true }))
+	defer m.Stop()
+
+	select {
+	case <-reloads:
+		t.Fatal("The current values should not trigger reloads")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	if _, err := kv.PutString("tree.redis.port", "6381"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-reloads:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Change did not trigger a reload")
+	}
+
+	if _, err := kv.PutString("other", "x"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-reloads:
+		t.Fatal("Other keys should not trigger reloads")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
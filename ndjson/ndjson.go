@@ -0,0 +1,56 @@
+// Package ndjson implements a gofigure decoder for newline delimited json (aka JSON Lines) files,
+// where every line is a json object merged into the config in order.
+package ndjson
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/EverythingMe/gofigure/internal/tree"
+)
+
+// Decoder decodes newline delimited json files, one object per line, into config structs.
+// Later lines override values set by earlier ones. Blank lines are ignored.
+type Decoder struct{}
+
+// Decode unmarshals every line read from r into config, which is a pointer to a struct. If config
+// is a pointer to an interface{}, the lines are deep merged into it
+func (d Decoder) Decode(r io.Reader, config interface{}) error {
+
+	generic, isGeneric := config.(*interface{})
+
+	br := bufio.NewReader(r)
+	for lineno := 1; ; lineno++ {
+		line, err := br.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		if trimmed := bytes.TrimSpace(line); len(trimmed) > 0 {
+			var jerr error
+			if isGeneric {
+				var v interface{}
+				jerr = json.Unmarshal(trimmed, &v)
+				*generic = tree.MergeValue(*generic, v)
+			} else {
+				jerr = json.Unmarshal(trimmed, config)
+			}
+			if jerr != nil {
+				return fmt.Errorf("line %d: %s", lineno, jerr)
+			}
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// CanDecode returns true if this is an ndjson or jsonl file
+func (d Decoder) CanDecode(path string) bool {
+	return strings.HasSuffix(path, ".ndjson") || strings.HasSuffix(path, ".jsonl")
+}
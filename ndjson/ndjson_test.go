@@ -0,0 +1,36 @@
+package ndjson
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+
+	var conf struct {
+		Server  string `json:"server"`
+		Monitor int    `json:"monitor"`
+		Timeout int    `json:"timeout"`
+	}
+
+	lines := `{"server": "localhost:6378", "monitor": 1000}
+
+{"server": "localhost:6379"}
+{"timeout": 10}`
+
+	if err := (Decoder{}).Decode(strings.NewReader(lines), &conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Server != "localhost:6379" || conf.Monitor != 1000 || conf.Timeout != 10 {
+		t.Errorf("Lines not merged in order: %v", conf)
+	}
+
+	err := (Decoder{}).Decode(strings.NewReader("{}\n{\"server\": \n"), &conf)
+	if err == nil || !strings.HasPrefix(err.Error(), "line 2:") {
+		t.Errorf("Expected an error on line 2, got %v", err)
+	}
+
+	if !(Decoder{}).CanDecode("overrides.jsonl") || (Decoder{}).CanDecode("overrides.json") {
+		t.Errorf("Wrong file extensions matched")
+	}
+}
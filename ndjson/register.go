@@ -0,0 +1,9 @@
+//go:build !gofigure_noregister
+
+package ndjson
+
+import "github.com/EverythingMe/gofigure"
+
+func init() {
+	gofigure.RegisterDecoder("ndjson", Decoder{})
+}
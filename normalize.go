@@ -0,0 +1,100 @@
+package gofigure
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Normalizer is implemented by config types that canonicalize their values once they are loaded,
+// like lowercasing hostnames or trimming trailing slashes off URLs:
+//
+//	func (s *Server) Normalize() error {
+//		s.Host = strings.ToLower(s.Host)
+//		return nil
+//	}
+//
+// Normalize is called after the documents are merged and bound, on the config and on every
+// struct, pointer, list element and map value in it implementing Normalizer, nested values
+// first. An error fails the load.
+type Normalizer interface {
+	Normalize() error
+}
+
+// NormalizeFunc is a normalization hook registered with a loader, called with the pointer to the
+// config that was loaded, see Loader.Normalizers
+type NormalizeFunc func(config interface{}) error
+
+// normalize calls the Normalize methods of config and of the values in it, then the loader's
+// normalization hooks
+func (l Loader) normalize(config interface{}) error {
+	if err := normalizeValue(reflect.ValueOf(config), ""); err != nil {
+		return err
+	}
+	for _, fn := range l.Normalizers {
+		if err := fn(config); err != nil {
+			return fmt.Errorf("gofigure: normalizing: %w", err)
+		}
+	}
+	return nil
+}
+
+// normalizeValue calls the Normalize methods of the values nested in v, then that of v. Map values
+// aren't addressable, so they are copied, normalized and stored back. The path is that of v in the
+// config, used for error reporting
+func normalizeValue(v reflect.Value, path string) error {
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		// the methods of pointers are those of the values they point to
+		return normalizeValue(v.Elem(), path)
+
+	case reflect.Struct:
+		for _, f := range structFields(v.Type()) {
+			fv, ok := fieldValue(v, f.index)
+			if !ok {
+				continue
+			}
+			if err := normalizeValue(fv, joinPath(path, f.names[0])); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := normalizeValue(v.Index(i), joinPath(path, fmt.Sprint(i))); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map:
+		for it := v.MapRange(); it.Next(); {
+			elem := reflect.New(it.Value().Type()).Elem()
+			elem.Set(it.Value())
+			if err := normalizeValue(elem, joinPath(path, fmt.Sprint(it.Key().Interface()))); err != nil {
+				return err
+			}
+			v.SetMapIndex(it.Key(), elem)
+		}
+	}
+
+	var n Normalizer
+	switch {
+	case v.CanAddr() && v.Addr().CanInterface():
+		n, _ = v.Addr().Interface().(Normalizer)
+	case v.CanInterface():
+		n, _ = v.Interface().(Normalizer)
+	}
+	if n == nil {
+		return nil
+	}
+	if err := n.Normalize(); err != nil {
+		if path == "" {
+			return fmt.Errorf("gofigure: normalizing: %w", err)
+		}
+		return fmt.Errorf("gofigure: normalizing %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package gofigure
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/EverythingMe/gofigure/yaml"
+)
+
+type normalizedServer struct {
+	Server string `yaml:"server"`
+}
+
+func (s *normalizedServer) Normalize() error {
+	if s.Server == "" {
+		return errors.New("no server")
+	}
+	s.Server = strings.ToUpper(s.Server)
+	return nil
+}
+
+type normalizedConfig struct {
+	Redis *normalizedServer           `yaml:"redis"`
+	Mysql normalizedServer            `yaml:"mysql"`
+	More  map[string]normalizedServer `yaml:"more"`
+	calls []string
+}
+
+func (c *normalizedConfig) Normalize() error {
+	// nested values are normalized first
+	c.calls = append(c.calls, c.Redis.Server)
+	return nil
+}
+
+func TestNormalize(t *testing.T) {
+
+	loader := NewLoader(yaml.Decoder{}, true)
+	loader.Normalizers = []NormalizeFunc{func(config interface{}) error {
+		c := config.(*normalizedConfig)
+		c.calls = append(c.calls, "hook")
+		return nil
+	}}
+
+	var conf normalizedConfig
+	err := loader.MergeDocuments([][]byte{[]byte("redis: {server: a}\nmysql: {server: b}\nmore: {c: {server: c}}")}, &conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Redis.Server != "A" || conf.Mysql.Server != "B" || conf.More["c"].Server != "C" {
+		t.Errorf("Unexpected normalized config %+v %+v", conf.Redis, conf)
+	}
+	if strings.Join(conf.calls, ",") != "A,hook" {
+		t.Errorf("Unexpected normalization order %v", conf.calls)
+	}
+
+	conf = normalizedConfig{}
+	err = loader.MergeDocuments([][]byte{[]byte("redis: {server: a}\nmore: {c: {}}")}, &conf)
+	if err == nil || !strings.Contains(err.Error(), "mysql") {
+		t.Errorf("Expected the error of normalizing mysql, got %v", err)
+	}
+}
@@ -0,0 +1,256 @@
+package gofigure
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// maxPatchSize is the largest JSON Merge Patch Overrides accepts
+const maxPatchSize = 1 << 20
+
+// Overrides is an in-memory layer of the config, changed at runtime through an HTTP admin endpoint
+// with JSON Merge Patches (RFC 7386), e.g. to turn an emergency knob without redeploying:
+//
+//	overrides := gofigure.NewOverrides(gofigure.BearerToken(os.Getenv("ADMIN_TOKEN")))
+//	loader.Sources = append(loader.Sources, overrides) // last, so it wins over the rest
+//	overrides.Watch(app)                               // app reloads its config with loader
+//	http.Handle("/admin/config", overrides)
+//
+//	curl -X PATCH -H "Authorization: Bearer $ADMIN_TOKEN" \
+//		-d '{"redis": {"timeout": 30}}' http://localhost/admin/config
+//
+// PATCH merges the patch into the overrides, GET returns them, and DELETE removes them all. A null
+// in a patch removes the override of a key rather than the key itself, so the value of the layers
+// below it applies again. Every change reloads the watchers of the overrides before the response,
+// so Overrides is a ReloadMonitor; it's also a VersionedSource that a PollMonitor can poll.
+type Overrides struct {
+	authorize func(*http.Request) bool
+
+	// schema, if set, is the type of config patches are checked against
+	schema reflect.Type
+
+	mu       sync.Mutex
+	doc      map[string]interface{}
+	version  int
+	watchers []Reloader
+}
+
+// NewOverrides creates empty overrides, changed by the requests authorize accepts. Requests are
+// refused if authorize is nil
+func NewOverrides(authorize func(*http.Request) bool) *Overrides {
+	return &Overrides{
+		authorize: authorize,
+		doc:       map[string]interface{}{},
+	}
+}
+
+// BearerToken authorizes the requests whose Authorization header is "Bearer " followed by the
+// token, which must not be empty
+func BearerToken(token string) func(*http.Request) bool {
+	return func(r *http.Request) bool {
+		h := r.Header.Get("Authorization")
+		if !strings.HasPrefix(h, "Bearer ") {
+			return false
+		}
+		got := strings.TrimPrefix(h, "Bearer ")
+		return token != "" && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+	}
+}
+
+// CheckTypes makes patches that set values of the wrong type for config, a config or a pointer to
+// one, refused with an error rather than failing the reloads of the watchers. Every value of a
+// patch must be one Set accepts at its path, and keys that match no field are refused too
+func (o *Overrides) CheckTypes(config interface{}) {
+	t := reflect.TypeOf(config)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.schema = t
+}
+
+// Name names the overrides in reports
+func (o *Overrides) Name() string {
+	return "admin overrides"
+}
+
+// Fetch returns the overrides as a JSON document
+func (o *Overrides) Fetch(ctx context.Context) (io.ReadCloser, error) {
+	data, err := o.document()
+	if err != nil {
+		return nil, err
+	}
+	return typedBody{ioutil.NopCloser(bytes.NewReader(data)), "json"}, nil
+}
+
+// Version returns the number of times the overrides were changed
+func (o *Overrides) Version(ctx context.Context) (string, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return strconv.Itoa(o.version), nil
+}
+
+// Patch merges a JSON Merge Patch, which must be an object, into the overrides and reloads their
+// watchers
+func (o *Overrides) Patch(patch []byte) error {
+	var p interface{}
+	if err := json.Unmarshal(patch, &p); err != nil {
+		return fmt.Errorf("gofigure: invalid merge patch: %s", err)
+	}
+	obj, ok := p.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("gofigure: invalid merge patch: not an object")
+	}
+
+	o.mu.Lock()
+	schema := o.schema
+	o.mu.Unlock()
+	if schema != nil {
+		if err := checkPatch(reflect.New(schema).Interface(), obj, ""); err != nil {
+			return fmt.Errorf("gofigure: invalid merge patch: %s", err)
+		}
+	}
+
+	o.change(func(doc map[string]interface{}) map[string]interface{} {
+		return mergePatch(doc, obj).(map[string]interface{})
+	})
+	return nil
+}
+
+// Reset removes all the overrides and reloads their watchers
+func (o *Overrides) Reset() {
+	o.change(func(map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{}
+	})
+}
+
+// change replaces the overrides by what fn makes of them, and reloads their watchers
+func (o *Overrides) change(fn func(map[string]interface{}) map[string]interface{}) {
+	o.mu.Lock()
+	o.doc = fn(o.doc)
+	o.version++
+	watchers := append([]Reloader(nil), o.watchers...)
+	o.mu.Unlock()
+
+	for _, r := range watchers {
+		r.Reload()
+	}
+}
+
+// document returns the overrides as JSON
+func (o *Overrides) document() ([]byte, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return json.Marshal(o.doc)
+}
+
+// Watch makes r reloaded whenever the overrides change
+func (o *Overrides) Watch(r Reloader) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.watchers = append(o.watchers, r)
+}
+
+// Stop stops notifying the watchers of the overrides of their changes
+func (o *Overrides) Stop() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.watchers = nil
+}
+
+// ServeHTTP serves the admin endpoint of the overrides
+func (o *Overrides) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	if o.authorize == nil || !o.authorize(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPatch:
+		patch, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxPatchSize))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err := o.Patch(patch); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Info("Config overrides patched by %s", r.RemoteAddr)
+	case http.MethodDelete:
+		o.Reset()
+		log.Info("Config overrides reset by %s", r.RemoteAddr)
+	default:
+		w.Header().Set("Allow", "GET, PATCH, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	data, err := o.document()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Write(data)
+}
+
+// checkPatch checks that every value a patch sets can be set at its path in config. Nulls, which
+// remove overrides, are always accepted
+func checkPatch(config interface{}, patch map[string]interface{}, path string) error {
+	for k, v := range patch {
+		p := joinPath(path, k)
+		switch v := v.(type) {
+		case nil:
+		case map[string]interface{}:
+			if err := checkPatch(config, v, p); err != nil {
+				return err
+			}
+		default:
+			if err := Set(config, p, v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// mergePatch applies a JSON Merge Patch to target: objects are merged recursively, nulls remove
+// keys, and any other value replaces the target
+func mergePatch(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = map[string]interface{}{}
+	}
+	out := make(map[string]interface{}, len(t))
+	for k, v := range t {
+		out[k] = v
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(out, k)
+			continue
+		}
+		out[k] = mergePatch(out[k], v)
+	}
+	return out
+}
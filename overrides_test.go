@@ -0,0 +1,105 @@
+package gofigure
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/EverythingMe/gofigure/yaml"
+)
+
+func TestOverrides(t *testing.T) {
+
+	overrides := NewOverrides(BearerToken("secret"))
+	loader := NewLoader(yaml.Decoder{}, true)
+	loader.Sources = []Source{overrides}
+
+	var conf config
+	reloads := 0
+	overrides.Watch(ReloadFunc(func() {
+		reloads++
+		if err := loader.LoadRecursive(&conf, "./testdata"); err != nil {
+			t.Error(err)
+		}
+	}))
+
+	request := func(method, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/admin/config", strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		overrides.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := request("PATCH", "", `{"redis": {"timeout": 30}}`); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected an unauthenticated patch to be refused, got %d", rec.Code)
+	}
+	if rec := request("PATCH", "wrong", `{"redis": {"timeout": 30}}`); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a patch with a wrong token to be refused, got %d", rec.Code)
+	}
+	bare := httptest.NewRequest("PATCH", "/admin/config", strings.NewReader(`{"redis": {"timeout": 30}}`))
+	bare.Header.Set("Authorization", "secret")
+	rec := httptest.NewRecorder()
+	if overrides.ServeHTTP(rec, bare); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a token without the Bearer scheme to be refused, got %d", rec.Code)
+	}
+	if reloads != 0 {
+		t.Fatal("Expected refused patches not to reload")
+	}
+
+	rec = request("PATCH", "secret", `{"redis": {"timeout": 30, "monitor": 5}}`)
+	if rec.Code != http.StatusOK || rec.Body.String() != `{"redis":{"monitor":5,"timeout":30}}` {
+		t.Errorf("Unexpected response %d %s", rec.Code, rec.Body)
+	}
+	if reloads != 1 || conf.Redis.Timeout != 30 || conf.Redis.Monitor != 5 || conf.Redis.Server != expectedConf.Redis.Server {
+		t.Errorf("Expected the overrides to win over the files, got %+v", conf.Redis)
+	}
+
+	// null removes an override, so the files apply again
+	request("PATCH", "secret", `{"redis": {"monitor": null}}`)
+	if conf.Redis.Timeout != 30 || conf.Redis.Monitor != expectedConf.Redis.Monitor {
+		t.Errorf("Unexpected config after removing an override %+v", conf.Redis)
+	}
+	if version, _ := overrides.Version(context.Background()); version != "2" {
+		t.Errorf("Unexpected version %s", version)
+	}
+
+	for _, patch := range []string{`[1]`, `{"redis": `} {
+		if rec := request("PATCH", "secret", patch); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected patch %s to be refused, got %d", patch, rec.Code)
+		}
+	}
+	if rec := request("PUT", "secret", `{}`); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected PUT to be refused, got %d", rec.Code)
+	}
+
+	if rec := request("GET", "secret", ""); rec.Body.String() != `{"redis":{"timeout":30}}` {
+		t.Errorf("Unexpected overrides %s", rec.Body)
+	}
+	request("DELETE", "secret", "")
+	if reloads != 3 || conf != expectedConf {
+		t.Errorf("Expected the files' config after resetting the overrides, got %+v", conf)
+	}
+}
+
+func TestOverridesCheckTypes(t *testing.T) {
+
+	overrides := NewOverrides(BearerToken("secret"))
+	overrides.CheckTypes(&config{})
+
+	for _, patch := range []string{`{"redis": {"timeout": "long"}}`, `{"redis": {"retries": 3}}`, `{"redis": 1}`} {
+		if err := overrides.Patch([]byte(patch)); err == nil {
+			t.Errorf("Expected patch %s to be refused", patch)
+		}
+	}
+	if err := overrides.Patch([]byte(`{"redis": {"timeout": 30, "monitor": null}}`)); err != nil {
+		t.Error(err)
+	}
+	if data, _ := overrides.document(); string(data) != `{"redis":{"timeout":30}}` {
+		t.Errorf("Unexpected overrides %s", data)
+	}
+}
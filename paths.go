@@ -0,0 +1,130 @@
+package gofigure
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/EverythingMe/gofigure/internal/tree"
+)
+
+// resolvePaths returns docs with the relative paths set to the fields of t tagged path:"resolve"
+// made absolute against the directory of the file that set them. The values documents have at
+// path are bound to t. Documents that don't come from files, like sources and defaults, and values
+// that are references, like "${DIR}/key", are left alone. Documents are copied before they are
+// changed, as they may be kept in a History
+func (l Loader) resolvePaths(docs []document, path string, t reflect.Type) []document {
+
+	if !hasPathFields(t, map[reflect.Type]bool{}) {
+		return docs
+	}
+
+	out := make([]document, len(docs))
+	for i, doc := range docs {
+		out[i] = doc
+		if doc.source == defaultsSource {
+			continue
+		}
+		info, err := os.Stat(doc.source)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		dir, err := filepath.Abs(filepath.Dir(doc.source))
+		if err != nil {
+			continue
+		}
+
+		copied := tree.Copy(doc.tree).(map[string]interface{})
+		if sub, found := tree.Lookup(copied, path); found {
+			l.resolvePathsIn(sub, t, dir)
+		}
+		out[i] = document{doc.source, copied}
+	}
+	return out
+}
+
+// resolvePathsIn resolves the paths in the document value v, bound to the type t, in place
+func (l Loader) resolvePathsIn(v interface{}, t reflect.Type, dir string) {
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return
+		}
+		fields := structFields(t)
+		for key, e := range m {
+			f, _ := fields.match(key, l.KeyMatching)
+			switch {
+			case f == nil:
+			case f.resolvePath:
+				m[key] = resolveFiles(dir, e)
+			default:
+				l.resolvePathsIn(e, t.FieldByIndex(f.index).Type, dir)
+			}
+		}
+
+	case reflect.Map:
+		if m, ok := v.(map[string]interface{}); ok {
+			for _, e := range m {
+				l.resolvePathsIn(e, t.Elem(), dir)
+			}
+		}
+
+	case reflect.Slice, reflect.Array:
+		if items, ok := v.([]interface{}); ok {
+			for _, e := range items {
+				l.resolvePathsIn(e, t.Elem(), dir)
+			}
+		}
+	}
+}
+
+// resolveFiles resolves the value of a field tagged path:"resolve", a path or a list of paths
+func resolveFiles(dir string, v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		if v == "" || filepath.IsAbs(v) || strings.Contains(v, "${") {
+			return v
+		}
+		return filepath.Join(dir, v)
+	case []interface{}:
+		for i, e := range v {
+			v[i] = resolveFiles(dir, e)
+		}
+	}
+	return v
+}
+
+// hasPathFields tells whether t has fields tagged path:"resolve", at any depth. seen holds the
+// struct types already looked at, which recursive types come back to
+func hasPathFields(t reflect.Type, seen map[reflect.Type]bool) bool {
+
+	if t == nil {
+		return false
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if seen[t] {
+			return false
+		}
+		seen[t] = true
+		for _, f := range structFields(t) {
+			if f.resolvePath || hasPathFields(t.FieldByIndex(f.index).Type, seen) {
+				return true
+			}
+		}
+	case reflect.Map, reflect.Slice, reflect.Array:
+		return hasPathFields(t.Elem(), seen)
+	}
+	return false
+}
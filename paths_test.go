@@ -0,0 +1,58 @@
+package gofigure
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/EverythingMe/gofigure/yaml"
+)
+
+func TestResolvePaths(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gofigure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	confd := filepath.Join(dir, "conf.d")
+	if err := os.Mkdir(confd, 0755); err != nil {
+		t.Fatal(err)
+	}
+	doc := "tls:\n  cert: certs/server.pem\n  key: /etc/ssl/server.key\n  cas: [ca.pem, '${CA_DIR}/ca.pem']\n  name: server.pem\n"
+	if err := ioutil.WriteFile(filepath.Join(confd, "tls.yaml"), []byte(doc), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	type tlsConfig struct {
+		Cert string   `yaml:"cert" path:"resolve"`
+		Key  string   `yaml:"key" path:"resolve"`
+		CAs  []string `yaml:"cas" path:"resolve"`
+		Name string   `yaml:"name"`
+	}
+	var conf struct {
+		TLS *tlsConfig `yaml:"tls"`
+	}
+
+	loader := NewLoader(yaml.Decoder{}, true)
+	if err := loader.LoadRecursive(&conf, dir); err != nil {
+		t.Fatal(err)
+	}
+	if conf.TLS.Cert != filepath.Join(confd, "certs/server.pem") || conf.TLS.Key != "/etc/ssl/server.key" {
+		t.Errorf("Unexpected paths %+v", conf.TLS)
+	}
+	if len(conf.TLS.CAs) != 2 || conf.TLS.CAs[0] != filepath.Join(confd, "ca.pem") || conf.TLS.CAs[1] != "${CA_DIR}/ca.pem" {
+		t.Errorf("Unexpected list of paths %v", conf.TLS.CAs)
+	}
+	if conf.TLS.Name != "server.pem" {
+		t.Errorf("Expected an untagged field to be left alone, got %s", conf.TLS.Name)
+	}
+
+	// sections are resolved too
+	var section tlsConfig
+	if err := loader.LoadPath(&section, "tls", dir); err != nil || section.Cert != conf.TLS.Cert {
+		t.Errorf("Unexpected section %+v %v", section, err)
+	}
+}
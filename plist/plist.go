@@ -0,0 +1,72 @@
+// Package plist implements a gofigure decoder for Apple property lists, so that macOS agents can
+// read their preferences, e.g. from /Library/Preferences, without converting them first. XML,
+// binary and OpenStep property lists are all supported.
+package plist
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/EverythingMe/gofigure/internal/tree"
+	"howett.net/plist"
+)
+
+// Decoder decodes property lists into config structs. The root of a property list must be a
+// dictionary. Dates are decoded as RFC 3339 strings, which time.Time fields accept, and data as
+// base64 strings.
+type Decoder struct{}
+
+// Decode unmarshals the property list read from r into config
+func (d Decoder) Decode(r io.Reader, config interface{}) error {
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	var v interface{}
+	if _, err := plist.Unmarshal(data, &v); err != nil {
+		return fmt.Errorf("plist: %s", err)
+	}
+	if _, ok := v.(map[string]interface{}); !ok {
+		return fmt.Errorf("plist: the root must be a dictionary, got %T", v)
+	}
+
+	return tree.Assign(normalize(v), config)
+}
+
+// CanDecode returns true if this is a property list
+func (d Decoder) CanDecode(path string) bool {
+	return strings.HasSuffix(path, ".plist")
+}
+
+// normalize converts the values property lists have but generic documents don't
+func normalize(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = normalize(e)
+		}
+		return v
+	case []interface{}:
+		for i, e := range v {
+			v[i] = normalize(e)
+		}
+		return v
+	case uint64:
+		if v <= math.MaxInt64 {
+			return int64(v)
+		}
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v)
+	}
+	return v
+}
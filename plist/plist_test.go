@@ -0,0 +1,91 @@
+package plist
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"howett.net/plist"
+)
+
+const xmlPlist = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Server</key>
+	<string>https://mdm.example.com</string>
+	<key>Interval</key>
+	<integer>300</integer>
+	<key>Offset</key>
+	<integer>-5</integer>
+	<key>Ratio</key>
+	<real>0.5</real>
+	<key>Enabled</key>
+	<true/>
+	<key>Tags</key>
+	<array>
+		<string>a</string>
+		<string>b</string>
+	</array>
+	<key>Since</key>
+	<date>2024-01-02T03:04:05Z</date>
+	<key>Token</key>
+	<data>aGVsbG8=</data>
+</dict>
+</plist>
+`
+
+func TestDecode(t *testing.T) {
+
+	expected := map[string]interface{}{
+		"Server":   "https://mdm.example.com",
+		"Interval": int64(300),
+		"Offset":   int64(-5),
+		"Ratio":    0.5,
+		"Enabled":  true,
+		"Tags":     []interface{}{"a", "b"},
+		"Since":    "2024-01-02T03:04:05Z",
+		"Token":    "aGVsbG8=",
+	}
+
+	var conf interface{}
+	if err := (Decoder{}).Decode(strings.NewReader(xmlPlist), &conf); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(conf, expected) {
+		t.Errorf("Unexpected config %#v", conf)
+	}
+
+	// the same property list in the binary format
+	var buf bytes.Buffer
+	if err := plist.NewEncoderForFormat(&buf, plist.BinaryFormat).Encode(map[string]interface{}{
+		"Server": "https://mdm.example.com",
+		"Since":  time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	var typed struct {
+		Server string
+		Since  time.Time
+	}
+	if err := (Decoder{}).Decode(&buf, &typed); err != nil {
+		t.Fatal(err)
+	}
+	if typed.Server != "https://mdm.example.com" || typed.Since.Year() != 2024 {
+		t.Errorf("Unexpected typed config %#v", typed)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	for _, src := range []string{
+		"<plist><dict><key>a</key>",
+		`<plist version="1.0"><array><string>a</string></array></plist>`,
+	} {
+		var conf interface{}
+		if err := (Decoder{}).Decode(strings.NewReader(src), &conf); err == nil {
+			t.Errorf("Expected an error decoding %q", src)
+		}
+	}
+}
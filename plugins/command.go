@@ -0,0 +1,77 @@
+package plugins
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/EverythingMe/gofigure/internal/tree"
+)
+
+// Command is a decoder running an executable for every document it decodes. The protocol is
+// minimal, so decoders can be written in any language: the executable reads the document on its
+// standard input and writes it as JSON to its standard output. It reports errors by exiting with
+// a non-zero status, with the message on its standard error.
+type Command struct {
+	// Path is the executable, and Args its arguments
+	Path string
+	Args []string
+
+	// Extensions are those of the files the decoder decodes, like ".ini"
+	Extensions []string
+
+	// Timeout bounds how long the executable can run, 10 seconds if zero
+	Timeout time.Duration
+}
+
+// Decode runs the executable on the document read from r, and unmarshals the JSON it writes into
+// config
+func (c Command) Decode(r io.Reader, config interface{}) error {
+
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.Path, c.Args...)
+	cmd.Stdin = r
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("plugins: %s: %s", filepath.Base(c.Path), msg)
+		}
+		return fmt.Errorf("plugins: %s: %s", filepath.Base(c.Path), err)
+	}
+
+	var doc interface{}
+	dec := json.NewDecoder(&stdout)
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return fmt.Errorf("plugins: %s wrote invalid JSON: %s", filepath.Base(c.Path), err)
+	}
+	return tree.Assign(tree.Normalize(doc), config)
+}
+
+// CanDecode returns true if the file has one of the decoder's extensions
+func (c Command) CanDecode(path string) bool {
+	for _, ext := range c.Extensions {
+		if strings.HasSuffix(path, ext) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+// Package plugins adds decoders to gofigure at runtime, so operators can read formats a program
+// wasn't built with, like proprietary ones, by dropping plugins in a directory:
+//
+//	if err := plugins.LoadDir("/etc/myapp/decoders"); err != nil {
+//		log.Fatal(err)
+//	}
+//
+// Plugins are either Go plugins, built with go build -buildmode=plugin, or executables speaking a
+// minimal protocol over pipes. Go plugins must be built with the same Go version and versions of
+// the packages they share with the program, while executables can be written in any language.
+// The decoders of both are registered with gofigure.RegisterDecoder, and so used by
+// gofigure.DefaultLoader.
+package plugins
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"plugin"
+	"sort"
+	"strings"
+
+	"github.com/EverythingMe/gofigure"
+	"github.com/op/go-logging"
+)
+
+var log = logging.MustGetLogger("gofigure")
+
+// CommandPrefix is the prefix of the names of executables LoadDir registers as decoders. The rest
+// of the name is the format, e.g. gofigure-decoder-ini decodes .ini files
+const CommandPrefix = "gofigure-decoder-"
+
+// Open loads a Go plugin and registers the decoder it exports. The plugin's main package must
+// export the decoder as a variable named Decoder, and the name of its format as a string named
+// Format:
+//
+//	package main
+//
+//	var Format = "ini"
+//	var Decoder gofigure.Decoder = ini.Decoder{}
+func Open(path string) error {
+
+	p, err := plugin.Open(path)
+	if err != nil {
+		return fmt.Errorf("plugins: opening %s: %s", path, err)
+	}
+
+	sym, err := p.Lookup("Format")
+	if err != nil {
+		return fmt.Errorf("plugins: %s: %s", path, err)
+	}
+	format, ok := sym.(*string)
+	if !ok {
+		return fmt.Errorf("plugins: %s: Format is a %T, not a string", path, sym)
+	}
+
+	if sym, err = p.Lookup("Decoder"); err != nil {
+		return fmt.Errorf("plugins: %s: %s", path, err)
+	}
+	var decoder gofigure.Decoder
+	switch d := sym.(type) {
+	case *gofigure.Decoder:
+		decoder = *d
+	case gofigure.Decoder:
+		decoder = d
+	}
+	if decoder == nil {
+		return fmt.Errorf("plugins: %s: Decoder is a %T, not a gofigure.Decoder", path, sym)
+	}
+
+	return register(*format, decoder, path)
+}
+
+// LoadDir registers the decoders of the plugins in a directory: the Go plugins, whose names end
+// with .so, see Open, and the executables whose names start with CommandPrefix, see Command.
+// Other files are ignored, as is a directory that doesn't exist. Plugins are loaded in the order
+// of their names
+func LoadDir(dir string) error {
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	for _, e := range entries {
+		path := filepath.Join(dir, e.Name())
+		switch {
+		case e.IsDir():
+		case strings.HasSuffix(e.Name(), ".so"):
+			if err := Open(path); err != nil {
+				return err
+			}
+		case strings.HasPrefix(e.Name(), CommandPrefix) && e.Mode()&0111 != 0:
+			format := strings.TrimPrefix(e.Name(), CommandPrefix)
+			format = strings.TrimSuffix(format, filepath.Ext(format))
+			if format == "" {
+				continue
+			}
+			if err := register(format, Command{Path: path, Extensions: []string{"." + format}}, path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// register registers a plugin's decoder, failing rather than panicking if its format is taken
+func register(format string, d gofigure.Decoder, path string) error {
+	if format == "" {
+		return fmt.Errorf("plugins: %s has no format", path)
+	}
+	if _, found := gofigure.LookupDecoder(format); found {
+		return fmt.Errorf("plugins: %s: a decoder of %s is already registered", path, format)
+	}
+	gofigure.RegisterDecoder(format, d)
+	log.Info("Registered the %s decoder of %s", format, path)
+	return nil
+}
@@ -0,0 +1,84 @@
+package plugins
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/EverythingMe/gofigure"
+)
+
+// script writes a shell script decoding "key=value" lines to a directory
+func script(t *testing.T, dir, name string) string {
+	path := filepath.Join(dir, name)
+	src := `#!/bin/sh
+sed -e 's/^\(.*\)=\(.*\)$/"\1": "\2"/' | paste -sd, - | sed -e 's/^/{/' -e 's/$/}/'
+`
+	if err := ioutil.WriteFile(path, []byte(src), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCommand(t *testing.T) {
+	dir, err := ioutil.TempDir("", "plugins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := Command{Path: script(t, dir, "kv"), Extensions: []string{".kv"}}
+	if !c.CanDecode("app.kv") || c.CanDecode("app.yaml") {
+		t.Error("wrong extensions decoded")
+	}
+
+	var conf struct {
+		Host string `json:"host"`
+		Port string `json:"port"`
+	}
+	if err := c.Decode(strings.NewReader("host=db\nport=5432\n"), &conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Host != "db" || conf.Port != "5432" {
+		t.Errorf("decoded %#v", conf)
+	}
+
+	failing := Command{Path: "/bin/sh", Args: []string{"-c", "echo bad syntax >&2; exit 1"}}
+	err = failing.Decode(strings.NewReader(""), &conf)
+	if err == nil || !strings.Contains(err.Error(), "bad syntax") {
+		t.Errorf("expected the error of the command, got %v", err)
+	}
+}
+
+func TestLoadDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "plugins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	script(t, dir, CommandPrefix+"pluginkv")
+	if err := ioutil.WriteFile(filepath.Join(dir, "README"), []byte("not a plugin"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := LoadDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	d, found := gofigure.LookupDecoder("pluginkv")
+	if !found {
+		t.Fatal("the decoder of the executable wasn't registered")
+	}
+	if !d.CanDecode("app.pluginkv") {
+		t.Error("the decoder doesn't decode the files of its format")
+	}
+
+	if err := LoadDir(dir); err == nil {
+		t.Error("registering a format twice should fail")
+	}
+	if err := LoadDir(filepath.Join(dir, "missing")); err != nil {
+		t.Errorf("a missing directory should be ignored, got %s", err)
+	}
+}
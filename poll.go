@@ -0,0 +1,147 @@
+package gofigure
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"time"
+)
+
+// VersionedSource is a Source that can tell the version of its document without fetching it, like
+// an HTTP ETag, so that polling it for changes is cheap
+type VersionedSource interface {
+	Source
+
+	// Version returns an opaque string that changes whenever the document does, or an empty
+	// string if the version is unknown and the document should be fetched and hashed instead
+	Version(ctx context.Context) (string, error)
+}
+
+// PollMonitor is a ReloadMonitor for sources that can't notify about changes, like HTTP servers.
+// It checks its sources every interval, and calls its Reloader when any of them changed. Changes
+// are detected by the sources' versions if they are VersionedSources, or by hashing their
+// documents otherwise.
+type PollMonitor struct {
+	interval time.Duration
+	sources  []Source
+	stopch   chan bool
+}
+
+// NewPollMonitor creates a monitor polling sources every interval. It's typically given the
+// sources of the loader the Reloader uses
+func NewPollMonitor(interval time.Duration, sources ...Source) *PollMonitor {
+	return &PollMonitor{
+		interval: interval,
+		sources:  sources,
+		stopch:   make(chan bool),
+	}
+}
+
+// Watch starts polling the sources, calling r's Reload method whenever they changed. The versions
+// the sources have when Watch is called are the baseline, so they don't trigger a reload
+func (m *PollMonitor) Watch(r Reloader) {
+
+	versions := m.poll(nil)
+
+	go func() {
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				current := m.poll(versions)
+				if changed(versions, current) {
+					log.Info("Config sources changed, reloading")
+					r.Reload()
+				}
+				versions = current
+
+			case <-m.stopch:
+				log.Info("Stopping poll monitor")
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the poll monitor
+func (m *PollMonitor) Stop() {
+	m.stopch <- true
+	log.Info("Stopped poll monitor")
+}
+
+// poll returns the current versions of the sources. Sources that fail to be checked keep their
+// previous version, so a transient failure doesn't trigger a reload
+func (m *PollMonitor) poll(previous []string) []string {
+
+	ctx, cancel := context.WithTimeout(context.Background(), m.interval)
+	defer cancel()
+
+	versions := make([]string, len(m.sources))
+	for i, s := range m.sources {
+		v, err := sourceVersion(ctx, s)
+		if err != nil {
+			log.Info("Could not poll %s: %s", s.Name(), err)
+			if previous != nil {
+				v = previous[i]
+			}
+		}
+		versions[i] = v
+	}
+	return versions
+}
+
+// changed returns true if any version differs. Unknown versions don't count as changes
+func changed(previous, current []string) bool {
+	for i := range current {
+		if current[i] != "" && current[i] != previous[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// sourceVersion returns the version of a source's document, asking the source for it if it can
+// tell, or hashing the document otherwise
+func sourceVersion(ctx context.Context, s Source) (string, error) {
+
+	if vs, ok := s.(VersionedSource); ok {
+		v, err := vs.Version(ctx)
+		if err != nil || v != "" {
+			return v, err
+		}
+	}
+
+	rc, err := s.Fetch(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, rc); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Version returns the ETag or Last-Modified header of a HEAD request to the source's URL
+func (s HTTPSource) Version(ctx context.Context) (string, error) {
+
+	res, err := s.request(ctx, "HEAD")
+	if err != nil {
+		return "", err
+	}
+	res.Body.Close()
+
+	// servers that don't support HEAD are polled by fetching the document
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return "", nil
+	}
+	if etag := res.Header.Get("ETag"); etag != "" {
+		return etag, nil
+	}
+	return res.Header.Get("Last-Modified"), nil
+}
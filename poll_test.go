@@ -0,0 +1,83 @@
+package gofigure
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memorySource is a Source whose document can be changed by tests
+type memorySource struct {
+	mu   sync.Mutex
+	data string
+}
+
+func (s *memorySource) Name() string {
+	return "memory"
+}
+
+func (s *memorySource) Fetch(ctx context.Context) (io.ReadCloser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return ioutil.NopCloser(strings.NewReader(s.data)), nil
+}
+
+func (s *memorySource) set(data string) {
+	s.mu.Lock()
+	s.data = data
+	s.mu.Unlock()
+}
+
+func TestPollMonitor(t *testing.T) {
+
+	var mu sync.Mutex
+	etag := `"v1"`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("ETag", etag)
+	}))
+	defer server.Close()
+
+	mem := &memorySource{data: "a: 1"}
+	var _ ReloadMonitor = NewSignalMonitor()
+	var m ReloadMonitor = NewPollMonitor(5*time.Millisecond, HTTPSource{URL: server.URL}, mem)
+
+	reloads := make(chan bool, 10)
+	m.Watch(ReloadFunc(func() { reloads <- true }))
+	defer m.Stop()
+
+	select {
+	case <-reloads:
+		t.Fatal("Unchanged sources should not trigger a reload")
+	case <-time.After(30 * time.Millisecond):
+	}
+
+	mem.set("a: 2")
+	select {
+	case <-reloads:
+	case <-time.After(time.Second):
+		t.Fatal("Changed document did not trigger a reload")
+	}
+
+	mu.Lock()
+	etag = `"v2"`
+	mu.Unlock()
+	select {
+	case <-reloads:
+	case <-time.After(time.Second):
+		t.Fatal("Changed ETag did not trigger a reload")
+	}
+
+	select {
+	case <-reloads:
+		t.Fatal("Expected a single reload per change")
+	case <-time.After(30 * time.Millisecond):
+	}
+}
@@ -0,0 +1,44 @@
+package gofigure
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// Preprocessor transforms the raw contents of a config file before it is decoded, e.g. to render
+// a template or decrypt it
+type Preprocessor interface {
+	Preprocess(path string, data []byte) ([]byte, error)
+}
+
+// PreprocessFunc is a convenience wrapper that lets us use a function as a Preprocessor
+type PreprocessFunc func(path string, data []byte) ([]byte, error)
+
+// Preprocess calls the underlying function
+func (f PreprocessFunc) Preprocess(path string, data []byte) ([]byte, error) {
+	return f(path, data)
+}
+
+// preprocess runs the contents of the file at path through the loader's preprocessors. Without
+// any, r is returned as is so the decoder can stream it
+func (l Loader) preprocess(path string, r io.Reader) (io.Reader, error) {
+
+	if len(l.Preprocessors) == 0 {
+		return r, nil
+	}
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range l.Preprocessors {
+		if data, err = p.Preprocess(path, data); err != nil {
+			return nil, fmt.Errorf("gofigure: preprocessing %s: %w", path, err)
+		}
+	}
+
+	return bytes.NewReader(data), nil
+}
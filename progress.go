@@ -0,0 +1,51 @@
+package gofigure
+
+import "os"
+
+// Progress is the progress of a load, passed to the loader's OnProgress hook to show a progress
+// bar when loading large trees from slow storage
+type Progress struct {
+	// Discovered is the number of files found so far that the loader reads. Files are found
+	// while the previous ones are decoded, so it keeps growing until the tree is walked
+	Discovered int
+
+	// Decoded is the number of files, and then of sources, decoded so far
+	Decoded int
+
+	// Bytes is the total size of the files decoded so far
+	Bytes int64
+
+	// Path is the file or source the update is about
+	Path string
+}
+
+// progress tracks the progress of a load and reports it to the loader's hook, if it has one
+type progress struct {
+	hook func(Progress)
+	Progress
+}
+
+// discovered records that the file at path was found
+func (p *progress) discovered(path string) {
+	if p.hook == nil {
+		return
+	}
+	p.Discovered++
+	p.Path = path
+	p.hook(p.Progress)
+}
+
+// decoded records that the file or source at path was decoded. Sources have no size
+func (p *progress) decoded(path string, isFile bool) {
+	if p.hook == nil {
+		return
+	}
+	if isFile {
+		if info, err := os.Stat(path); err == nil {
+			p.Bytes += info.Size()
+		}
+	}
+	p.Decoded++
+	p.Path = path
+	p.hook(p.Progress)
+}
@@ -0,0 +1,38 @@
+package gofigure
+
+import (
+	"testing"
+
+	"github.com/EverythingMe/gofigure/yaml"
+)
+
+func TestProgress(t *testing.T) {
+
+	var updates []Progress
+	loader := NewLoader(yaml.Decoder{}, true)
+	loader.Sources = []Source{BytesSource("defaults", []byte("redis:\n  server: default:6379\n"))}
+	loader.OnProgress = func(p Progress) {
+		updates = append(updates, p)
+	}
+
+	var conf config
+	report, err := loader.LoadWithReport(&conf, "./testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every file is discovered then decoded, then the source is decoded
+	files := len(report.Files) - 1
+	if len(updates) != 2*files+1 {
+		t.Fatalf("Expected %d updates, got %v", 2*files+1, updates)
+	}
+	last := updates[len(updates)-1]
+	if last.Discovered != files || last.Decoded != files+1 || last.Path != "defaults" || last.Bytes == 0 {
+		t.Errorf("Unexpected final progress: %+v", last)
+	}
+	for i := 1; i < len(updates); i++ {
+		if updates[i].Bytes < updates[i-1].Bytes || updates[i].Decoded < updates[i-1].Decoded {
+			t.Errorf("Progress went backwards: %+v", updates)
+		}
+	}
+}
@@ -0,0 +1,391 @@
+package gofigure
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/EverythingMe/gofigure/internal/tree"
+)
+
+// ErrKeyNotFound is returned when a dotted path leads to nothing in a config
+var ErrKeyNotFound = errors.New("gofigure: key not found")
+
+// Get returns the value at a dotted path like "server.tls.cert" in a loaded config, for tools that
+// don't know its type at compile time, like CLIs and admin handlers. Struct fields are matched by
+// the keys they are loaded from, as in the documents, map entries by their keys and list elements
+// by their indexes, e.g. "servers.0.host". The empty path returns config itself.
+//
+// The error wraps ErrKeyNotFound if there is nothing at path, including under nil pointers and
+// maps.
+func Get(config interface{}, path string) (interface{}, error) {
+	v, err := lookupValue(reflect.ValueOf(config), path)
+	if err != nil {
+		return nil, err
+	}
+	if !v.CanInterface() {
+		return nil, fmt.Errorf("gofigure: %s can't be read", path)
+	}
+	return v.Interface(), nil
+}
+
+// GetString returns the string at path in config, see Get. Values of other types are an error
+func GetString(config interface{}, path string) (string, error) {
+	v, err := lookupValue(reflect.ValueOf(config), path)
+	if err != nil {
+		return "", err
+	}
+	if v.Kind() != reflect.String {
+		return "", typeError(path, v, "a string")
+	}
+	return v.String(), nil
+}
+
+// GetInt returns the integer at path in config, see Get. Values of other types, and unsigned
+// integers that overflow an int64, are an error
+func GetInt(config interface{}, path string) (int64, error) {
+	v, err := lookupValue(reflect.ValueOf(config), path)
+	if err != nil {
+		return 0, err
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if u := v.Uint(); u <= 1<<63-1 {
+			return int64(u), nil
+		}
+	}
+	return 0, typeError(path, v, "an int64")
+}
+
+// GetFloat returns the number at path in config, see Get. Integers are converted, values of other
+// types are an error
+func GetFloat(config interface{}, path string) (float64, error) {
+	v, err := lookupValue(reflect.ValueOf(config), path)
+	if err != nil {
+		return 0, err
+	}
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), nil
+	}
+	return 0, typeError(path, v, "a number")
+}
+
+// GetBool returns the bool at path in config, see Get. Values of other types are an error
+func GetBool(config interface{}, path string) (bool, error) {
+	v, err := lookupValue(reflect.ValueOf(config), path)
+	if err != nil {
+		return false, err
+	}
+	if v.Kind() != reflect.Bool {
+		return false, typeError(path, v, "a bool")
+	}
+	return v.Bool(), nil
+}
+
+// GetDuration returns the time.Duration at path in config, see Get. Values of other types are an
+// error
+func GetDuration(config interface{}, path string) (time.Duration, error) {
+	v, err := lookupValue(reflect.ValueOf(config), path)
+	if err != nil {
+		return 0, err
+	}
+	if v.Type() != durationType {
+		return 0, typeError(path, v, "a duration")
+	}
+	return time.Duration(v.Int()), nil
+}
+
+// lookupValue walks a config value down a dotted path, through pointers and interfaces
+func lookupValue(v reflect.Value, path string) (reflect.Value, error) {
+
+	var walked string
+	for _, key := range tree.Split(path) {
+		v = indirectValue(v)
+		walked = joinPath(walked, key)
+		if !v.IsValid() {
+			return v, fmt.Errorf("%w: %s", ErrKeyNotFound, walked)
+		}
+
+		switch v.Kind() {
+		case reflect.Struct:
+			f, _ := structFields(v.Type()).match(key, MatchCaseInsensitive)
+			if f == nil {
+				return reflect.Value{}, fmt.Errorf("%w: %s", ErrKeyNotFound, walked)
+			}
+			var ok bool
+			if v, ok = fieldValue(v, f.index); !ok {
+				return reflect.Value{}, fmt.Errorf("%w: %s", ErrKeyNotFound, walked)
+			}
+
+		case reflect.Map:
+			k, err := mapKey(v.Type().Key(), key)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("gofigure: %s: %s", walked, err)
+			}
+			if v = v.MapIndex(k); !v.IsValid() {
+				return v, fmt.Errorf("%w: %s", ErrKeyNotFound, walked)
+			}
+
+		case reflect.Slice, reflect.Array:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= v.Len() {
+				return reflect.Value{}, fmt.Errorf("%w: %s", ErrKeyNotFound, walked)
+			}
+			v = v.Index(i)
+
+		default:
+			return reflect.Value{}, fmt.Errorf("%w: %s", ErrKeyNotFound, walked)
+		}
+	}
+
+	if v = indirectValue(v); !v.IsValid() {
+		return v, fmt.Errorf("%w: %s", ErrKeyNotFound, path)
+	}
+	return v, nil
+}
+
+// indirectValue follows pointers and interfaces to the value they hold, returning the zero Value
+// if one of them is nil
+func indirectValue(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// mapKey converts a path component to a key of type t, which can be a string or an integer
+func mapKey(t reflect.Type, key string) (reflect.Value, error) {
+	k := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.String:
+		k.SetString(key)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(key, 10, t.Bits())
+		if err != nil {
+			return k, err
+		}
+		k.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(key, 10, t.Bits())
+		if err != nil {
+			return k, err
+		}
+		k.SetUint(u)
+	default:
+		return k, fmt.Errorf("maps keyed by %s can't be looked up", t)
+	}
+	return k, nil
+}
+
+// typeError is the error of a typed getter finding a value of another type at path
+func typeError(path string, v reflect.Value, want string) error {
+	return fmt.Errorf("gofigure: %s is a %s, not %s", path, v.Type(), want)
+}
+
+// Set assigns value to the field, map entry or list element at a dotted path in the config that
+// config points to, the way Get finds it, for admin handlers and command line overrides that
+// change configs they don't know the type of. Nil pointers and maps on the way are allocated, and
+// entries missing from maps are added, but only if the value is set.
+//
+// The value must be of a type the destination accepts: one assignable to it, or one that loading
+// would bind to it, like a float64 of JSON for an int field, a string for a time.Duration or a
+// map[string]interface{} for a struct. Anything else is an error, as are integers that overflow
+// the destination, and the config is left untouched. See SetString to parse values from strings.
+func Set(config interface{}, path string, value interface{}) error {
+	return set(config, path, value, false)
+}
+
+// SetString is Set for values written as strings, like those of --set key=value flags. The string
+// is coerced to the type of the destination, as Loader.WeaklyTyped coerces values, so "8080" sets
+// an int and "true" a bool
+func SetString(config interface{}, path string, value string) error {
+	return set(config, path, value, true)
+}
+
+// Settings returns a Resolver setting the values of key=value settings, like those of command line
+// flags, in the merged config tree, on top of what the files and sources set. Appended last to a
+// loader's Resolvers, the settings are bound, normalized and validated with the rest of the config.
+//
+// Values are parsed for the fields of config, a config or a pointer to one, the way SetString
+// parses them, and settings it refuses are an error. As in documents, the elements of lists can't
+// be set one by one.
+func Settings(config interface{}, settings ...string) (Resolver, error) {
+
+	t := reflect.TypeOf(config)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil {
+		return nil, errors.New("gofigure: cannot parse settings for a nil config")
+	}
+
+	// every value is parsed into a scratch config, and dumped back the way it would be written in a
+	// document
+	scratch := reflect.New(t)
+	doc := map[string]interface{}{}
+	for _, setting := range settings {
+		kv := strings.SplitN(setting, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("gofigure: setting %q is not key=value", setting)
+		}
+		if err := SetString(scratch.Interface(), kv[0], kv[1]); err != nil {
+			return nil, fmt.Errorf("gofigure: setting %s: %s", setting, err)
+		}
+		v, err := lookupValue(scratch.Elem(), kv[0])
+		if err != nil && !errors.Is(err, ErrKeyNotFound) {
+			return nil, err
+		}
+		tree.Place(doc, kv[0], dump(v, false))
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("gofigure: settings: %s", err)
+	}
+	var values map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&values); err != nil {
+		return nil, fmt.Errorf("gofigure: settings: %s", err)
+	}
+
+	return ResolveFunc(func(merged map[string]interface{}) error {
+		tree.Merge(merged, tree.Copy(values).(map[string]interface{}))
+		return nil
+	}), nil
+}
+
+// set implements Set and SetString
+func set(config interface{}, path string, value interface{}, weak bool) error {
+	v := reflect.ValueOf(config)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("gofigure: cannot set into non-pointer %T", config)
+	}
+
+	b := &binder{weak: weak}
+	return setValue(v.Elem(), tree.Split(path), "", func(dst reflect.Value, walked string) error {
+		sv := reflect.ValueOf(value)
+		if value != nil && sv.Type().AssignableTo(dst.Type()) {
+			dst.Set(sv)
+			return nil
+		}
+
+		// dst is only changed once the value is bound successfully
+		tmp := reflect.New(dst.Type()).Elem()
+		if err := b.bind(widen(value), tmp, walked); err != nil {
+			return err
+		}
+		dst.Set(tmp)
+		return nil
+	})
+}
+
+// setValue walks v down keys, allocating what it needs to, and calls assign with the value at
+// their end. Map entries and interfaces aren't settable in place, so they are copied, changed and
+// stored back. walked is the path walked to v
+func setValue(v reflect.Value, keys []string, walked string, assign func(reflect.Value, string) error) error {
+
+	if len(keys) == 0 {
+		return assign(v, walked)
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			return setValue(v.Elem(), keys, walked, assign)
+		}
+		p := reflect.New(v.Type().Elem())
+		if err := setValue(p.Elem(), keys, walked, assign); err != nil {
+			return err
+		}
+		v.Set(p)
+		return nil
+
+	case reflect.Interface:
+		if v.IsNil() {
+			break
+		}
+		elem := reflect.New(v.Elem().Type()).Elem()
+		elem.Set(v.Elem())
+		if err := setValue(elem, keys, walked, assign); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+
+	case reflect.Struct:
+		key := keys[0]
+		f, _ := structFields(v.Type()).match(key, MatchCaseInsensitive)
+		if f == nil {
+			return fmt.Errorf("%w: %s", ErrKeyNotFound, joinPath(walked, key))
+		}
+		fv, err := fieldByIndex(v, f.index)
+		if err != nil {
+			return fmt.Errorf("gofigure: %s: %s", joinPath(walked, key), err)
+		}
+		return setValue(fv, keys[1:], joinPath(walked, key), assign)
+
+	case reflect.Map:
+		key := keys[0]
+		k, err := mapKey(v.Type().Key(), key)
+		if err != nil {
+			return fmt.Errorf("gofigure: %s: %s", joinPath(walked, key), err)
+		}
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if existing := v.MapIndex(k); existing.IsValid() {
+			elem.Set(existing)
+		}
+		if err := setValue(elem, keys[1:], joinPath(walked, key), assign); err != nil {
+			return err
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		v.SetMapIndex(k, elem)
+		return nil
+
+	case reflect.Slice, reflect.Array:
+		key := keys[0]
+		i, err := strconv.Atoi(key)
+		if err != nil || i < 0 || i >= v.Len() {
+			return fmt.Errorf("%w: %s", ErrKeyNotFound, joinPath(walked, key))
+		}
+		return setValue(v.Index(i), keys[1:], joinPath(walked, key), assign)
+	}
+
+	return fmt.Errorf("%w: %s", ErrKeyNotFound, joinPath(walked, keys[0]))
+}
+
+// widen converts Go numbers of any size to the int64, uint64 and float64 of generic documents, and
+// strings and bools of named types to plain ones, so they can be bound like decoded values
+func widen(value interface{}) interface{} {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint()
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	}
+	return value
+}
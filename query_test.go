@@ -0,0 +1,154 @@
+package gofigure
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/EverythingMe/gofigure/yaml"
+)
+
+func TestGet(t *testing.T) {
+
+	type server struct {
+		Host    string        `yaml:"host"`
+		Port    uint16        `yaml:"port"`
+		Timeout time.Duration `yaml:"timeout"`
+	}
+	conf := struct {
+		Servers []server           `yaml:"servers"`
+		Primary *server            `yaml:"primary"`
+		Backup  *server            `yaml:"backup"`
+		Weights map[string]float64 `yaml:"weights"`
+		Debug   bool               `yaml:"debug"`
+	}{
+		Servers: []server{{Host: "a", Port: 80, Timeout: time.Second}},
+		Primary: &server{Host: "p"},
+		Weights: map[string]float64{"a": 0.5},
+		Debug:   true,
+	}
+
+	if v, err := Get(&conf, "servers.0.host"); err != nil || v != "a" {
+		t.Errorf("Unexpected servers.0.host %v %v", v, err)
+	}
+	if s, err := GetString(&conf, "primary.host"); err != nil || s != "p" {
+		t.Errorf("Unexpected primary.host %q %v", s, err)
+	}
+	if i, err := GetInt(&conf, "servers.0.port"); err != nil || i != 80 {
+		t.Errorf("Unexpected servers.0.port %d %v", i, err)
+	}
+	if f, err := GetFloat(&conf, "weights.a"); err != nil || f != 0.5 {
+		t.Errorf("Unexpected weights.a %v %v", f, err)
+	}
+	if b, err := GetBool(&conf, "Debug"); err != nil || !b {
+		t.Errorf("Unexpected debug %v %v", b, err)
+	}
+	if d, err := GetDuration(&conf, "servers.0.timeout"); err != nil || d != time.Second {
+		t.Errorf("Unexpected servers.0.timeout %v %v", d, err)
+	}
+
+	for _, path := range []string{"backup.host", "servers.1.host", "weights.b", "primary.missing", "debug.x"} {
+		if _, err := Get(&conf, path); !errors.Is(err, ErrKeyNotFound) {
+			t.Errorf("Expected %s not to be found, got %v", path, err)
+		}
+	}
+	if _, err := GetInt(&conf, "primary.host"); err == nil || errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected a type error reading a string as an int, got %v", err)
+	}
+}
+
+func TestSet(t *testing.T) {
+
+	type server struct {
+		Host    string        `yaml:"host"`
+		Port    int           `yaml:"port"`
+		Timeout time.Duration `yaml:"timeout"`
+	}
+	var conf struct {
+		Primary *server           `yaml:"primary"`
+		Servers map[string]server `yaml:"servers"`
+		Tags    []string          `yaml:"tags"`
+	}
+
+	if err := Set(&conf, "primary.host", "a"); err != nil || conf.Primary == nil || conf.Primary.Host != "a" {
+		t.Errorf("Unexpected primary %+v %v", conf.Primary, err)
+	}
+	if err := Set(&conf, "primary.port", float64(8080)); err != nil || conf.Primary.Port != 8080 {
+		t.Errorf("Unexpected primary port %+v %v", conf.Primary, err)
+	}
+	if err := Set(&conf, "servers.b.timeout", "2s"); err != nil || conf.Servers["b"].Timeout != 2*time.Second {
+		t.Errorf("Unexpected servers %+v %v", conf.Servers, err)
+	}
+	if err := SetString(&conf, "servers.b.port", "81"); err != nil || conf.Servers["b"].Port != 81 {
+		t.Errorf("Unexpected servers %+v %v", conf.Servers, err)
+	}
+	if err := Set(&conf, "tags", []string{"x"}); err != nil || len(conf.Tags) != 1 {
+		t.Errorf("Unexpected tags %v %v", conf.Tags, err)
+	}
+
+	if err := Set(&conf, "primary.port", "8080"); err == nil || conf.Primary.Port != 8080 {
+		t.Errorf("Expected a string not to be set into an int, got %v", err)
+	}
+	if err := Set(&conf, "primary.port", 1.5); err == nil {
+		t.Error("Expected a fraction not to be set into an int")
+	}
+	if err := Set(&conf, "primary.missing", 1); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected an unknown key not to be found, got %v", err)
+	}
+	if err := Set(&conf, "servers.c.port", "x"); err == nil || len(conf.Servers) != 1 {
+		t.Errorf("Expected a failed set not to add map entries, got %v %v", conf.Servers, err)
+	}
+	if err := Set(conf, "tags", nil); err == nil {
+		t.Error("Expected an error setting into a non-pointer")
+	}
+}
+
+func TestSettings(t *testing.T) {
+
+	type server struct {
+		Host    string        `yaml:"host"`
+		Port    int           `yaml:"port" range:"max=65535"`
+		Timeout time.Duration `yaml:"timeout"`
+	}
+	type settingsConfig struct {
+		Primary server            `yaml:"primary"`
+		Servers map[string]server `yaml:"servers"`
+	}
+
+	settings, err := Settings(&settingsConfig{}, "primary.port=8080", "primary.timeout=2s", "servers.b.port=81")
+	if err != nil {
+		t.Fatal(err)
+	}
+	loader := NewLoader(yaml.Decoder{}, true)
+	loader.Resolvers = []Resolver{settings}
+
+	var conf settingsConfig
+	doc := []byte("primary: {host: a, port: 80}\nservers: {b: {host: b}}\n")
+	if err := loader.MergeDocuments([][]byte{doc}, &conf); err != nil {
+		t.Fatal(err)
+	}
+	expected := settingsConfig{
+		Primary: server{Host: "a", Port: 8080, Timeout: 2 * time.Second},
+		Servers: map[string]server{"b": {Host: "b", Port: 81}},
+	}
+	if !reflect.DeepEqual(conf, expected) {
+		t.Errorf("Unexpected config %+v", conf)
+	}
+
+	// settings are validated with the config
+	settings, err = Settings(&settingsConfig{}, "primary.port=70000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	loader.Resolvers = []Resolver{settings}
+	if err := loader.MergeDocuments([][]byte{doc}, &settingsConfig{}); err == nil {
+		t.Error("Expected a setting out of range to fail validation")
+	}
+
+	for _, bad := range []string{"primary.port=x", "primary.missing=1", "primary.port"} {
+		if _, err := Settings(&settingsConfig{}, bad); err == nil {
+			t.Errorf("Expected an error for setting %s", bad)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package gofigure
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// Registry routes the top-level sections of the configuration to the structs registered for them.
+// It lets every module of a large program own its piece of the configuration: each one registers
+// its struct under a section name, and a single load fills them all.
+//
+// Third-party libraries can be configured the same way as the program using them. A gofigure-aware
+// library registers its config struct in the DefaultRegistry from an init function, under a
+// section named after it, with a function checking the loaded values:
+//
+//	package ratelimit
+//
+//	var Config struct {
+//		Rate  int           `yaml:"rate"`
+//		Burst int           `yaml:"burst"`
+//		Every time.Duration `yaml:"every"`
+//	}
+//
+//	func init() {
+//		gofigure.RegisterSection("ratelimit", &Config, func() error {
+//			if Config.Rate <= 0 {
+//				return errors.New("rate must be positive")
+//			}
+//			return nil
+//		})
+//	}
+//
+// and the program loads the sections of all the libraries it imports, next to its own config, with
+// LoadAll or DefaultRegistry.Load. Section names can be dotted paths, like "libs.ratelimit", to
+// keep the sections of libraries apart.
+type Registry struct {
+	mu       sync.Mutex
+	sections map[string]section
+}
+
+// section is a struct registered for a section, with the function validating it once loaded
+type section struct {
+	target   interface{}
+	validate func() error
+}
+
+// DefaultRegistry is the registry used by the package level Register and LoadAll
+var DefaultRegistry = NewRegistry()
+
+// NewRegistry creates a new empty section registry
+func NewRegistry() *Registry {
+	return &Registry{
+		sections: map[string]section{},
+	}
+}
+
+// Register makes the top-level section name of the configuration be decoded into target, which
+// must be a pointer. It panics if the section is already registered, or if target is not a
+// pointer, as these are programming errors usually made in init functions
+func (r *Registry) Register(name string, target interface{}) {
+	r.RegisterSection(name, target, nil)
+}
+
+// RegisterSection is Register, with a function validating target after every load of the
+// section, if validate isn't nil. Its errors fail strict loads as ValidationErrors, and are
+// reported otherwise. It's called while the registry is locked, so it must not use the registry
+func (r *Registry) RegisterSection(name string, target interface{}, validate func() error) {
+	if v := reflect.ValueOf(target); v.Kind() != reflect.Ptr || v.IsNil() {
+		panic(fmt.Sprintf("gofigure: cannot register non-pointer %T for section %s", target, name))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, found := r.sections[name]; found {
+		panic("gofigure: section registered twice: " + name)
+	}
+	r.sections[name] = section{target, validate}
+}
+
+// Sections returns the names of all registered sections, sorted
+func (r *Registry) Sections() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names := make([]string, 0, len(r.sections))
+	for name := range r.sections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Load uses the loader l to read and merge all the files under paths, and then decodes each
+// top-level section into the struct registered for it. Sections nothing is registered for are
+// ignored, and registered structs whose section is missing are left untouched.
+//
+// In strict mode loading stops at the first section that cannot be decoded, otherwise the error is
+// logged and the other sections are still loaded.
+func (r *Registry) Load(l *Loader, paths ...string) error {
+
+	report := l.newReport()
+	docs, err := l.loadDocuments(report, paths...)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for name, s := range r.sections {
+		if err := l.bindDocuments(report, docs, name, s.target); err != nil {
+			log.Info("Error loading section %s: %s", name, err)
+			if l.strictFor(err) {
+				return err
+			}
+			report.warn(Warning{Key: name, Message: "section skipped: " + err.Error(), Err: err})
+			continue
+		}
+
+		if s.validate == nil {
+			continue
+		}
+		if err := s.validate(); err != nil {
+			err = fmt.Errorf("gofigure: section %s is invalid: %w", name, err)
+			if l.strict(ValidationErrors) {
+				return err
+			}
+			report.warn(Warning{Key: name, Message: err.Error(), Err: err})
+		}
+	}
+
+	return nil
+}
+
+// Register registers target for the top-level section name in the DefaultRegistry
+func Register(name string, target interface{}) {
+	DefaultRegistry.Register(name, target)
+}
+
+// RegisterSection registers target for the section name in the DefaultRegistry, with a function
+// validating it, see Registry.RegisterSection
+func RegisterSection(name string, target interface{}, validate func() error) {
+	DefaultRegistry.RegisterSection(name, target, validate)
+}
+
+// LoadAll loads all of the DefaultRegistry's sections from the files under paths, using the
+// DefaultLoader
+func LoadAll(paths ...string) error {
+	return DefaultRegistry.Load(DefaultLoader, paths...)
+}
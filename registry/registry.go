@@ -0,0 +1,77 @@
+// Package registry provides a gofigure source reading config from a subtree of the Windows
+// registry, for services on Windows fleets whose config is managed with group policies.
+//
+// The subkeys of the source's key are nested mappings, like the subdirectories of a config
+// directory, and its values are the keys of those mappings:
+//
+//	HKLM\SOFTWARE\Policies\Example\App
+//	    Server   REG_SZ     api.example.com     ->  server: api.example.com
+//	    Redis
+//	        Port REG_DWORD  6379                ->  redis: {port: 6379}
+//
+// Strings, expandable strings (with environment variables expanded), DWORDs and QWORDs are
+// scalars, multi-strings are lists of strings, and binary values are base64 strings. The unnamed
+// default values of keys are ignored.
+//
+// On other platforms, fetching a registry source always fails.
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+
+	"github.com/EverythingMe/gofigure"
+)
+
+// Root is a predefined registry key, the root of the path of a source
+type Root int
+
+// The predefined keys, HKEY_LOCAL_MACHINE and so on
+const (
+	LocalMachine Root = iota
+	CurrentUser
+	Users
+	ClassesRoot
+	CurrentConfig
+)
+
+// rootNames are the abbreviations roots are named by
+var rootNames = map[Root]string{
+	LocalMachine:  "HKLM",
+	CurrentUser:   "HKCU",
+	Users:         "HKU",
+	ClassesRoot:   "HKCR",
+	CurrentConfig: "HKCC",
+}
+
+// Source is a gofigure.Source reading the registry subtree at Path under Root, e.g.
+// Source{LocalMachine, `SOFTWARE\Policies\Example\App`}. A missing key is a permanent failure
+type Source struct {
+	Root Root
+	Path string
+}
+
+// Name returns the source's key, e.g. HKLM\SOFTWARE\Policies\Example\App
+func (s Source) Name() string {
+	return rootNames[s.Root] + `\` + s.Path
+}
+
+// Fetch reads the subtree
+func (s Source) Fetch(ctx context.Context) (io.ReadCloser, error) {
+	doc, err := readTree(s.Root, s.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, gofigure.Permanent(err)
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+// maxDepth bounds how deep a subtree is read
+const maxDepth = 32
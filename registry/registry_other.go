@@ -0,0 +1,14 @@
+//go:build !windows
+
+package registry
+
+import (
+	"errors"
+
+	"github.com/EverythingMe/gofigure"
+)
+
+// readTree always fails on platforms without a registry
+func readTree(root Root, path string) (map[string]interface{}, error) {
+	return nil, gofigure.Permanent(errors.New("registry: only supported on Windows"))
+}
@@ -0,0 +1,54 @@
+//go:build windows
+
+package registry
+
+import (
+	"testing"
+
+	"github.com/EverythingMe/gofigure"
+	"github.com/EverythingMe/gofigure/json"
+	"golang.org/x/sys/windows/registry"
+)
+
+func TestSource(t *testing.T) {
+
+	const path = `Software\gofigure-test`
+	k, _, err := registry.CreateKey(registry.CURRENT_USER, path, registry.ALL_ACCESS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer registry.DeleteKey(registry.CURRENT_USER, path)
+	defer k.Close()
+
+	redis, _, err := registry.CreateKey(k, "Redis", registry.ALL_ACCESS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer registry.DeleteKey(k, "Redis")
+	defer redis.Close()
+
+	k.SetStringValue("Server", "api.example.com")
+	k.SetStringsValue("Tags", []string{"a", "b"})
+	redis.SetDWordValue("Port", 6379)
+
+	var conf struct {
+		Server string
+		Tags   []string
+		Redis  struct {
+			Port int
+		}
+	}
+	loader := gofigure.NewLoader(json.Decoder{}, true)
+	loader.Sources = []gofigure.Source{Source{CurrentUser, path}}
+	if err := loader.LoadRecursive(&conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Server != "api.example.com" || len(conf.Tags) != 2 || conf.Redis.Port != 6379 {
+		t.Errorf("Unexpected config %#v", conf)
+	}
+
+	loader.Sources = []gofigure.Source{Source{CurrentUser, `Software\gofigure-missing`}}
+	if err := loader.LoadRecursive(&conf); err == nil {
+		t.Error("Expected an error for a missing key")
+	}
+}
@@ -0,0 +1,107 @@
+//go:build windows
+
+package registry
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"github.com/EverythingMe/gofigure"
+	"golang.org/x/sys/windows/registry"
+)
+
+// roots are the registry keys of the roots
+var roots = map[Root]registry.Key{
+	LocalMachine:  registry.LOCAL_MACHINE,
+	CurrentUser:   registry.CURRENT_USER,
+	Users:         registry.USERS,
+	ClassesRoot:   registry.CLASSES_ROOT,
+	CurrentConfig: registry.CURRENT_CONFIG,
+}
+
+// readTree reads the subtree at path under root
+func readTree(root Root, path string) (map[string]interface{}, error) {
+	parent, found := roots[root]
+	if !found {
+		return nil, gofigure.Permanent(fmt.Errorf("registry: unknown root %d", root))
+	}
+
+	k, err := registry.OpenKey(parent, path, registry.READ)
+	if err == registry.ErrNotExist {
+		return nil, gofigure.Permanent(fmt.Errorf("registry: %s\\%s not found", rootNames[root], path))
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer k.Close()
+
+	return readKey(k, 0)
+}
+
+// readKey reads the values and subkeys of an open key
+func readKey(k registry.Key, depth int) (map[string]interface{}, error) {
+
+	if depth >= maxDepth {
+		return nil, gofigure.Permanent(fmt.Errorf("registry: keys nested too deep"))
+	}
+
+	m := map[string]interface{}{}
+
+	names, err := k.ReadValueNames(-1)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		if m[name], err = readValue(k, name); err != nil {
+			return nil, fmt.Errorf("registry: %s: %s", name, err)
+		}
+	}
+
+	subkeys, err := k.ReadSubKeyNames(-1)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range subkeys {
+		sub, err := registry.OpenKey(k, name, registry.READ)
+		if err != nil {
+			return nil, fmt.Errorf("registry: %s: %s", name, err)
+		}
+		m[name], err = readKey(sub, depth+1)
+		sub.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// readValue reads a value as a generic document value
+func readValue(k registry.Key, name string) (interface{}, error) {
+
+	_, valtype, err := k.GetValue(name, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	switch valtype {
+	case registry.SZ, registry.EXPAND_SZ:
+		s, _, err := k.GetStringValue(name)
+		if err == nil && valtype == registry.EXPAND_SZ {
+			s, err = registry.ExpandString(s)
+		}
+		return s, err
+	case registry.DWORD, registry.QWORD:
+		i, _, err := k.GetIntegerValue(name)
+		return i, err
+	case registry.MULTI_SZ:
+		ss, _, err := k.GetStringsValue(name)
+		return ss, err
+	case registry.BINARY:
+		data, _, err := k.GetBinaryValue(name)
+		return base64.StdEncoding.EncodeToString(data), err
+	}
+	return nil, fmt.Errorf("unsupported value type %d", valtype)
+}
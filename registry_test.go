@@ -0,0 +1,93 @@
+package gofigure
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/EverythingMe/gofigure/json"
+)
+
+func TestRegistry(t *testing.T) {
+
+	var redis redisConfig
+	var mysql mysqlConfig
+	var other struct{ Foo string }
+
+	r := NewRegistry()
+	r.Register("redis", &redis)
+	r.Register("mysql", &mysql)
+	r.Register("other", &other)
+
+	if err := r.Load(NewLoader(json.Decoder{}, true), "./testdata"); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(redis, expectedConf.Redis) {
+		t.Errorf("Redis section not as expected: %v", redis)
+	}
+	if !reflect.DeepEqual(mysql, expectedConf.Mysql) {
+		t.Errorf("Mysql section not as expected: %v", mysql)
+	}
+
+	if s := r.Sections(); !reflect.DeepEqual(s, []string{"mysql", "other", "redis"}) {
+		t.Errorf("Unexpected sections: %v", s)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Expected registering a section twice to panic")
+			}
+		}()
+		r.Register("redis", &redis)
+	}()
+}
+
+func TestRegistryStrictness(t *testing.T) {
+
+	var redis struct {
+		Server int `json:"server"`
+	}
+	var mysql mysqlConfig
+
+	r := NewRegistry()
+	r.Register("redis", &redis)
+	r.Register("mysql", &mysql)
+
+	if err := r.Load(NewLoader(json.Decoder{}, true), "./testdata"); err == nil {
+		t.Errorf("Expected a bad section to fail a strict load")
+	}
+
+	if err := r.Load(NewLoader(json.Decoder{}, false), "./testdata"); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(mysql, expectedConf.Mysql) {
+		t.Errorf("Good sections should still load in lenient mode: %v", mysql)
+	}
+}
+
+func TestRegisterSection(t *testing.T) {
+
+	var redis redisConfig
+	validated := 0
+	r := NewRegistry()
+	r.RegisterSection("redis", &redis, func() error {
+		validated++
+		if redis.Timeout > 5 {
+			return errors.New("timeout too long")
+		}
+		return nil
+	})
+
+	loader := NewLoader(json.Decoder{}, true)
+	if err := r.Load(loader, "./testdata"); err == nil || !strings.Contains(err.Error(), "timeout too long") {
+		t.Errorf("Expected the section's validation to fail a strict load, got %v", err)
+	}
+
+	loader.StrictMode = false
+	if err := r.Load(loader, "./testdata"); err != nil || validated != 2 || redis.Server != expectedConf.Redis.Server {
+		t.Errorf("Expected an invalid section to be reported outside of strict mode, got %v %+v", err, redis)
+	}
+}
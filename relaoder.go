@@ -20,7 +20,7 @@ func (f ReloadFunc) Reload() {
 }
 
 // ReloadMonitor is an interface for waiting for external notifications that we need to reload our configs.
-// The only implementation right now is a SIGHUP listener
+// The implementations are a SIGHUP listener, and a poller for remote sources
 type ReloadMonitor interface {
 	Watch(Reloader)
 	Stop()
@@ -44,6 +44,7 @@ func (m *SignalMonitor) Monitor(r Reloader) {
 	go func() {
 		sigch := make(chan os.Signal, 1)
 		signal.Notify(sigch, syscall.SIGHUP)
+		defer signal.Stop(sigch)
 		for {
 			// Block until a signal is received.
 
@@ -54,14 +55,17 @@ func (m *SignalMonitor) Monitor(r Reloader) {
 				r.Reload()
 			case <-m.stopch:
 				log.Info("Stopping reload listener")
-				break
+				return
 			}
 		}
-		signal.Stop(sigch)
-
 	}()
 }
 
+// Watch is Monitor, making SignalMonitor a ReloadMonitor
+func (m *SignalMonitor) Watch(r Reloader) {
+	m.Monitor(r)
+}
+
 // Stop stops the signal monitor
 func (m *SignalMonitor) Stop() {
 	m.stopch <- true
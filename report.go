@@ -0,0 +1,95 @@
+package gofigure
+
+import (
+	"fmt"
+)
+
+// Report describes a load: what was read, and the problems that did not make it fail
+type Report struct {
+	// Files are the files that were decoded, in the order they were merged, followed by the names
+	// of the loader's sources
+	Files []string
+
+	// Cached is true if the config was filled from the loader's cache rather than by decoding
+	Cached bool
+
+	// Warnings are the problems found while loading that did not make it fail
+	Warnings []Warning
+
+	// Origins maps the dotted path of every value that was loaded to the file or source it came
+	// from, i.e. the last one that set it
+	Origins map[string]string
+
+	// Version is the version of the loaded config, see Loader.Version. It's only set by
+	// LoadWithReport and LoadRecursive
+	Version string
+
+	// onWarning is the loader's OnWarning hook
+	onWarning func(Warning)
+}
+
+// Warning is a problem found while loading that did not make it fail, like the use of a
+// deprecated key, or a value skipped outside of strict mode
+type Warning struct {
+	// Key is the dotted path of the key the warning is about, if any
+	Key string
+
+	// Source is the file the key was found in, if known
+	Source string
+
+	// Message describes the problem
+	Message string
+
+	// Err is the error that was skipped, for warnings about errors that don't fail loads outside
+	// of strict mode
+	Err error
+}
+
+func (w Warning) String() string {
+	if w.Source == "" {
+		return w.Message
+	}
+	return fmt.Sprintf("%s: %s", w.Source, w.Message)
+}
+
+// warn logs a warning, adds it to the report and passes it to the loader's hook. Reports can be
+// nil when the caller doesn't care about them, in which case the warning is only logged
+func (r *Report) warn(w Warning) {
+	log.Warning("%s", w)
+	if r == nil {
+		return
+	}
+	r.Warnings = append(r.Warnings, w)
+	if r.onWarning != nil {
+		r.onWarning(w)
+	}
+}
+
+// newReport returns a report for a load, passing its warnings to the loader's hook
+func (l Loader) newReport() *Report {
+	return &Report{onWarning: l.OnWarning}
+}
+
+// origin records source as the origin of v and everything under it. Mappings are descended into,
+// other values, lists included, are recorded as a whole
+func (r *Report) origin(source string, v interface{}, path string) {
+	if r == nil {
+		return
+	}
+
+	m, ok := v.(map[string]interface{})
+	if !ok || len(m) == 0 {
+		if r.Origins == nil {
+			r.Origins = map[string]string{}
+		}
+		r.Origins[path] = source
+		return
+	}
+	for k, e := range m {
+		p := k
+		if path != "" {
+			p = path + "." + k
+		}
+		r.origin(source, e, p)
+	}
+}
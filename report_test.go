@@ -0,0 +1,124 @@
+package gofigure
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/EverythingMe/gofigure/yaml"
+)
+
+func TestLoadReport(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gofigure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"a.yaml": "server:\n  host: localhost\n  port: 80\n",
+		"b.yaml": "server:\n  addr: localhost:8080\n  timeout_secs: 5\n  workers: [{threads: 2}]\n",
+	}
+	for name, contents := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	type worker struct {
+		Threads int `yaml:"threads" deprecated:"threads are sized automatically"`
+	}
+	var conf struct {
+		Server struct {
+			Addr    string   `yaml:"addr"`
+			Host    string   `yaml:"host" deprecated:"use addr instead"`
+			Port    int      `yaml:"port" deprecated:"use addr instead"`
+			Timeout int      `gofigure:"timeout,alias=timeout_secs"`
+			Workers []worker `yaml:"workers"`
+		} `yaml:"server"`
+	}
+
+	report, err := NewLoader(yaml.Decoder{}, true).LoadWithReport(&conf, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if conf.Server.Host != "localhost" || conf.Server.Timeout != 5 || conf.Server.Workers[0].Threads != 2 {
+		t.Errorf("Deprecated fields should still be loaded: %#v", conf)
+	}
+	if len(report.Files) != 2 || report.Cached {
+		t.Errorf("Unexpected files in report: %v", report.Files)
+	}
+
+	expected := map[string]string{
+		"server.host":               filepath.Join(dir, "a.yaml"),
+		"server.port":               filepath.Join(dir, "a.yaml"),
+		"server.timeout_secs":       filepath.Join(dir, "b.yaml"),
+		"server.workers[0].threads": filepath.Join(dir, "b.yaml"),
+	}
+	if len(report.Warnings) != len(expected) {
+		t.Errorf("Expected %d warnings, got %v", len(expected), report.Warnings)
+	}
+	for _, w := range report.Warnings {
+		if source, found := expected[w.Key]; !found || source != w.Source {
+			t.Errorf("Unexpected warning %#v", w)
+		}
+	}
+}
+
+func TestLoadReportLenient(t *testing.T) {
+
+	var conf struct {
+		Port int `yaml:"port"`
+	}
+
+	report := &Report{}
+	if err := (Loader{}).bind(report, map[string]interface{}{"port": "http"}, &conf); err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Warnings) != 1 || report.Warnings[0].Key != "port" {
+		t.Errorf("Skipped value not reported: %v", report.Warnings)
+	}
+}
+
+func TestOnWarning(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gofigure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeFiles(t, dir, map[string]string{
+		"good.yaml":   "redis:\n  server: good:6379\n",
+		"broken.yaml": "redis: [\n",
+	})
+
+	var warnings []Warning
+	loader := NewLoader(yaml.Decoder{}, false)
+	loader.OnWarning = func(w Warning) {
+		warnings = append(warnings, w)
+	}
+
+	var conf config
+	if err := loader.LoadRecursive(&conf, dir); err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 1 || warnings[0].Source != filepath.Join(dir, "broken.yaml") || warnings[0].Err == nil {
+		t.Errorf("Skipped file not passed to the hook: %v", warnings)
+	}
+
+	// loads without a report pass their warnings too
+	warnings = nil
+	if _, err := loader.LoadMap(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := loader.LoadFile(&conf, filepath.Join(dir, "broken.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 2 {
+		t.Errorf("Expected 2 warnings, got %v", warnings)
+	}
+}
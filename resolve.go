@@ -0,0 +1,15 @@
+package gofigure
+
+// Resolver rewrites the merged config tree before it is bound to the config struct, e.g. to
+// compute derived values or fetch secrets. Resolvers run in order, after references are expanded
+type Resolver interface {
+	Resolve(tree map[string]interface{}) error
+}
+
+// ResolveFunc is a convenience wrapper that lets us use a function as a Resolver
+type ResolveFunc func(tree map[string]interface{}) error
+
+// Resolve calls the underlying function
+func (f ResolveFunc) Resolve(tree map[string]interface{}) error {
+	return f(tree)
+}
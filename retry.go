@@ -0,0 +1,111 @@
+package gofigure
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy describes how fetching a remote source is retried when it fails, so a transient
+// network blip doesn't fail a whole load. Attempts are spaced by an exponential backoff with
+// jitter, and can each be given a timeout
+type RetryPolicy struct {
+	// Attempts is the maximum number of attempts, including the first one. Zero or one means no
+	// retries
+	Attempts int
+
+	// Backoff is the delay before the first retry, 100ms if zero
+	Backoff time.Duration
+
+	// MaxBackoff caps the delay between attempts, 10s if zero
+	MaxBackoff time.Duration
+
+	// Multiplier is what the delay is multiplied by after every retry, 2 if zero
+	Multiplier float64
+
+	// Jitter randomizes every delay by up to this fraction of it, in either direction. It should
+	// be between 0 and 1
+	Jitter float64
+
+	// Timeout, if set, limits how long every attempt can take
+	Timeout time.Duration
+}
+
+// permanentError marks an error that retrying won't fix
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent wraps an error returned by a Source to mark it as permanent, so it isn't retried. Nil
+// errors stay nil
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err}
+}
+
+// do calls fn until it succeeds, returns a permanent error, or runs out of attempts. name is used
+// in logs
+func (p RetryPolicy) do(ctx context.Context, name string, fn func(ctx context.Context) error) error {
+
+	delay := p.Backoff
+	if delay == 0 {
+		delay = 100 * time.Millisecond
+	}
+	maxDelay := p.MaxBackoff
+	if maxDelay == 0 {
+		maxDelay = 10 * time.Second
+	}
+	multiplier := p.Multiplier
+	if multiplier == 0 {
+		multiplier = 2
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := p.attempt(ctx, fn)
+
+		var permanent permanentError
+		if errors.As(err, &permanent) {
+			return permanent.err
+		}
+		if err == nil || attempt >= p.Attempts {
+			return err
+		}
+
+		wait := delay
+		if p.Jitter > 0 {
+			wait += time.Duration((rand.Float64()*2 - 1) * p.Jitter * float64(delay))
+		}
+		log.Info("Attempt %d at %s failed, retrying in %s: %s", attempt, name, wait, err)
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		if delay = time.Duration(float64(delay) * multiplier); delay > maxDelay {
+			delay = maxDelay
+		}
+	}
+}
+
+// attempt calls fn once, within the policy's timeout
+func (p RetryPolicy) attempt(ctx context.Context, fn func(ctx context.Context) error) error {
+	if p.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.Timeout)
+		defer cancel()
+	}
+	return fn(ctx)
+}
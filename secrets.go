@@ -0,0 +1,59 @@
+package gofigure
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SecretFunc resolves the reference to a secret, like an encrypted blob or the path of a secret
+// in a vault, to the secret's value
+type SecretFunc func(ref string) (string, error)
+
+// Secrets is a Resolver replacing inline secret references in string values with the secrets they
+// refer to, so that only the secrets of a config need to be encrypted rather than whole files.
+//
+// It maps schemes to the functions resolving their references. A string value is a reference if
+// it has the form "ENC[scheme:ref]" or "scheme:ref" for one of the schemes, e.g.
+// "ENC[kms:AQICAHh...]" or "vault:secret/db#password"; the function of the scheme gets the ref part.
+// Every distinct reference is only resolved once per load.
+type Secrets map[string]SecretFunc
+
+// Resolve replaces the secret references in the merged config t
+func (s Secrets) Resolve(t map[string]interface{}) error {
+	cache := map[string]string{}
+	_, err := interpolate(t, func(v string) (interface{}, error) {
+		return s.resolve(v, cache)
+	}, "")
+	if err != nil {
+		return fmt.Errorf("gofigure: resolving secret at %s", err)
+	}
+	return nil
+}
+
+// resolve returns the secret a string value refers to, or the value itself if it isn't a reference
+func (s Secrets) resolve(v string, cache map[string]string) (string, error) {
+
+	ref := v
+	if strings.HasPrefix(ref, "ENC[") && strings.HasSuffix(ref, "]") {
+		ref = ref[4 : len(ref)-1]
+	}
+
+	i := strings.IndexByte(ref, ':')
+	if i < 0 {
+		return v, nil
+	}
+	fn, found := s[ref[:i]]
+	if !found {
+		return v, nil
+	}
+
+	if secret, found := cache[ref]; found {
+		return secret, nil
+	}
+	secret, err := fn(ref[i+1:])
+	if err != nil {
+		return "", err
+	}
+	cache[ref] = secret
+	return secret, nil
+}
@@ -0,0 +1,55 @@
+package gofigure
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSecrets(t *testing.T) {
+
+	calls := 0
+	secrets := Secrets{
+		"kms": func(ref string) (string, error) {
+			calls++
+			return strings.ToUpper(ref), nil
+		},
+		"vault": func(ref string) (string, error) {
+			if ref != "secret/db#password" {
+				return "", errors.New("no such secret")
+			}
+			return "hunter2", nil
+		},
+	}
+
+	doc := map[string]interface{}{
+		"db": map[string]interface{}{
+			"password": "vault:secret/db#password",
+			"url":      "http://example.com",
+		},
+		"tokens": []interface{}{"ENC[kms:abc]", "ENC[kms:abc]", "kms:def", "plain", 42},
+	}
+	if err := secrets.Resolve(doc); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]interface{}{
+		"db": map[string]interface{}{
+			"password": "hunter2",
+			"url":      "http://example.com",
+		},
+		"tokens": []interface{}{"ABC", "ABC", "DEF", "plain", 42},
+	}
+	if !reflect.DeepEqual(doc, expected) {
+		t.Errorf("Secrets not resolved as expected: %#v", doc)
+	}
+	if calls != 2 {
+		t.Errorf("Expected each reference to be resolved once, got %d calls", calls)
+	}
+
+	err := secrets.Resolve(map[string]interface{}{"db": map[string]interface{}{"password": "vault:secret/nope"}})
+	if err == nil || !strings.Contains(err.Error(), "db.password") {
+		t.Errorf("Expected an error naming the key, got %v", err)
+	}
+}
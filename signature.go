@@ -0,0 +1,70 @@
+package gofigure
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+)
+
+// ErrBadSignature is returned for files whose signature doesn't match any of the trusted keys
+var ErrBadSignature = errors.New("bad signature")
+
+// Signatures is a Preprocessor verifying the detached ed25519 signature of every file before it's
+// decoded. The signature of a file is read from the file with the same path and Suffix, and holds
+// the signature either raw or base64 encoded. Files without a valid signature by one of the keys
+// fail to load; in strict mode this fails the whole load, otherwise they are skipped.
+//
+// Signatures should be the first of the loader's preprocessors, so it verifies the files as they
+// are on disk, or as they are once decompressed for compressed files.
+type Signatures struct {
+	// Keys are the public keys trusted to sign config files
+	Keys []ed25519.PublicKey
+
+	// Suffix is appended to a file's path to find its signature, ".sig" if empty
+	Suffix string
+}
+
+// Preprocess verifies the file's signature, and returns its contents untouched
+func (s Signatures) Preprocess(path string, data []byte) ([]byte, error) {
+
+	suffix := s.Suffix
+	if suffix == "" {
+		suffix = ".sig"
+	}
+
+	sig, err := readSignature(path + suffix)
+	if err != nil {
+		return nil, fmt.Errorf("reading signature: %s", err)
+	}
+
+	for _, key := range s.Keys {
+		if ed25519.Verify(key, data, sig) {
+			return data, nil
+		}
+	}
+	return nil, ErrBadSignature
+}
+
+// readSignature reads a raw or base64 encoded ed25519 signature from a file
+func readSignature(path string) ([]byte, error) {
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == ed25519.SignatureSize {
+		return data, nil
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(data)))
+	if err != nil {
+		return nil, err
+	}
+	if len(sig) != ed25519.SignatureSize {
+		return nil, fmt.Errorf("signature is %d bytes long, expected %d", len(sig), ed25519.SignatureSize)
+	}
+	return sig, nil
+}
@@ -0,0 +1,68 @@
+package gofigure
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/EverythingMe/gofigure/yaml"
+)
+
+func TestSignatures(t *testing.T) {
+
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPub, _, _ := ed25519.GenerateKey(nil)
+
+	dir, err := ioutil.TempDir("", "gofigure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	a := "redis:\n  server: localhost:6379\n"
+	b := "redis:\n  monitor: 10\n"
+	writeFiles(t, dir, map[string]string{
+		"a.yaml":     a,
+		"a.yaml.sig": base64.StdEncoding.EncodeToString(ed25519.Sign(priv, []byte(a))) + "\n",
+		"b.yaml":     b,
+		"b.yaml.sig": string(ed25519.Sign(priv, []byte(b))),
+	})
+
+	var conf config
+	loader := NewLoader(yaml.Decoder{}, true)
+	loader.Preprocessors = []Preprocessor{Signatures{Keys: []ed25519.PublicKey{otherPub, pub}}}
+	if err := loader.LoadRecursive(&conf, dir); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Redis.Server != "localhost:6379" || conf.Redis.Monitor != 10 {
+		t.Errorf("Signed files not loaded: %#v", conf.Redis)
+	}
+
+	// tampered and unsigned files fail strict loads and are skipped otherwise
+	writeFiles(t, dir, map[string]string{
+		"b.yaml": "redis:\n  monitor: 20\n",
+		"c.yaml": "redis:\n  server: evil\n",
+	})
+	if err := loader.LoadRecursive(&conf, dir); err == nil {
+		t.Error("Expected an error loading tampered files")
+	}
+
+	conf = config{}
+	loader.StrictMode = false
+	if err := loader.LoadRecursive(&conf, dir); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Redis.Server != "localhost:6379" || conf.Redis.Monitor != 0 {
+		t.Errorf("Tampered files should be skipped: %#v", conf.Redis)
+	}
+
+	if _, err := (Signatures{Keys: []ed25519.PublicKey{pub}}).Preprocess(filepath.Join(dir, "a.yaml"), []byte("x")); err != ErrBadSignature {
+		t.Errorf("Expected ErrBadSignature, got %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+package gofigure
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Bytes is a byte count that can be written in configs in human readable form, e.g. "512MiB" or
+// "2GB". Plain numbers are taken as a count of bytes.
+//
+// Units are case insensitive. IEC units (KiB, MiB, GiB, TiB, PiB) are powers of 1024, SI units (KB,
+// MB, GB, TB, PB) are powers of 1000, and single letter units (K, M, G, T, P) are powers of 1024,
+// the way most servers read them. Fractions like "1.5GiB" are allowed.
+type Bytes int64
+
+// Byte count units
+const (
+	Byte Bytes = 1
+	KB   Bytes = 1000 * Byte
+	MB   Bytes = 1000 * KB
+	GB   Bytes = 1000 * MB
+	TB   Bytes = 1000 * GB
+	PB   Bytes = 1000 * TB
+	KiB  Bytes = 1024 * Byte
+	MiB  Bytes = 1024 * KiB
+	GiB  Bytes = 1024 * MiB
+	TiB  Bytes = 1024 * GiB
+	PiB  Bytes = 1024 * TiB
+)
+
+// byteUnits maps lower cased unit suffixes to their size
+var byteUnits = map[string]Bytes{
+	"": Byte, "b": Byte,
+	"k": KiB, "kb": KB, "kib": KiB,
+	"m": MiB, "mb": MB, "mib": MiB,
+	"g": GiB, "gb": GB, "gib": GiB,
+	"t": TiB, "tb": TB, "tib": TiB,
+	"p": PiB, "pb": PB, "pib": PiB,
+}
+
+// ParseBytes parses a human readable byte count such as "512MiB". Counts below zero are invalid
+func ParseBytes(s string) (Bytes, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.' && r != '-' && r != '+'
+	})
+	if i < 0 {
+		i = len(s)
+	}
+
+	num, unit := s[:i], strings.ToLower(strings.TrimSpace(s[i:]))
+	size, found := byteUnits[unit]
+	if !found || num == "" {
+		return 0, fmt.Errorf("gofigure: invalid byte size %q", s)
+	}
+
+	if n, err := strconv.ParseInt(num, 10, 64); err == nil {
+		if n < 0 {
+			return 0, fmt.Errorf("gofigure: byte size %q is negative", s)
+		}
+		if n > math.MaxInt64/int64(size) {
+			return 0, fmt.Errorf("gofigure: byte size %q overflows", s)
+		}
+		return Bytes(n) * size, nil
+	}
+
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, fmt.Errorf("gofigure: invalid byte size %q", s)
+	}
+	if f < 0 {
+		return 0, fmt.Errorf("gofigure: byte size %q is negative", s)
+	}
+	f *= float64(size)
+	if f >= math.MaxInt64 {
+		return 0, fmt.Errorf("gofigure: byte size %q overflows", s)
+	}
+	return Bytes(f), nil
+}
+
+// UnmarshalText parses a human readable byte count
+func (b *Bytes) UnmarshalText(text []byte) error {
+	v, err := ParseBytes(string(text))
+	if err != nil {
+		return err
+	}
+	*b = v
+	return nil
+}
+
+// MarshalText formats the byte count the way String does
+func (b Bytes) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
+
+// String formats the byte count with the largest IEC unit that represents it exactly
+func (b Bytes) String() string {
+	for _, u := range []struct {
+		size Bytes
+		name string
+	}{{PiB, "PiB"}, {TiB, "TiB"}, {GiB, "GiB"}, {MiB, "MiB"}, {KiB, "KiB"}} {
+		if b != 0 && b%u.size == 0 {
+			return fmt.Sprintf("%d%s", b/u.size, u.name)
+		}
+	}
+	return fmt.Sprintf("%dB", int64(b))
+}
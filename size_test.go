@@ -0,0 +1,69 @@
+package gofigure
+
+import (
+	"testing"
+)
+
+func TestParseBytes(t *testing.T) {
+
+	for s, expected := range map[string]Bytes{
+		"0":      0,
+		"1024":   1024,
+		"512MiB": 512 * MiB,
+		"2GB":    2 * GB,
+		"2 gb":   2 * GB,
+		"16k":    16 * KiB,
+		"1.5GiB": 3 * GiB / 2,
+		"10b":    10,
+		"-0":     0,
+	} {
+		v, err := ParseBytes(s)
+		if err != nil || v != expected {
+			t.Errorf("Parsing %q: expected %d, got %d (%v)", s, expected, v, err)
+		}
+	}
+
+	for _, s := range []string{"", "8EB", "MiB", "12 bytes", "9000000PiB", "8192.0PiB", "-5MB", "-0.5GiB"} {
+		if v, err := ParseBytes(s); err == nil {
+			t.Errorf("Expected %q to be invalid, got %d", s, v)
+		}
+	}
+}
+
+func TestBytesString(t *testing.T) {
+	for b, expected := range map[Bytes]string{
+		0:           "0B",
+		1000:        "1000B",
+		512 * MiB:   "512MiB",
+		3 * GiB / 2: "1536MiB",
+	} {
+		if s := b.String(); s != expected {
+			t.Errorf("Expected %s, got %s", expected, s)
+		}
+	}
+}
+
+func TestBindBytes(t *testing.T) {
+
+	var conf struct {
+		Buffer Bytes            `yaml:"buffer"`
+		Limit  Bytes            `yaml:"limit"`
+		Caches map[string]Bytes `yaml:"caches"`
+	}
+
+	doc := map[string]interface{}{
+		"buffer": "64KiB",
+		"limit":  4096,
+		"caches": map[string]interface{}{"pages": "1G"},
+	}
+	if err := bind(doc, &conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Buffer != 64*KiB || conf.Limit != 4096 || conf.Caches["pages"] != GiB {
+		t.Errorf("Byte sizes not decoded as expected: %v", conf)
+	}
+
+	if err := bind(map[string]interface{}{"buffer": "lots"}, &conf); err == nil {
+		t.Errorf("Expected an invalid size to fail")
+	}
+}
@@ -0,0 +1,50 @@
+// Package sops decrypts SOPS (https://getsops.io) encrypted config files before they are decoded,
+// so secrets can be kept encrypted in git and still be loaded directly.
+package sops
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/getsops/sops/v3"
+	"github.com/getsops/sops/v3/decrypt"
+)
+
+// formats maps file extensions to the SOPS format of the files
+var formats = map[string]string{
+	".yaml": "yaml",
+	".yml":  "yaml",
+	".json": "json",
+	".env":  "dotenv",
+	".ini":  "ini",
+}
+
+// Preprocessor is a gofigure.Preprocessor decrypting SOPS encrypted YAML, JSON, dotenv and INI
+// files. Files without SOPS metadata are passed through untouched, so encrypted and plain files
+// can live side by side.
+//
+// Data keys are decrypted with the same key sources the sops command line tool uses: age
+// identities (SOPS_AGE_KEY_FILE or SOPS_AGE_KEY), the PGP keyring, and the ambient credentials of
+// the cloud KMS services.
+type Preprocessor struct {
+	// Required makes plain files an error, for directories that should only hold encrypted files
+	Required bool
+}
+
+// Preprocess decrypts the file's contents if they are SOPS encrypted
+func (p Preprocessor) Preprocess(path string, data []byte) ([]byte, error) {
+
+	format, found := formats[strings.ToLower(filepath.Ext(path))]
+	if !found {
+		if p.Required {
+			return nil, sops.MetadataNotFound
+		}
+		return data, nil
+	}
+
+	cleartext, err := decrypt.Data(data, format)
+	if err == sops.MetadataNotFound && !p.Required {
+		return data, nil
+	}
+	return cleartext, err
+}
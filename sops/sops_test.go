@@ -0,0 +1,40 @@
+package sops
+
+import (
+	"testing"
+)
+
+func TestPreprocessor(t *testing.T) {
+
+	plain := []byte("redis:\n  server: localhost:6379\n")
+
+	out, err := Preprocessor{}.Preprocess("conf.yaml", plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != string(plain) {
+		t.Errorf("Plain file not passed through: %s", out)
+	}
+
+	out, err = Preprocessor{}.Preprocess("conf.txt", []byte("whatever"))
+	if err != nil || string(out) != "whatever" {
+		t.Errorf("Unknown format not passed through: %s, %v", out, err)
+	}
+
+	if _, err := (Preprocessor{Required: true}).Preprocess("conf.yaml", plain); err == nil {
+		t.Error("Expected an error for a plain file when encryption is required")
+	}
+
+	// SOPS metadata without any usable key can't be decrypted
+	encrypted := []byte(`{
+	"password": "ENC[AES256_GCM,data:AAAA,iv:AAAA,tag:AAAA,type:str]",
+	"sops": {
+		"mac": "ENC[AES256_GCM,data:AAAA,iv:AAAA,tag:AAAA,type:str]",
+		"lastmodified": "2024-01-01T00:00:00Z",
+		"version": "3.9.4"
+	}
+}`)
+	if _, err := (Preprocessor{}).Preprocess("secrets.json", encrypted); err == nil {
+		t.Error("Expected an error decrypting without keys")
+	}
+}
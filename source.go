@@ -0,0 +1,361 @@
+package gofigure
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Source is a source of config other than the local file system, like a config service. A source
+// provides a single document, decoded by the loader's decoder. Sources that build their document
+// from records, like the rows of a database, write it as JSON, which the yaml decoder reads too.
+//
+// Includes are only followed in files, not in the documents of sources.
+type Source interface {
+	// Name identifies the source in logs, errors and reports, e.g. by its URL
+	Name() string
+
+	// Fetch returns the source's document. It should give up when ctx is done. Errors are retried
+	// according to the loader's retry policy, unless they are wrapped with Permanent
+	Fetch(ctx context.Context) (io.ReadCloser, error)
+}
+
+// loadSource fetches and decodes the document of a source, retrying according to the loader's
+// retry policy. The sources of a fallback chain are tried in turn, and the one that is used is
+// the returned document's source
+func (l Loader) loadSource(report *Report, source Source) (document, error) {
+
+	if chain, ok := source.(fallback); ok {
+		var errs []string
+		for _, s := range chain {
+			doc, err := l.loadSource(report, s)
+			if err == nil {
+				if len(errs) > 0 {
+					report.warn(Warning{Source: s.Name(), Message: "used as a fallback: " + strings.Join(errs, "; ")})
+				}
+				return doc, nil
+			}
+			log.Info("Error loading %s, falling back: %s", s.Name(), err)
+			errs = append(errs, err.Error())
+		}
+		return document{}, ioError{fmt.Errorf("gofigure: all fallbacks failed: %s", strings.Join(errs, "; "))}
+	}
+
+	var doc document
+	fetched := false
+	err := l.Retry.do(context.Background(), source.Name(), func(ctx context.Context) error {
+		rc, err := source.Fetch(ctx)
+		if fetched = err == nil; !fetched {
+			return err
+		}
+		defer rc.Close()
+
+		var d Decoder
+		if typed, ok := rc.(typedBody); ok {
+			if d, err = typed.decoder(); err != nil {
+				return Permanent(err)
+			}
+		}
+
+		v, err := l.decodeReaderWith(source.Name(), rc, d)
+		if err != nil {
+			// the body is read as it's decoded, so a dropped connection looks like a bad document;
+			// only what came through in full is a permanent failure
+			if ctx.Err() != nil {
+				return err
+			}
+			return Permanent(err)
+		}
+
+		doc, err = l.prepareDocument(source.Name(), v)
+		return Permanent(err)
+	})
+	if err != nil {
+		if !fetched {
+			err = ioError{err}
+		}
+		return document{}, fmt.Errorf("gofigure: %s: %w", source.Name(), err)
+	}
+	return doc, nil
+}
+
+// HTTPSource is a Source fetching a document from a URL with a GET request. Server errors and
+// failed requests are retried; other responses that aren't a 2xx are permanent failures.
+//
+// Documents are decoded according to the Content-Type of the response, with the registered
+// decoder of its format, see RegisterDecoder. Responses of other types, like text/plain, are
+// decoded with the loader's decoder, as if the URL was a file name
+type HTTPSource struct {
+	URL string
+
+	// Format, if set, names the registered decoder documents are decoded with, regardless of the
+	// Content-Type of responses, for servers that don't send the right one
+	Format string
+
+	// Header holds extra request headers, e.g. for authentication
+	Header http.Header
+
+	// Client makes the requests, http.DefaultClient if nil
+	Client *http.Client
+}
+
+// Name returns the source's URL
+func (s HTTPSource) Name() string {
+	return s.URL
+}
+
+// Fetch gets the document at the source's URL
+func (s HTTPSource) Fetch(ctx context.Context) (io.ReadCloser, error) {
+
+	res, err := s.request(ctx, "GET")
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		io.Copy(ioutil.Discard, res.Body)
+		res.Body.Close()
+
+		err := fmt.Errorf("unexpected status %s", res.Status)
+		if res.StatusCode < 500 && res.StatusCode != http.StatusTooManyRequests && res.StatusCode != http.StatusRequestTimeout {
+			return nil, Permanent(err)
+		}
+		return nil, err
+	}
+
+	format := s.Format
+	if format == "" {
+		mediaType, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type"))
+		format = mediaFormat(mediaType)
+	}
+	if format == "" {
+		return res.Body, nil
+	}
+	return typedBody{res.Body, format}, nil
+}
+
+// mediaFormats maps the media types of config documents to the names of their formats
+var mediaFormats = map[string]string{
+	"application/json":   "json",
+	"text/json":          "json",
+	"application/yaml":   "yaml",
+	"application/x-yaml": "yaml",
+	"text/yaml":          "yaml",
+	"text/x-yaml":        "yaml",
+	"application/toml":   "toml",
+	"text/toml":          "toml",
+}
+
+// mediaFormat returns the name of the format of a media type, or "" if it's not a known config
+// format. Structured syntax suffixes are understood, so application/vnd.app+json is json
+func mediaFormat(mediaType string) string {
+	if format, found := mediaFormats[mediaType]; found {
+		return format
+	}
+	if i := strings.LastIndexByte(mediaType, '+'); i >= 0 {
+		switch suffix := mediaType[i+1:]; suffix {
+		case "json", "yaml", "toml":
+			return suffix
+		}
+	}
+	return ""
+}
+
+// typedBody is the body of a document whose format is known, e.g. from its Content-Type, so it's
+// decoded with the registered decoder of the format rather than by its name
+type typedBody struct {
+	io.ReadCloser
+	format string
+}
+
+// decoder returns the registered decoder of the body's format
+func (b typedBody) decoder() (Decoder, error) {
+	d, found := LookupDecoder(b.format)
+	if !found {
+		return nil, fmt.Errorf("no decoder registered for format %s", b.format)
+	}
+	return d, nil
+}
+
+// formatted is a Source whose documents are in a known format
+type formatted struct {
+	Source
+	format string
+}
+
+// WithFormat wraps a source so that its documents are decoded with the registered decoder of
+// format, like "yaml" or "toml", whatever the source's name, see RegisterDecoder
+func WithFormat(source Source, format string) Source {
+	return formatted{source, format}
+}
+
+// Fetch fetches the wrapped source's document, marked with the source's format
+func (s formatted) Fetch(ctx context.Context) (io.ReadCloser, error) {
+	rc, err := s.Source.Fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if typed, ok := rc.(typedBody); ok {
+		rc = typed.ReadCloser
+	}
+	return typedBody{rc, s.format}, nil
+}
+
+// fallback is a chain of sources, the first of which that can be loaded is used
+type fallback []Source
+
+// Fallback chains sources so that the first one that can be loaded is used, and the next ones are
+// only tried when it can't. This lets a program start when its config service is unreachable, by
+// falling back to a local copy or to embedded defaults, e.g.
+//
+//	Fallback(
+//		Persist(HTTPSource{URL: "https://config/app.yaml"}, "/var/cache/app.yaml"),
+//		FileSource("/var/cache/app.yaml"),
+//		BytesSource("defaults", defaults),
+//	)
+//
+// The loader's retry policy applies to every source of the chain, and the report of a load
+// records which source was used.
+func Fallback(sources ...Source) Source {
+	return fallback(sources)
+}
+
+// Name returns the names of the sources of the chain
+func (f fallback) Name() string {
+	names := make([]string, len(f))
+	for i, s := range f {
+		names[i] = s.Name()
+	}
+	return strings.Join(names, " | ")
+}
+
+// Fetch returns the document of the first source that can be fetched
+func (f fallback) Fetch(ctx context.Context) (io.ReadCloser, error) {
+	var errs []string
+	for _, s := range f {
+		rc, err := s.Fetch(ctx)
+		if err == nil {
+			return rc, nil
+		}
+		errs = append(errs, err.Error())
+	}
+	return nil, fmt.Errorf("all fallbacks failed: %s", strings.Join(errs, "; "))
+}
+
+// request makes a request to the source's URL
+func (s HTTPSource) request(ctx context.Context, method string) (*http.Response, error) {
+
+	req, err := http.NewRequest(method, s.URL, nil)
+	if err != nil {
+		return nil, Permanent(err)
+	}
+	req = req.WithContext(ctx)
+	for k, v := range s.Header {
+		req.Header[k] = v
+	}
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return client.Do(req)
+}
+
+// FileSource is a Source reading a single local file, e.g. a copy saved by Persist
+type FileSource string
+
+// Name returns the file's path
+func (s FileSource) Name() string {
+	return string(s)
+}
+
+// Fetch opens the file. A missing file is a permanent failure
+func (s FileSource) Fetch(ctx context.Context) (io.ReadCloser, error) {
+	fp, err := os.Open(string(s))
+	if os.IsNotExist(err) {
+		return nil, Permanent(err)
+	}
+	return fp, err
+}
+
+// bytesSource is a Source of a document held in memory
+type bytesSource struct {
+	name string
+	data []byte
+}
+
+// BytesSource returns a Source of a document held in memory, like defaults embedded in the binary
+func BytesSource(name string, data []byte) Source {
+	return bytesSource{name, data}
+}
+
+func (s bytesSource) Name() string {
+	return s.name
+}
+
+func (s bytesSource) Fetch(ctx context.Context) (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(s.data)), nil
+}
+
+// persisted is a Source saving a copy of every document it fetches
+type persisted struct {
+	Source
+	path string
+}
+
+// Persist wraps a source so that every document it fetches is saved to the file at path, to be
+// used as a fallback when the source is unreachable. The file is replaced atomically
+func Persist(source Source, path string) Source {
+	return persisted{source, path}
+}
+
+// Fetch fetches the wrapped source's document and saves it
+func (s persisted) Fetch(ctx context.Context) (io.ReadCloser, error) {
+
+	rc, err := s.Source.Fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	data, err := ioutil.ReadAll(rc)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := writeFileAtomic(s.path, data); err != nil {
+		log.Info("Could not save a copy of %s to %s: %s", s.Name(), s.path, err)
+	}
+
+	body := ioutil.NopCloser(bytes.NewReader(data))
+	if typed, ok := rc.(typedBody); ok {
+		return typedBody{body, typed.format}, nil
+	}
+	return body, nil
+}
+
+// writeFileAtomic replaces the file at path with data, by writing a temporary file next to it
+// and renaming it into place. The file is only readable by its owner
+func writeFileAtomic(path string, data []byte) error {
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,210 @@
+package gofigure
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/EverythingMe/gofigure/yaml"
+)
+
+func TestHTTPSource(t *testing.T) {
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&requests, 1)
+		switch {
+		case r.URL.Path == "/missing":
+			http.NotFound(w, r)
+		case r.Header.Get("X-Token") != "secret":
+			w.WriteHeader(http.StatusForbidden)
+		case n == 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case n == 2:
+			time.Sleep(100 * time.Millisecond)
+		default:
+			w.Write([]byte("redis:\n  server: remote:6379\n"))
+		}
+	}))
+	defer server.Close()
+
+	header := http.Header{"X-Token": []string{"secret"}}
+	loader := NewLoader(yaml.Decoder{}, true)
+	loader.Sources = []Source{HTTPSource{URL: server.URL + "/conf.yaml", Header: header}}
+	loader.Retry = RetryPolicy{Attempts: 3, Backoff: time.Millisecond, Jitter: 0.5, Timeout: 50 * time.Millisecond}
+
+	var conf config
+	report, err := loader.LoadWithReport(&conf, "./testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Redis.Server != "remote:6379" || conf.Mysql.Server == "" {
+		t.Errorf("Remote source not merged on top of files: %#v", conf)
+	}
+	if requests != 3 {
+		t.Errorf("Expected 3 attempts, got %d", requests)
+	}
+	if n := len(report.Files); n != 3 || report.Files[n-1] != server.URL+"/conf.yaml" {
+		t.Errorf("Source missing from the report: %v", report.Files)
+	}
+
+	// client errors aren't retried
+	requests = 0
+	loader.Sources = []Source{HTTPSource{URL: server.URL + "/missing"}}
+	if err := loader.LoadRecursive(&conf); err == nil {
+		t.Error("Expected an error for a missing document")
+	}
+	if requests != 1 {
+		t.Errorf("Expected a single attempt, got %d", requests)
+	}
+
+	// outside of strict mode, failing sources are reported and skipped
+	loader.StrictMode = false
+	report, err = loader.LoadWithReport(&conf)
+	if err != nil || len(report.Warnings) != 1 {
+		t.Errorf("Expected the source to be skipped: %v, %v", report.Warnings, err)
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+
+	failing := errors.New("boom")
+
+	calls := 0
+	err := RetryPolicy{Attempts: 4, Backoff: time.Millisecond}.do(context.Background(), "test", func(ctx context.Context) error {
+		calls++
+		return failing
+	})
+	if err != failing || calls != 4 {
+		t.Errorf("Expected 4 failed attempts, got %d: %v", calls, err)
+	}
+
+	calls = 0
+	err = RetryPolicy{Attempts: 4, Backoff: time.Millisecond}.do(context.Background(), "test", func(ctx context.Context) error {
+		calls++
+		return Permanent(failing)
+	})
+	if err != failing || calls != 1 {
+		t.Errorf("Permanent errors should not be retried, got %d attempts: %v", calls, err)
+	}
+
+	calls = 0
+	err = RetryPolicy{}.do(context.Background(), "test", func(ctx context.Context) error {
+		calls++
+		return failing
+	})
+	if err != failing || calls != 1 {
+		t.Errorf("The zero policy should try once, got %d attempts", calls)
+	}
+
+	if Permanent(nil) != nil {
+		t.Error("Permanent(nil) should be nil")
+	}
+}
+
+func TestFallback(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gofigure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	up := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !up {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("redis:\n  server: remote:6379\n"))
+	}))
+	defer server.Close()
+
+	saved := filepath.Join(dir, "saved.yaml")
+	remote := HTTPSource{URL: server.URL}
+	loader := NewLoader(yaml.Decoder{}, true)
+	loader.Sources = []Source{Fallback(
+		Persist(remote, saved),
+		FileSource(saved),
+		BytesSource("defaults", []byte("redis:\n  server: default:6379\n")),
+	)}
+
+	// nothing saved yet and the service is down: the defaults win
+	up = false
+	var conf config
+	report, err := loader.LoadWithReport(&conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Redis.Server != "default:6379" || report.Files[0] != "defaults" || len(report.Warnings) != 1 {
+		t.Errorf("Expected the defaults to be used: %#v, %v", conf.Redis, report)
+	}
+
+	up = true
+	if report, err = loader.LoadWithReport(&conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Redis.Server != "remote:6379" || report.Files[0] != server.URL || len(report.Warnings) != 0 {
+		t.Errorf("Expected the remote source to be used: %#v, %v", conf.Redis, report)
+	}
+
+	// the copy saved on the last successful fetch is used while the service is down
+	up = false
+	conf = config{}
+	if report, err = loader.LoadWithReport(&conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Redis.Server != "remote:6379" || report.Files[0] != saved {
+		t.Errorf("Expected the saved copy to be used: %#v, %v", conf.Redis, report)
+	}
+
+	loader.Sources = []Source{Fallback(remote, FileSource(filepath.Join(dir, "missing.yaml")))}
+	if err := loader.LoadRecursive(&conf); err == nil {
+		t.Error("Expected an error when all fallbacks fail")
+	}
+}
+
+func TestHTTPSourceContentType(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", r.URL.Query().Get("type"))
+		w.Write([]byte("redis:\n  server: remote:6379\n"))
+	}))
+	defer server.Close()
+
+	cases := []struct {
+		contentType string
+		format      string
+		ok          bool
+	}{
+		{"application/x-yaml; charset=utf-8", "", true},
+		{"application/vnd.app+yaml", "", true},
+		{"text/plain", "", true}, // decoded by the loader's decoder
+		{"application/json", "", false},
+		{"application/json", "yaml", true},
+		{"application/toml", "", false}, // not registered
+		{"text/plain", "xml", false},
+	}
+
+	for _, c := range cases {
+		loader := NewLoader(yaml.Decoder{}, true)
+		loader.Sources = []Source{HTTPSource{URL: server.URL + "/conf.yaml?type=" + url.QueryEscape(c.contentType), Format: c.format}}
+
+		var conf config
+		err := loader.LoadRecursive(&conf)
+		if c.ok && (err != nil || conf.Redis.Server != "remote:6379") {
+			t.Errorf("%s (format %q): expected the document to be decoded, got %#v, %v", c.contentType, c.format, conf.Redis, err)
+		}
+		if !c.ok && err == nil {
+			t.Errorf("%s (format %q): expected an error", c.contentType, c.format)
+		}
+	}
+}
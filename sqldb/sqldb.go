@@ -0,0 +1,101 @@
+// Package sqldb loads config from the rows of a SQL table, through database/sql and so with any
+// driver, e.g. for control planes keeping the overrides of their tenants in Postgres.
+//
+// The query of a source selects two columns: the dotted path of a value or a section of the
+// config, empty for the root, and the value or the document of the section, in YAML or JSON.
+// Plain key/value rows and rows of JSON documents can be mixed:
+//
+//	key             value
+//	redis.server    cache:6379                   ->  redis: {server: cache:6379, pool: 20}
+//	redis           {"pool": 20}
+//
+// Rows are merged in the order the query returns them, so later rows win, and ordering them sets
+// their precedence, e.g. to have the overrides of a tenant win over the defaults of all tenants:
+//
+//	SELECT key, value FROM settings WHERE tenant IN ('', $1) ORDER BY tenant, key
+//
+// To reload the config when rows change, poll the source with a gofigure.PollMonitor.
+package sqldb
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/EverythingMe/gofigure"
+	"github.com/EverythingMe/gofigure/internal/tree"
+	"github.com/EverythingMe/gofigure/yaml"
+)
+
+// Source is a gofigure.Source reading the config from the rows selected by a query
+type Source struct {
+	DB *sql.DB
+
+	// Query selects the path and the value of every row, with Args as its arguments
+	Query string
+	Args  []interface{}
+
+	// VersionQuery, if set, selects a single value that changes whenever the rows do, like
+	// "SELECT max(updated_at) FROM settings WHERE tenant IN ('', $1)", so that polling the source
+	// is cheap. It's run with Args too. Otherwise the rows are fetched and hashed to be polled
+	VersionQuery string
+}
+
+// Name returns the source's query
+func (s Source) Name() string {
+	return "sql:" + strings.Join(strings.Fields(s.Query), " ")
+}
+
+// Fetch runs the query and merges the rows into a document. A value that can't be decoded is a
+// permanent failure
+func (s Source) Fetch(ctx context.Context) (io.ReadCloser, error) {
+
+	rows, err := s.DB.QueryContext(ctx, s.Query, s.Args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	doc := map[string]interface{}{}
+	for rows.Next() {
+		var key string
+		var value sql.NullString
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, gofigure.Permanent(fmt.Errorf("sqldb: %s", err))
+		}
+
+		var v interface{}
+		if value.Valid {
+			if err := (yaml.Decoder{}).Decode(strings.NewReader(value.String), &v); err != nil {
+				return nil, gofigure.Permanent(fmt.Errorf("sqldb: the value of %s: %s", key, err))
+			}
+		}
+		tree.Place(doc, key, tree.Normalize(v))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, gofigure.Permanent(err)
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+// Version runs the source's VersionQuery, if it has one
+func (s Source) Version(ctx context.Context) (string, error) {
+	if s.VersionQuery == "" {
+		return "", nil
+	}
+	var version sql.NullString
+	if err := s.DB.QueryRowContext(ctx, s.VersionQuery, s.Args...).Scan(&version); err != nil {
+		return "", err
+	}
+	return version.String, nil
+}
@@ -0,0 +1,75 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/EverythingMe/gofigure"
+	"github.com/EverythingMe/gofigure/yaml"
+	_ "modernc.org/sqlite"
+)
+
+func TestSource(t *testing.T) {
+
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	for _, stmt := range []string{
+		"CREATE TABLE settings (tenant TEXT, key TEXT, value TEXT, updated INTEGER)",
+		`INSERT INTO settings VALUES ('', 'redis', '{"server": "cache:6379", "pool": 10}', 1)`,
+		"INSERT INTO settings VALUES ('', 'name', 'app', 1)",
+		"INSERT INTO settings VALUES ('acme', 'redis.pool', '20', 2)",
+		"INSERT INTO settings VALUES ('other', 'redis.pool', '30', 3)",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	source := Source{
+		DB:           db,
+		Query:        "SELECT key, value FROM settings WHERE tenant IN ('', ?) ORDER BY tenant, key",
+		Args:         []interface{}{"acme"},
+		VersionQuery: "SELECT max(updated) FROM settings WHERE tenant IN ('', ?)",
+	}
+
+	var conf struct {
+		Name  string
+		Redis struct {
+			Server string
+			Pool   int
+		}
+	}
+	loader := gofigure.NewLoader(yaml.Decoder{}, true)
+	loader.Sources = []gofigure.Source{source}
+	if err := loader.LoadRecursive(&conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Name != "app" || conf.Redis.Server != "cache:6379" || conf.Redis.Pool != 20 {
+		t.Errorf("Unexpected config %#v", conf)
+	}
+
+	ctx := context.Background()
+	before, err := source.Version(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("UPDATE settings SET value = '25', updated = 4 WHERE tenant = 'acme'"); err != nil {
+		t.Fatal(err)
+	}
+	if after, err := source.Version(ctx); err != nil || after == before {
+		t.Errorf("Expected the version to change from %s, got %s (%v)", before, after, err)
+	}
+
+	if _, err := db.Exec(`INSERT INTO settings VALUES ('acme', 'broken', '{"unclosed": ', 5)`); err != nil {
+		t.Fatal(err)
+	}
+	if err := loader.LoadRecursive(&conf); err == nil {
+		t.Error("Expected an error for a value that can't be decoded")
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !gofigure_noregister
+
+package starlark
+
+import "github.com/EverythingMe/gofigure"
+
+func init() {
+	gofigure.RegisterDecoder("starlark", Decoder{})
+}
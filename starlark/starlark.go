@@ -0,0 +1,165 @@
+// Package starlark implements a gofigure decoder for config files written in Starlark, a hermetic
+// and deterministic dialect of Python, for configs that are easier to compute with loops and
+// conditionals than to write out:
+//
+//	regions = ["us", "eu"]
+//	config = {
+//	    "servers": [{"host": "api-%s.example.com" % r, "port": 443} for r in regions],
+//	    "debug": False,
+//	}
+//
+// The file is executed as a program, and the dict it assigns to the config global is decoded. The
+// program cannot load other files, and has no access to the file system, the network or the
+// environment, except through the values it is given in Predeclared.
+package starlark
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"strings"
+
+	"github.com/EverythingMe/gofigure/internal/tree"
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+// Decoder evaluates Starlark config files and decodes what they set into config structs
+type Decoder struct {
+	// Global is the name of the global holding the config, "config" if empty
+	Global string
+
+	// Predeclared are values available to the programs, such as a build's version or helper
+	// functions
+	Predeclared starlark.StringDict
+
+	// MaxSteps bounds the computation a program can do, so that a runaway loop fails rather than
+	// hanging the loader. Zero means no limit
+	MaxSteps uint64
+}
+
+// Decode executes the program read from r, and unmarshals the dict it sets into config
+func (d Decoder) Decode(r io.Reader, config interface{}) error {
+
+	src, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	thread := &starlark.Thread{Name: "gofigure"}
+	if d.MaxSteps > 0 {
+		thread.SetMaxExecutionSteps(d.MaxSteps)
+	}
+	globals, err := starlark.ExecFileOptions(&syntax.FileOptions{}, thread, "config.star", src, d.Predeclared)
+	if err != nil {
+		if evalErr, ok := err.(*starlark.EvalError); ok {
+			return fmt.Errorf("starlark: %s", evalErr.Backtrace())
+		}
+		return fmt.Errorf("starlark: %s", err)
+	}
+
+	name := d.Global
+	if name == "" {
+		name = "config"
+	}
+	v, found := globals[name]
+	if !found {
+		return fmt.Errorf("starlark: the program does not set %s", name)
+	}
+	if _, ok := v.(*starlark.Dict); !ok {
+		return fmt.Errorf("starlark: %s must be a dict, got %s", name, v.Type())
+	}
+
+	doc, err := toGo(v, name, nil)
+	if err != nil {
+		return err
+	}
+	return tree.Assign(doc, config)
+}
+
+// CanDecode returns true if this is a Starlark file
+func (d Decoder) CanDecode(path string) bool {
+	return strings.HasSuffix(path, ".star")
+}
+
+// maxDepth bounds the nesting of dicts and lists, which can contain themselves
+const maxDepth = 100
+
+// toGo converts a Starlark value to a generic tree value. The path of the value is used in errors,
+// and parents are the dicts and lists it is nested in
+func toGo(v starlark.Value, path string, parents []starlark.Value) (interface{}, error) {
+
+	switch v := v.(type) {
+	case starlark.NoneType:
+		return nil, nil
+	case starlark.Bool:
+		return bool(v), nil
+	case starlark.Int:
+		if i, ok := v.Int64(); ok {
+			return int(i), nil
+		}
+		return nil, fmt.Errorf("starlark: %s: %s overflows", path, v)
+	case starlark.Float:
+		if math.IsInf(float64(v), 0) || math.IsNaN(float64(v)) {
+			return nil, fmt.Errorf("starlark: %s: %s is not a number", path, v)
+		}
+		return float64(v), nil
+	case starlark.String:
+		return string(v), nil
+
+	case *starlark.Dict:
+		parents, err := nest(v, path, parents)
+		if err != nil {
+			return nil, err
+		}
+		m := make(map[string]interface{}, v.Len())
+		for _, item := range v.Items() {
+			k, ok := item[0].(starlark.String)
+			if !ok {
+				return nil, fmt.Errorf("starlark: %s: keys must be strings, got %s", path, item[0].Type())
+			}
+			e, err := toGo(item[1], path+"."+string(k), parents)
+			if err != nil {
+				return nil, err
+			}
+			m[string(k)] = e
+		}
+		return m, nil
+
+	case starlark.Indexable:
+		// lists and tuples
+		parents, err := nest(v, path, parents)
+		if err != nil {
+			return nil, err
+		}
+		l := make([]interface{}, v.Len())
+		for i := range l {
+			e, err := toGo(v.Index(i), fmt.Sprintf("%s.%d", path, i), parents)
+			if err != nil {
+				return nil, err
+			}
+			l[i] = e
+		}
+		return l, nil
+	}
+
+	return nil, fmt.Errorf("starlark: %s: cannot use a %s in a config", path, v.Type())
+}
+
+// nest adds a dict or a list to the parents of the values it holds, failing if it's nested too deep
+// or is one of its own parents
+func nest(v starlark.Value, path string, parents []starlark.Value) ([]starlark.Value, error) {
+	if len(parents) >= maxDepth {
+		return nil, fmt.Errorf("starlark: %s: nested too deep", path)
+	}
+	// tuples can't be compared, and only contain themselves through a list, which is caught
+	if _, isTuple := v.(starlark.Tuple); !isTuple {
+		for _, p := range parents {
+			if p == v {
+				return nil, fmt.Errorf("starlark: %s: contains itself", path)
+			}
+		}
+	}
+	return append(parents, v), nil
+}
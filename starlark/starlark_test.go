@@ -0,0 +1,85 @@
+package starlark
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.starlark.net/starlark"
+)
+
+func TestDecode(t *testing.T) {
+
+	src := `
+regions = ["us", "eu"]
+
+def server(region):
+    return {"host": "api-%s.example.com" % region, "port": 443}
+
+config = {
+    "servers": [server(r) for r in regions],
+    "debug": False,
+    "ratio": 0.5,
+    "version": version,
+    "empty": None,
+    "pair": (1, 2),
+}
+`
+	var conf interface{}
+	d := Decoder{Predeclared: starlark.StringDict{"version": starlark.String("1.2")}}
+	if err := d.Decode(strings.NewReader(src), &conf); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]interface{}{
+		"servers": []interface{}{
+			map[string]interface{}{"host": "api-us.example.com", "port": 443},
+			map[string]interface{}{"host": "api-eu.example.com", "port": 443},
+		},
+		"debug":   false,
+		"ratio":   0.5,
+		"version": "1.2",
+		"empty":   nil,
+		"pair":    []interface{}{1, 2},
+	}
+	if !reflect.DeepEqual(conf, expected) {
+		t.Errorf("Unexpected config %#v", conf)
+	}
+
+	var typed struct {
+		Servers []struct {
+			Host string
+			Port int
+		}
+	}
+	if err := d.Decode(strings.NewReader(src), &typed); err != nil {
+		t.Fatal(err)
+	}
+	if len(typed.Servers) != 2 || typed.Servers[1].Host != "api-eu.example.com" {
+		t.Errorf("Unexpected typed config %#v", typed)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+
+	cases := map[string]string{
+		"syntax":      "config = {",
+		"runtime":     "config = {'a': 1 // 0}",
+		"missing":     "other = {}",
+		"not a dict":  "config = [1]",
+		"key":         "config = {1: 2}",
+		"function":    "def f(): pass\nconfig = {'f': f}",
+		"load":        "load('other.star', 'x')\nconfig = {}",
+		"steps":       "def f():\n    for i in range(1000000): pass\nf()\nconfig = {}",
+		"cycle":       "x = []\nx.append(x)\nx.append(x)\nconfig = {'a': x}",
+		"dict cycle":  "config = {}\nconfig['self'] = config",
+		"tuple cycle": "x = []\nx.append((x,))\nconfig = {'a': x}",
+		"deep":        "def f():\n    x = 1\n    for i in range(200):\n        x = [x]\n    return x\nconfig = {'a': f()}",
+	}
+	for name, src := range cases {
+		var conf interface{}
+		if err := (Decoder{MaxSteps: 10000}).Decode(strings.NewReader(src), &conf); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
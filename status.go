@@ -0,0 +1,350 @@
+package gofigure
+
+import (
+	"crypto/sha256"
+	"encoding"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// redacted replaces the values of secret fields in debug output
+const redacted = "<redacted>"
+
+// cycle replaces the values that contain themselves, where they do, in debug output
+const cycle = "<cycle>"
+
+// Status records the outcome of a loader's loads: the effective config, where each of its values
+// came from, and when the last load succeeded or failed. Set it as a Loader's Status to have it
+// updated on every LoadRecursive call.
+//
+// A Status is an http.Handler serving itself as JSON, which can be mounted next to expvar for live
+// inspection of a running program:
+//
+//	http.Handle("/debug/config", status)
+//
+// The values of fields tagged secret:"true" are redacted, e.g.
+//
+//	Password string `yaml:"password" secret:"true"`
+//
+// Note that the handler serves whatever is in the config to whoever can reach it, so it should
+// only be mounted where the rest of the debug endpoints are.
+//
+// A Status is also an expvar.Var publishing the metadata of the loads, without the config itself,
+// and Healthy tells readiness probes whether the last load succeeded.
+type Status struct {
+	mu sync.RWMutex
+
+	config   interface{}
+	version  string
+	files    []string
+	origins  map[string]string
+	warnings []Warning
+	loaded   time.Time
+	loads    int
+
+	err      error
+	failed   time.Time
+	failures int
+}
+
+// ErrNotLoaded is returned by Status.Healthy before anything was loaded successfully
+var ErrNotLoaded = errors.New("gofigure: config not loaded")
+
+// NewStatus creates a status with nothing loaded yet
+func NewStatus() *Status {
+	return &Status{}
+}
+
+// record updates the status with the outcome of a load into config. A failed load only records
+// the error, keeping the last config that was loaded successfully. Status can be nil, in which
+// case nothing is recorded
+func (s *Status) record(config interface{}, report *Report, err error) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if err != nil {
+		s.err = err
+		s.failed = now
+		s.failures++
+		return
+	}
+
+	s.config = dump(reflect.ValueOf(config), true)
+	s.version = report.Version
+	s.warnings = report.Warnings
+	s.loaded = now
+	s.loads++
+	if !report.Cached {
+		s.files = report.Files
+		s.origins = report.Origins
+	}
+}
+
+// LastError returns the error of the last load that failed, and when it failed, or nil if every
+// load succeeded
+func (s *Status) LastError() (error, time.Time) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.err, s.failed
+}
+
+// Version returns a hash of the config that was loaded last, which changes whenever any of its
+// values does, secret ones included
+func (s *Status) Version() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.version
+}
+
+// Healthy returns nil if the last load succeeded, the error it failed with otherwise, or
+// ErrNotLoaded if nothing was loaded yet. Note that a failed load leaves the config that was
+// loaded before it in place, so a program can keep running with it while it's reported unhealthy
+func (s *Status) Healthy() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.err != nil && !s.failed.Before(s.loaded) {
+		return s.err
+	}
+	if s.loaded.IsZero() {
+		return ErrNotLoaded
+	}
+	return nil
+}
+
+// Publish publishes the status as an expvar named name. Like expvar.Publish, it panics if the
+// name is already taken
+func (s *Status) Publish(name string) {
+	expvar.Publish(name, s)
+}
+
+// String returns the metadata of the loads as JSON, making Status an expvar.Var
+func (s *Status) String() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	vars := map[string]interface{}{
+		"version":  s.version,
+		"loads":    s.loads,
+		"failures": s.failures,
+	}
+	if !s.loaded.IsZero() {
+		vars["loaded"] = s.loaded
+	}
+	if s.err != nil {
+		vars["error"] = s.err.Error()
+		vars["failed"] = s.failed
+	}
+
+	data, err := json.Marshal(vars)
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
+
+// statusJSON is how a Status is served
+type statusJSON struct {
+	Config   interface{}       `json:"config"`
+	Version  string            `json:"version,omitempty"`
+	Files    []string          `json:"files,omitempty"`
+	Origins  map[string]string `json:"origins,omitempty"`
+	Warnings []string          `json:"warnings,omitempty"`
+	Loaded   *time.Time        `json:"loaded,omitempty"`
+	Error    string            `json:"error,omitempty"`
+	Failed   *time.Time        `json:"failed,omitempty"`
+}
+
+// ServeHTTP serves the status as JSON
+func (s *Status) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	s.mu.RLock()
+	out := statusJSON{
+		Config:  s.config,
+		Version: s.version,
+		Files:   s.files,
+		Origins: s.origins,
+	}
+	for _, warning := range s.warnings {
+		out.Warnings = append(out.Warnings, warning.String())
+	}
+	if !s.loaded.IsZero() {
+		loaded := s.loaded
+		out.Loaded = &loaded
+	}
+	if s.err != nil {
+		failed := s.failed
+		out.Error = s.err.Error()
+		out.Failed = &failed
+	}
+	s.mu.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(out); err != nil {
+		log.Info("Could not serve config status: %s", err)
+	}
+}
+
+// Dump returns config as indented JSON, with the keys it's loaded from and the values of secret
+// fields redacted, the way a Status serves it. Keys are sorted, so dumps of equal configs are equal
+func Dump(config interface{}) ([]byte, error) {
+	return json.MarshalIndent(dump(reflect.ValueOf(config), true), "", "  ")
+}
+
+// configVersion hashes a config value. It fails for configs that can't be marshaled to JSON, such
+// as those holding funcs or channels
+func configVersion(config interface{}) (string, error) {
+	data, err := json.Marshal(dump(reflect.ValueOf(config), false))
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// dump converts a config value to a generic tree keyed by the names its fields are loaded from,
+// with secret fields redacted if redact is true. Pointers, maps and slices that contain themselves
+// are dumped as "<cycle>" where they do
+func dump(v reflect.Value, redact bool) interface{} {
+	return dumper{redact: redact, parents: map[reference]bool{}}.dump(v)
+}
+
+// reference identifies a pointer, map or slice
+type reference struct {
+	pointer uintptr
+	typ     reflect.Type
+	len     int
+}
+
+// dumper remembers the pointers, maps and slices holding the value it dumps, to notice cycles
+type dumper struct {
+	redact  bool
+	parents map[reference]bool
+}
+
+// enter records v, a non-nil pointer, map or slice, as a parent of the values it holds, and returns
+// a function forgetting it once they're dumped. It returns false if v is already one of its parents
+func (d dumper) enter(v reflect.Value) (func(), bool) {
+	r := reference{pointer: v.Pointer(), typ: v.Type()}
+	if v.Kind() == reflect.Slice {
+		r.len = v.Len()
+	}
+	if d.parents[r] {
+		return nil, false
+	}
+	d.parents[r] = true
+	return func() { delete(d.parents, r) }, true
+}
+
+func (d dumper) dump(v reflect.Value) interface{} {
+
+	if !v.IsValid() {
+		return nil
+	}
+	if v.Type() == durationType {
+		return time.Duration(v.Int()).String()
+	}
+	if v.CanInterface() {
+		if m, ok := v.Interface().(encoding.TextMarshaler); ok && (v.Kind() != reflect.Ptr || !v.IsNil()) {
+			if text, err := m.MarshalText(); err == nil {
+				return string(text)
+			}
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		if v.Kind() == reflect.Ptr {
+			leave, ok := d.enter(v)
+			if !ok {
+				return cycle
+			}
+			defer leave()
+		}
+		return d.dump(v.Elem())
+
+	case reflect.Struct:
+		out := map[string]interface{}{}
+		for _, f := range structFields(v.Type()) {
+			fv, ok := fieldValue(v, f.index)
+			if !ok {
+				continue
+			}
+			if d.redact && f.secret && !fv.IsZero() {
+				out[f.names[0]] = redacted
+				continue
+			}
+			out[f.names[0]] = d.dump(fv)
+		}
+		return out
+
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		leave, ok := d.enter(v)
+		if !ok {
+			return cycle
+		}
+		defer leave()
+		out := map[string]interface{}{}
+		for it := v.MapRange(); it.Next(); {
+			out[fmt.Sprint(it.Key().Interface())] = d.dump(it.Value())
+		}
+		return out
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice {
+			if v.IsNil() {
+				return nil
+			}
+			leave, ok := d.enter(v)
+			if !ok {
+				return cycle
+			}
+			defer leave()
+		}
+		out := make([]interface{}, v.Len())
+		for i := range out {
+			out[i] = d.dump(v.Index(i))
+		}
+		return out
+	}
+
+	if v.CanInterface() {
+		return v.Interface()
+	}
+	return nil
+}
+
+// fieldValue is reflect.Value.FieldByIndex without allocating, returning false if the field is in
+// a nil embedded struct pointer
+func fieldValue(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
@@ -0,0 +1,131 @@
+package gofigure
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/EverythingMe/gofigure/yaml"
+)
+
+func TestStatus(t *testing.T) {
+
+	var conf struct {
+		Redis redisConfig `yaml:"redis"`
+		Mysql struct {
+			Server   string `yaml:"server"`
+			Password string `yaml:"password" secret:"true"`
+		} `yaml:"mysql"`
+	}
+
+	loader := NewLoader(yaml.Decoder{}, true)
+	loader.Status = NewStatus()
+	if err := loader.LoadRecursive(&conf, "./testdata"); err != nil {
+		t.Fatal(err)
+	}
+
+	var status struct {
+		Config  map[string]map[string]interface{}
+		Origins map[string]string
+		Error   string
+		Loaded  string
+	}
+	serve := func() {
+		rec := httptest.NewRecorder()
+		loader.Status.ServeHTTP(rec, httptest.NewRequest("GET", "/debug/config", nil))
+		if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	serve()
+	if status.Config["mysql"]["server"] != "localhost:3306" || status.Config["redis"]["timeout"] != 10.0 {
+		t.Errorf("Unexpected config %v", status.Config)
+	}
+	if status.Config["mysql"]["password"] != redacted {
+		t.Errorf("Secret not redacted: %v", status.Config["mysql"])
+	}
+	if status.Origins["redis.timeout"] != "testdata/sub/overrided.yaml" || status.Origins["mysql.user"] != "testdata/test.yaml" {
+		t.Errorf("Unexpected origins %v", status.Origins)
+	}
+	if status.Loaded == "" || status.Error != "" {
+		t.Errorf("Unexpected status %+v", status)
+	}
+
+	// a failed load keeps the last good config
+	loader.Retry.Attempts = 1
+	loader.Sources = []Source{FileSource("testdata/missing.yaml")}
+	if err := loader.LoadRecursive(&conf); err == nil {
+		t.Fatal("Expected an error loading a missing source")
+	}
+	serve()
+	if status.Error == "" || status.Config["mysql"]["server"] != "localhost:3306" {
+		t.Errorf("Unexpected status after a failure %+v", status)
+	}
+	if err, _ := loader.Status.LastError(); err == nil {
+		t.Error("Expected the last error to be recorded")
+	}
+}
+
+func TestStatusHealth(t *testing.T) {
+
+	status := NewStatus()
+	if err := status.Healthy(); err != ErrNotLoaded {
+		t.Errorf("Expected ErrNotLoaded before loading, got %v", err)
+	}
+
+	var conf config
+	loader := NewLoader(yaml.Decoder{}, true)
+	loader.Status = status
+	if err := loader.LoadRecursive(&conf, "./testdata"); err != nil {
+		t.Fatal(err)
+	}
+	if err := status.Healthy(); err != nil {
+		t.Errorf("Expected a healthy status, got %v", err)
+	}
+	version := status.Version()
+	if version == "" {
+		t.Error("Expected a version")
+	}
+
+	good := *loader
+	loader.Retry.Attempts = 1
+	loader.Sources = []Source{FileSource("testdata/missing.yaml")}
+	if err := loader.LoadRecursive(&conf); err == nil {
+		t.Fatal("Expected an error loading a missing source")
+	}
+	if err := status.Healthy(); err == nil {
+		t.Error("Expected an unhealthy status after a failed load")
+	}
+
+	status.Publish("gofigure-test")
+	var vars struct {
+		Version  string
+		Loads    int
+		Failures int
+		Error    string
+	}
+	if err := json.Unmarshal([]byte(expvar.Get("gofigure-test").String()), &vars); err != nil {
+		t.Fatal(err)
+	}
+	if vars.Version != version || vars.Loads != 1 || vars.Failures != 1 || vars.Error == "" {
+		t.Errorf("Unexpected expvar %+v", vars)
+	}
+
+	if err := good.LoadRecursive(&conf, "./testdata"); err != nil {
+		t.Fatal(err)
+	}
+	if err := status.Healthy(); err != nil {
+		t.Errorf("Expected a healthy status after recovering, got %v", err)
+	}
+	if status.Version() != version {
+		t.Error("Version changed without the config changing")
+	}
+
+	conf.Mysql.Password = "changed"
+	status.record(&conf, &Report{}, nil)
+	if status.Version() == version {
+		t.Error("Version did not change with the config")
+	}
+}
@@ -0,0 +1,102 @@
+package gofigure
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+)
+
+// ItemFunc is called by Stream with every item of the collection being streamed: the key of a
+// mapping entry or the index of a list element, and a function binding the item to a value, the
+// way LoadRecursive binds documents to configs
+type ItemFunc func(key string, bind func(v interface{}) error) error
+
+// StreamDecoder is implemented by decoders that can decode a collection in a file item by item,
+// like the yaml and json decoders. It calls fn with every item of the collection at the dotted
+// path at, decoded into a generic tree
+type StreamDecoder interface {
+	Stream(r io.Reader, at string, fn func(key string, item interface{}) error) error
+}
+
+// Stream decodes the mapping or list at the dotted path at ("" for the whole document) of the
+// file at path item by item, calling fn with each of them, so huge generated files don't need to
+// be held in memory as generic trees and then as config structs. Returning an error from fn stops
+// the stream and is returned.
+//
+// The file is decompressed and transcoded as LoadRecursive would, but it's a single file: its
+// includes, conditional sections and references are left as they are. Preprocessors, if the
+// loader has any, read the whole file before it's streamed. The file's decoder must be a
+// StreamDecoder.
+func (l Loader) Stream(path, at string, fn ItemFunc) error {
+
+	fp, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+
+	rc, base, err := decompress(path, fp)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	r, err := l.preprocess(path, rc)
+	if err != nil {
+		return err
+	}
+	if r, err = transcode(path, r); err != nil {
+		return err
+	}
+
+	d, ok := l.decoderFor(base).(StreamDecoder)
+	if !ok {
+		return fmt.Errorf("gofigure: %s can't be streamed, its decoder is not a StreamDecoder", path)
+	}
+
+	err = d.Stream(r, at, func(key string, item interface{}) error {
+		return fn(key, func(v interface{}) error {
+			return l.bind(nil, item, v)
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("gofigure: streaming %s: %w", path, err)
+	}
+	return nil
+}
+
+// StreamInto is Stream, appending every item to the slice collection points to, or setting it in
+// the map with string keys collection points to
+func (l Loader) StreamInto(path, at string, collection interface{}) error {
+
+	ptr := reflect.ValueOf(collection)
+	if ptr.Kind() != reflect.Ptr || ptr.IsNil() {
+		return fmt.Errorf("gofigure: StreamInto needs a pointer to a slice or a map, got %T", collection)
+	}
+	c := ptr.Elem()
+
+	switch {
+	case c.Kind() == reflect.Slice:
+	case c.Kind() == reflect.Map && c.Type().Key().Kind() == reflect.String:
+		if c.IsNil() {
+			c.Set(reflect.MakeMap(c.Type()))
+		}
+	default:
+		return fmt.Errorf("gofigure: StreamInto needs a pointer to a slice or a map, got %T", collection)
+	}
+
+	return l.Stream(path, at, func(key string, bind func(v interface{}) error) error {
+		item := reflect.New(c.Type().Elem())
+		if err := bind(item.Interface()); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+
+		if c.Kind() == reflect.Slice {
+			c.Set(reflect.Append(c, item.Elem()))
+		} else {
+			c.SetMapIndex(reflect.ValueOf(key).Convert(c.Type().Key()), item.Elem())
+		}
+		return nil
+	})
+}
@@ -0,0 +1,69 @@
+package gofigure
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/EverythingMe/gofigure/json"
+)
+
+func TestStream(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gofigure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "hosts.json")
+	doc := `{"version": 2, "skipped": {"a": [1, {"b": 2}]}, "hosts": {
+		"web": {"server": "web:80", "timeout": 1},
+		"cache": {"server": "cache:6379", "timeout": 2}
+	}, "list": [{"server": "a"}, {"server": "b"}]}`
+	if err := ioutil.WriteFile(path, []byte(doc), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	loader := NewLoader(json.Decoder{}, true)
+
+	hosts := map[string]redisConfig{}
+	if err := loader.StreamInto(path, "hosts", &hosts); err != nil {
+		t.Fatal(err)
+	}
+	if len(hosts) != 2 || hosts["web"].Server != "web:80" || hosts["cache"].Timeout != 2 {
+		t.Errorf("Unexpected items: %#v", hosts)
+	}
+
+	var list []redisConfig
+	if err := loader.StreamInto(path, "list", &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[1].Server != "b" {
+		t.Errorf("Unexpected items: %#v", list)
+	}
+
+	// nothing at the path
+	if err := loader.StreamInto(path, "missing", &list); err != nil || len(list) != 2 {
+		t.Errorf("Expected nothing to be streamed: %v, %v", list, err)
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err = loader.Stream(path, "list", func(key string, bind func(interface{}) error) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("Expected the stream to stop at the first item: %d calls, %v", calls, err)
+	}
+
+	if err := loader.StreamInto(path, "version", &list); err == nil {
+		t.Error("Expected an error streaming a scalar")
+	}
+	if err := loader.StreamInto(path, "hosts", list); err == nil {
+		t.Error("Expected an error streaming into a non-pointer")
+	}
+}
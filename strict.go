@@ -0,0 +1,80 @@
+package gofigure
+
+import (
+	"errors"
+)
+
+// ErrorClass is a class of the errors that can happen while loading, for which strict mode can be
+// set separately with Loader.Strictness. Classes are flags, combined with |
+type ErrorClass int
+
+const (
+	// IOErrors are files, sources and manifests that can't be read, and files that don't match
+	// their manifest
+	IOErrors ErrorClass = 1 << iota
+
+	// DecodeErrors are malformed documents, and the documents that can't be processed for other
+	// reasons, like a bad conditional section or a failed migration
+	DecodeErrors
+
+	// ValidationErrors are values that can't be assigned to their fields, like a string for an int,
+	// values out of the range of their fields, see RangeError, and structs breaking the constraints
+	// between their fields, see ConstraintError
+	ValidationErrors
+
+	// UnknownKeyErrors are keys matching no field of the config struct. They are only errors if
+	// the loader's Strictness has this class, and are ignored otherwise
+	UnknownKeyErrors
+
+	// AllErrors has all the classes of errors
+	AllErrors = IOErrors | DecodeErrors | ValidationErrors | UnknownKeyErrors
+)
+
+// ErrUnknownKey is the error of the BindError for a key matching no field, when unknown keys are
+// errors
+var ErrUnknownKey = errors.New("no field matches the key")
+
+// ioError marks an error reading a file or fetching a source, as opposed to one decoding it
+type ioError struct {
+	err error
+}
+
+func (e ioError) Error() string {
+	return e.err.Error()
+}
+
+func (e ioError) Unwrap() error {
+	return e.err
+}
+
+// errorClass returns the class of an error that happened while loading. Errors that aren't IO or
+// bind errors are about the contents of a document
+func errorClass(err error) ErrorClass {
+	var bindErr *BindError
+	var rangeErr *RangeError
+	var constraintErr *ConstraintError
+	switch {
+	case errors.Is(err, ErrUnknownKey):
+		return UnknownKeyErrors
+	case errors.As(err, &bindErr), errors.As(err, &rangeErr), errors.As(err, &constraintErr):
+		return ValidationErrors
+	case errors.As(err, &ioError{}):
+		return IOErrors
+	default:
+		return DecodeErrors
+	}
+}
+
+// strict tells whether the loader fails on the errors of a class, rather than logging and skipping
+// them. Without Strictness, StrictMode applies to all classes but unknown keys
+func (l Loader) strict(class ErrorClass) bool {
+	if l.Strictness != 0 {
+		return l.Strictness&class != 0
+	}
+	return l.StrictMode && class != UnknownKeyErrors
+}
+
+// strictFor tells whether the loader fails on err
+func (l Loader) strictFor(err error) bool {
+	return l.strict(errorClass(err))
+}
@@ -0,0 +1,77 @@
+package gofigure
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/EverythingMe/gofigure/yaml"
+)
+
+// failingSource is a Source that can never be fetched
+type failingSource struct{}
+
+func (failingSource) Name() string {
+	return "failing"
+}
+
+func (failingSource) Fetch(ctx context.Context) (io.ReadCloser, error) {
+	return nil, Permanent(errors.New("unreachable"))
+}
+
+func TestStrictness(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gofigure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cases := []struct {
+		files   map[string]string
+		sources []Source
+		class   ErrorClass
+	}{
+		{nil, []Source{failingSource{}}, IOErrors},
+		{map[string]string{"bad.yaml": "redis: [\n"}, nil, DecodeErrors},
+		{map[string]string{"bad.yaml": "redis:\n  timeout: soon\n"}, nil, ValidationErrors},
+		{map[string]string{"bad.yaml": "redis:\n  tiemout: 1\n"}, nil, UnknownKeyErrors},
+	}
+
+	for i, c := range cases {
+		root, err := ioutil.TempDir(dir, "case")
+		if err != nil {
+			t.Fatal(err)
+		}
+		writeFiles(t, root, c.files)
+
+		loader := NewLoader(yaml.Decoder{}, false)
+		loader.Sources = c.sources
+
+		// failing on every class but this one, the error is skipped
+		loader.Strictness = AllErrors &^ c.class
+		var conf config
+		if err := loader.LoadRecursive(&conf, root); err != nil {
+			t.Errorf("case %d: expected the error to be skipped, got %v", i, err)
+		}
+
+		loader.Strictness = c.class
+		err = loader.LoadRecursive(&conf, root)
+		if err == nil {
+			t.Errorf("case %d: expected an error", i)
+		} else if class := errorClass(err); class != c.class {
+			t.Errorf("case %d: expected an error of class %d, got %d: %v", i, c.class, class, err)
+		}
+
+		// StrictMode fails on every class but unknown keys
+		loader.Strictness = 0
+		loader.StrictMode = true
+		err = loader.LoadRecursive(&conf, root)
+		if (err == nil) != (c.class == UnknownKeyErrors) {
+			t.Errorf("case %d: unexpected strict mode result: %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package gofigure
+
+import (
+	"testing"
+
+	"github.com/EverythingMe/gofigure/yaml"
+)
+
+func TestSub(t *testing.T) {
+
+	loader := NewLoader(yaml.Decoder{}, true)
+	loader.Status = NewStatus()
+	redisLoader := loader.Sub("redis")
+
+	var redis redisConfig
+	if err := redisLoader.LoadRecursive(&redis, "./testdata"); err != nil {
+		t.Fatal(err)
+	}
+	if redis != expectedConf.Redis {
+		t.Errorf("Unexpected section %+v", redis)
+	}
+	if redisLoader.Status != nil || loader.Status.Healthy() != ErrNotLoaded {
+		t.Error("Expected the sub loader not to record its loads in the loader's status")
+	}
+
+	m, err := redisLoader.LoadMap("./testdata")
+	if err != nil || m.GetString("server", "") != expectedConf.Redis.Server || m.Has("mysql") {
+		t.Errorf("Unexpected map %v %v", m, err)
+	}
+
+	var server string
+	if err := redisLoader.Sub("server").LoadPath(&server, "", "./testdata"); err != nil || server != expectedConf.Redis.Server {
+		t.Errorf("Unexpected value of the sub loader of a sub loader %q %v", server, err)
+	}
+
+	if m, err := loader.Sub("missing").LoadMap("./testdata"); err != nil || len(m) != 0 {
+		t.Errorf("Expected an empty map for a missing prefix, got %v %v", m, err)
+	}
+}
@@ -0,0 +1,81 @@
+package gofigure
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// Template is a Preprocessor rendering every config file as a text/template before it is decoded,
+// for installations that need a little templating, like different values per host.
+//
+// Templates are rendered with Data as their dot, and can use the functions in Funcs on top of a
+// small set of sprig-style helpers: env, default, required, upper, lower, trim, quote, replace,
+// split, join, contains, hasPrefix, hasSuffix and toJson. Referencing a missing key of a map in
+// Data is an error.
+//
+// Note that a Cache only notices changes to files, not to Data or to the environment.
+type Template struct {
+	// Data is the dot the templates are executed with
+	Data interface{}
+
+	// Funcs are extra functions available to templates, overriding the built-in helpers with the
+	// same name
+	Funcs template.FuncMap
+}
+
+// Preprocess renders the file's contents as a template
+func (t Template) Preprocess(path string, data []byte) ([]byte, error) {
+
+	tmpl, err := template.New(filepath.Base(path)).
+		Option("missingkey=error").
+		Funcs(templateFuncs).
+		Funcs(t.Funcs).
+		Parse(string(data))
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, t.Data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// templateFuncs are the helpers available to every Template
+var templateFuncs = template.FuncMap{
+	"env": os.Getenv,
+	"default": func(def, v interface{}) interface{} {
+		if v == nil || v == "" {
+			return def
+		}
+		return v
+	},
+	"required": func(msg string, v interface{}) (interface{}, error) {
+		if v == nil || v == "" {
+			return nil, fmt.Errorf("%s", msg)
+		}
+		return v, nil
+	},
+	"upper":   strings.ToUpper,
+	"lower":   strings.ToLower,
+	"trim":    strings.TrimSpace,
+	"quote":   func(s string) string { return fmt.Sprintf("%q", s) },
+	"replace": func(old, new, s string) string { return strings.Replace(s, old, new, -1) },
+	"split":   func(sep, s string) []string { return strings.Split(s, sep) },
+	"join": func(sep string, l []string) string {
+		return strings.Join(l, sep)
+	},
+	"contains":  func(substr, s string) bool { return strings.Contains(s, substr) },
+	"hasPrefix": func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+	"hasSuffix": func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+	"toJson": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
@@ -0,0 +1,84 @@
+package gofigure
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/EverythingMe/gofigure/yaml"
+)
+
+func TestTemplate(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gofigure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	contents := `
+host: {{ .host | upper }}
+port: {{ env "GOFIGURE_TEST_PORT" | default 8080 }}
+tags: {{ split "," "a,b" | toJson }}
+name: {{ greet "app" }}
+`
+	if err := ioutil.WriteFile(filepath.Join(dir, "conf.yaml"), []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.Unsetenv("GOFIGURE_TEST_PORT")
+
+	var conf struct {
+		Host string
+		Port int
+		Tags []string
+		Name string
+	}
+
+	loader := NewLoader(yaml.Decoder{}, true)
+	loader.Preprocessors = []Preprocessor{Template{
+		Data:  map[string]interface{}{"host": "example.com"},
+		Funcs: template.FuncMap{"greet": func(s string) string { return "hello " + s }},
+	}}
+	if err := loader.LoadRecursive(&conf, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if conf.Host != "EXAMPLE.COM" || conf.Port != 8080 || strings.Join(conf.Tags, ",") != "a,b" || conf.Name != "hello app" {
+		t.Errorf("Template not rendered as expected: %#v", conf)
+	}
+
+	loader.Preprocessors = []Preprocessor{Template{Data: map[string]interface{}{}}}
+	if err := loader.LoadRecursive(&conf, dir); err == nil {
+		t.Error("Expected an error for a missing key")
+	}
+}
+
+func TestPreprocessors(t *testing.T) {
+
+	var order []string
+	loader := NewLoader(yaml.Decoder{}, true)
+	loader.Preprocessors = []Preprocessor{
+		PreprocessFunc(func(path string, data []byte) ([]byte, error) {
+			order = append(order, "first")
+			return []byte(strings.Replace(string(data), "6379", "6380", 1)), nil
+		}),
+		PreprocessFunc(func(path string, data []byte) ([]byte, error) {
+			order = append(order, "second")
+			return data, nil
+		}),
+	}
+
+	var conf config
+	if err := loader.LoadFile(&conf, "testdata/test.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(order, ",") != "first,second" {
+		t.Errorf("Preprocessors not run in order: %v", order)
+	}
+	if !strings.HasSuffix(conf.Redis.Server, "6380") {
+		t.Errorf("Preprocessed contents not decoded: %v", conf.Redis)
+	}
+}
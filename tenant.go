@@ -0,0 +1,132 @@
+package gofigure
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Tenants loads the configs of the tenants of a multi-tenant program, each being a shared base
+// tree overridden by the tenant's own directory under a tenants directory:
+//
+//	conf.d/
+//	    app.yaml
+//	tenants/
+//	    acme/
+//	        app.yaml
+//	    globex/
+//	        app.yaml
+//
+//	tenants := gofigure.NewTenants[Config](loader, "tenants", "conf.d")
+//	conf, err := tenants.Tenant("acme") // conf.d, then tenants/acme
+//
+// A tenant's config is loaded the first time it's asked for, and cached until Forget or Reset.
+// Tenants is safe for concurrent use, and different tenants are loaded concurrently.
+type Tenants[T any] struct {
+	loader Loader
+	dir    string
+	base   []string
+
+	mu      sync.Mutex
+	configs map[string]*tenant[T]
+}
+
+// tenant is the cached config of a tenant, loaded once
+type tenant[T any] struct {
+	mu     sync.Mutex
+	config *T
+}
+
+// NewTenants creates tenants loaded with l, or DefaultLoader if l is nil, from the directories under
+// dir, each on top of the trees under the base paths. The loader's Status, History, Version and
+// OnChange hook aren't updated by tenant loads, so that they keep reporting the program's own
+// config, and rolling it back doesn't replace the configs of the tenants
+func NewTenants[T any](l *Loader, dir string, base ...string) *Tenants[T] {
+	if l == nil {
+		l = DefaultLoader
+	}
+	loader := *l
+	loader.Status = nil
+	loader.History = nil
+	loader.OnChange = nil
+	loader.versions = nil
+	return &Tenants[T]{
+		loader:  loader,
+		dir:     dir,
+		base:    base,
+		configs: map[string]*tenant[T]{},
+	}
+}
+
+// Tenant returns the config of the tenant id, loading it if it isn't cached. It fails if the tenant
+// has no directory, or if its config can't be loaded; failed loads aren't cached. The config is
+// shared by all the callers, and must not be changed; Clone it to derive a variant
+func (t *Tenants[T]) Tenant(id string) (*T, error) {
+
+	if id == "" || id == "." || id == ".." || strings.ContainsAny(id, `/\`) {
+		return nil, fmt.Errorf("gofigure: invalid tenant id %q", id)
+	}
+
+	t.mu.Lock()
+	entry, found := t.configs[id]
+	if !found {
+		entry = &tenant[T]{}
+		t.configs[id] = entry
+	}
+	t.mu.Unlock()
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	if entry.config != nil {
+		return entry.config, nil
+	}
+
+	path := filepath.Join(t.dir, id)
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("gofigure: unknown tenant %s: no directory %s", id, path)
+	}
+
+	config := new(T)
+	if err := t.loader.LoadRecursive(config, append(append([]string{}, t.base...), path)...); err != nil {
+		return nil, fmt.Errorf("gofigure: loading tenant %s: %w", id, err)
+	}
+	entry.config = config
+	return config, nil
+}
+
+// IDs returns the ids of the tenants found in the tenants directory, sorted, whether they're loaded
+// or not
+func (t *Tenants[T]) IDs() ([]string, error) {
+	infos, err := ioutil.ReadDir(t.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, info := range infos {
+		if info.IsDir() && !strings.HasPrefix(info.Name(), ".") {
+			ids = append(ids, info.Name())
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Forget drops the cached config of the tenant id, so that the next Tenant call loads it again
+func (t *Tenants[T]) Forget(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.configs, id)
+}
+
+// Reset drops the cached configs of all the tenants, e.g. after the base tree changed
+func (t *Tenants[T]) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.configs = map[string]*tenant[T]{}
+}
@@ -0,0 +1,77 @@
+package gofigure
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/EverythingMe/gofigure/yaml"
+)
+
+func TestTenants(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gofigure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeFiles(t, dir, map[string]string{
+		"conf.d/app.yaml":         "redis:\n  server: localhost:6379\n  timeout: 10\nmysql:\n  user: root\n",
+		"tenants/acme/app.yaml":   "redis:\n  server: acme:6379\n",
+		"tenants/globex/app.yaml": "mysql:\n  user: globex\n",
+		"tenants/broken/app.yaml": "redis: [\n",
+	})
+
+	loader := NewLoader(yaml.Decoder{}, true)
+	loader.Status = NewStatus()
+	loader.History = NewHistory(2)
+	tenants := NewTenants[config](loader, filepath.Join(dir, "tenants"), filepath.Join(dir, "conf.d"))
+
+	acme, err := tenants.Tenant("acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acme.Redis.Server != "acme:6379" || acme.Redis.Timeout != 10 || acme.Mysql.User != "root" {
+		t.Errorf("Unexpected acme config %+v", acme)
+	}
+	globex, err := tenants.Tenant("globex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if globex.Redis.Server != "localhost:6379" || globex.Mysql.User != "globex" {
+		t.Errorf("Unexpected globex config %+v", globex)
+	}
+	if loader.Status.Version() != "" {
+		t.Error("Expected tenant loads not to update the loader's status")
+	}
+	if len(loader.History.Snapshots()) != 0 {
+		t.Error("Expected tenant loads not to be recorded in the loader's history")
+	}
+
+	// configs are cached until forgotten
+	writeFiles(t, dir, map[string]string{"tenants/acme/app.yaml": "redis:\n  server: acme:6380\n"})
+	if again, _ := tenants.Tenant("acme"); again != acme {
+		t.Error("Expected the cached acme config")
+	}
+	tenants.Forget("acme")
+	if again, _ := tenants.Tenant("acme"); again == acme || again.Redis.Server != "acme:6380" {
+		t.Errorf("Expected acme to be reloaded, got %+v", again)
+	}
+
+	for _, id := range []string{"missing", "broken", "", "..", "acme/../globex"} {
+		if _, err := tenants.Tenant(id); err == nil {
+			t.Errorf("Expected an error loading tenant %q", id)
+		}
+	}
+
+	ids, err := tenants.IDs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ids, []string{"acme", "broken", "globex"}) {
+		t.Errorf("Unexpected tenants %v", ids)
+	}
+}
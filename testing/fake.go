@@ -0,0 +1,116 @@
+package testing
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"sync"
+
+	"github.com/EverythingMe/gofigure"
+)
+
+// FakeSource is an in-memory gofigure.Source whose document tests can change, which is also the
+// ReloadMonitor notifying of the changes, so hot reloading can be tested without files or
+// servers:
+//
+//	src := gofiguretesting.NewFakeSource("app.yaml", "workers: 2\n")
+//	loader.Sources = []gofigure.Source{src}
+//	src.Watch(app) // app reloads its config with loader
+//	src.Set("workers: 4\n")
+//
+// Its documents are decoded like a file with its name would be, so the name should have the
+// extension of their format. It's a VersionedSource too, so it can be polled by a PollMonitor.
+type FakeSource struct {
+	name string
+
+	mu       sync.Mutex
+	doc      []byte
+	err      error
+	version  int
+	fetches  int
+	watchers []gofigure.Reloader
+}
+
+// NewFakeSource creates a fake source named name, holding doc
+func NewFakeSource(name, doc string) *FakeSource {
+	return &FakeSource{
+		name: name,
+		doc:  []byte(doc),
+	}
+}
+
+// Name returns the source's name
+func (s *FakeSource) Name() string {
+	return s.name
+}
+
+// Fetch returns the source's document, or fails with the error set by Fail
+func (s *FakeSource) Fetch(ctx context.Context) (io.ReadCloser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.fetches++
+	if s.err != nil {
+		return nil, s.err
+	}
+	return ioutil.NopCloser(bytes.NewReader(s.doc)), nil
+}
+
+// Version returns the number of times the document was changed
+func (s *FakeSource) Version(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return strconv.Itoa(s.version), nil
+}
+
+// Set replaces the source's document, and makes it fetchable again if Fail was called. The
+// watchers of the source are reloaded before Set returns
+func (s *FakeSource) Set(doc string) {
+	s.change([]byte(doc), nil)
+}
+
+// Fail makes fetching the source fail with err until the next Set, e.g. to test that a program
+// keeps its config when a reload fails. Wrap err with gofigure.Permanent to keep loaders from
+// retrying. The watchers of the source are reloaded before Fail returns
+func (s *FakeSource) Fail(err error) {
+	s.change(nil, err)
+}
+
+// change updates the source, keeping its document if doc is nil, and reloads its watchers
+func (s *FakeSource) change(doc []byte, err error) {
+	s.mu.Lock()
+	if doc != nil {
+		s.doc = doc
+	}
+	s.err = err
+	s.version++
+	watchers := append([]gofigure.Reloader(nil), s.watchers...)
+	s.mu.Unlock()
+
+	for _, r := range watchers {
+		r.Reload()
+	}
+}
+
+// Fetches returns how many times the source was fetched
+func (s *FakeSource) Fetches() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fetches
+}
+
+// Watch makes r reloaded whenever the source changes, making FakeSource a ReloadMonitor
+func (s *FakeSource) Watch(r gofigure.Reloader) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.watchers = append(s.watchers, r)
+}
+
+// Stop stops notifying the source's watchers of its changes
+func (s *FakeSource) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.watchers = nil
+}
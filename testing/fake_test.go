@@ -0,0 +1,73 @@
+package testing
+
+import (
+	"errors"
+	stdtesting "testing"
+	"time"
+
+	"github.com/EverythingMe/gofigure"
+)
+
+func TestFakeSource(t *stdtesting.T) {
+
+	src := NewFakeSource("app.yaml", "name: first\n")
+	loader := *gofigure.DefaultLoader
+	loader.Sources = []gofigure.Source{src}
+
+	var conf config
+	var reloadErr error
+	reloads := 0
+	var monitor gofigure.ReloadMonitor = src
+	monitor.Watch(gofigure.ReloadFunc(func() {
+		reloads++
+		var next config
+		if reloadErr = loader.LoadRecursive(&next); reloadErr == nil {
+			conf = next
+		}
+	}))
+
+	if err := loader.LoadRecursive(&conf); err != nil || conf.Name != "first" {
+		t.Fatalf("Unexpected config: %#v, %v", conf, err)
+	}
+
+	src.Set("name: second\n")
+	if reloads != 1 || conf.Name != "second" {
+		t.Errorf("Expected a reload with the new document: %d reloads, %#v", reloads, conf)
+	}
+
+	// a failed reload keeps the previous config
+	src.Fail(gofigure.Permanent(errors.New("down")))
+	if reloads != 2 || reloadErr == nil || conf.Name != "second" {
+		t.Errorf("Expected a failed reload: %d reloads, %#v, %v", reloads, conf, reloadErr)
+	}
+	if src.Fetches() != 3 {
+		t.Errorf("Expected 3 fetches, got %d", src.Fetches())
+	}
+
+	monitor.Stop()
+	src.Set("name: third\n")
+	if reloads != 2 {
+		t.Errorf("Expected no reload once stopped, got %d", reloads)
+	}
+}
+
+func TestFakeSourcePolled(t *stdtesting.T) {
+
+	src := NewFakeSource("app.yaml", "name: first\n")
+	reloaded := make(chan bool, 1)
+	m := gofigure.NewPollMonitor(5*time.Millisecond, src)
+	m.Watch(gofigure.ReloadFunc(func() {
+		select {
+		case reloaded <- true:
+		default:
+		}
+	}))
+	defer m.Stop()
+
+	src.Set("name: second\n")
+	select {
+	case <-reloaded:
+	case <-time.After(time.Second):
+		t.Error("Change not noticed by the poll monitor")
+	}
+}
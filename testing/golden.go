@@ -0,0 +1,127 @@
+package testing
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	stdtesting "testing"
+
+	"github.com/EverythingMe/gofigure"
+)
+
+// update makes Golden write the golden files instead of comparing them
+var update = flag.Bool("gofigure.update", false, "write the golden files of config trees instead of comparing them")
+
+// Golden loads the config tree under paths into config with l, or DefaultLoader if l is nil, and
+// compares the effective config, as dumped by gofigure.Dump, to the golden file. If they differ,
+// t fails with a diff of the two. Running the tests with -gofigure.update writes the golden files
+// instead, e.g.
+//
+//	func TestConfD(t *testing.T) {
+//		var conf Config
+//		gofiguretesting.Golden(t, nil, &conf, "testdata/conf.golden.json", "testdata/conf.d")
+//	}
+func Golden(t stdtesting.TB, l *gofigure.Loader, config interface{}, golden string, paths ...string) {
+	t.Helper()
+
+	if l == nil {
+		l = gofigure.DefaultLoader
+	}
+	if err := l.LoadRecursive(config, paths...); err != nil {
+		t.Fatalf("Loading %s: %s", strings.Join(paths, ", "), err)
+	}
+
+	got, err := gofigure.Dump(config)
+	if err != nil {
+		t.Fatalf("Dumping the config: %s", err)
+	}
+	got = append(got, '\n')
+
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(golden), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(golden, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("Reading the golden file: %s (run the tests with -gofigure.update to write it)", err)
+	}
+	if !bytes.Equal(want, got) {
+		t.Errorf("The config differs from %s (- golden, + loaded):\n%s", golden, diff(string(want), string(got)))
+	}
+}
+
+// diff returns a line diff of a and b, with the lines only in a prefixed by "-", those only in b
+// by "+", and a little context around them
+func diff(a, b string) string {
+
+	x := strings.Split(strings.TrimSuffix(a, "\n"), "\n")
+	y := strings.Split(strings.TrimSuffix(b, "\n"), "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of x[i:] and y[j:]
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var lines []string
+	for i, j := 0, 0; i < len(x) || j < len(y); {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			lines = append(lines, "  "+x[i])
+			i++
+			j++
+		case i < len(x) && (j == len(y) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, "- "+x[i])
+			i++
+		default:
+			lines = append(lines, "+ "+y[j])
+			j++
+		}
+	}
+
+	const context = 3
+	var out strings.Builder
+	last := -1
+	for i, line := range lines {
+		if !near(lines, i, context) {
+			continue
+		}
+		if last >= 0 && i > last+1 {
+			out.WriteString("  ...\n")
+		}
+		fmt.Fprintln(&out, line)
+		last = i
+	}
+	return out.String()
+}
+
+// near tells whether line i is within context lines of a changed line
+func near(lines []string, i, context int) bool {
+	for j := i - context; j <= i+context; j++ {
+		if j >= 0 && j < len(lines) && !strings.HasPrefix(lines[j], "  ") {
+			return true
+		}
+	}
+	return false
+}
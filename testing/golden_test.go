@@ -0,0 +1,92 @@
+package testing
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	stdtesting "testing"
+)
+
+// recorder is a TB recording failures rather than failing the test
+type recorder struct {
+	stdtesting.TB
+	failures []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Fatalf(format string, args ...interface{}) {
+	r.Errorf(format, args...)
+}
+
+func TestGolden(t *stdtesting.T) {
+
+	dir, err := ioutil.TempDir("", "gofigure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	confd := filepath.Join(dir, "conf.d")
+	os.Mkdir(confd, 0755)
+	write := func(name, contents string) {
+		if err := ioutil.WriteFile(filepath.Join(confd, name), []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("10-base.yaml", "name: base\ntimeout: 1s\nports: [80]\n")
+	write("20-override.yaml", "name: override\n")
+	golden := filepath.Join(dir, "conf.golden.json")
+
+	// a missing golden file fails, until it's written
+	r := &recorder{TB: t}
+	Golden(r, nil, &config{}, golden, confd)
+	if len(r.failures) == 0 || !strings.Contains(r.failures[0], "-gofigure.update") {
+		t.Errorf("Expected a missing golden file to fail: %v", r.failures)
+	}
+
+	*update = true
+	Golden(t, nil, &config{}, golden, confd)
+	*update = false
+
+	r = &recorder{TB: t}
+	Golden(r, nil, &config{}, golden, confd)
+	if len(r.failures) != 0 {
+		t.Errorf("Expected the config to match: %v", r.failures)
+	}
+
+	write("30-more.yaml", "timeout: 2s\n")
+	r = &recorder{TB: t}
+	Golden(r, nil, &config{}, golden, confd)
+	if len(r.failures) != 1 || !strings.Contains(r.failures[0], `-   "Timeout": "1s"`) || !strings.Contains(r.failures[0], `+   "Timeout": "2s"`) {
+		t.Errorf("Expected a diff of the change: %v", r.failures)
+	}
+}
+
+func TestDiff(t *stdtesting.T) {
+
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n"
+	b := "1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\n11\n"
+	expected := `  1
+  2
+- 3
++ three
+  4
+  5
+  6
+  ...
+  8
+  9
+  10
++ 11
+`
+	if d := diff(a, b); d != expected {
+		t.Errorf("Unexpected diff:\n%s", d)
+	}
+}
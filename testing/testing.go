@@ -0,0 +1,57 @@
+// Package testing helps testing programs configured with gofigure. It loads config structs from
+// inline documents rather than from files in temporary directories, compares the effective config
+// of trees to golden files, and fakes sources whose changes trigger reloads.
+//
+// Inline documents go through the same pipeline as files: they are merged in order, expanded and
+// resolved, and bound with the loader's options, strict mode included.
+package testing
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/EverythingMe/gofigure"
+)
+
+// LoadFromString loads docs, in the format of a registered decoder such as "yaml" or "json",
+// into config, merging them in order as if they were files. The loader's options apply, but not
+// its sources; if l is nil, DefaultLoader is used
+func LoadFromString(l *gofigure.Loader, config interface{}, format string, docs ...string) error {
+
+	sources := make([]gofigure.Source, len(docs))
+	for i, doc := range docs {
+		sources[i] = gofigure.WithFormat(gofigure.BytesSource(docName(i), []byte(doc)), format)
+	}
+	return load(l, config, sources)
+}
+
+// LoadFromMap loads docs into config, merging them in order as if they were files. The loader's
+// options apply, but not its sources; if l is nil, DefaultLoader is used
+func LoadFromMap(l *gofigure.Loader, config interface{}, docs ...map[string]interface{}) error {
+
+	sources := make([]gofigure.Source, len(docs))
+	for i, doc := range docs {
+		data, err := json.Marshal(doc)
+		if err != nil {
+			return fmt.Errorf("gofigure: %s: %s", docName(i), err)
+		}
+		sources[i] = gofigure.WithFormat(gofigure.BytesSource(docName(i), data), "json")
+	}
+	return load(l, config, sources)
+}
+
+// docName names the i-th inline document in errors and reports
+func docName(i int) string {
+	return fmt.Sprintf("inline document %d", i+1)
+}
+
+// load loads sources into config with a copy of l
+func load(l *gofigure.Loader, config interface{}, sources []gofigure.Source) error {
+	if l == nil {
+		l = gofigure.DefaultLoader
+	}
+	loader := *l
+	loader.Sources = sources
+	loader.Cache = nil
+	return loader.LoadRecursive(config)
+}
@@ -0,0 +1,64 @@
+package testing
+
+import (
+	stdtesting "testing"
+	"time"
+
+	"github.com/EverythingMe/gofigure"
+	"github.com/EverythingMe/gofigure/yaml"
+)
+
+type config struct {
+	Name    string
+	Timeout time.Duration
+	Ports   []int
+}
+
+func TestLoadFromString(t *stdtesting.T) {
+
+	var conf config
+	err := LoadFromString(nil, &conf, "yaml", "name: base\ntimeout: 1s\nports: [80]\n", "name: override\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Name != "override" || conf.Timeout != time.Second || len(conf.Ports) != 1 {
+		t.Errorf("Documents not merged: %#v", conf)
+	}
+
+	if err := LoadFromString(nil, &conf, "json", `{"name": `); err == nil {
+		t.Error("Expected an error for a malformed document")
+	}
+	if err := LoadFromString(nil, &conf, "yaml", "timeout: soon\n"); err == nil {
+		t.Error("Expected an error for a bad value in strict mode")
+	}
+
+	// the loader's options apply
+	loader := gofigure.NewLoader(yaml.Decoder{}, false)
+	loader.ExpandReferences = true
+	conf = config{}
+	if err := LoadFromString(loader, &conf, "yaml", "name: ${timeout}\ntimeout: soon\n"); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Name != "soon" || conf.Timeout != 0 {
+		t.Errorf("Loader options not applied: %#v", conf)
+	}
+}
+
+func TestLoadFromMap(t *stdtesting.T) {
+
+	var conf config
+	err := LoadFromMap(nil, &conf,
+		map[string]interface{}{"name": "base", "ports": []int{80, 443}},
+		map[string]interface{}{"timeout": "2s"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Name != "base" || conf.Timeout != 2*time.Second || len(conf.Ports) != 2 || conf.Ports[1] != 443 {
+		t.Errorf("Documents not merged: %#v", conf)
+	}
+
+	if err := LoadFromMap(nil, &conf, map[string]interface{}{"bad": func() {}}); err == nil {
+		t.Error("Expected an error for a value that isn't a document")
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !gofigure_noregister
+
+package tfvars
+
+import "github.com/EverythingMe/gofigure"
+
+func init() {
+	gofigure.RegisterDecoder("tfvars", Decoder{})
+}
@@ -0,0 +1,128 @@
+// Package tfvars implements a gofigure decoder for Terraform variable definition files, so that
+// services can read the values they share with the infrastructure code that deploys them. Both
+// the native syntax of .tfvars files and the JSON syntax of .tfvars.json files are supported.
+package tfvars
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/EverythingMe/gofigure/internal/tree"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Decoder decodes Terraform variable files into config structs. Like Terraform, it only accepts
+// literal values: expressions referring to variables or calling functions are errors.
+type Decoder struct{}
+
+// Decode unmarshals the variables read from r into config. Files starting with a brace are read
+// as JSON, others in the native syntax
+func (d Decoder) Decode(r io.Reader, config interface{}) error {
+
+	src, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	var doc interface{}
+	if bytes.HasPrefix(bytes.TrimSpace(src), []byte("{")) {
+		dec := json.NewDecoder(bytes.NewReader(src))
+		dec.UseNumber()
+		if err := dec.Decode(&doc); err != nil {
+			return fmt.Errorf("tfvars: %s", err)
+		}
+		doc = tree.Normalize(doc)
+	} else if doc, err = decodeNative(src); err != nil {
+		return err
+	}
+
+	return tree.Assign(doc, config)
+}
+
+// CanDecode returns true if this is a tfvars file
+func (d Decoder) CanDecode(path string) bool {
+	return strings.HasSuffix(path, ".tfvars") || strings.HasSuffix(path, ".tfvars.json")
+}
+
+// decodeNative decodes the native syntax
+func decodeNative(src []byte) (map[string]interface{}, error) {
+
+	file, diags := hclsyntax.ParseConfig(src, "terraform.tfvars", hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("tfvars: %s", diags.Error())
+	}
+	attrs, diags := file.Body.JustAttributes()
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("tfvars: %s", diags.Error())
+	}
+
+	doc := make(map[string]interface{}, len(attrs))
+	for name, attr := range attrs {
+		v, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("tfvars: %s", diags.Error())
+		}
+		if doc[name], diags = toGo(v, attr.Range); diags.HasErrors() {
+			return nil, fmt.Errorf("tfvars: %s", diags.Error())
+		}
+	}
+	return doc, nil
+}
+
+// toGo converts a cty value to a generic tree value
+func toGo(v cty.Value, rng hcl.Range) (interface{}, hcl.Diagnostics) {
+
+	if v.IsNull() {
+		return nil, nil
+	}
+	if !v.IsKnown() {
+		return nil, hcl.Diagnostics{{Severity: hcl.DiagError, Summary: "unknown value", Subject: &rng}}
+	}
+
+	t := v.Type()
+	switch {
+	case t == cty.String:
+		return v.AsString(), nil
+	case t == cty.Bool:
+		return v.True(), nil
+	case t == cty.Number:
+		f := v.AsBigFloat()
+		if i, accuracy := f.Int64(); accuracy == 0 {
+			return i, nil
+		}
+		n, _ := f.Float64()
+		return n, nil
+
+	case t.IsListType() || t.IsTupleType() || t.IsSetType():
+		l := []interface{}{}
+		for it := v.ElementIterator(); it.Next(); {
+			_, e := it.Element()
+			ge, diags := toGo(e, rng)
+			if diags.HasErrors() {
+				return nil, diags
+			}
+			l = append(l, ge)
+		}
+		return l, nil
+
+	case t.IsMapType() || t.IsObjectType():
+		m := map[string]interface{}{}
+		for it := v.ElementIterator(); it.Next(); {
+			k, e := it.Element()
+			ge, diags := toGo(e, rng)
+			if diags.HasErrors() {
+				return nil, diags
+			}
+			m[k.AsString()] = ge
+		}
+		return m, nil
+	}
+
+	return nil, hcl.Diagnostics{{Severity: hcl.DiagError, Summary: "unsupported value of type " + t.FriendlyName(), Subject: &rng}}
+}
@@ -0,0 +1,82 @@
+package tfvars
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+
+	expected := map[string]interface{}{
+		"region":    "eu-west-1",
+		"instances": int64(3),
+		"ratio":     0.5,
+		"public":    true,
+		"zones":     []interface{}{"a", "b"},
+		"tags":      map[string]interface{}{"team": "infra", "cost-center": int64(42)},
+		"optional":  nil,
+	}
+
+	native := `
+# shared with the service
+region    = "eu-west-1"
+instances = 3
+ratio     = 0.5
+public    = true
+zones     = ["a", "b"]
+tags = {
+  team          = "infra"
+  "cost-center" = 42
+}
+optional = null
+`
+	json := `{
+  "region": "eu-west-1", "instances": 3, "ratio": 0.5, "public": true, "zones": ["a", "b"],
+  "tags": {"team": "infra", "cost-center": 42}, "optional": null
+}`
+
+	for name, src := range map[string]string{"native": native, "json": json} {
+		var conf interface{}
+		if err := (Decoder{}).Decode(strings.NewReader(src), &conf); err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		if !reflect.DeepEqual(conf, expected) {
+			t.Errorf("%s: unexpected config %#v", name, conf)
+		}
+	}
+
+	var typed struct {
+		Region    string
+		Instances int
+		Zones     []string
+	}
+	if err := (Decoder{}).Decode(strings.NewReader(native), &typed); err != nil {
+		t.Fatal(err)
+	}
+	if typed.Region != "eu-west-1" || typed.Instances != 3 || len(typed.Zones) != 2 {
+		t.Errorf("Unexpected typed config %#v", typed)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	for _, src := range []string{
+		"region = ",
+		"region = var.other",
+		"region = upper(\"a\")",
+		"block {\n  a = 1\n}",
+		"{\"a\": ",
+	} {
+		var conf interface{}
+		if err := (Decoder{}).Decode(strings.NewReader(src), &conf); err == nil {
+			t.Errorf("Expected an error decoding %q", src)
+		}
+	}
+}
+
+func TestCanDecode(t *testing.T) {
+	d := Decoder{}
+	if !d.CanDecode("prod.tfvars") || !d.CanDecode("prod.tfvars.json") || d.CanDecode("main.tf") {
+		t.Error("Unexpected CanDecode results")
+	}
+}
@@ -0,0 +1,235 @@
+package gofigure
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// CertFile is the path of a PEM file of certificates, like a CA bundle. In configs it's a plain
+// string; tag fields of this type path:"resolve" to have it relative to the file that sets it
+type CertFile string
+
+// Certificates reads and parses the certificates of the file
+func (f CertFile) Certificates() ([]*x509.Certificate, error) {
+	data, err := ioutil.ReadFile(string(f))
+	if err != nil {
+		return nil, err
+	}
+
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		if block, data = pem.Decode(data); block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("gofigure: %s: %s", f, err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("gofigure: no certificates in %s", f)
+	}
+	return certs, nil
+}
+
+// Pool returns a pool of the certificates of the file
+func (f CertFile) Pool() (*x509.CertPool, error) {
+	certs, err := f.Certificates()
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	for _, cert := range certs {
+		pool.AddCert(cert)
+	}
+	return pool, nil
+}
+
+// tlsVersions maps the versions a TLSConfig accepts to those of crypto/tls
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// TLSConfig is the TLS section of a config, which every service otherwise turns into a tls.Config
+// by hand:
+//
+//	tls:
+//	  cert: certs/server.pem
+//	  key: certs/server.key
+//	  ca: [certs/clients-ca.pem]
+//	  client_auth: true
+//	  min_version: "1.2"
+//	  reload: true
+//
+// Paths are resolved against the directory of the file that sets them. ServerConfig and
+// ClientConfig return a tls.Config for each side of connections. With Reload, the certificate and
+// key are read again when their files change, so certificates can be rotated without restarting.
+type TLSConfig struct {
+	// Cert is the certificate chain presented to peers, with Key its private key. They are
+	// required by servers and optional for clients
+	Cert CertFile `yaml:"cert" json:"cert" path:"resolve"`
+	Key  string   `yaml:"key" json:"key" path:"resolve"`
+
+	// CA are the certificates peers are verified against: servers for clients, and clients for
+	// servers with ClientAuth. Clients use the system's roots if there are none
+	CA []CertFile `yaml:"ca" json:"ca" path:"resolve"`
+
+	// ServerName is the name clients verify the certificates of servers against, if it's not that
+	// of the address they connect to
+	ServerName string `yaml:"server_name" json:"server_name"`
+
+	// MinVersion is the lowest version of TLS accepted, "1.0" to "1.3", or the default of
+	// crypto/tls if empty
+	MinVersion string `yaml:"min_version" json:"min_version"`
+
+	// ClientAuth makes servers require certificates from clients, verified against CA
+	ClientAuth bool `yaml:"client_auth" json:"client_auth"`
+
+	// InsecureSkipVerify makes clients accept any certificate, for tests only
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify" json:"insecure_skip_verify"`
+
+	// Reload makes the certificate and key read again when either file changes
+	Reload bool `yaml:"reload" json:"reload"`
+}
+
+// ServerConfig returns the tls.Config of a server, e.g. for http.Server.TLSConfig
+func (c TLSConfig) ServerConfig() (*tls.Config, error) {
+	if c.Cert == "" || c.Key == "" {
+		return nil, errors.New("gofigure: a TLS server needs a cert and a key")
+	}
+
+	conf, pair, err := c.config()
+	if err != nil {
+		return nil, err
+	}
+	if c.ClientAuth {
+		if conf.ClientCAs = conf.RootCAs; conf.ClientCAs == nil {
+			return nil, errors.New("gofigure: TLS client auth needs a ca")
+		}
+		conf.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	conf.RootCAs = nil
+
+	if c.Reload {
+		conf.GetCertificate = func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return pair.get()
+		}
+	} else {
+		conf.Certificates = []tls.Certificate{*pair.cert}
+	}
+	return conf, nil
+}
+
+// ClientConfig returns the tls.Config of a client, e.g. for http.Transport.TLSClientConfig
+func (c TLSConfig) ClientConfig() (*tls.Config, error) {
+	conf, pair, err := c.config()
+	if err != nil {
+		return nil, err
+	}
+	conf.ServerName = c.ServerName
+	conf.InsecureSkipVerify = c.InsecureSkipVerify
+
+	switch {
+	case pair == nil:
+	case c.Reload:
+		conf.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return pair.get()
+		}
+	default:
+		conf.Certificates = []tls.Certificate{*pair.cert}
+	}
+	return conf, nil
+}
+
+// config returns what the configs of both sides have in common, with the CA pool as RootCAs, and
+// the loaded key pair, or nil if there is none
+func (c TLSConfig) config() (*tls.Config, *keyPair, error) {
+
+	conf := &tls.Config{}
+	if c.MinVersion != "" {
+		v, found := tlsVersions[c.MinVersion]
+		if !found {
+			return nil, nil, fmt.Errorf("gofigure: unknown TLS version %s", c.MinVersion)
+		}
+		conf.MinVersion = v
+	}
+
+	if len(c.CA) > 0 {
+		conf.RootCAs = x509.NewCertPool()
+		for _, f := range c.CA {
+			certs, err := f.Certificates()
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, cert := range certs {
+				conf.RootCAs.AddCert(cert)
+			}
+		}
+	}
+
+	if c.Cert == "" && c.Key == "" {
+		return conf, nil, nil
+	}
+	pair := &keyPair{certFile: string(c.Cert), keyFile: c.Key}
+	if _, err := pair.get(); err != nil {
+		return nil, nil, err
+	}
+	return conf, pair, nil
+}
+
+// keyPair is a certificate and its key, read again from their files when they change
+type keyPair struct {
+	certFile, keyFile string
+
+	mu       sync.Mutex
+	cert     *tls.Certificate
+	modified time.Time
+}
+
+// get returns the certificate, reading the files again if either was modified since they were
+// last read. If they can't be read, the certificate read before is kept
+func (p *keyPair) get() (*tls.Certificate, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var modified time.Time
+	for _, path := range []string{p.certFile, p.keyFile} {
+		if info, err := os.Stat(path); err == nil && info.ModTime().After(modified) {
+			modified = info.ModTime()
+		}
+	}
+	if p.cert != nil && !modified.After(p.modified) {
+		return p.cert, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(p.certFile, p.keyFile)
+	if err != nil {
+		if p.cert == nil {
+			return nil, fmt.Errorf("gofigure: loading %s: %s", p.certFile, err)
+		}
+		log.Warning("Could not reload %s, keeping the certificate loaded before: %s", p.certFile, err)
+		p.modified = modified
+		return p.cert, nil
+	}
+	if p.cert != nil {
+		log.Info("Reloaded %s", p.certFile)
+	}
+	p.cert = &cert
+	p.modified = modified
+	return p.cert, nil
+}
@@ -0,0 +1,120 @@
+package gofigure
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeKeyPair writes a self-signed certificate for name and its key to dir
+func writeKeyPair(t *testing.T, dir, name string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		DNSNames:              []string{name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	if err := ioutil.WriteFile(filepath.Join(dir, "cert.pem"), certPEM, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "key.pem"), keyPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTLSConfig(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gofigure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeKeyPair(t, dir, "first.example.com")
+
+	c := TLSConfig{
+		Cert:       CertFile(filepath.Join(dir, "cert.pem")),
+		Key:        filepath.Join(dir, "key.pem"),
+		CA:         []CertFile{CertFile(filepath.Join(dir, "cert.pem"))},
+		MinVersion: "1.2",
+		ClientAuth: true,
+		Reload:     true,
+	}
+
+	server, err := c.ServerConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if server.MinVersion != tls.VersionTLS12 || server.ClientAuth != tls.RequireAndVerifyClientCert || server.ClientCAs == nil {
+		t.Errorf("Unexpected server config %+v", server)
+	}
+	commonName := func() string {
+		cert, err := server.GetCertificate(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return leaf.Subject.CommonName
+	}
+	if name := commonName(); name != "first.example.com" {
+		t.Errorf("Unexpected certificate %s", name)
+	}
+
+	// rotated certificates are reloaded
+	writeKeyPair(t, dir, "second.example.com")
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(filepath.Join(dir, "cert.pem"), later, later)
+	if name := commonName(); name != "second.example.com" {
+		t.Errorf("Expected the rotated certificate, got %s", name)
+	}
+
+	client, err := TLSConfig{CA: c.CA, ServerName: "second.example.com"}.ClientConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.RootCAs == nil || client.ServerName != "second.example.com" || len(client.Certificates) != 0 {
+		t.Errorf("Unexpected client config %+v", client)
+	}
+
+	if _, err := (TLSConfig{Cert: c.Cert}).ServerConfig(); err == nil {
+		t.Error("Expected an error for a server without a key")
+	}
+	if _, err := (TLSConfig{MinVersion: "2.0"}).ClientConfig(); err == nil {
+		t.Error("Expected an error for an unknown TLS version")
+	}
+	if _, err := (TLSConfig{CA: []CertFile{CertFile(filepath.Join(dir, "key.pem"))}}).ClientConfig(); err == nil {
+		t.Error("Expected an error for a CA without certificates")
+	}
+}
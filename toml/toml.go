@@ -0,0 +1,79 @@
+// Package toml implements a gofigure decoder for TOML files. Importing it registers the decoder
+// under the toml format, see gofigure.RegisterDecoder.
+package toml
+
+import (
+	"io"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/EverythingMe/gofigure/internal/tree"
+)
+
+// Decoder decodes TOML files into config structs
+type Decoder struct{}
+
+// Decode unmarshals the TOML read from r into config. When decoding into a pointer to an
+// interface{}, dates and times are decoded as RFC 3339 strings, which time.Time fields accept, and
+// local dates and times without an offset as they are written
+func (d Decoder) Decode(r io.Reader, config interface{}) error {
+
+	if _, isGeneric := config.(*interface{}); !isGeneric {
+		_, err := toml.NewDecoder(r).Decode(config)
+		return err
+	}
+
+	var doc map[string]interface{}
+	if _, err := toml.NewDecoder(r).Decode(&doc); err != nil {
+		return err
+	}
+	return tree.Assign(normalize(doc), config)
+}
+
+// Encode writes v, which must be a mapping or a struct, to w as TOML, making Decoder a
+// gofigure.Encoder
+func (d Decoder) Encode(w io.Writer, v interface{}) error {
+	return toml.NewEncoder(w).Encode(v)
+}
+
+// CanDecode returns true if this is a TOML file
+func (d Decoder) CanDecode(path string) bool {
+	return strings.HasSuffix(path, ".toml")
+}
+
+// normalize converts the values TOML has but generic documents don't
+func normalize(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = normalize(e)
+		}
+		return v
+	case []map[string]interface{}:
+		l := make([]interface{}, len(v))
+		for i, e := range v {
+			l[i] = normalize(e)
+		}
+		return l
+	case []interface{}:
+		for i, e := range v {
+			v[i] = normalize(e)
+		}
+		return v
+	case time.Time:
+		if layout, found := localLayouts[v.Location().String()]; found {
+			return v.Format(layout)
+		}
+		return v.Format(time.RFC3339Nano)
+	}
+	return v
+}
+
+// localLayouts are the layouts of the local dates and times of TOML, by the names of the
+// locations the toml package decodes them in
+var localLayouts = map[string]string{
+	"datetime-local": "2006-01-02T15:04:05.999999999",
+	"date-local":     "2006-01-02",
+	"time-local":     "15:04:05.999999999",
+}
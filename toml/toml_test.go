@@ -0,0 +1,81 @@
+package toml
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/EverythingMe/gofigure"
+)
+
+const src = `
+name = "app"
+since = 2024-01-02T03:04:05Z
+day = 2024-01-02
+
+[redis]
+server = "localhost:6379"
+timeout = 10
+
+[[servers]]
+host = "a"
+
+[[servers]]
+host = "b"
+`
+
+func TestDecode(t *testing.T) {
+
+	var conf interface{}
+	if err := (Decoder{}).Decode(strings.NewReader(src), &conf); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"name":    "app",
+		"since":   "2024-01-02T03:04:05Z",
+		"day":     "2024-01-02",
+		"redis":   map[string]interface{}{"server": "localhost:6379", "timeout": int64(10)},
+		"servers": []interface{}{map[string]interface{}{"host": "a"}, map[string]interface{}{"host": "b"}},
+	}
+	if !reflect.DeepEqual(conf, expected) {
+		t.Errorf("Unexpected config %#v", conf)
+	}
+}
+
+func TestRegistered(t *testing.T) {
+
+	if _, found := gofigure.LookupDecoder("toml"); !found {
+		t.Fatal("toml decoder not registered")
+	}
+
+	dir, err := ioutil.TempDir("", "gofigure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "conf.toml"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var conf struct {
+		Name  string
+		Since time.Time
+		Redis struct {
+			Server  string `toml:"server"`
+			Timeout int
+		}
+		Servers []struct {
+			Host string
+		}
+	}
+	if err := gofigure.DefaultLoader.LoadRecursive(&conf, dir); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Name != "app" || conf.Since.Year() != 2024 || conf.Redis.Timeout != 10 || len(conf.Servers) != 2 {
+		t.Errorf("Unexpected config %#v", conf)
+	}
+}
@@ -0,0 +1,36 @@
+package gofigure
+
+import (
+	"reflect"
+
+	"github.com/EverythingMe/gofigure/internal/tree"
+)
+
+// traceDocuments logs, for every traced key, every document that sets it with the value it
+// assigned, in the order they are merged
+func (l Loader) traceDocuments(docs []document) {
+	for _, key := range l.Trace {
+		var set bool
+		for _, doc := range docs {
+			if v, found := tree.Lookup(doc.tree, key); found {
+				log.Info("Trace %s: %s sets %#v", key, doc.source, v)
+				set = true
+			}
+		}
+		if !set {
+			log.Info("Trace %s: not set by any file or source", key)
+		}
+	}
+}
+
+// traceResolved logs the value of every traced key after the merged tree was expanded and
+// resolved, if it's not the value the documents merged into
+func (l Loader) traceResolved(before, after map[string]interface{}) {
+	for _, key := range l.Trace {
+		was, _ := tree.Lookup(before, key)
+		v, found := tree.Lookup(after, key)
+		if found && !reflect.DeepEqual(was, v) {
+			log.Info("Trace %s: expanded and resolved to %#v", key, v)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package gofigure
+
+import (
+	stdlog "log"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/EverythingMe/gofigure/yaml"
+	"github.com/op/go-logging"
+)
+
+func TestTrace(t *testing.T) {
+
+	backend := logging.NewMemoryBackend(64)
+	logging.SetBackend(backend)
+	defer logging.SetBackend(logging.NewLogBackend(os.Stderr, "", stdlog.LstdFlags))
+
+	loader := NewLoader(yaml.Decoder{}, true)
+	loader.Trace = []string{"redis.server", "redis.missing"}
+	var conf config
+	if err := loader.LoadRecursive(&conf, "./testdata"); err != nil {
+		t.Fatal(err)
+	}
+
+	var traces []string
+	for n := backend.Head(); n != nil; n = n.Next() {
+		if msg := n.Record.Message(); strings.HasPrefix(msg, "Trace ") {
+			traces = append(traces, msg)
+		}
+	}
+
+	expected := []string{
+		`Trace redis.server: testdata/sub/overrided.yaml sets "localhost:6378"`,
+		`Trace redis.server: testdata/test.yaml sets "localhost:6379"`,
+		`Trace redis.missing: not set by any file or source`,
+	}
+	if strings.Join(traces, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Unexpected traces %q", traces)
+	}
+}
@@ -0,0 +1,369 @@
+package gofigure
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// bytesType is special cased so that ranges of byte counts can be written like "10GiB"
+var bytesType = reflect.TypeOf(Bytes(0))
+
+// RangeError is the error of a value outside of the range set by the range tag of its field, e.g.
+//
+//	Timeout time.Duration  `yaml:"timeout" range:"min=1s,max=1m"`
+//	MaxBody gofigure.Bytes `yaml:"max_body" range:"max=10MiB"`
+//	Workers int            `yaml:"workers" range:"min=1"`
+//
+// Ranges can be set on durations, Bytes, and numbers, and on lists and maps of them, whose every
+// element must be in range. Bounds are inclusive, and written the way values are in configs.
+// Values out of range fail strict loads, as ValidationErrors, and are reported and kept otherwise.
+type RangeError struct {
+	// Path is the dotted path of the value
+	Path string
+
+	// Value is the value out of range
+	Value interface{}
+
+	// Bound is the option of the range tag the value is outside of, like "max=1m"
+	Bound string
+}
+
+func (e *RangeError) Error() string {
+	if strings.HasPrefix(e.Bound, "min=") {
+		return fmt.Sprintf("gofigure: %s is %v, below the minimum of %s", e.Path, e.Value, e.Bound[len("min="):])
+	}
+	return fmt.Sprintf("gofigure: %s is %v, above the maximum of %s", e.Path, e.Value, e.Bound[len("max="):])
+}
+
+// ConstraintError is the error of a value breaking a constraint between the fields of a struct,
+// set with their tags:
+//
+//	type TLS struct {
+//		Enabled bool   `yaml:"enabled"`
+//		Cert    string `yaml:"cert" required_if:"enabled"`
+//		Mode    string `yaml:"mode"`
+//		CA      string `yaml:"ca" required_if:"mode=verify"`
+//		Level   string `yaml:"level" oneof:"debug info warn error"`
+//	}
+//
+//	type Auth struct {
+//		Token    string `yaml:"token" exclusive:"credentials"`
+//		Password string `yaml:"password" exclusive:"credentials"`
+//	}
+//
+// A field tagged oneof, like `oneof:"debug info warn error"`, must be one of the values it lists,
+// separated by spaces, if it's set; lists and maps tagged oneof must only hold these values.
+// A field tagged required_if must be set, i.e. not be zero, if the value at a dotted path from the
+// struct holding it is set, or, for "path=value", if it's value. Of the fields tagged exclusive
+// with the same group name, at most one can be set. Broken constraints fail strict loads, as
+// ValidationErrors, and are reported otherwise.
+type ConstraintError struct {
+	// Path is the dotted path of the value
+	Path string
+
+	// Message describes the constraint that's broken
+	Message string
+}
+
+func (e *ConstraintError) Error() string {
+	return fmt.Sprintf("gofigure: %s %s", e.Path, e.Message)
+}
+
+// validate checks that the values of config are in the ranges of their fields, and that its
+// structs meet the constraints of their fields. Outside of strict mode, invalid values are
+// reported and kept
+func (l Loader) validate(report *Report, config interface{}) error {
+	return validateValue(reflect.ValueOf(config), "", func(path string, err error) error {
+		if l.strict(ValidationErrors) {
+			return err
+		}
+		report.warn(Warning{Key: path, Message: err.Error(), Err: err})
+		return nil
+	})
+}
+
+// validateValue walks v, checking the values of the fields that have a range tag and the
+// constraints of structs, and calls fail with the path and error of every invalid value. The path
+// is that of v in the config
+func validateValue(v reflect.Value, path string, fail func(string, error) error) error {
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return validateValue(v.Elem(), path, fail)
+
+	case reflect.Struct:
+		if err := checkConstraints(v, path, fail); err != nil {
+			return err
+		}
+		for _, f := range structFields(v.Type()) {
+			fv, ok := fieldValue(v, f.index)
+			if !ok {
+				continue
+			}
+			p := joinPath(path, f.names[0])
+			if len(f.oneOf) > 0 {
+				if err := checkOneOf(fv, f.oneOf, p, fail); err != nil {
+					return err
+				}
+			}
+			if f.bounds != "" {
+				if err := checkRange(fv, f.bounds, p, fail); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := validateValue(fv, p, fail); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := validateValue(v.Index(i), joinPath(path, strconv.Itoa(i)), fail); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map:
+		for it := v.MapRange(); it.Next(); {
+			if err := validateValue(it.Value(), joinPath(path, fmt.Sprint(it.Key().Interface())), fail); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkConstraints checks the required_if and exclusive constraints of the fields of the struct v
+func checkConstraints(v reflect.Value, path string, fail func(string, error) error) error {
+
+	var groups map[string][]string
+	for _, f := range structFields(v.Type()) {
+		if f.requiredIf == "" && f.exclusive == "" {
+			continue
+		}
+		fv, ok := fieldValue(v, f.index)
+		set := ok && !fv.IsZero()
+		p := joinPath(path, f.names[0])
+
+		if f.requiredIf != "" && !set && conditionHolds(v, f.requiredIf) {
+			cond := f.requiredIf + " is set"
+			if i := strings.Index(f.requiredIf, "="); i >= 0 {
+				cond = f.requiredIf[:i] + " is " + f.requiredIf[i+1:]
+			}
+			if err := fail(p, &ConstraintError{p, "is required when " + joinPath(path, cond)}); err != nil {
+				return err
+			}
+		}
+
+		if f.exclusive != "" && set {
+			if groups == nil {
+				groups = map[string][]string{}
+			}
+			groups[f.exclusive] = append(groups[f.exclusive], p)
+		}
+	}
+
+	for _, paths := range groups {
+		if len(paths) < 2 {
+			continue
+		}
+		msg := "can't be set along with " + strings.Join(paths[1:], ", ")
+		if err := fail(paths[0], &ConstraintError{paths[0], msg}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// conditionHolds tells whether the value at a dotted path from the struct v is set, or, for a
+// condition like "mode=verify", whether it's formatted as the value after the equal sign
+func conditionHolds(v reflect.Value, cond string) bool {
+	path, want := cond, ""
+	if i := strings.Index(cond, "="); i >= 0 {
+		path, want = cond[:i], cond[i+1:]
+	}
+
+	cv, err := lookupValue(v, path)
+	if err != nil || !cv.CanInterface() {
+		return false
+	}
+	if want == "" {
+		return !cv.IsZero()
+	}
+	return fmt.Sprint(cv.Interface()) == want
+}
+
+// checkOneOf checks that v, or every element of v if it's a list or a map, is one of the values
+// of a oneof tag, when formatted. Zero values are unset, and accepted
+func checkOneOf(v reflect.Value, values []string, path string, fail func(string, error) error) error {
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return checkOneOf(v.Elem(), values, path, fail)
+
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := checkOneOf(v.Index(i), values, joinPath(path, strconv.Itoa(i)), fail); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case reflect.Map:
+		for it := v.MapRange(); it.Next(); {
+			if err := checkOneOf(it.Value(), values, joinPath(path, fmt.Sprint(it.Key().Interface())), fail); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if v.IsZero() {
+		return nil
+	}
+	s := fmt.Sprint(v.Interface())
+	for _, value := range values {
+		if s == value {
+			return nil
+		}
+	}
+	msg := fmt.Sprintf("is %q, not one of %s", s, strings.Join(values, ", "))
+	return fail(path, &ConstraintError{path, msg})
+}
+
+// checkRange checks that v, or every element of v if it's a list or a map, is within the bounds of
+// a range tag
+func checkRange(v reflect.Value, bounds, path string, fail func(string, error) error) error {
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return checkRange(v.Elem(), bounds, path, fail)
+
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := checkRange(v.Index(i), bounds, joinPath(path, strconv.Itoa(i)), fail); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case reflect.Map:
+		for it := v.MapRange(); it.Next(); {
+			if err := checkRange(it.Value(), bounds, joinPath(path, fmt.Sprint(it.Key().Interface())), fail); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, opt := range strings.Split(bounds, ",") {
+		var below bool
+		switch {
+		case strings.HasPrefix(opt, "min="):
+			below = true
+		case strings.HasPrefix(opt, "max="):
+		default:
+			return fmt.Errorf("gofigure: %s: invalid range option %q", path, opt)
+		}
+
+		cmp, err := compareBound(v, opt[len("min="):])
+		if err != nil {
+			return fmt.Errorf("gofigure: %s: invalid range %q: %s", path, opt, err)
+		}
+		if (below && cmp < 0) || (!below && cmp > 0) || isNaN(v) {
+			if err := fail(path, &RangeError{path, v.Interface(), opt}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// compareBound compares v to a bound written the way values of its type are, returning -1, 0 or
+// 1 if v is below, at or above it
+func compareBound(v reflect.Value, bound string) (int, error) {
+
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(bound)
+		if err != nil {
+			return 0, err
+		}
+		return compareInts(v.Int(), int64(d)), nil
+
+	case v.Type() == bytesType:
+		b, err := ParseBytes(bound)
+		if err != nil {
+			return 0, err
+		}
+		return compareInts(v.Int(), int64(b)), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(bound, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		return compareInts(v.Int(), i), nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u, err := strconv.ParseUint(bound, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		switch x := v.Uint(); {
+		case x < u:
+			return -1, nil
+		case x > u:
+			return 1, nil
+		}
+		return 0, nil
+
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(bound, 64)
+		if err != nil {
+			return 0, err
+		}
+		switch x := v.Float(); {
+		case x < f:
+			return -1, nil
+		case x > f:
+			return 1, nil
+		}
+		return 0, nil
+	}
+	return 0, fmt.Errorf("ranges can't be set on %s", v.Type())
+}
+
+// isNaN returns true if v is a float that is NaN, which compares to no bound, so it's outside of
+// them all
+func isNaN(v reflect.Value) bool {
+	k := v.Kind()
+	return (k == reflect.Float32 || k == reflect.Float64) && math.IsNaN(v.Float())
+}
+
+// compareInts returns -1, 0 or 1 if a is less than, equal to or greater than b
+func compareInts(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,137 @@
+package gofigure
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/EverythingMe/gofigure/yaml"
+)
+
+func TestRanges(t *testing.T) {
+
+	type limits struct {
+		Timeout time.Duration   `yaml:"timeout" range:"min=1s,max=1m"`
+		MaxBody Bytes           `yaml:"max_body" range:"max=10MiB"`
+		Workers uint            `yaml:"workers" range:"min=1"`
+		Ratio   float64         `yaml:"ratio" range:"min=0,max=1"`
+		Retries []time.Duration `yaml:"retries" range:"max=10s"`
+	}
+	var conf struct {
+		Limits limits `yaml:"limits"`
+	}
+
+	loader := NewLoader(yaml.Decoder{}, true)
+	load := func(doc string) error {
+		return loader.MergeDocuments([][]byte{[]byte(doc)}, &conf)
+	}
+
+	if err := load("limits: {timeout: 1m, max_body: 10MiB, workers: 4, ratio: 0.5, retries: [1s, 10s]}"); err != nil {
+		t.Fatal(err)
+	}
+
+	for doc, expected := range map[string]string{
+		"limits: {timeout: 2m, workers: 1}":                    "limits.timeout is 2m0s, above the maximum of 1m",
+		"limits: {timeout: 1s, workers: 1, max_body: 1GiB}":    "limits.max_body is 1GiB, above the maximum of 10MiB",
+		"limits: {timeout: 1s}":                                "limits.workers is 0, below the minimum of 1",
+		"limits: {timeout: 1s, workers: 1, ratio: -1}":         "limits.ratio is -1, below the minimum of 0",
+		"limits: {timeout: 1s, workers: 1, retries: [1s, 1m]}": "limits.retries.1 is 1m0s, above the maximum of 10s",
+		"limits: {timeout: 1s, workers: 1, ratio: .nan}":       "limits.ratio is NaN, below the minimum of 0",
+	} {
+		conf.Limits = limits{}
+		err := load(doc)
+		var rangeErr *RangeError
+		if !errors.As(err, &rangeErr) || !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected %q loading %s, got %v", expected, doc, err)
+		}
+	}
+
+	// values out of range are reported and kept outside of strict mode
+	var warnings []Warning
+	loader.StrictMode = false
+	loader.OnWarning = func(w Warning) { warnings = append(warnings, w) }
+	conf.Limits = limits{}
+	if err := load("limits: {timeout: 2m, workers: 1}"); err != nil || conf.Limits.Timeout != 2*time.Minute {
+		t.Errorf("Expected a value out of range to be kept outside of strict mode, got %v %v", conf.Limits, err)
+	}
+	if len(warnings) != 1 || warnings[0].Key != "limits.timeout" {
+		t.Errorf("Unexpected warnings %v", warnings)
+	}
+
+	var bad struct {
+		Name string `yaml:"name" range:"min=1"`
+	}
+	if err := loader.MergeDocuments([][]byte{[]byte("name: x")}, &bad); err == nil {
+		t.Error("Expected a range on a string to be an error")
+	}
+}
+
+func TestConstraints(t *testing.T) {
+
+	type tlsConfig struct {
+		Enabled bool   `yaml:"enabled"`
+		Cert    string `yaml:"cert" required_if:"enabled"`
+		Mode    string `yaml:"mode"`
+		CA      string `yaml:"ca" required_if:"mode=verify"`
+	}
+	type authConfig struct {
+		Token    string `yaml:"token" exclusive:"credentials"`
+		Password string `yaml:"password" exclusive:"credentials"`
+	}
+	var conf struct {
+		TLS  tlsConfig   `yaml:"tls"`
+		Auth *authConfig `yaml:"auth"`
+	}
+
+	loader := NewLoader(yaml.Decoder{}, true)
+	for doc, expected := range map[string]string{
+		"tls: {enabled: false}":             "",
+		"tls: {enabled: true, cert: a.pem}": "",
+		"tls: {enabled: true}":              "tls.cert is required when tls.enabled is set",
+		"tls: {mode: verify}":               "tls.ca is required when tls.mode is verify",
+		"tls: {mode: skip}":                 "",
+		"auth: {token: t}":                  "",
+		"auth: {token: t, password: p}":     "auth.token can't be set along with auth.password",
+	} {
+		conf.TLS, conf.Auth = tlsConfig{}, nil
+		err := loader.MergeDocuments([][]byte{[]byte(doc)}, &conf)
+		var constraintErr *ConstraintError
+		switch {
+		case expected == "" && err != nil:
+			t.Errorf("Unexpected error loading %s: %v", doc, err)
+		case expected != "" && (!errors.As(err, &constraintErr) || !strings.Contains(err.Error(), expected)):
+			t.Errorf("Expected %q loading %s, got %v", expected, doc, err)
+		}
+	}
+}
+
+func TestOneOf(t *testing.T) {
+
+	var conf struct {
+		Level   string            `yaml:"level" oneof:"debug info warn error"`
+		Modules map[string]string `yaml:"modules" oneof:"debug info warn error"`
+		Name    string            `yaml:"name"`
+	}
+
+	loader := NewLoader(yaml.Decoder{}, true)
+	if err := loader.MergeDocuments([][]byte{[]byte("modules: {db: debug}")}, &conf); err != nil {
+		t.Errorf("Expected an unset enum to be accepted, got %v", err)
+	}
+	if err := loader.MergeDocuments([][]byte{[]byte("level: info")}, &conf); err != nil || conf.Level != "info" {
+		t.Errorf("Unexpected level %s %v", conf.Level, err)
+	}
+	if err := loader.MergeDocuments([][]byte{[]byte("name: app")}, &conf); err != nil || conf.Name != "app" {
+		t.Errorf("Expected a field without a oneof tag to be accepted, got %s %v", conf.Name, err)
+	}
+
+	err := loader.MergeDocuments([][]byte{[]byte("level: verbose")}, &conf)
+	if err == nil || err.Error() != `gofigure: level is "verbose", not one of debug, info, warn, error` {
+		t.Errorf("Unexpected error %v", err)
+	}
+	conf.Level = ""
+	err = loader.MergeDocuments([][]byte{[]byte("modules: {db: trace}")}, &conf)
+	if err == nil || !strings.Contains(err.Error(), "modules.db") {
+		t.Errorf("Expected an error for a map value, got %v", err)
+	}
+}
@@ -0,0 +1,55 @@
+package gofigure
+
+import (
+	"sync"
+)
+
+// Change describes a load that changed the effective config, for Loader.OnChange
+type Change struct {
+	// Version is the version of the loaded config, see Loader.Version
+	Version string
+
+	// Previous is the version of the config the loader loaded before, empty for the first load
+	Previous string
+
+	// Report is the report of the load
+	Report *Report
+}
+
+// versions remembers the version of the config a loader loaded last, shared by its copies
+type versions struct {
+	mu   sync.Mutex
+	last string
+}
+
+// Version returns the version of the config the loader loaded last with LoadRecursive or
+// LoadWithReport, a hash of the effective config that's equal for equal configs, whatever the
+// files they came from. Services can log or report it to tell exactly which config revision they
+// run. It's empty before the first load, and for loaders not created by NewLoader
+func (l Loader) Version() string {
+	if l.versions == nil {
+		return ""
+	}
+	l.versions.mu.Lock()
+	defer l.versions.mu.Unlock()
+	return l.versions.last
+}
+
+// stamp records the version of a loaded config in its report, and calls the OnChange hook if the
+// version changed
+func (l Loader) stamp(report *Report, config interface{}) {
+
+	report.Version, _ = configVersion(config)
+
+	var previous string
+	if l.versions != nil {
+		l.versions.mu.Lock()
+		previous = l.versions.last
+		l.versions.last = report.Version
+		l.versions.mu.Unlock()
+	}
+
+	if l.OnChange != nil && report.Version != previous {
+		l.OnChange(Change{Version: report.Version, Previous: previous, Report: report})
+	}
+}
@@ -0,0 +1,67 @@
+package gofigure
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/EverythingMe/gofigure/yaml"
+)
+
+func TestVersion(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gofigure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeFiles(t, dir, map[string]string{"conf.yaml": "redis:\n  timeout: 10\n"})
+
+	var changes []Change
+	loader := NewLoader(yaml.Decoder{}, true)
+	loader.OnChange = func(c Change) { changes = append(changes, c) }
+	loader.Status = NewStatus()
+	if loader.Version() != "" {
+		t.Error("Expected no version before the first load")
+	}
+
+	var conf config
+	report, err := loader.LoadWithReport(&conf, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := report.Version
+	if first == "" || loader.Version() != first || loader.Status.Version() != first {
+		t.Errorf("Unexpected versions %q %q %q", first, loader.Version(), loader.Status.Version())
+	}
+	if len(changes) != 1 || changes[0].Version != first || changes[0].Previous != "" || changes[0].Report != report {
+		t.Errorf("Unexpected changes %+v", changes)
+	}
+
+	// the same effective config has the same version, even from different files
+	writeFiles(t, dir, map[string]string{"conf.yaml": "redis: {timeout: 10}\n"})
+	if err := loader.LoadRecursive(&conf, dir); err != nil {
+		t.Fatal(err)
+	}
+	if loader.Version() != first || len(changes) != 1 {
+		t.Errorf("Expected an unchanged version, got %q and %d changes", loader.Version(), len(changes))
+	}
+
+	writeFiles(t, dir, map[string]string{"conf.yaml": "redis:\n  timeout: 20\n"})
+	if err := loader.LoadRecursive(&conf, dir); err != nil {
+		t.Fatal(err)
+	}
+	if loader.Version() == first || len(changes) != 2 || changes[1].Previous != first || changes[1].Version != loader.Version() {
+		t.Errorf("Expected a new version, got %+v", changes)
+	}
+
+	// failed loads keep the version
+	writeFiles(t, dir, map[string]string{"conf.yaml": "redis: [\n"})
+	if err := loader.LoadRecursive(&conf, dir); err == nil {
+		t.Fatal("Expected an error loading a broken file")
+	}
+	if loader.Version() != changes[1].Version || len(changes) != 2 {
+		t.Error("Expected a failed load not to change the version")
+	}
+}
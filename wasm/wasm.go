@@ -0,0 +1,197 @@
+// Package wasm implements gofigure decoders compiled to WebAssembly, and run in a sandbox, so that
+// formats can be added safely in locked down environments, with a single portable binary for all
+// platforms.
+//
+// Decoders are WASI command modules speaking the protocol of plugins.Command: they read the
+// document on their standard input, write it as JSON to their standard output, and report errors
+// by exiting with a non-zero status, with the message on their standard error. Any language
+// targeting WASI can be used, e.g. with Go:
+//
+//	GOOS=wasip1 GOARCH=wasm go build -o ini.wasm ./cmd/ini-decoder
+//
+// Modules run without access to files, the network, the environment or the real clock, in at
+// most MemoryLimit bytes of memory.
+package wasm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/EverythingMe/gofigure/internal/tree"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// MemoryLimit is the most memory a module can use
+const MemoryLimit = 64 << 20
+
+// OutputLimit is the most a module can write to its standard output, or to its standard error, by
+// default
+const OutputLimit = 16 << 20
+
+// pageSize is the size of the pages of WebAssembly memory
+const pageSize = 64 << 10
+
+// Decoder runs a WebAssembly decoder in a sandbox for every document it decodes. The module is
+// compiled once, and instantiated afresh for every document, so no state leaks between them.
+// Close the decoder to free the compiled module
+type Decoder struct {
+	// Name is that of the module, used in errors
+	Name string
+
+	// Extensions are those of the files the decoder decodes, like ".ini"
+	Extensions []string
+
+	// Timeout bounds how long the module can run, 10 seconds if zero
+	Timeout time.Duration
+
+	// OutputLimit bounds how much the module can write to its standard output and to its standard
+	// error, OutputLimit if zero. Modules writing more fail to decode
+	OutputLimit int
+
+	runtime wazero.Runtime
+	module  wazero.CompiledModule
+}
+
+// New compiles a decoder from the binary of a module
+func New(binary []byte, extensions ...string) (*Decoder, error) {
+
+	ctx := context.Background()
+	config := wazero.NewRuntimeConfig().
+		WithCloseOnContextDone(true).
+		WithMemoryLimitPages(MemoryLimit / pageSize)
+	runtime := wazero.NewRuntimeWithConfig(ctx, config)
+
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, runtime); err != nil {
+		runtime.Close(ctx)
+		return nil, fmt.Errorf("wasm: %s", err)
+	}
+	module, err := runtime.CompileModule(ctx, binary)
+	if err != nil {
+		runtime.Close(ctx)
+		return nil, fmt.Errorf("wasm: compiling module: %s", err)
+	}
+
+	return &Decoder{
+		Name:       "module",
+		Extensions: extensions,
+		runtime:    runtime,
+		module:     module,
+	}, nil
+}
+
+// Open compiles the decoder of a module file. Its name is the format it decodes, e.g. ini.wasm
+// decodes .ini files
+func Open(path string) (*Decoder, error) {
+	binary, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	format := strings.TrimSuffix(filepath.Base(path), ".wasm")
+	d, err := New(binary, "."+format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	d.Name = filepath.Base(path)
+	return d, nil
+}
+
+// Decode runs the module on the document read from r, and unmarshals the JSON it writes into
+// config
+func (d *Decoder) Decode(r io.Reader, config interface{}) error {
+
+	timeout := d.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	limit := d.OutputLimit
+	if limit == 0 {
+		limit = OutputLimit
+	}
+	stdout, stderr := &limitedWriter{limit: limit}, &limitedWriter{limit: limit}
+	// anonymous modules can be instantiated concurrently
+	mc := wazero.NewModuleConfig().
+		WithName("").
+		WithArgs(d.Name).
+		WithStdin(r).
+		WithStdout(stdout).
+		WithStderr(stderr)
+
+	mod, err := d.runtime.InstantiateModule(ctx, d.module, mc)
+	if mod != nil {
+		mod.Close(ctx)
+	}
+	if exit, ok := err.(*sys.ExitError); ok && exit.ExitCode() == 0 {
+		err = nil
+	}
+	// modules may ignore failed writes, and exit as if all went well
+	if stdout.exceeded || stderr.exceeded {
+		return fmt.Errorf("wasm: %s wrote more than %d bytes", d.Name, limit)
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("wasm: %s: %s", d.Name, msg)
+		}
+		return fmt.Errorf("wasm: %s: %s", d.Name, err)
+	}
+
+	var doc interface{}
+	dec := json.NewDecoder(&stdout.buf)
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return fmt.Errorf("wasm: %s wrote invalid JSON: %s", d.Name, err)
+	}
+	return tree.Assign(tree.Normalize(doc), config)
+}
+
+// errOutputLimit is returned to modules writing more than the output limit
+var errOutputLimit = errors.New("output limit exceeded")
+
+// limitedWriter buffers at most limit bytes, and refuses writes past them
+type limitedWriter struct {
+	buf      bytes.Buffer
+	limit    int
+	exceeded bool
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	if w.buf.Len()+len(p) > w.limit {
+		w.exceeded = true
+		return 0, errOutputLimit
+	}
+	return w.buf.Write(p)
+}
+
+func (w *limitedWriter) String() string {
+	return w.buf.String()
+}
+
+// CanDecode returns true if the file has one of the decoder's extensions
+func (d *Decoder) CanDecode(path string) bool {
+	for _, ext := range d.Extensions {
+		if strings.HasSuffix(path, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// Close frees the compiled module. The decoder can't be used after it's closed
+func (d *Decoder) Close() error {
+	return d.runtime.Close(context.Background())
+}
@@ -0,0 +1,87 @@
+package wasm
+
+import (
+	"strings"
+	"testing"
+)
+
+// echo is a module writing its standard input to its standard output, assembled from:
+//
+//	(module
+//	  (import "wasi_snapshot_preview1" "fd_read" (func $read (param i32 i32 i32 i32) (result i32)))
+//	  (import "wasi_snapshot_preview1" "fd_write" (func $write (param i32 i32 i32 i32) (result i32)))
+//	  (memory (export "memory") 1)
+//	  (func (export "_start")
+//	    (i32.store (i32.const 0) (i32.const 64))
+//	    (i32.store (i32.const 4) (i32.const 1024))
+//	    (drop (call $read (i32.const 0) (i32.const 0) (i32.const 1) (i32.const 8)))
+//	    (i32.store (i32.const 16) (i32.const 64))
+//	    (i32.store (i32.const 20) (i32.load (i32.const 8)))
+//	    (drop (call $write (i32.const 1) (i32.const 16) (i32.const 1) (i32.const 24)))))
+var echo = []byte{
+	0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00, 0x01, 0x0c, 0x02, 0x60, 0x04, 0x7f, 0x7f, 0x7f,
+	0x7f, 0x01, 0x7f, 0x60, 0x00, 0x00, 0x02, 0x44, 0x02, 0x16, 0x77, 0x61, 0x73, 0x69, 0x5f, 0x73,
+	0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x5f, 0x70, 0x72, 0x65, 0x76, 0x69, 0x65, 0x77, 0x31,
+	0x07, 0x66, 0x64, 0x5f, 0x72, 0x65, 0x61, 0x64, 0x00, 0x00, 0x16, 0x77, 0x61, 0x73, 0x69, 0x5f,
+	0x73, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x5f, 0x70, 0x72, 0x65, 0x76, 0x69, 0x65, 0x77,
+	0x31, 0x08, 0x66, 0x64, 0x5f, 0x77, 0x72, 0x69, 0x74, 0x65, 0x00, 0x00, 0x03, 0x02, 0x01, 0x01,
+	0x05, 0x03, 0x01, 0x00, 0x01, 0x07, 0x13, 0x02, 0x06, 0x6d, 0x65, 0x6d, 0x6f, 0x72, 0x79, 0x02,
+	0x00, 0x06, 0x5f, 0x73, 0x74, 0x61, 0x72, 0x74, 0x00, 0x02, 0x0a, 0x3c, 0x01, 0x3a, 0x00, 0x41,
+	0x00, 0x41, 0xc0, 0x00, 0x36, 0x02, 0x00, 0x41, 0x04, 0x41, 0x80, 0x08, 0x36, 0x02, 0x00, 0x41,
+	0x00, 0x41, 0x00, 0x41, 0x01, 0x41, 0x08, 0x10, 0x00, 0x1a, 0x41, 0x10, 0x41, 0xc0, 0x00, 0x36,
+	0x02, 0x00, 0x41, 0x14, 0x41, 0x08, 0x28, 0x02, 0x00, 0x36, 0x02, 0x00, 0x41, 0x01, 0x41, 0x10,
+	0x41, 0x01, 0x41, 0x18, 0x10, 0x01, 0x1a, 0x0b,
+}
+
+func TestDecode(t *testing.T) {
+	d, err := New(echo, ".echo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	if !d.CanDecode("app.echo") || d.CanDecode("app.yaml") {
+		t.Error("wrong extensions decoded")
+	}
+
+	var conf struct {
+		Host string `json:"host"`
+		Port int    `json:"port"`
+	}
+	for i := 0; i < 2; i++ {
+		if err := d.Decode(strings.NewReader(`{"host": "db", "port": 5432}`), &conf); err != nil {
+			t.Fatal(err)
+		}
+		if conf.Host != "db" || conf.Port != 5432 {
+			t.Errorf("decoded %#v", conf)
+		}
+	}
+
+	if err := d.Decode(strings.NewReader("host: db"), &conf); err == nil {
+		t.Error("expected an error for output that isn't JSON")
+	}
+}
+
+func TestInvalidModule(t *testing.T) {
+	if _, err := New([]byte("not wasm")); err == nil {
+		t.Error("expected an error compiling an invalid module")
+	}
+}
+
+func TestOutputLimit(t *testing.T) {
+	d, err := New(echo, ".echo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	d.OutputLimit = 8
+
+	var conf map[string]interface{}
+	err = d.Decode(strings.NewReader(`{"host": "db"}`), &conf)
+	if err == nil || !strings.Contains(err.Error(), "more than 8 bytes") {
+		t.Errorf("expected an error for output over the limit, got %v", err)
+	}
+	if err := d.Decode(strings.NewReader(`{}`), &conf); err != nil {
+		t.Errorf("output under the limit refused: %s", err)
+	}
+}
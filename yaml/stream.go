@@ -0,0 +1,111 @@
+package yaml
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/EverythingMe/gofigure/internal/tree"
+	"gopkg.in/yaml.v3"
+)
+
+// Stream calls fn with every item of the collection at the dotted path at of the yaml stream read
+// from r ("" for the documents themselves), with the key of every mapping entry or the index of
+// every list element. The documents of a multi-document stream are read one at a time, and their
+// items streamed in turn, with list indexes following on from one document to the next. Items are
+// only decoded when they are streamed, but a document is parsed as a whole, so very large
+// configs should be split into documents separated by "---"
+func (d Decoder) Stream(r io.Reader, at string, fn func(key string, item interface{}) error) error {
+
+	dec := yaml.NewDecoder(r)
+	keys := tree.Split(at)
+
+	index := 0
+	for {
+		var doc yaml.Node
+		err := dec.Decode(&doc)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := d.prepare(&doc); err != nil {
+			return err
+		}
+
+		// empty documents and collections have nothing to stream
+		n := lookup(&doc, keys)
+		if n == nil || n.Kind == yaml.DocumentNode || n.Tag == "!!null" {
+			continue
+		}
+
+		switch n.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				if err := streamItem(n.Content[i].Value, n.Content[i+1], fn); err != nil {
+					return err
+				}
+			}
+
+		case yaml.SequenceNode:
+			for _, item := range n.Content {
+				if err := streamItem(strconv.Itoa(index), item, fn); err != nil {
+					return err
+				}
+				index++
+			}
+
+		default:
+			return fmt.Errorf("yaml: line %d: the value at %q is not a mapping or a list", n.Line, at)
+		}
+	}
+}
+
+// streamItem decodes a single item and calls fn with it
+func streamItem(key string, n *yaml.Node, fn func(key string, item interface{}) error) error {
+	var item interface{}
+	if err := n.Decode(&item); err != nil {
+		return err
+	}
+	return fn(key, tree.Normalize(item))
+}
+
+// lookup returns the node at the path keys of a document, or nil if there's none
+func lookup(doc *yaml.Node, keys []string) *yaml.Node {
+
+	n := doc
+	if n.Kind == yaml.DocumentNode && len(n.Content) > 0 {
+		n = n.Content[0]
+	}
+
+	for _, key := range keys {
+		for n.Kind == yaml.AliasNode {
+			n = n.Alias
+		}
+
+		var next *yaml.Node
+		switch n.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(n.Content) && next == nil; i += 2 {
+				if n.Content[i].Value == key {
+					next = n.Content[i+1]
+				}
+			}
+		case yaml.SequenceNode:
+			if i, err := strconv.Atoi(key); err == nil && i >= 0 && i < len(n.Content) {
+				next = n.Content[i]
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		n = next
+	}
+
+	for n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	return n
+}
@@ -1,24 +1,221 @@
 package yaml
 
 import (
+	"fmt"
 	"io"
-	"io/ioutil"
 	"strings"
 
-	"gopkg.in/yaml.v2"
+	"github.com/EverythingMe/gofigure/internal/tree"
+	"gopkg.in/yaml.v3"
 )
 
-type Decoder struct{}
+// Semantics selects how plain scalars that YAML 1.1 and YAML 1.2 disagree about are resolved
+type Semantics int
 
+const (
+	// Default is the underlying library's behavior: YAML 1.2 resolution, except that the YAML 1.1
+	// booleans (yes/no/on/off/y/n) are still accepted when decoding into bool fields, and ints with
+	// leading zeros like 0777 are read as octal
+	Default Semantics = iota
+
+	// YAML11 resolves the YAML 1.1 booleans as booleans everywhere, including untyped fields
+	YAML11
+
+	// YAML12 follows the YAML 1.2 core schema for numbers as well: ints with leading zeros like 0777
+	// are decimal, and octal ints must be written as 0o777. Note that the underlying library still
+	// accepts yes/no/on/off when decoding into bool fields
+	YAML12
+)
+
+// Decoder can take configurations encoded as yaml and decode them to config structs.
+//
+// The zero value is ready to use; its fields tune how the less obvious parts of YAML are treated.
+type Decoder struct {
+
+	// Semantics selects YAML 1.1 or 1.2 resolution of booleans and numbers
+	Semantics Semantics
+
+	// DisallowAliases makes documents using anchors and aliases fail to decode
+	DisallowAliases bool
+
+	// DisallowMergeKeys makes documents using "<<" merge keys fail to decode
+	DisallowMergeKeys bool
+
+	// AllowDuplicateKeys makes mappings with duplicate keys valid, with the last occurrence
+	// winning. By default a duplicate key is a decoding error
+	AllowDuplicateKeys bool
+}
+
+// Decode unmarshals the yaml stream in r into config, which is a pointer to a struct.
+//
+// A value tagged !include, like "!include db.yaml" or "!include [a.yaml, b.yaml]", is decoded as
+// the equivalent "$include" mapping, for loaders that follow includes.
+//
+// If the stream holds multiple documents separated by "---", they are all decoded into config
+// in order, so later documents override the values set by earlier ones. This also holds when
+// config is a pointer to an interface{}, in which case the documents are deep merged.
 func (d Decoder) Decode(r io.Reader, config interface{}) error {
-	data, err := ioutil.ReadAll(r)
-	if err != nil {
-		return err
+	dec := yaml.NewDecoder(r)
+
+	// generic documents get replaced rather than merged when decoded into, so they are decoded
+	// one by one and merged explicitly
+	generic, isGeneric := config.(*interface{})
+
+	for {
+		var doc yaml.Node
+		err := dec.Decode(&doc)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := d.prepare(&doc); err != nil {
+			return err
+		}
+
+		if isGeneric {
+			var v interface{}
+			if err := doc.Decode(&v); err != nil {
+				return err
+			}
+			*generic = tree.MergeValue(*generic, tree.Normalize(v))
+			continue
+		}
+		if err := doc.Decode(config); err != nil {
+			return err
+		}
 	}
+}
 
-	return yaml.Unmarshal(data, config)
+// Encode writes v to w as a yaml document, making Decoder a gofigure.Encoder
+func (d Decoder) Encode(w io.Writer, v interface{}) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	return enc.Close()
 }
 
+// CanDecode returns true if this is a yaml file
 func (d Decoder) CanDecode(path string) bool {
 	return strings.HasSuffix(path, ".yaml")
 }
+
+// yaml11Bools are the plain scalars that YAML 1.1 considers booleans but YAML 1.2 doesn't
+var yaml11Bools = map[string]bool{
+	"y": true, "yes": true, "on": true,
+	"n": false, "no": false, "off": false,
+}
+
+// prepare walks a parsed document, enforcing the decoder's restrictions and rewriting nodes
+// according to its options before they are decoded
+func (d Decoder) prepare(n *yaml.Node) error {
+
+	if d.DisallowAliases && (n.Kind == yaml.AliasNode || n.Anchor != "") {
+		return fmt.Errorf("yaml: line %d: anchors and aliases are not allowed", n.Line)
+	}
+
+	if n.Tag == "!include" {
+		include(n)
+	}
+
+	switch n.Kind {
+	case yaml.ScalarNode:
+		d.resolveScalar(n)
+
+	case yaml.MappingNode:
+		if d.DisallowMergeKeys {
+			for i := 0; i < len(n.Content); i += 2 {
+				if n.Content[i].Tag == "!!merge" {
+					return fmt.Errorf("yaml: line %d: merge keys are not allowed", n.Content[i].Line)
+				}
+			}
+		}
+		if d.AllowDuplicateKeys {
+			n.Content = dedupe(n.Content)
+		}
+	}
+
+	for _, child := range n.Content {
+		if err := d.prepare(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveScalar re-tags plain scalars whose meaning depends on the YAML version
+func (d Decoder) resolveScalar(n *yaml.Node) {
+	if n.Style != 0 {
+		return
+	}
+
+	switch d.Semantics {
+	case YAML11:
+		if b, found := yaml11Bools[strings.ToLower(n.Value)]; found && n.Tag == "!!str" {
+			n.Tag = "!!bool"
+			n.Value = fmt.Sprint(b)
+		}
+
+	case YAML12:
+		if n.Tag == "!!int" {
+			n.Value = decimal(n.Value)
+		}
+	}
+}
+
+// include rewrites a node tagged !include into the equivalent "$include" mapping, which the
+// loader replaces with the contents of the included files if it follows includes
+func include(n *yaml.Node) {
+	target := *n
+	target.Tag = ""
+	*n = yaml.Node{
+		Kind: yaml.MappingNode,
+		Tag:  "!!map",
+		Line: n.Line,
+		Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Tag: "!!str", Value: "$include"},
+			&target,
+		},
+	}
+}
+
+// decimal strips the leading zeros YAML 1.1 reads as an octal prefix
+func decimal(v string) string {
+	sign := ""
+	if strings.HasPrefix(v, "-") || strings.HasPrefix(v, "+") {
+		sign, v = v[:1], v[1:]
+	}
+	if len(v) < 2 || v[0] != '0' || strings.IndexFunc(v, func(r rune) bool { return r < '0' || r > '9' }) >= 0 {
+		return sign + v
+	}
+
+	v = strings.TrimLeft(v, "0")
+	if v == "" {
+		v = "0"
+	}
+	return sign + v
+}
+
+// dedupe removes key/value pairs from mapping content whose scalar key appears again later
+func dedupe(content []*yaml.Node) []*yaml.Node {
+	last := map[string]int{}
+	for i := 0; i < len(content); i += 2 {
+		if content[i].Kind == yaml.ScalarNode {
+			last[content[i].Value] = i
+		}
+	}
+
+	out := content[:0]
+	for i := 0; i < len(content); i += 2 {
+		k := content[i]
+		if k.Kind == yaml.ScalarNode && last[k.Value] != i {
+			continue
+		}
+		out = append(out, k, content[i+1])
+	}
+	return out
+}
@@ -0,0 +1,140 @@
+package yaml
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMultiDocument(t *testing.T) {
+
+	var conf struct {
+		Server  string `yaml:"server"`
+		Monitor int    `yaml:"monitor"`
+		Timeout int    `yaml:"timeout"`
+	}
+
+	stream := `
+server: localhost:6378
+monitor: 1000
+---
+server: localhost:6379
+---
+timeout: 10
+`
+	if err := (Decoder{}).Decode(strings.NewReader(stream), &conf); err != nil {
+		t.Fatal(err)
+	}
+
+	if conf.Server != "localhost:6379" || conf.Monitor != 1000 || conf.Timeout != 10 {
+		t.Errorf("Documents not merged in order: %v", conf)
+	}
+
+	var generic interface{}
+	if err := (Decoder{}).Decode(strings.NewReader(stream), &generic); err != nil {
+		t.Fatal(err)
+	}
+	if m := generic.(map[string]interface{}); m["server"] != "localhost:6379" || m["monitor"] != 1000 || m["timeout"] != 10 {
+		t.Errorf("Generic documents not merged in order: %v", generic)
+	}
+
+	if err := (Decoder{}).Decode(strings.NewReader(""), &conf); err != nil {
+		t.Errorf("Empty stream should decode cleanly: %s", err)
+	}
+}
+
+func TestSemantics(t *testing.T) {
+
+	type conf struct {
+		Enabled interface{} `yaml:"enabled"`
+		Flag    bool        `yaml:"flag"`
+		Mode    int         `yaml:"mode"`
+	}
+	doc := "enabled: yes\nflag: on\nmode: 0755\n"
+
+	var c conf
+	if err := (Decoder{}).Decode(strings.NewReader(doc), &c); err != nil {
+		t.Fatal(err)
+	}
+	if c.Enabled != "yes" || !c.Flag || c.Mode != 0755 {
+		t.Errorf("Unexpected default semantics: %v", c)
+	}
+
+	c = conf{}
+	if err := (Decoder{Semantics: YAML11}).Decode(strings.NewReader(doc), &c); err != nil {
+		t.Fatal(err)
+	}
+	if c.Enabled != true || !c.Flag || c.Mode != 0755 {
+		t.Errorf("Unexpected YAML 1.1 semantics: %v", c)
+	}
+
+	c = conf{}
+	if err := (Decoder{Semantics: YAML12}).Decode(strings.NewReader("enabled: yes\nmode: 0755\n"), &c); err != nil {
+		t.Fatal(err)
+	}
+	if c.Enabled != "yes" || c.Mode != 755 {
+		t.Errorf("Unexpected YAML 1.2 semantics: %v", c)
+	}
+}
+
+func TestAnchorsAndDuplicates(t *testing.T) {
+
+	var conf map[string]interface{}
+	doc := "base: &base {timeout: 10}\nredis:\n  <<: *base\n  server: localhost\n"
+
+	if err := (Decoder{}).Decode(strings.NewReader(doc), &conf); err != nil {
+		t.Fatal(err)
+	}
+	if redis := conf["redis"].(map[string]interface{}); redis["timeout"] != 10 {
+		t.Errorf("Merge key not resolved: %v", conf)
+	}
+
+	if err := (Decoder{DisallowMergeKeys: true}).Decode(strings.NewReader(doc), &conf); err == nil {
+		t.Errorf("Expected merge keys to be rejected")
+	}
+	if err := (Decoder{DisallowAliases: true}).Decode(strings.NewReader(doc), &conf); err == nil {
+		t.Errorf("Expected aliases to be rejected")
+	}
+
+	dup := "server: a\nserver: b\n"
+	if err := (Decoder{}).Decode(strings.NewReader(dup), &conf); err == nil {
+		t.Errorf("Expected duplicate keys to be rejected")
+	}
+
+	conf = nil
+	if err := (Decoder{AllowDuplicateKeys: true}).Decode(strings.NewReader(dup), &conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf["server"] != "b" {
+		t.Errorf("Expected the last duplicate key to win: %v", conf)
+	}
+}
+
+func TestStream(t *testing.T) {
+
+	stream := `
+hosts:
+  - a
+  - b
+other: x
+---
+hosts: [c]
+---
+other: y
+`
+	var keys, items []string
+	err := (Decoder{}).Stream(strings.NewReader(stream), "hosts", func(key string, item interface{}) error {
+		keys = append(keys, key)
+		items = append(items, item.(string))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(keys, ",") != "0,1,2" || strings.Join(items, ",") != "a,b,c" {
+		t.Errorf("Unexpected items: %v %v", keys, items)
+	}
+
+	if err := (Decoder{}).Stream(strings.NewReader(stream), "other", func(string, interface{}) error { return nil }); err == nil {
+		t.Error("Expected an error streaming a scalar")
+	}
+}
@@ -0,0 +1,249 @@
+// Package zookeeper loads config documents from ZooKeeper znodes, and watches them for changes
+// with ZooKeeper's watches, for service discovery stacks that keep their runtime config in ZK.
+//
+// A source reads either the data of a single znode as a document, or with Tree, a whole subtree
+// of znodes: the children of a znode are the keys of a mapping, like the subdirectories of a
+// config directory, and the data of the znodes without children are its values.
+//
+//	/app/config/server           api.example.com   ->  server: api.example.com
+//	/app/config/redis/port       6379              ->  redis: {port: 6379}
+//
+// The data of znodes with children is ignored. Values are kept as strings, so loaders binding
+// subtrees into typed fields should be WeaklyTyped.
+package zookeeper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"reflect"
+	"strconv"
+
+	"github.com/EverythingMe/gofigure"
+	"github.com/EverythingMe/gofigure/internal/monitor"
+	"github.com/go-zookeeper/zk"
+	"github.com/op/go-logging"
+)
+
+var log = logging.MustGetLogger("gofigure")
+
+// maxDepth bounds how deep a subtree is read and watched
+const maxDepth = 32
+
+// Source is a gofigure.Source reading a document from a znode, or from the subtree under it. A
+// missing znode is a permanent failure
+type Source struct {
+	Conn *zk.Conn
+	Path string
+
+	// Tree makes the source read the subtree under Path rather than the data of Path
+	Tree bool
+}
+
+// Name returns the source's path
+func (s Source) Name() string {
+	return "zk:" + s.Path
+}
+
+// Fetch reads the data of the source's znode, or its subtree
+func (s Source) Fetch(ctx context.Context) (io.ReadCloser, error) {
+
+	if !s.Tree {
+		data, _, err := s.Conn.Get(s.Path)
+		if err == zk.ErrNoNode {
+			return nil, gofigure.Permanent(fmt.Errorf("zookeeper: znode %s not found", s.Path))
+		}
+		if err != nil {
+			return nil, err
+		}
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	}
+
+	flat := map[string]string{}
+	if err := s.readTree(ctx, s.Path, "", 0, flat); err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(gofigure.Unflatten(flat))
+	if err != nil {
+		return nil, gofigure.Permanent(err)
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+// Version returns the transaction id the source's znode was last modified at. Subtrees have no
+// single version, so they are fetched and hashed to be polled
+func (s Source) Version(ctx context.Context) (string, error) {
+	if s.Tree {
+		return "", nil
+	}
+	exists, stat, err := s.Conn.Exists(s.Path)
+	if err != nil || !exists {
+		return "", err
+	}
+	return strconv.FormatInt(stat.Mzxid, 10), nil
+}
+
+// readTree adds the values of the subtree at znode to flat, keyed by their dotted paths under key
+func (s Source) readTree(ctx context.Context, znode, key string, depth int, flat map[string]string) error {
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if depth > maxDepth {
+		return gofigure.Permanent(fmt.Errorf("zookeeper: %s is nested deeper than %d znodes", s.Path, maxDepth))
+	}
+
+	children, _, err := s.Conn.Children(znode)
+	if err == zk.ErrNoNode {
+		if depth == 0 {
+			return gofigure.Permanent(fmt.Errorf("zookeeper: znode %s not found", s.Path))
+		}
+		// deleted while the subtree was read
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(children) == 0 {
+		if depth == 0 {
+			// a subtree of one znode is an empty mapping
+			return nil
+		}
+		data, _, err := s.Conn.Get(znode)
+		if err == zk.ErrNoNode {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		flat[key] = string(data)
+		return nil
+	}
+
+	for _, child := range children {
+		k := child
+		if key != "" {
+			k = key + "." + child
+		}
+		if err := s.readTree(ctx, path.Join(znode, child), k, depth+1, flat); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Monitor is a gofigure.ReloadMonitor calling its Reloader whenever one of its znodes changes,
+// or, with Tree, any znode under them.
+//
+// ZooKeeper watches fire once, so they are set again after every change. The client reconnects on
+// its own and keeps its watches, but if its session expires they are lost: the Reloader is then
+// called once they are set again, since changes may have been missed.
+type Monitor struct {
+	conn  *zk.Conn
+	paths []string
+
+	// Tree makes the monitor watch every znode under its paths, as well as the paths
+	Tree bool
+
+	group monitor.Group
+}
+
+// NewMonitor creates a monitor watching the znodes at paths
+func NewMonitor(conn *zk.Conn, paths ...string) *Monitor {
+	return &Monitor{
+		conn:  conn,
+		paths: paths,
+	}
+}
+
+// Watch starts watching the znodes, calling r whenever one of them changes
+func (m *Monitor) Watch(r gofigure.Reloader) {
+	m.group.Start(len(m.paths), func(ctx context.Context, i int) {
+		m.watch(ctx, m.paths[i], r)
+	})
+}
+
+// Stop stops watching, and waits for the watches to end
+func (m *Monitor) Stop() {
+	m.group.Stop("zookeeper")
+}
+
+// watch watches a single path until ctx is done
+func (m *Monitor) watch(ctx context.Context, p string, r gofigure.Reloader) {
+
+	var backoff monitor.Backoff
+	watching, missed := false, false
+
+	for ctx.Err() == nil {
+		events, err := m.watches(p, 0)
+		if err != nil {
+			missed = missed || watching
+			log.Info("zookeeper watch of %s failed, retrying in %s: %s", p, backoff.Delay(), err)
+			backoff.Wait(ctx)
+			continue
+		}
+		backoff.Reset()
+		watching = true
+
+		if missed {
+			log.Info("zookeeper watch of %s was lost, reloading", p)
+			m.group.Reload(r)
+			missed = false
+		}
+
+		cases := make([]reflect.SelectCase, len(events)+1)
+		cases[0] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())}
+		for i, ch := range events {
+			cases[i+1] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ch)}
+		}
+		chosen, v, ok := reflect.Select(cases)
+		if chosen == 0 {
+			return
+		}
+		ev := v.Interface().(zk.Event)
+		if !ok || ev.Type == zk.EventNotWatching {
+			missed = true
+			continue
+		}
+		log.Info("zookeeper znode %s changed, reloading", ev.Path)
+		m.group.Reload(r)
+	}
+}
+
+// watches sets watches on the data of the znode at p, and with Tree, on its children and the
+// znodes under it, returning the channels of their events
+func (m *Monitor) watches(p string, depth int) ([]<-chan zk.Event, error) {
+
+	exists, _, ch, err := m.conn.ExistsW(p)
+	if err != nil {
+		return nil, err
+	}
+	events := []<-chan zk.Event{ch}
+	if !exists || !m.Tree || depth >= maxDepth {
+		return events, nil
+	}
+
+	children, _, ch, err := m.conn.ChildrenW(p)
+	if err == zk.ErrNoNode {
+		// deleted in the meantime, which fires the first watch
+		return events, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	events = append(events, ch)
+
+	for _, child := range children {
+		sub, err := m.watches(path.Join(p, child), depth+1)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, sub...)
+	}
+	return events, nil
+}
@@ -0,0 +1,129 @@
+package zookeeper
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/EverythingMe/gofigure"
+	"github.com/EverythingMe/gofigure/yaml"
+	"github.com/go-zookeeper/zk"
+)
+
+// connect connects to the servers in ZOOKEEPER_SERVERS, like "localhost:2181", skipping the test
+// if there are none, and returns a fresh root path for it
+func connect(t *testing.T) (*zk.Conn, string) {
+	servers := os.Getenv("ZOOKEEPER_SERVERS")
+	if servers == "" {
+		t.Skip("ZOOKEEPER_SERVERS is not set")
+	}
+	conn, _, err := zk.Connect(strings.Split(servers, ","), 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := fmt.Sprintf("/gofigure-test-%d", time.Now().UnixNano())
+	create(t, conn, root, "")
+	return conn, root
+}
+
+func create(t *testing.T, conn *zk.Conn, path, data string) {
+	if _, err := conn.Create(path, []byte(data), 0, zk.WorldACL(zk.PermAll)); err != nil {
+		t.Fatalf("creating %s: %s", path, err)
+	}
+}
+
+// remove deletes the subtree at path
+func remove(conn *zk.Conn, path string) {
+	children, _, _ := conn.Children(path)
+	for _, child := range children {
+		remove(conn, path+"/"+child)
+	}
+	conn.Delete(path, -1)
+}
+
+func TestSource(t *testing.T) {
+
+	conn, root := connect(t)
+	defer conn.Close()
+	defer remove(conn, root)
+
+	create(t, conn, root+"/config", "redis:\n  server: zk:6379\n")
+
+	var conf struct {
+		Redis struct {
+			Server string
+			Port   int
+		}
+	}
+	loader := gofigure.NewLoader(yaml.Decoder{}, true)
+	loader.Sources = []gofigure.Source{Source{Conn: conn, Path: root + "/config"}}
+	if err := loader.LoadRecursive(&conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Redis.Server != "zk:6379" {
+		t.Errorf("Unexpected config %#v", conf)
+	}
+
+	loader.Sources = []gofigure.Source{Source{Conn: conn, Path: root + "/missing"}}
+	if err := loader.LoadRecursive(&conf); err == nil {
+		t.Error("Expected an error for a missing znode")
+	}
+
+	create(t, conn, root+"/tree", "")
+	create(t, conn, root+"/tree/redis", "ignored")
+	create(t, conn, root+"/tree/redis/server", "tree:6379")
+	create(t, conn, root+"/tree/redis/port", "6380")
+
+	loader.WeaklyTyped = true
+	loader.Sources = []gofigure.Source{Source{Conn: conn, Path: root + "/tree", Tree: true}}
+	if err := loader.LoadRecursive(&conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Redis.Server != "tree:6379" || conf.Redis.Port != 6380 {
+		t.Errorf("Unexpected config %#v", conf)
+	}
+}
+
+func TestMonitor(t *testing.T) {
+
+	conn, root := connect(t)
+	defer conn.Close()
+	defer remove(conn, root)
+
+	create(t, conn, root+"/redis", "")
+	create(t, conn, root+"/redis/server", "zk:6379")
+
+	reloads := make(chan bool, 10)
+	m := NewMonitor(conn, root)
+	m.Tree = true
+	var _ gofigure.ReloadMonitor = m
+	m.Watch(gofigure.ReloadFunc(func() { reloads <- true }))
+	defer m.Stop()
+
+	expectReload := func(what string) {
+		select {
+		case <-reloads:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s did not trigger a reload", what)
+		}
+	}
+
+	// give the watches time to be set
+	time.Sleep(100 * time.Millisecond)
+	if _, err := conn.Set(root+"/redis/server", []byte("zk:6380"), -1); err != nil {
+		t.Fatal(err)
+	}
+	expectReload("Changing a znode")
+
+	time.Sleep(100 * time.Millisecond)
+	create(t, conn, root+"/redis/port", "6380")
+	expectReload("Adding a znode")
+
+	select {
+	case <-reloads:
+		t.Fatal("Unexpected reload")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
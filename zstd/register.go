@@ -0,0 +1,9 @@
+//go:build !gofigure_noregister
+
+package zstd
+
+import "github.com/EverythingMe/gofigure"
+
+func init() {
+	gofigure.RegisterCompression(".zst", Decompress)
+}
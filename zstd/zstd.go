@@ -0,0 +1,18 @@
+// Package zstd lets gofigure loaders read zstd compressed config files, like "conf.json.zst".
+// Importing it registers the ".zst" extension, see gofigure.RegisterCompression.
+package zstd
+
+import (
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Decompress returns a reader of the decompressed contents of r. It's a gofigure.Decompressor
+func Decompress(r io.Reader) (io.ReadCloser, error) {
+	d, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	return d.IOReadCloser(), nil
+}
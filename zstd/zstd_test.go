@@ -0,0 +1,49 @@
+package zstd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/EverythingMe/gofigure"
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestLoadCompressed(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gofigure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	enc, err := zstd.NewWriter(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := enc.EncodeAll([]byte(`{"name": "generated", "size": 3}`), nil)
+	if err := ioutil.WriteFile(filepath.Join(dir, "conf.yaml.zst"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "broken.yaml.zst"), []byte("not zstd"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var conf struct {
+		Name string
+		Size int
+	}
+	loader := *gofigure.DefaultLoader
+	if err := loader.LoadRecursive(&conf, dir); err == nil {
+		t.Error("Expected an error for a corrupt file in strict mode")
+	}
+
+	loader.StrictMode = false
+	if err := loader.LoadRecursive(&conf, dir); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Name != "generated" || conf.Size != 3 {
+		t.Errorf("Unexpected config: %#v", conf)
+	}
+}